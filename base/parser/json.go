@@ -2,6 +2,8 @@ package parser
 
 import (
 	"encoding/json"
+	"fmt"
+	"io/fs"
 	"os"
 
 	"stellarsky.ai/platform/codegen/data-service-generator/base"
@@ -46,6 +48,25 @@ func ReadJsonToSlice[T any](filename string, key string) ([]T, error) {
 	return t[key], nil
 }
 
+// ReadJsonToSliceFS is ReadJsonToSlice reading from a file system (os.DirFS, embed.FS, ...)
+func ReadJsonToSliceFS[T any](fsys fs.FS, filename string, key string) ([]T, error) {
+	data, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		base.LOG.Error("Error reading json file", "error", err, "filename", filename)
+		return nil, err
+	}
+	t := make(map[string][]T)
+	err = json.Unmarshal(data, &t)
+	if err != nil {
+		base.LOG.Error("Error parsing json", "error", err, "filename", filename)
+		return nil, err
+	}
+	if _, ok := t[key]; !ok {
+		return nil, fmt.Errorf("%s: missing key %q", filename, key)
+	}
+	return t[key], nil
+}
+
 func MustReadJsonToSlice[T any](filename string, key string) []T {
 	t, err := ReadJsonToSlice[T](filename, key)
 	if err != nil {
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"stellarsky.ai/platform/codegen/data-service-generator/base"
+	"stellarsky.ai/platform/codegen/data-service-generator/base/parser"
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/importer"
+	"stellarsky.ai/platform/codegen/data-service-generator/server"
+)
+
+// Module name of the generated data access package, see generator.Generate
+const generatedModuleName = "database"
+
+type commonFlags struct {
+	configPath    string
+	appConfigPath string
+	catalogDir    string
+	verbose       bool
+}
+
+func newFlagSet(name string, common *commonFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet("dsgen "+name, flag.ContinueOnError)
+	fs.StringVar(&common.configPath, "config", "", "path to the data config YAML (family_name, models, connection_config)")
+	fs.StringVar(&common.appConfigPath, "app-config", "", "generator config.yaml (default: $"+config.EnvConfigFile+", ./config.yaml or ./config/config.yaml)")
+	fs.StringVar(&common.catalogDir, "catalog", "", "directory of the type/attribute catalog (default: $"+config.EnvCatalogDir+", model.baseDir of the generator config, or the built-in catalog)")
+	fs.BoolVar(&common.verbose, "v", false, "verbose generator logs on stderr")
+	return fs
+}
+
+// loadDataConfig parses flags, loads the application config with the attribute/type catalog and the data config, and
+// validates it
+func loadDataConfig(fs *flag.FlagSet, common *commonFlags, args []string) (*config.Config, *defs.DataConfig, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if common.configPath == "" {
+		return nil, nil, fmt.Errorf("-config is required")
+	}
+	base.LOG = setupLogging(common.verbose, os.Stderr)
+
+	appConfig, err := config.Load(config.LoadOptions{ConfigFile: common.appConfigPath, CatalogDir: common.catalogDir})
+	if err != nil {
+		return nil, nil, err
+	}
+	dataConfig, err := parser.ReadYamlTo[defs.DataConfig](common.configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", common.configPath, err)
+	}
+	if err := generator.ValidateDataConfig(dataConfig); err != nil {
+		return nil, nil, fmt.Errorf("invalid data config %s:\n%w", common.configPath, err)
+	}
+	return appConfig, dataConfig, nil
+}
+
+func runGenerate(args []string, stdout io.Writer) error {
+	common := &commonFlags{}
+	fs := newFlagSet("generate", common)
+	outDir := fs.String("out", "", "output directory of the generated Go module")
+	moduleName := fs.String("module", "", "Go module path of the generated module (default: snake_case family name)")
+	goVersion := fs.String("go", "1.22", "go version written to the generated go.mod")
+
+	_, dataConfig, err := loadDataConfig(fs, common, args)
+	if err != nil {
+		return err
+	}
+	if *outDir == "" {
+		return fmt.Errorf("-out is required")
+	}
+	if *moduleName == "" {
+		*moduleName = golang.ToSnakeCase(dataConfig.FamilyName)
+	}
+
+	unitModules, err := generator.GenerateDB(dataConfig)
+	if err != nil {
+		return fmt.Errorf("generating family %s: %w", dataConfig.FamilyName, err)
+	}
+	// Driver required by the generated code, imported by SetupDBConnection
+	driver, err := generator.DriverRequirement(dataConfig)
+	if err != nil {
+		return err
+	}
+
+	// Migrations are history, a regenerated module keeps them and gets new ones from dsgen diff -migrations
+	migrationsDir := filepath.Join(*outDir, generatedModuleName, generator.MigrationsDir)
+	existing, err := migrationFileNames(migrationsDir)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		for _, unit := range unitModules {
+			unit.Files = nil
+		}
+		fmt.Fprintf(stdout, "keeping %d existing migration files in %s\n", len(existing), migrationsDir)
+	}
+
+	project := &golang.Project{
+		Name:         *moduleName,
+		GoVersion:    *goVersion,
+		Modules:      []*golang.Module{{Name: generatedModuleName, Units: unitModules}},
+		Requirements: []*golang.ProjectRequirement{driver},
+	}
+	if err := project.GenerateProject(*outDir); err != nil {
+		return fmt.Errorf("writing project to %s: %w", *outDir, err)
+	}
+
+	fmt.Fprintf(stdout, "generated %d models of %s into %s\n", len(dataConfig.Models), dataConfig.FamilyName,
+		filepath.Join(*outDir, generatedModuleName))
+	return nil
+}
+
+func runDDL(args []string, stdout io.Writer) error {
+	common := &commonFlags{}
+	fs := newFlagSet("ddl", common)
+	outFile := fs.String("out", "", "write the DDL to this file instead of stdout")
+
+	_, dataConfig, err := loadDataConfig(fs, common, args)
+	if err != nil {
+		return err
+	}
+
+	dialect, err := datahelpers.DialectForDriver(dataConfig.DatabaseConfig.DriverName)
+	if err != nil {
+		return err
+	}
+	schemaBuilder := datahelpers.NewSchemaBuilder(dialect, dataConfig.DatabaseConfig.DBName, *dataConfig)
+	// Tables are written after the tables they reference
+	models, err := dataConfig.ModelsInReferenceOrder()
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	for _, model := range models {
+		sb.WriteString(schemaBuilder.BuildCreateTable(model))
+		sb.WriteString("\n")
+	}
+	if dataConfig.HasEvents() {
+		sb.WriteString(schemaBuilder.BuildCreateOutboxTable())
+		sb.WriteString("\n\n")
+	}
+
+	if *outFile == "" {
+		_, err = io.WriteString(stdout, sb.String())
+		return err
+	}
+	return os.WriteFile(*outFile, []byte(sb.String()), 0644)
+}
+
+func runDiff(args []string, stdout io.Writer) error {
+	common := &commonFlags{}
+	fs := newFlagSet("diff", common)
+	fromPath := fs.String("from", "", "previous data config YAML, the migration goes from it to -config")
+	fromCatalogDir := fs.String("from-catalog", "", "directory of the catalog -from was written against (default: the catalog of -config)")
+	migrationsDir := fs.String("migrations", "", "write the migration as the next <version>_<name>.up.sql/.down.sql pair into this directory instead of stdout")
+	name := fs.String("name", "", "name of the migration written with -migrations")
+
+	appConfig, dataConfig, err := loadDataConfig(fs, common, args)
+	if err != nil {
+		return err
+	}
+	if *fromPath == "" {
+		return fmt.Errorf("-from is required")
+	}
+	if *migrationsDir != "" && *name == "" {
+		return fmt.Errorf("-name is required with -migrations")
+	}
+	fromConfig, err := parser.ReadYamlTo[defs.DataConfig](*fromPath)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", *fromPath, err)
+	}
+	from := datahelpers.SchemaSnapshot{DataConfig: *fromConfig}
+	if *fromCatalogDir != "" {
+		catalog, err := config.LoadCatalog(os.DirFS(*fromCatalogDir), appConfig.Model)
+		if err != nil {
+			return fmt.Errorf("catalog %s: %w", *fromCatalogDir, err)
+		}
+		from.Attributes = catalog.Attributes
+	}
+
+	dialect, err := datahelpers.DialectForDriver(dataConfig.DatabaseConfig.DriverName)
+	if err != nil {
+		return err
+	}
+	migration, err := datahelpers.DiffSchemas(dialect, from, datahelpers.SchemaSnapshot{DataConfig: *dataConfig})
+	if err != nil {
+		return err
+	}
+	if migration.IsEmpty() {
+		fmt.Fprintf(stdout, "-- no schema changes from %s to %s\n", *fromPath, common.configPath)
+		return nil
+	}
+	if *migrationsDir == "" {
+		_, err = fmt.Fprintf(stdout, "-- up\n%s\n-- down\n%s", migration.UpSQL(), migration.DownSQL())
+		return err
+	}
+
+	existing, err := migrationFileNames(*migrationsDir)
+	if err != nil {
+		return err
+	}
+	for _, file := range generator.MigrationFiles(generator.NextMigrationVersion(existing), *name, migration) {
+		filePath := filepath.Join(*migrationsDir, path.Base(file.Path))
+		if err := os.WriteFile(filePath, []byte(file.Content), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "wrote %s\n", filePath)
+	}
+	return nil
+}
+
+// migrationFileNames lists the files of a migrations directory, none when it doesn't exist yet
+func migrationFileNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".sql") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func runImport(args []string, stdout io.Writer) error {
+	common := &commonFlags{}
+	fs := newFlagSet("import", common)
+	ddlPath := fs.String("ddl", "", "pg_dump schema output (pg_dump --schema-only) to import")
+	informationSchemaPath := fs.String("information-schema", "", "information_schema snapshot JSON to import, see -print-query")
+	printQuery := fs.Bool("print-query", false, "print the query that exports the information_schema snapshot and exit")
+	familyName := fs.String("family", "", "family_name of the imported data config")
+	namespace := fs.String("namespace", "", "namespace of the models and new attributes (default: the snake cased -family)")
+	firstAttributeID := fs.Int64("first-attribute-id", 0, "id of the first new attribute (default: after the highest catalog attribute id)")
+	firstModelID := fs.Int("first-model-id", 0, "id of the model of the first table (default: 1)")
+	outFile := fs.String("out", "", "write the YAML to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *printQuery {
+		_, err := fmt.Fprintln(stdout, importer.InformationSchemaQuery)
+		return err
+	}
+	if (*ddlPath == "") == (*informationSchemaPath == "") {
+		return fmt.Errorf("one of -ddl or -information-schema is required")
+	}
+	if *familyName == "" {
+		return fmt.Errorf("-family is required")
+	}
+	base.LOG = setupLogging(common.verbose, os.Stderr)
+	if _, err := config.Load(config.LoadOptions{ConfigFile: common.appConfigPath, CatalogDir: common.catalogDir}); err != nil {
+		return err
+	}
+
+	var schema *importer.Schema
+	if *ddlPath != "" {
+		ddl, err := os.ReadFile(*ddlPath)
+		if err != nil {
+			return err
+		}
+		if schema, err = importer.ParseDDL(string(ddl)); err != nil {
+			return fmt.Errorf("reading %s:\n%w", *ddlPath, err)
+		}
+	} else {
+		snapshot, err := os.ReadFile(*informationSchemaPath)
+		if err != nil {
+			return err
+		}
+		if schema, err = importer.ParseInformationSchema(snapshot); err != nil {
+			return fmt.Errorf("reading %s:\n%w", *informationSchemaPath, err)
+		}
+	}
+	result, err := importer.Import(schema, importer.Options{
+		FamilyName:       *familyName,
+		Namespace:        *namespace,
+		FirstAttributeID: *firstAttributeID,
+		FirstModelID:     *firstModelID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Warnings go on top of the YAML, so that they are seen when the imported config is reviewed
+	var sb strings.Builder
+	sb.WriteString("# Imported by dsgen import. Add the new attributes to the catalog (attributes.json)\n")
+	sb.WriteString("# and a connection_config before generating, types are the catalog types they use.\n")
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(&sb, "# warning: %s\n", warning)
+	}
+	encoder := yaml.NewEncoder(&sb)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(result); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	if *outFile == "" {
+		_, err = io.WriteString(stdout, sb.String())
+		return err
+	}
+	return os.WriteFile(*outFile, []byte(sb.String()), 0644)
+}
+
+func runValidate(args []string, stdout io.Writer) error {
+	common := &commonFlags{}
+	fs := newFlagSet("validate", common)
+
+	_, dataConfig, err := loadDataConfig(fs, common, args)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s: ok (%d models)\n", common.configPath, len(dataConfig.Models))
+	return nil
+}
+
+func runExplain(args []string, stdout io.Writer) error {
+	common := &commonFlags{}
+	fs := newFlagSet("explain", common)
+
+	_, dataConfig, err := loadDataConfig(fs, common, args)
+	if err != nil {
+		return err
+	}
+
+	for _, modelConfig := range dataConfig.Models {
+		queries, err := generator.ExplainModel(modelConfig, dataConfig)
+		if err != nil {
+			return fmt.Errorf("model %s: %w", modelConfig.Model.Name, err)
+		}
+		fmt.Fprintf(stdout, "model %s (table %s)\n", modelConfig.Model.Name, golang.ToSnakeCase(modelConfig.Model.Name))
+		for _, query := range queries {
+			fmt.Fprintf(stdout, "  %s\n    %s\n", query.Name, query.Query)
+		}
+	}
+	return nil
+}
+
+func runServe(args []string, stdout io.Writer) error {
+	common := &commonFlags{}
+	fs := newFlagSet("serve", common)
+	addr := fs.String("addr", ":8080", "listen address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	base.LOG = setupLogging(common.verbose, os.Stderr)
+	if _, err := config.Load(config.LoadOptions{ConfigFile: common.appConfigPath, CatalogDir: common.catalogDir}); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/generate-sql", server.GenerateSQLHandler)
+	fmt.Fprintf(stdout, "serving on %s\n", *addr)
+	return http.ListenAndServe(*addr, mux)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+)
+
+// The example data config, written against the embedded catalog
+const exampleConfig = "../../db/data/ecommerce_db.yaml"
+
+// withEmbeddedCatalog keeps the catalog of the environment out of a test, there is no config.yaml in cmd/dsgen
+func withEmbeddedCatalog(t *testing.T) {
+	t.Setenv(config.EnvConfigFile, "")
+	t.Setenv(config.EnvCatalogDir, "")
+}
+
+func TestValidateExampleConfig(t *testing.T) {
+	withEmbeddedCatalog(t)
+	var stdout bytes.Buffer
+	assert.NoError(t, runValidate([]string{"-config", exampleConfig}, &stdout))
+	assert.Equal(t, exampleConfig+": ok (2 models)\n", stdout.String())
+}
+
+func TestValidateInvalidConfig(t *testing.T) {
+	withEmbeddedCatalog(t)
+	configPath := filepath.Join(t.TempDir(), "invalid.yaml")
+	invalid := strings.Replace(readExampleConfig(t), "attributes: [2000007, 2000008", "attributes: [99, 2000008", 1)
+	assert.NoError(t, os.WriteFile(configPath, []byte(invalid), 0644))
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, run([]string{"validate", "-config", configPath}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "invalid data config "+configPath)
+	assert.Contains(t, stderr.String(), "model User: attribute 99 not found in catalog")
+	assert.Empty(t, stdout.String())
+}
+
+func TestDDLExampleConfig(t *testing.T) {
+	withEmbeddedCatalog(t)
+	var stdout bytes.Buffer
+	assert.NoError(t, runDDL([]string{"-config", exampleConfig}, &stdout))
+	ddl := stdout.String()
+	assert.Contains(t, ddl, "CREATE TABLE \"user\" (\n\t\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),")
+	assert.Contains(t, ddl, "CREATE UNIQUE INDEX ON \"user\" (\"email\");")
+	// Tables are written after the tables they reference
+	assert.Less(t, strings.Index(ddl, "CREATE TABLE \"user\""), strings.Index(ddl, "CREATE TABLE \"product\""))
+
+	// -out writes the same DDL
+	outFile := filepath.Join(t.TempDir(), "schema.sql")
+	stdout.Reset()
+	assert.NoError(t, runDDL([]string{"-config", exampleConfig, "-out", outFile}, &stdout))
+	assert.Empty(t, stdout.String())
+	written, err := os.ReadFile(outFile)
+	assert.NoError(t, err)
+	assert.Equal(t, ddl, string(written))
+}
+
+func TestDDLDialect(t *testing.T) {
+	withEmbeddedCatalog(t)
+	configPath := filepath.Join(t.TempDir(), "mysql.yaml")
+	mysql := strings.Replace(readExampleConfig(t), "driver_name: postgres", "driver_name: mysql", 1)
+	assert.NoError(t, os.WriteFile(configPath, []byte(mysql), 0644))
+
+	var stdout bytes.Buffer
+	assert.NoError(t, runDDL([]string{"-config", configPath}, &stdout))
+	assert.Contains(t, stdout.String(), "CREATE TABLE `user` (")
+}
+
+func TestDiffFromCatalog(t *testing.T) {
+	withEmbeddedCatalog(t)
+	// The catalog of -from is read with the file names of the loaded application config
+	var stdout bytes.Buffer
+	assert.NoError(t, runDiff([]string{"-config", exampleConfig, "-from", exampleConfig, "-from-catalog", "../../db"}, &stdout))
+	assert.Equal(t, "-- no schema changes from "+exampleConfig+" to "+exampleConfig+"\n", stdout.String())
+}
+
+func readExampleConfig(t *testing.T) string {
+	content, err := os.ReadFile(exampleConfig)
+	assert.NoError(t, err)
+	return string(content)
+}
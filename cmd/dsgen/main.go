@@ -0,0 +1,101 @@
+// dsgen generates a Go data access package and DDL (Postgres, MySQL or SQLite, by driver_name) from a data config
+// (defs.DataConfig YAML).
+//
+// Usage:
+//
+//	dsgen generate -config ecommerce.yaml -out ./ecommerce-db -module example.com/ecommerce-db
+//	dsgen ddl      -config ecommerce.yaml [-out schema.sql]
+//	dsgen diff     -config ecommerce.yaml -from ecommerce.v1.yaml [-from-catalog ./v1-catalog] [-migrations ./ecommerce-db/database/migrations -name add_stock]
+//	dsgen import   -ddl schema.sql -family EcommerceDB [-out ecommerce.yaml]
+//	dsgen import   -information-schema ecommerce.information_schema.json -family EcommerceDB
+//	dsgen validate -config ecommerce.yaml
+//	dsgen explain  -config ecommerce.yaml
+//	dsgen serve    -addr :8080
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+)
+
+type command struct {
+	name  string
+	short string
+	run   func(args []string, stdout io.Writer) error
+}
+
+var commands = []*command{
+	{name: "generate", short: "generate the Go data access module and go.mod into an output directory", run: runGenerate},
+	{name: "ddl", short: "print CREATE TABLE and CREATE INDEX statements for all models", run: runDDL},
+	{name: "diff", short: "print the up/down migration SQL from a previous data config (-from) to -config", run: runDiff},
+	{name: "import", short: "reverse-engineer types, attributes and models from pg_dump DDL or an information_schema snapshot", run: runImport},
+	{name: "validate", short: "check the data config against the attribute/type catalog", run: runValidate},
+	{name: "explain", short: "print every generated access function with the exact SQL it prepares", run: runExplain},
+	{name: "serve", short: "serve generation over HTTP (POST /generate-sql)", run: runServe},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: dsgen <command> [flags]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	byName := make(map[string]*command, len(commands))
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+		byName[cmd.name] = cmd
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, byName[name].short)
+	}
+	fmt.Fprintf(w, "\nRun 'dsgen <command> -h' for the flags of a command.\n")
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		if err := cmd.run(args[1:], stdout); err != nil {
+			fmt.Fprintf(stderr, "dsgen %s: %s\n", cmd.name, indentErrors(err))
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "dsgen: unknown command %q\n\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+// indentErrors puts every joined error on its own indented line
+func indentErrors(err error) string {
+	msg := err.Error()
+	if !strings.Contains(msg, "\n") {
+		return msg
+	}
+	return "\n  " + strings.ReplaceAll(msg, "\n", "\n  ")
+}
+
+// setupLogging keeps generator logs off stdout, so that ddl/explain output can be piped
+func setupLogging(verbose bool, stderr io.Writer) *slog.Logger {
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelDebug
+	}
+	return slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run(nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Usage: dsgen <command> [flags]")
+	assert.Contains(t, stderr.String(), "  validate   check the data config against the attribute/type catalog")
+	assert.Empty(t, stdout.String())
+
+	stderr.Reset()
+	assert.Equal(t, 0, run([]string{"help"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Commands:")
+
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"build"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), `dsgen: unknown command "build"`)
+}
+
+func TestRunFlagErrors(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"validate"}, "dsgen validate: -config is required"},
+		{[]string{"validate", "-config", exampleConfig, "extra"}, "dsgen validate: unexpected arguments: extra"},
+		{[]string{"ddl", "-dialect", "mysql"}, "dsgen ddl: flag provided but not defined: -dialect"},
+		{[]string{"generate", "-config", exampleConfig}, "dsgen generate: -out is required"},
+		{[]string{"diff", "-config", exampleConfig}, "dsgen diff: -from is required"},
+		{[]string{"diff", "-config", exampleConfig, "-from", exampleConfig, "-migrations", t.TempDir()},
+			"dsgen diff: -name is required with -migrations"},
+		{[]string{"import", "-family", "EcommerceDB"}, "dsgen import: one of -ddl or -information-schema is required"},
+		{[]string{"import", "-ddl", "schema.sql"}, "dsgen import: -family is required"},
+	}
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, 1, run(test.args, &stdout, &stderr))
+			assert.Contains(t, stderr.String(), test.expected)
+		})
+	}
+}
+
+func TestIndentErrors(t *testing.T) {
+	assert.Equal(t, "-config is required", indentErrors(errors.New("-config is required")))
+	assert.Equal(t, "\n  invalid data config:\n  model User: attribute 99 not found in catalog",
+		indentErrors(errors.New("invalid data config:\nmodel User: attribute 99 not found in catalog")))
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/base"
+	"stellarsky.ai/platform/codegen/data-service-generator/base/parser"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/models"
+)
+
+// Catalog is the metadata the generator works from: types, validations, type maps and attributes, keyed by id
+type Catalog struct {
+	Types            map[int64]models.TypeInfo
+	Validations      map[int64]models.Validation
+	PostgresTypeMaps map[int64]models.TypeMapping
+	MySQLTypeMaps    map[int64]models.TypeMapping
+	SQLiteTypeMaps   map[int64]models.TypeMapping
+	Attributes       map[int64]models.AttributeRow
+}
+
+// LoadCatalog reads the catalog files named in modelConfig from fsys (os.DirFS, embed.FS, ...).
+// The file paths are relative to the root of fsys, modelConfig.BaseDir is not applied here,
+// callers pick the file system root (see Load).
+func LoadCatalog(fsys fs.FS, modelConfig ModelConfig) (*Catalog, error) {
+	typesRead, err := parser.ReadJsonToSliceFS[models.TypeInfo](fsys, catalogPath(modelConfig.TypesPath), "types")
+	if err != nil {
+		return nil, fmt.Errorf("loading types: %w", err)
+	}
+	validationsRead, err := parser.ReadJsonToSliceFS[models.Validation](fsys, catalogPath(modelConfig.ValidationsPath), "validations")
+	if err != nil {
+		return nil, fmt.Errorf("loading validations: %w", err)
+	}
+	typeMapsRead, err := parser.ReadJsonToSliceFS[models.TypeMapping](fsys, catalogPath(modelConfig.PostgresTypeMapsPath), "postgres_type_mappings")
+	if err != nil {
+		return nil, fmt.Errorf("loading postgres type maps: %w", err)
+	}
+	mysqlTypeMapsRead := []models.TypeMapping{}
+	if modelConfig.MySQLTypeMapsPath != "" {
+		mysqlTypeMapsRead, err = parser.ReadJsonToSliceFS[models.TypeMapping](fsys, catalogPath(modelConfig.MySQLTypeMapsPath), "mysql_type_mappings")
+		if err != nil {
+			return nil, fmt.Errorf("loading mysql type maps: %w", err)
+		}
+	}
+	sqliteTypeMapsRead := []models.TypeMapping{}
+	if modelConfig.SQLiteTypeMapsPath != "" {
+		sqliteTypeMapsRead, err = parser.ReadJsonToSliceFS[models.TypeMapping](fsys, catalogPath(modelConfig.SQLiteTypeMapsPath), "sqlite_type_mappings")
+		if err != nil {
+			return nil, fmt.Errorf("loading sqlite type maps: %w", err)
+		}
+	}
+	attributesRead, err := parser.ReadJsonToSliceFS[models.AttributeRow](fsys, catalogPath(modelConfig.AttributesPath), "attributes")
+	if err != nil {
+		return nil, fmt.Errorf("loading attributes: %w", err)
+	}
+
+	catalog := &Catalog{
+		Types:            make(map[int64]models.TypeInfo),
+		Validations:      make(map[int64]models.Validation),
+		PostgresTypeMaps: make(map[int64]models.TypeMapping),
+		MySQLTypeMaps:    make(map[int64]models.TypeMapping),
+		SQLiteTypeMaps:   make(map[int64]models.TypeMapping),
+		Attributes:       make(map[int64]models.AttributeRow),
+	}
+	for _, t := range typesRead {
+		catalog.Types[t.ID] = t
+	}
+	for _, v := range validationsRead {
+		catalog.Validations[v.ID] = v
+	}
+	for _, t := range typeMapsRead {
+		catalog.PostgresTypeMaps[t.TypeID] = t
+	}
+	for _, t := range mysqlTypeMapsRead {
+		catalog.MySQLTypeMaps[t.TypeID] = t
+	}
+	for _, t := range sqliteTypeMapsRead {
+		catalog.SQLiteTypeMaps[t.TypeID] = t
+	}
+	for _, attribute := range attributesRead {
+		if _, ok := catalog.Types[attribute.TypeId]; !ok {
+			return nil, fmt.Errorf("attribute %d (%s) has unknown type %d", attribute.ID, attribute.Name, attribute.TypeId)
+		}
+		catalog.Attributes[attribute.ID] = attribute
+	}
+	return catalog, nil
+}
+
+// Use makes the catalog the one read by the generator (Types, Validations, type maps and Attributes)
+func (c *Catalog) Use() {
+	Types = c.Types
+	Validations = c.Validations
+	PostgresTypeMaps = c.PostgresTypeMaps
+	MySQLTypeMaps = c.MySQLTypeMaps
+	SQLiteTypeMaps = c.SQLiteTypeMaps
+	Attributes = c.Attributes
+	base.LOG.Info("Data loaded", "Types", Types, "Validations", Validations, "typeMaps", PostgresTypeMaps, "Attributes", Attributes)
+}
+
+// fs.FS paths are unrooted and slash separated, config paths are often written as "./data/x.json"
+func catalogPath(p string) string {
+	return path.Clean(p)
+}
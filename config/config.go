@@ -26,5 +26,8 @@ type ModelConfig struct {
 	TypesPath            string
 	ValidationsPath      string
 	PostgresTypeMapsPath string
-	AttributesPath       string
+	// optional, needed for MySQL and SQLite only
+	MySQLTypeMapsPath  string
+	SQLiteTypeMapsPath string
+	AttributesPath     string
 }
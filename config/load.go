@@ -2,79 +2,153 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
-	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/viper"
 	"stellarsky.ai/platform/codegen/data-service-generator/base"
-	"stellarsky.ai/platform/codegen/data-service-generator/base/parser"
+	"stellarsky.ai/platform/codegen/data-service-generator/db"
 	"stellarsky.ai/platform/codegen/data-service-generator/db/models"
 )
 
-const ProjectDir = "/Users/yugandhar/code/stellarsky.ai/platform/codegen/data-service-generator"
+const (
+	// EnvConfigFile names the config file to load, instead of searching for config.yaml
+	EnvConfigFile = "DSGEN_CONFIG"
+	// EnvCatalogDir names the directory the catalog paths (model.typesPath, ...) are relative to
+	EnvCatalogDir = "DSGEN_CATALOG_DIR"
+)
 
 var (
 	Types            map[int64]models.TypeInfo
 	Validations      map[int64]models.Validation
 	PostgresTypeMaps map[int64]models.TypeMapping
+	MySQLTypeMaps    map[int64]models.TypeMapping
+	SQLiteTypeMaps   map[int64]models.TypeMapping
 	Attributes       map[int64]models.AttributeRow
 )
 
-func readBaseData(cfg *Config) {
-	modelConfig := cfg.Model
-	projectDir := ProjectDir
-	baseDir := modelConfig.BaseDir
-	typesPath := path.Join(projectDir, baseDir, modelConfig.TypesPath)
-	validationsPath := path.Join(projectDir, baseDir, modelConfig.ValidationsPath)
-	postgresTypeMapsPath := path.Join(projectDir, baseDir, modelConfig.PostgresTypeMapsPath)
-	attributesPath := path.Join(projectDir, baseDir, modelConfig.AttributesPath)
-
-	typesRead := parser.MustReadJsonToSlice[models.TypeInfo](typesPath, "types")
-	validationsRead := parser.MustReadJsonToSlice[models.Validation](validationsPath, "validations")
-	typeMapsRead := parser.MustReadJsonToSlice[models.TypeMapping](postgresTypeMapsPath, "postgres_type_mappings")
-	attributesRead := parser.MustReadJsonToSlice[models.AttributeRow](attributesPath, "attributes")
-
-	Types = make(map[int64]models.TypeInfo)
-	for _, t := range typesRead {
-		Types[t.ID] = t
+// LoadOptions override where the config and the catalog are read from, empty fields fall back to
+// the environment (DSGEN_CONFIG, DSGEN_CATALOG_DIR) and then to the defaults described on Load
+type LoadOptions struct {
+	ConfigFile string
+	CatalogDir string
+	// CatalogFS is used as is when set, it takes precedence over CatalogDir
+	CatalogFS fs.FS
+}
+
+// Catalog paths of the embedded catalog (db.CatalogFS), used when no config file is found
+var defaultModelConfig = ModelConfig{
+	TypesPath:            "data/types.json",
+	ValidationsPath:      "data/validations.json",
+	PostgresTypeMapsPath: "postgres/data/type_maps.json",
+	MySQLTypeMapsPath:    "mysql/data/type_maps.json",
+	SQLiteTypeMapsPath:   "sqlite/data/type_maps.json",
+	AttributesPath:       "data/attributes.json",
+}
+
+// Load reads the application config and the catalog, and makes the catalog the one used by the generator.
+//
+// The config file is opts.ConfigFile, $DSGEN_CONFIG, or config.yaml searched in "." and "./config".
+// Without a config file, defaults are used along with the catalog embedded in the binary (db.CatalogFS).
+//
+// The catalog is read from opts.CatalogFS, opts.CatalogDir, $DSGEN_CATALOG_DIR, or model.baseDir of the config.
+// A relative model.baseDir is resolved against the directory of the config file, so the config works from any working directory.
+func Load(opts LoadOptions) (*Config, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	configFile := firstNonEmpty(opts.ConfigFile, os.Getenv(EnvConfigFile))
+	if configFile != "" {
+		v.SetConfigFile(configFile)
+	} else {
+		v.SetConfigName("config")
+		v.AddConfigPath(".")
+		v.AddConfigPath("./config")
+	}
+
+	usedConfigFile := ""
+	err := v.ReadInConfig()
+	var notFound viper.ConfigFileNotFoundError
+	switch {
+	case err == nil:
+		usedConfigFile = v.ConfigFileUsed()
+	case errors.As(err, &notFound):
+		base.LOG.Info("No config file found, using defaults and the embedded catalog")
+	default:
+		return nil, fmt.Errorf("reading config file: %w", err)
 	}
-	Validations = make(map[int64]models.Validation)
-	for _, v := range validationsRead {
-		Validations[v.ID] = v
+
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("unmarshaling config: %w", err)
 	}
-	PostgresTypeMaps = make(map[int64]models.TypeMapping)
-	for _, t := range typeMapsRead {
-		PostgresTypeMaps[t.TypeID] = t
+	if usedConfigFile == "" {
+		config.Model = defaultModelConfig
 	}
+	base.LOG.Info("Application Config loaded", "config", config, "file", usedConfigFile)
 
-	Attributes = make(map[int64]models.AttributeRow)
-	for _, attribute := range attributesRead {
-		Attributes[attribute.ID] = attribute
+	catalogFS, err := resolveCatalogFS(opts, &config, usedConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := LoadCatalog(catalogFS, config.Model)
+	if err != nil {
+		return nil, err
 	}
-	base.LOG.Info("Data loaded", "Types", Types, "Validations", Validations, "typeMaps", PostgresTypeMaps, "Attributes", Attributes)
+	catalog.Use()
+	return &config, nil
 }
 
-func LoadConfig() *Config {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath(path.Join(ProjectDir, "config"))
-	viper.AutomaticEnv()
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-
-	if err := viper.ReadInConfig(); err != nil {
-		base.LOG.Error("Error reading config file", "error", err)
-		os.Exit(1)
+func resolveCatalogFS(opts LoadOptions, config *Config, configFile string) (fs.FS, error) {
+	if opts.CatalogFS != nil {
+		return opts.CatalogFS, nil
+	}
+	if dir := firstNonEmpty(opts.CatalogDir, os.Getenv(EnvCatalogDir)); dir != "" {
+		return dirFS(dir)
+	}
+	if configFile == "" {
+		return db.CatalogFS, nil
 	}
 
-	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
-		base.LOG.Error("Error unmarshaling config", "error", err)
-		os.Exit(1)
+	baseDir := config.Model.BaseDir
+	if !filepath.IsAbs(baseDir) {
+		baseDir = filepath.Join(filepath.Dir(configFile), baseDir)
+	}
+	return dirFS(baseDir)
+}
+
+func dirFS(dir string) (fs.FS, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("catalog directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("catalog directory %s is not a directory", dir)
 	}
+	return os.DirFS(dir), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
 
-	base.LOG.Info("Application Config loaded", "config", config)
-	readBaseData(&config)
-	return &config
+// LoadConfig is Load with default options, it exits the process when the config or the catalog can't be read
+func LoadConfig() *Config {
+	config, err := Load(LoadOptions{})
+	if err != nil {
+		base.LOG.Error("Error loading config", "error", err)
+		os.Exit(1)
+	}
+	return config
 }
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 	"stellarsky.ai/platform/codegen/data-service-generator/base"
@@ -12,3 +15,125 @@ func TestLoadConfig(t *testing.T) {
 	assert.NotNil(t, cfg)
 	base.LOG.Info("Config loaded", "config", cfg)
 }
+
+func TestLoadWithConfigFile(t *testing.T) {
+	// Catalog paths in the config file are relative to the config file, not the working directory
+	dir := t.TempDir()
+	catalogDir := filepath.Join(dir, "catalog")
+	writeCatalog(t, catalogDir)
+	configYaml := `model:
+  baseDir: "catalog"
+  typesPath: "types.json"
+  validationsPath: "validations.json"
+  postgresTypeMapsPath: "type_maps.json"
+  attributesPath: "attributes.json"
+`
+	configFile := filepath.Join(dir, "dsgen.yaml")
+	assert.NoError(t, os.WriteFile(configFile, []byte(configYaml), 0644))
+
+	cfg, err := Load(LoadOptions{ConfigFile: configFile})
+	assert.NoError(t, err)
+	assert.Equal(t, "catalog", cfg.Model.BaseDir)
+	assert.Equal(t, "sku", Attributes[1].Name)
+	assert.Equal(t, "TEXT", PostgresTypeMaps[10].MappedType)
+}
+
+func TestLoadEmbeddedCatalog(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	cfg, err := Load(LoadOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, defaultModelConfig, cfg.Model)
+	assert.Equal(t, "sku", Attributes[2000001].Name)
+}
+
+func TestLoadErrors(t *testing.T) {
+	_, err := Load(LoadOptions{ConfigFile: filepath.Join(t.TempDir(), "missing.yaml")})
+	assert.Error(t, err)
+
+	_, err = Load(LoadOptions{CatalogDir: filepath.Join(t.TempDir(), "missing")})
+	assert.ErrorContains(t, err, "catalog directory")
+}
+
+func TestLoadCatalog(t *testing.T) {
+	modelConfig := ModelConfig{
+		TypesPath:            "./types.json",
+		ValidationsPath:      "validations.json",
+		PostgresTypeMapsPath: "type_maps.json",
+		AttributesPath:       "attributes.json",
+	}
+
+	tests := []struct {
+		name        string
+		files       map[string]string
+		expectedErr string
+	}{
+		{
+			name:  "valid",
+			files: catalogFiles,
+		},
+		{
+			name:        "missing file",
+			files:       withFile(catalogFiles, "validations.json", ""),
+			expectedErr: "loading validations",
+		},
+		{
+			name:        "missing key",
+			files:       withFile(catalogFiles, "types.json", `{"type": []}`),
+			expectedErr: `missing key "types"`,
+		},
+		{
+			name:        "unknown attribute type",
+			files:       withFile(catalogFiles, "attributes.json", `{"attributes": [{"id": 1, "name": "sku", "type_id": 99}]}`),
+			expectedErr: "attribute 1 (sku) has unknown type 99",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, content := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(content)}
+			}
+			catalog, err := LoadCatalog(fsys, modelConfig)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, catalog.Types, 1)
+			assert.Len(t, catalog.Attributes, 1)
+			assert.Equal(t, "TEXT", catalog.PostgresTypeMaps[10].MappedType)
+		})
+	}
+}
+
+var catalogFiles = map[string]string{
+	"types.json":       `{"types": [{"id": 10, "name": "text"}]}`,
+	"validations.json": `{"validations": [{"id": 20, "rule_name": "required"}]}`,
+	"type_maps.json":   `{"postgres_type_mappings": [{"type_id": 10, "mapped_type": "TEXT"}]}`,
+	"attributes.json":  `{"attributes": [{"id": 1, "name": "sku", "type_id": 10}]}`,
+}
+
+// withFile returns a copy of files with name replaced, an empty content removes the file
+func withFile(files map[string]string, name, content string) map[string]string {
+	result := map[string]string{}
+	for k, v := range files {
+		result[k] = v
+	}
+	delete(result, name)
+	if content != "" {
+		result[name] = content
+	}
+	return result
+}
+
+func writeCatalog(t *testing.T, dir string) {
+	assert.NoError(t, os.MkdirAll(dir, 0755))
+	for name, content := range catalogFiles {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+}
@@ -4,8 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"go/format"
-	"log"
 	"strings"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/base"
 )
 
 // GoType represents a type in Go with a name and an optional import source.
@@ -316,6 +317,7 @@ type GoSourceFile struct {
 	Package      string         `yaml:"package,omitempty"`
 	Variables    []*Variable    `yaml:"variables,omitempty"`
 	Constants    []*Constant    `yaml:"constants,omitempty"`
+	Types        []*TypeDef     `yaml:"types,omitempty"`
 	Structs      []*StructDef   `yaml:"structs,omitempty"`
 	Functions    []*FunctionDef `yaml:"functions,omitempty"`
 	InitFunction CodeElements   `yaml:"init,omitempty"`
@@ -326,7 +328,7 @@ type GoSourceFile struct {
 }
 
 func (s *GoSourceFile) SourceCode() (string, map[Dependency]bool, error) {
-	return GenerateGoFile(s.Package, s.Structs, s.Functions,
+	return generateGoFile(s.Package, s.Types, s.Structs, s.Functions,
 		s.Variables, s.Constants, s.InitFunction, s.MainFunction,
 		s.Imports, s.Dependencies)
 }
@@ -373,7 +375,8 @@ func gatherSources(params []*Parameter, elems CodeElements, fields []*Field, ret
 // 	return dedup
 // }
 
-// generateImports creates unique import statements from a list of import paths.
+// generateImports creates unique import statements from a list of import paths. A blank import (_ path) is left
+// out when the package is imported by name, which links it already.
 func generateImports(sources map[string]bool) string {
 	if len(sources) == 0 {
 		return ""
@@ -387,7 +390,11 @@ func generateImports(sources map[string]bool) string {
 		source = strings.TrimSpace(source)
 		importLine := fmt.Sprintf("%s\"%s\"", Indent, source)
 		if strings.HasPrefix(source, "_") {
-			importLine = fmt.Sprintf("%s _ \"%s\"", Indent, strings.TrimSpace(strings.TrimPrefix(source, "_")))
+			path := strings.TrimSpace(strings.TrimPrefix(source, "_"))
+			if sources[path] {
+				continue
+			}
+			importLine = fmt.Sprintf("%s _ \"%s\"", Indent, path)
 		}
 		importLines = append(importLines, importLine)
 	}
@@ -409,6 +416,14 @@ func formatReturnTypes(returns []string) string {
 // GenerateGoFile generates a complete Go source file including the specified package name,
 // structs, functions, and standalone functions.
 func GenerateGoFile(packageName string, structs []*StructDef, functions []*FunctionDef,
+	variables []*Variable, constants []*Constant, initFunction CodeElements, mainFunction CodeElements,
+	additionalImports []string, dependencies []Dependency) (string, map[Dependency]bool, error) {
+	return generateGoFile(packageName, nil, structs, functions, variables, constants, initFunction, mainFunction,
+		additionalImports, dependencies)
+}
+
+// generateGoFile is GenerateGoFile with type declarations, written after the constants
+func generateGoFile(packageName string, types []*TypeDef, structs []*StructDef, functions []*FunctionDef,
 	variables []*Variable, constants []*Constant, initFunction CodeElements, mainFunction CodeElements,
 	additionalImports []string, dependencies []Dependency) (string, map[Dependency]bool, error) {
 	var buffer bytes.Buffer
@@ -496,6 +511,9 @@ func GenerateGoFile(packageName string, structs []*StructDef, functions []*Funct
 	if len(constantDefinitions) > 0 {
 		buffer.WriteString(strings.Join(constantDefinitions, "\n") + "\n\n")
 	}
+	for _, t := range types {
+		buffer.WriteString(t.ToCode() + "\n\n")
+	}
 
 	// Write each struct and its methods
 	for _, def := range structDefinitions {
@@ -525,8 +543,8 @@ func GenerateGoFile(packageName string, structs []*StructDef, functions []*Funct
 
 	srcCode, err := format.Source(buffer.Bytes())
 	if err != nil {
-		log.Fatalf("Error formatting generated code: %s, buffer: %s", err, buffer.String())
-		return "", allDependencies, err
+		base.LOG.Error("Error formatting generated code", "error", err, "buffer", buffer.String())
+		return "", allDependencies, fmt.Errorf("formatting generated code of package %s: %w", packageName, err)
 	}
 	return string(srcCode), allDependencies, nil
 }
@@ -99,6 +99,21 @@ func TestFunctionCodeGeneration(t *testing.T) {
 	}
 }
 
+func TestGenerateImportsBlankImport(t *testing.T) {
+	// The driver is linked by its named import, the blank import is left out
+	imports := generateImports(map[string]bool{"github.com/lib/pq": true, "_ github.com/lib/pq": true})
+	expected := fmt.Sprintf("import (\n%s\"github.com/lib/pq\"\n)\n", Indent)
+	if imports != expected {
+		t.Errorf("Expected imports: %s, got %s", expected, imports)
+	}
+
+	imports = generateImports(map[string]bool{"_ github.com/lib/pq": true})
+	expected = fmt.Sprintf("import (\n%s _ \"github.com/lib/pq\"\n)\n", Indent)
+	if imports != expected {
+		t.Errorf("Expected imports: %s, got %s", expected, imports)
+	}
+}
+
 func TestStructCodeGeneration(t *testing.T) {
 
 	s := StructDef{
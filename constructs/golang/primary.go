@@ -287,6 +287,8 @@ type Variable struct {
 	// Value can be anything, string or array of strings or code elements
 	Values    interface{} `yaml:"val,omitempty"`
 	Variables interface{} `yaml:"var,omitempty"`
+	// Directives are written as comments right above the declaration, for example "go:embed migrations/*.sql"
+	Directives []string `yaml:"directives,omitempty"`
 }
 
 type Constant struct {
@@ -297,6 +299,13 @@ type Constant struct {
 	Variable interface{} `yaml:"var,omitempty"`
 }
 
+// TypeDef declares a named type, type CustomerID string
+type TypeDef struct {
+	Name string `yaml:"name"`
+	// Type is the underlying type
+	Type string `yaml:"type"`
+}
+
 // Supporting structs
 type Assignment struct {
 	Left  interface{} `yaml:"left"`
@@ -724,7 +733,11 @@ func (vc *Variable) ToCode() string {
 	}
 
 	names := resolveStringOrCodeElement(vc.Names, 0, ", ")
-	return fmt.Sprintf("var %s %s%s", names, typeName, valueName)
+	directives := ""
+	for _, directive := range vc.Directives {
+		directives += fmt.Sprintf("//%s\n", directive)
+	}
+	return fmt.Sprintf("%svar %s %s%s", directives, names, typeName, valueName)
 }
 
 func (c *Constant) ToCode() string {
@@ -739,6 +752,10 @@ func (c *Constant) ToCode() string {
 	return fmt.Sprintf("const %s%s = %s", varName, typeNameWithSpace, valueName)
 }
 
+func (t *TypeDef) ToCode() string {
+	return fmt.Sprintf("type %s %s", t.Name, t.Type)
+}
+
 // Implementation of ToCode for each struct
 func (a *Assignment) ToCode() string {
 	leftSide := resolveStringOrCodeElement(a.Left, 0, ", ")
@@ -1197,6 +1214,10 @@ func ReturnToCode(a interface{}) string {
 	return fmt.Sprintf("return %v", resolveStringOrCodeElement(a, 0, ", "))
 }
 
+func LiteralToCode(a interface{}) string {
+	return resolveStringOrCodeElement(a, 0, ", ")
+}
+
 func IfErrorToCode(ce CodeElements) string {
 	codePart := ce.ToCode()
 	indentedCode := IndentCode(codePart, 1)
@@ -1237,6 +1258,7 @@ func init() {
 		"to_code":   CodeElementToCode,
 		"return":    ReturnToCode,
 		"ife":       IfErrorToCode,
+		"lit":       LiteralToCode,
 	}).Parse(`
 {{define "Arithmetic"}}
 {{if .Add}}{{add .Add}}{{end}}
@@ -1310,6 +1332,7 @@ if {{template "code" .Condition}} {
 {{if .Return}}{{return .Return}}{{end}}
 {{if .MapLookup}}{{.MapLookup.ToCode}}{{end}}
 {{if .IfError}}{{ife .IfError}}{{end}}
+{{if .Literal}}{{lit .Literal}}{{end}}
 {{if .Steps}}{{range $index, $step := .Steps}}
 {{to_code $step}}{{end}}{{end}}
 {{end}}
@@ -1320,7 +1343,7 @@ if {{template "code" .Condition}} {
 func (ce *CodeElement) ToCode() string {
 	var buf bytes.Buffer
 	if err := tpl.ExecuteTemplate(&buf, "code", ce); err != nil {
-		base.LOG.Error("Template execution error: %v", err)
+		base.LOG.Error("Template execution error", "error", err)
 		panic(err)
 	}
 	return strings.Trim(buf.String(), "\n")
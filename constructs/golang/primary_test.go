@@ -840,3 +840,15 @@ func TestVariableCreation_ToCode(t *testing.T) {
 	expectedCode := `var user1 *User = nil`
 	assert.Equal(t, expectedCode, resultCode)
 }
+
+func TestVariableDirectives_ToCode(t *testing.T) {
+	v := Variable{
+		Names:      "migrationFiles",
+		Type:       "embed.FS",
+		Directives: []string{"go:embed migrations/*.sql"},
+	}
+
+	resultCode := v.ToCode()
+	expectedCode := "//go:embed migrations/*.sql\nvar migrationFiles embed.FS"
+	assert.Equal(t, expectedCode, resultCode)
+}
@@ -15,9 +15,18 @@ type UnitModule struct {
 	Functions    []*FunctionDef `yaml:"functions"`
 	Variables    []*Variable    `yaml:"variables"`
 	Constants    []*Constant    `yaml:"constants"`
+	Types        []*TypeDef     `yaml:"types"`
 	InitFunction CodeElements   `yaml:"init_fn"`
 	MainFunction CodeElements   `yaml:"main"`
 	Dependencies []Dependency   `yaml:"dependencies"`
+	// Files written next to the unit's source, like SQL scripts the unit embeds with //go:embed
+	Files []*File `yaml:"files"`
+}
+
+// File is a non Go file of a unit, Path is relative to the directory of the module
+type File struct {
+	Path    string `yaml:"path"`
+	Content string `yaml:"content"`
 }
 
 type Module struct {
@@ -94,6 +103,11 @@ func writeFile(path, data string) {
 
 // GenerateGoMod creates the go.mod file based on project configurations
 func GenerateGoMod(project Project, cleanDeps map[string]string, basePath string) {
+	writeFileFun(filepath.Join(basePath, "go.mod"), GoModContent(project, cleanDeps))
+}
+
+// GoModContent returns the go.mod of the project, cleanDeps are the dependencies collected from the generated code
+func GoModContent(project Project, cleanDeps map[string]string) string {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("module %s\n", project.Name))
 	sb.WriteString(fmt.Sprintf("\ngo %s\n", project.GoVersion))
@@ -138,8 +152,7 @@ func GenerateGoMod(project Project, cleanDeps map[string]string, basePath string
 		sb.WriteString(")\n")
 	}
 
-	// Write the go.mod file
-	writeFileFun(filepath.Join(basePath, "go.mod"), sb.String())
+	return sb.String()
 }
 
 func (u *UnitModule) GenerateCode(moduleName string) (string, map[Dependency]bool, error) {
@@ -151,17 +164,13 @@ func (u *UnitModule) GenerateCode(moduleName string) (string, map[Dependency]boo
 		Functions:    u.Functions,
 		Variables:    u.Variables,
 		Constants:    u.Constants,
+		Types:        u.Types,
 		InitFunction: u.InitFunction,
 		MainFunction: u.MainFunction,
 		Dependencies: u.Dependencies,
 	}
 
-	srcCode, deps, err := srcFile.SourceCode()
-	if err != nil {
-		log.Fatalf("UnitModule.GenerateCode Unable to generate source code: %v, module: %s", err, moduleName)
-	}
-
-	return srcCode, deps, err
+	return srcFile.SourceCode()
 }
 
 func (u *UnitModule) GenerateAndWriteCode(filePath string, moduleName string) map[Dependency]bool {
@@ -173,6 +182,9 @@ func (u *UnitModule) GenerateAndWriteCode(filePath string, moduleName string) ma
 
 	goSrcPath := filepath.Join(filePath, u.Name+".go")
 	writeFileFun(goSrcPath, srcCode)
+	for _, file := range u.Files {
+		writeFileFun(filepath.Join(filePath, filepath.FromSlash(file.Path)), file.Content)
+	}
 
 	return deps
 }
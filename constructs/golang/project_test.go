@@ -98,6 +98,9 @@ func TestUnitModule_GenerateCode(t *testing.T) {
 		Constants: []*Constant{
 			{Name: "TestConstant", Value: 123},
 		},
+		Types: []*TypeDef{
+			{Name: "TestID", Type: "string"},
+		},
 		InitFunction: CodeElements{
 			{FunctionCall: &FunctionCall{Receiver: "fmt", Function: "Println", Args: &Literal{Value: "Initializing module"}}},
 		},
@@ -122,6 +125,8 @@ var TestVariable = "test value"
 
 const TestConstant = 123
 
+type TestID string
+
 type TestStruct struct {
 	Field1 string
 	Field2 int
@@ -161,6 +166,17 @@ func main() {
 	if got, exists := mockFileData[goSrcPath2]; !exists || got != expectedCode {
 		t.Errorf("GenerateGoMod() = %v, want %v", got, expectedCode)
 	}
+
+	// Test case 3: Files are written relative to the module path
+	um = &UnitModule{
+		Name:  "testModule",
+		Files: []*File{{Path: "migrations/0001_init.up.sql", Content: "CREATE TABLE t (id INTEGER);\n"}},
+	}
+	um.GenerateAndWriteCode(modulePath, "testModule")
+	sqlPath := filepath.Join(modulePath, "migrations", "0001_init.up.sql")
+	if got, exists := mockFileData[sqlPath]; !exists || got != um.Files[0].Content {
+		t.Errorf("GenerateAndWriteCode() wrote %v to %s, want %v", got, sqlPath, um.Files[0].Content)
+	}
 }
 
 func TestModule_GenerateModuleCode(t *testing.T) {
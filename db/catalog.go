@@ -0,0 +1,10 @@
+// Package db ships the default metadata catalog (types, validations, type maps and attributes)
+// with the generator, so that it works without a checkout of this repository.
+package db
+
+import "embed"
+
+// CatalogFS holds the catalog files, paths match the model section of config/config.yaml
+//
+//go:embed data/*.json postgres/data/*.json mysql/data/*.json sqlite/data/*.json
+var CatalogFS embed.FS
@@ -2,18 +2,24 @@ package generator
 
 import (
 	"fmt"
+	"time"
 
 	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
 	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang/goutils"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
 	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
 )
 
-func lookupStmtCE(confName string, dbName, cacheName, stmtName string) *golang.MapLookup {
-	return &golang.MapLookup{
+// lookupStmtCE looks up the prepared statement of a query, bound to the transaction of the model DB in a
+// transaction, see statementFunction:
+//
+//	stmt := db.statement(ctx, "GetProductByID")
+func lookupStmtCE(confName string, dbName, stmtName string) *golang.FunctionCall {
+	return &golang.FunctionCall{
 		NewOutput: stmtName,
 		Receiver:  dbName,
-		Name:      cacheName,
-		Key:       confName,
+		Function:  statementFunctionName,
+		Args:      []string{"ctx", fmt.Sprintf("%q", confName)},
 	}
 }
 
@@ -32,8 +38,8 @@ func queryStmtCE(stmtName, valuesName, rowsName string, returnParams []*golang.P
 	return &golang.FunctionCall{
 		NewOutput: []string{rowsName, "err"},
 		Receiver:  stmtName,
-		Function:  "Query",
-		Args:      []string{fmt.Sprintf("%s...", valuesName)},
+		Function:  "QueryContext",
+		Args:      []string{"ctx", fmt.Sprintf("%s...", valuesName)},
 		ErrorHandler: &golang.ErrorHandler{
 			ErrorFunctionReturns: returnParams,
 		},
@@ -47,7 +53,7 @@ func queryStmtCE(stmtName, valuesName, rowsName string, returnParams []*golang.P
 func queryRowStmtCE(stmtName string, args []string, valuesName string, returnParams []*golang.Parameter) *golang.FunctionCall {
 	return &golang.FunctionCall{
 		NewOutput: []string{"queryErr"},
-		Receiver:  fmt.Sprintf("%s.QueryRow(%s...)", stmtName, valuesName),
+		Receiver:  fmt.Sprintf("%s.QueryRowContext(ctx, %s...)", stmtName, valuesName),
 		Function:  "Scan",
 		Args:      args,
 		ErrorHandler: &golang.ErrorHandler{
@@ -61,14 +67,66 @@ func execStmtCE(stmtName, valuesName, resultName string, returnParams []*golang.
 	return &golang.FunctionCall{
 		NewOutput: []string{resultName, "err"},
 		Receiver:  stmtName,
-		Function:  "Exec",
-		Args:      []string{fmt.Sprintf("%s...", valuesName)},
+		Function:  "ExecContext",
+		Args:      []string{"ctx", fmt.Sprintf("%s...", valuesName)},
 		ErrorHandler: &golang.ErrorHandler{
 			ErrorFunctionReturns: returnParams,
 		},
 	}
 }
 
+// timeoutUnits are the units the timeout of an access is written in, the largest dividing the timeout is used
+var timeoutUnits = []struct {
+	unit time.Duration
+	name string
+}{
+	{time.Hour, "time.Hour"},
+	{time.Minute, "time.Minute"},
+	{time.Second, "time.Second"},
+	{time.Millisecond, "time.Millisecond"},
+	{time.Microsecond, "time.Microsecond"},
+}
+
+// durationExpression is the Go expression of a duration, 2*time.Second for 2s
+func durationExpression(d time.Duration) string {
+	for _, u := range timeoutUnits {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d*%s", d/u.unit, u.name)
+		}
+	}
+	return fmt.Sprintf("time.Duration(%d)", d)
+}
+
+// withTimeout bounds the statements of an access function by the timeout of its access config, a Go duration like
+// 500ms or 2s. The context of the function is replaced by one cancelled when the timeout elapses:
+//
+//	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+//	defer cancel()
+func withTimeout(fn *golang.FunctionDef, conf *defs.AccessConfig) error {
+	if conf.Timeout == "" {
+		return nil
+	}
+	timeout, err := time.ParseDuration(conf.Timeout)
+	if err != nil {
+		return fmt.Errorf("access %s: invalid timeout: %w", conf.Name, err)
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("access %s: timeout %s is not positive", conf.Name, conf.Timeout)
+	}
+	timeoutElems := golang.CodeElements{
+		{FunctionCall: &golang.FunctionCall{
+			NewOutput: []string{"ctx", "cancel"},
+			Receiver:  "context",
+			Function:  "WithTimeout",
+			Args:      []string{"ctx", durationExpression(timeout)},
+		}},
+		{FunctionCall: &golang.FunctionCall{Function: "cancel", Defer: true}},
+	}
+	fn.Body = append(timeoutElems, fn.Body...)
+	fn.Imports = append(fn.Imports, "time")
+	return nil
+}
+
 func createVarCE(name string, typ string) *golang.Variable {
 	return &golang.Variable{
 		Names: name,
@@ -244,29 +302,38 @@ func callRowsAffectedCE(objName, resultName, errorName string, returnParams []*g
 	return callResultErrorCE(objName, "RowsAffected", []string{}, resultName, errorName, returnParams)
 }
 
-func FindCodeFunction(modelName, modelDBName, name string, attributes []string) *golang.FunctionDef {
+// FindCodeFunction generates a finder returning the found models, a page of them (see pageCE) when pagination is set
+func FindCodeFunction(modelName, modelDBName, name string, attributes []string, pagination *defs.Pagination, orderBy []defs.OrderBy,
+	sorted bool) *golang.FunctionDef {
 	resultsTypeName := fmt.Sprintf("[]%s", modelName)
-	fnReturns := typeOnlyParamsCE(resultsTypeName, "error")
-	codeElems := golang.CodeElements{
-		{
-			MapLookup: lookupStmtCE(name, "db", "preparedCache", "stmt"),
-		},
-		{
+	returnName, returnTypeName := "results", resultsTypeName
+	if pagination != nil {
+		returnName, returnTypeName = "page", "*"+pageStructName(name)
+	}
+	fnReturns := typeOnlyParamsCE(returnTypeName, "error")
+	codeElems := golang.CodeElements{}
+	if pagination != nil {
+		codeElems = append(codeElems, pageLimitCE(pagination, "requestParams"))
+	}
+	codeElems = append(codeElems, findStmtCE(name, sorted, "requestParams", fnReturns)...)
+	codeElems = append(codeElems,
+		&golang.CodeElement{
 			FunctionCall: parseParamsCE(name, "requestParams", "values", fnReturns),
 		},
-		{
+		&golang.CodeElement{
 			FunctionCall: queryStmtCE("stmt", "values", "rows", fnReturns),
 		},
-		{
+		&golang.CodeElement{
 			Variable: createVarCE("results", resultsTypeName),
 		},
-		{
+		&golang.CodeElement{
 			RepeatCond: scanResultsCE(modelName, attributes, "results"),
 		},
-		{
-			Return: []string{"results", "nil"},
-		},
+	)
+	if pagination != nil {
+		codeElems = append(codeElems, pageCE(name, pagination, orderBy, "requestParams", "results", "page", "")...)
 	}
+	codeElems = append(codeElems, &golang.CodeElement{Return: []string{returnName, "nil"}})
 
 	params := ctxDBRequestParamsCE("ctx", "db", modelDBName, name, "requestParams")
 	dependencies := []golang.Dependency{}
@@ -276,19 +343,146 @@ func FindCodeFunction(modelName, modelDBName, name string, attributes []string)
 		Parameters:   params,
 		Body:         codeElems,
 		Returns:      fnReturns,
-		Imports:      []string{"context", "database/sql", "_ github.com/lib/pq"},
+		Imports:      []string{"context", "database/sql"},
 		Dependencies: dependencies,
 	}
 
 	return fn
 }
 
+// includeQueryName is the name of the prepared query loading an included model, GetUserWithOrdersOrder
+func includeQueryName(name string, included *defs.IncludedModel) string {
+	return name + golang.ToPascalCase(included.Model)
+}
+
+// includeFieldName is the field of the result struct holding the included models, Orders
+func includeFieldName(included *defs.IncludedModel) string {
+	return golang.ToPascalCase(included.Model) + "s"
+}
+
+// FindWithIncludesCodeFunction generates a finder returning the found models along with their included children,
+// see GenerateFindWithIncludesConfigs. The children are matched to the found models by their reference column.
+// The page of a paginated finder (see pageCE) is made before the children are loaded, for the models of the page.
+// The ids of the models are bound as an array, or as a list when the dialect expands IN lists (see expandIds).
+func FindWithIncludesCodeFunction(modelName, modelDBName, name string, attributes []string, includes []*defs.IncludedModel,
+	pagination *defs.Pagination, orderBy []defs.OrderBy, sorted bool, expandIds bool) *golang.FunctionDef {
+	resultTypeName := name + "Result"
+	resultsTypeName := fmt.Sprintf("[]%s", resultTypeName)
+	returnName, returnTypeName := "results", resultsTypeName
+	if pagination != nil {
+		returnName, returnTypeName = "page", "*"+pageStructName(name)
+	}
+	fnReturns := typeOnlyParamsCE(returnTypeName, "error")
+
+	modelAttributes := make([]string, 0, len(attributes))
+	for _, attribute := range attributes {
+		modelAttributes = append(modelAttributes, modelName+"."+attribute)
+	}
+	idField := fmt.Sprintf("result.%s.Id", modelName)
+	codeElems := golang.CodeElements{}
+	if pagination != nil {
+		codeElems = append(codeElems, pageLimitCE(pagination, "requestParams"))
+	}
+	codeElems = append(codeElems, findStmtCE(name, sorted, "requestParams", fnReturns)...)
+	codeElems = append(codeElems,
+		&golang.CodeElement{
+			FunctionCall: parseParamsCE(name, "requestParams", "values", fnReturns),
+		},
+		&golang.CodeElement{
+			FunctionCall: queryStmtCE("stmt", "values", "rows", fnReturns),
+		},
+		&golang.CodeElement{
+			Variable: createVarCE("results", resultsTypeName),
+		},
+		&golang.CodeElement{
+			RepeatCond: scanResultsCE(resultTypeName, modelAttributes, "results"),
+		},
+	)
+	if pagination != nil {
+		codeElems = append(codeElems, pageCE(name, pagination, orderBy, "requestParams", "results", "page", "."+modelName)...)
+	}
+	codeElems = append(codeElems,
+		// Children are loaded for all the found models at once, and appended to the model they reference
+		&golang.CodeElement{
+			NewAssign: &golang.NewAssignment{Left: "ids", Right: "make([]string, 0, len(results))"},
+		},
+		&golang.CodeElement{
+			NewAssign: &golang.NewAssignment{Left: "positions", Right: "make(map[string]int, len(results))"},
+		},
+		&golang.CodeElement{
+			Iterate: &golang.IterateElement{
+				Variables: []string{"i", "result"},
+				RangeOn:   &golang.CodeElement{Literal: "results"},
+				Body: golang.CodeElements{
+					{FunctionCall: appendCE("ids", idField)},
+					{Assign: &golang.Assignment{Left: fmt.Sprintf("positions[%s]", idField), Right: "i"}},
+				},
+			},
+		},
+		&golang.CodeElement{
+			If: &golang.IfElement{
+				Condition: "len(ids) == 0",
+				Then:      golang.CodeElements{returnResultNilCE(returnName)},
+			},
+		},
+	)
+
+	idsArg, imports := "pq.Array(ids)", []string{"context", "database/sql", "github.com/lib/pq"}
+	if expandIds {
+		idsArg, imports = "ids", []string{"context", "database/sql"}
+	}
+	for _, included := range includes {
+		childName := golang.ToPascalCase(included.Model)
+		childVar := golang.ToCamelCase(childName)
+		stmtName, rowsName := childVar+"Stmt", childVar+"Rows"
+		reference := fmt.Sprintf("%s.%s", childVar, golang.ToPascalCase(included.Reference.Column))
+		if !included.Reference.NotNull {
+			// Never nil, the children are selected by their reference
+			reference = "*" + reference
+		}
+		field := fmt.Sprintf("results[position].%s", includeFieldName(included))
+		codeElems = append(codeElems,
+			&golang.CodeElement{FunctionCall: lookupStmtCE(includeQueryName(name, included), "db", stmtName)},
+			&golang.CodeElement{FunctionCall: &golang.FunctionCall{
+				NewOutput:        []string{rowsName, "err"},
+				Receiver:         stmtName,
+				Function:         "QueryContext",
+				Args:             []string{"ctx", idsArg},
+				ErrorHandler:     &golang.ErrorHandler{ErrorFunctionReturns: fnReturns},
+				CleanningHandler: &golang.CleanningHandler{Receiver: rowsName, Function: "Close"},
+			}},
+			&golang.CodeElement{RepeatCond: &golang.RepeatByCondition{
+				Condition: &golang.CodeElement{FunctionCall: &golang.FunctionCall{Receiver: rowsName, Function: "Next"}},
+				Body: golang.CodeElements{
+					{Variable: createVarCE(childVar, childName)},
+					{FunctionCall: scanRowCE(childVar, golang.ToPascalCaseArray(included.Attributes), rowsName)},
+					{NewAssign: &golang.NewAssignment{Left: "position", Right: fmt.Sprintf("positions[string(%s)]", reference)}},
+					{FunctionCall: appendCE(field, childVar)},
+				},
+			}},
+		)
+	}
+	codeElems = append(codeElems, returnResultNilCE(returnName))
+
+	return &golang.FunctionDef{
+		Name:         name,
+		Parameters:   ctxDBRequestParamsCE("ctx", "db", modelDBName, name, "requestParams"),
+		Body:         codeElems,
+		Returns:      fnReturns,
+		Imports:      imports,
+		Dependencies: []golang.Dependency{},
+	}
+}
+
 func UpdateCodeFunction(name string, modelDBName string) *golang.FunctionDef {
 	fnReturns := typeOnlyParamsCE("int64", "error")
 
 	codeElems := golang.CodeElements{
 		{
-			MapLookup: lookupStmtCE(name, "db", "preparedCache", "stmt"),
+			FunctionCall: validateParamsCE("requestParams", fnReturns),
+		},
+		{
+			FunctionCall: lookupStmtCE(name, "db", "stmt"),
 		},
 		{
 			FunctionCall: parseParamsCE(name, "requestParams", "values", fnReturns),
@@ -310,23 +504,28 @@ func UpdateCodeFunction(name string, modelDBName string) *golang.FunctionDef {
 		Parameters:   params,
 		Body:         codeElems,
 		Returns:      fnReturns,
-		Imports:      []string{"context", "database/sql", "_ github.com/lib/pq"},
+		Imports:      []string{"context", "database/sql"},
 		Dependencies: dependencies,
 	}
 	return fn
 }
 
-func AddCodeFunction(name string, modelDBName string) *golang.FunctionDef {
-	fnReturns := typeOnlyParamsCE("int64", "error")
+// AddCodeFunction generates the insert of an add config, returning the id of the row scanned into idType, the Go type
+// of the id column
+func AddCodeFunction(name string, modelDBName string, idType string) *golang.FunctionDef {
+	fnReturns := typeOnlyParamsCE(idType, "error")
 	codeElems := golang.CodeElements{
 		{
-			MapLookup: lookupStmtCE(name, "db", "preparedCache", "stmt"),
+			FunctionCall: validateParamsCE("requestParams", fnReturns),
+		},
+		{
+			FunctionCall: lookupStmtCE(name, "db", "stmt"),
 		},
 		{
 			FunctionCall: parseParamsCE(name, "requestParams", "values", fnReturns),
 		},
 		{
-			Variable: createVarCE("id", "int64"),
+			Variable: createVarCE("id", idType),
 		},
 		{
 			FunctionCall: queryRowStmtCE("stmt", []string{"&id"}, "values", fnReturns),
@@ -341,23 +540,28 @@ func AddCodeFunction(name string, modelDBName string) *golang.FunctionDef {
 		Parameters:   params,
 		Body:         codeElems,
 		Returns:      fnReturns,
-		Imports:      []string{"context", "database/sql", "_ github.com/lib/pq"},
+		Imports:      []string{"context", "database/sql"},
 		Dependencies: nil,
 	}
 	return fn
 }
 
-func AddOrReplaceCodeFunction(name string, modelDBName string) *golang.FunctionDef {
-	fnReturns := typeOnlyParamsCE("int64", "bool", "error")
+// AddOrReplaceCodeFunction generates the upsert of an add_or_replace config, returning the id of the row scanned into
+// idType, the Go type of the id column, and whether the row was inserted
+func AddOrReplaceCodeFunction(name string, modelDBName string, idType string) *golang.FunctionDef {
+	fnReturns := typeOnlyParamsCE(idType, "bool", "error")
 	codeElems := golang.CodeElements{
 		{
-			MapLookup: lookupStmtCE(name, "db", "preparedCache", "stmt"),
+			FunctionCall: validateParamsCE("requestParams", fnReturns),
+		},
+		{
+			FunctionCall: lookupStmtCE(name, "db", "stmt"),
 		},
 		{
 			FunctionCall: parseParamsCE(name, "requestParams", "values", fnReturns),
 		},
 		{
-			Variable: createVarCE("id", "int64"),
+			Variable: createVarCE("id", idType),
 		},
 		{
 			Variable: createVarCE("inserted", "bool"),
@@ -375,7 +579,7 @@ func AddOrReplaceCodeFunction(name string, modelDBName string) *golang.FunctionD
 		Parameters:   params,
 		Body:         codeElems,
 		Returns:      fnReturns,
-		Imports:      []string{"context", "database/sql", "_ github.com/lib/pq"},
+		Imports:      []string{"context", "database/sql"},
 		Dependencies: nil,
 	}
 	return fn
@@ -385,7 +589,10 @@ func DeleteCodeFunction(name string, modelDBName string) *golang.FunctionDef {
 	fnReturns := typeOnlyParamsCE("int64", "error")
 	codeElems := golang.CodeElements{
 		{
-			MapLookup: lookupStmtCE(name, "db", "preparedCache", "stmt"),
+			FunctionCall: validateParamsCE("requestParams", fnReturns),
+		},
+		{
+			FunctionCall: lookupStmtCE(name, "db", "stmt"),
 		},
 		{
 			FunctionCall: parseParamsCE(name, "requestParams", "values", fnReturns),
@@ -406,13 +613,25 @@ func DeleteCodeFunction(name string, modelDBName string) *golang.FunctionDef {
 		Parameters:   params,
 		Body:         codeElems,
 		Returns:      fnReturns,
-		Imports:      []string{"context", "database/sql", "_ github.com/lib/pq"},
+		Imports:      []string{"context", "database/sql"},
 		Dependencies: nil,
 	}
 	return fn
 }
 
-func ReadParamsFunction(paramRefs []defs.ParameterRef, confName string,
+// ReadParamsFunction generates the function binding the params of an access config to the placeholders of its query,
+// the params of IN filters are bound as arrays, or as lists the dialect expands (see defs.ParameterRef.Expand),
+// the params of BETWEEN filters bind From and To, page cursors are decoded and page limits bind one more row (see
+// datahelpers.MakeFindQuery):
+//
+//	func GetOrderByStatusReadParams(params GetOrderByStatusParams) ([]interface{}, error) {
+//		var values []interface{}
+//		values = append(values, pq.Array(params.Statuses))
+//		values = append(values, params.DateRange.From)
+//		values = append(values, params.DateRange.To)
+//		return values, nil
+//	}
+func ReadParamsFunction(paramRefs []defs.ParameterRef, params map[string]*accessParam, confName string,
 	valuesName string, paramsName string) *golang.FunctionDef {
 	body := golang.CodeElements{
 		{
@@ -420,10 +639,31 @@ func ReadParamsFunction(paramRefs []defs.ParameterRef, confName string,
 		},
 	}
 
+	imports := []string{}
 	for _, paramRef := range paramRefs {
 		paramArg := fmt.Sprintf("%s.%s", paramsName, golang.ToPascalCase(paramRef.Name))
-		if paramRef.Index != -1 {
-			paramArg = fmt.Sprintf("%s.([]interface{})[%d]", paramArg, paramRef.Index)
+		switch {
+		case paramRef.Index == 0:
+			paramArg += ".From"
+		case paramRef.Index == 1:
+			paramArg += ".To"
+		case params[paramRef.Name].PageLimit:
+			paramArg += " + 1"
+		case params[paramRef.Name].Operator == datahelpers.OperatorIn && !paramRef.Expand:
+			paramArg = fmt.Sprintf("pq.Array(%s)", paramArg)
+			imports = append(imports, "github.com/lib/pq")
+		case params[paramRef.Name].Decode != "":
+			decoded := golang.ToCamelCase(paramRef.Name)
+			body = append(body,
+				&golang.CodeElement{NewAssign: &golang.NewAssignment{
+					Left:  []string{decoded, "err"},
+					Right: fmt.Sprintf("%s(%s)", params[paramRef.Name].Decode, paramArg),
+				}},
+				&golang.CodeElement{If: &golang.IfElement{
+					Condition: "err != nil",
+					Then:      golang.CodeElements{returnValuesCE("nil", "err")},
+				}})
+			paramArg = decoded
 		}
 		body = append(body, &golang.CodeElement{
 			FunctionCall: appendCE(valuesName, paramArg),
@@ -448,12 +688,25 @@ func ReadParamsFunction(paramRefs []defs.ParameterRef, confName string,
 		Parameters:   fnParams,
 		Body:         body,
 		Returns:      fnReturns,
+		Imports:      imports,
 		Dependencies: nil,
 	}
 
 	return fn
 }
 
+// validateParamsCE returns the failed validations of the request params before the statement executes
+func validateParamsCE(requestParamsName string, returnParams []*golang.Parameter) *golang.FunctionCall {
+	return &golang.FunctionCall{
+		NewOutput: []string{"err"},
+		Receiver:  requestParamsName,
+		Function:  "Validate",
+		ErrorHandler: &golang.ErrorHandler{
+			ErrorFunctionReturns: returnParams,
+		},
+	}
+}
+
 func makeNewMapCE(name string, mapType string) *golang.FunctionCall {
 	return &golang.FunctionCall{
 		NewOutput: name,
@@ -483,6 +736,11 @@ func prepareStmtCE(dbVar string, query string, mapName string, indexName string,
 type NamedQuery struct {
 	Name  string
 	Query string
+	// Expression is a Go expression making the query at run time, prepared instead of Query when set
+	Expression string
+	// Expand tells which placeholders of the query are IN lists bound element by element, see inListExpansion.
+	// A query with lists to expand is not prepared, it runs as an inListStmt, see inListStmtsVariable.
+	Expand []bool
 }
 
 // Generates function to prepare statements
@@ -500,9 +758,16 @@ func PrepareStmtsFunction(modelName string, queries []NamedQuery) *golang.Functi
 	}
 
 	for _, namedQuery := range queries {
-		body = append(body, &golang.CodeElement{
-			FunctionCall: prepareStmtCE("db", namedQuery.Query, "preparedCache", namedQuery.Name, returnFn),
-		})
+		if namedQuery.Expand != nil {
+			continue
+		}
+		prepareCall := prepareStmtCE("db", namedQuery.Query, "preparedCache", namedQuery.Name, returnFn)
+		// The identifiers of the query are quoted by the dialect
+		prepareCall.Args = []string{fmt.Sprintf("%q", namedQuery.Query)}
+		if namedQuery.Expression != "" {
+			prepareCall.Args = []string{namedQuery.Expression}
+		}
+		body = append(body, &golang.CodeElement{FunctionCall: prepareCall})
 	}
 
 	body = append(body, returnResultNilCE("preparedCache"))
@@ -523,6 +788,77 @@ func PrepareStmtsFunction(modelName string, queries []NamedQuery) *golang.Functi
 
 }
 
+// sqlDriver is the database/sql driver the generated code opens the database of a dialect with
+type sqlDriver struct {
+	// Name the driver registers with sql.Register
+	name string
+	// Module of the driver, imported for its registration
+	requirement golang.ProjectRequirement
+	// Data source name of a database config
+	dsn func(dbConf *defs.DatabaseConfig) string
+	// Whether the driver logs in to a database server (user_name, password, host and port), a SQLite database is the
+	// file db_name
+	server bool
+}
+
+// sqlDrivers are the drivers of the dialects by dialect name, a DatabaseConfig.DriverName of the dialect, like pgx,
+// opens the database with the driver of its dialect
+var sqlDrivers = map[string]*sqlDriver{
+	"postgres": {
+		name:        "postgres",
+		requirement: golang.ProjectRequirement{Name: "github.com/lib/pq", Version: "v1.12.3"},
+		dsn: func(dbConf *defs.DatabaseConfig) string {
+			return fmt.Sprintf("user=%s password=%s dbname=%s port=%d host=%s",
+				dbConf.UserName, dbConf.Password, dbConf.DBName, dbConf.Port, dbConf.Host)
+		},
+		server: true,
+	},
+	// Timestamps are scanned into time.Time with parseTime, and migrations run many statements in one Exec
+	"mysql": {
+		name:        "mysql",
+		requirement: golang.ProjectRequirement{Name: "github.com/go-sql-driver/mysql", Version: "v1.8.1"},
+		dsn: func(dbConf *defs.DatabaseConfig) string {
+			return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&multiStatements=true",
+				dbConf.UserName, dbConf.Password, dbConf.Host, dbConf.Port, dbConf.DBName)
+		},
+		server: true,
+	},
+	// SQLite enforces foreign keys when enabled per connection
+	"sqlite": {
+		name:        "sqlite3",
+		requirement: golang.ProjectRequirement{Name: "github.com/mattn/go-sqlite3", Version: "v1.14.22"},
+		dsn: func(dbConf *defs.DatabaseConfig) string {
+			return fmt.Sprintf("file:%s?_foreign_keys=on", dbConf.DBName)
+		},
+	},
+}
+
+// driverForConfig returns the driver of the dialect of a database config
+func driverForConfig(dbConf *defs.DatabaseConfig) (*sqlDriver, error) {
+	dialect, err := datahelpers.DialectForDriver(dbConf.DriverName)
+	if err != nil {
+		return nil, err
+	}
+	driver, ok := sqlDrivers[dialect.GetName()]
+	if !ok {
+		return nil, fmt.Errorf("no database/sql driver for dialect %s", dialect.GetName())
+	}
+	return driver, nil
+}
+
+// DriverRequirement is the module of the driver the generated code of a data config imports, see SetupDBConnectionFunction
+func DriverRequirement(dataConf *defs.DataConfig) (*golang.ProjectRequirement, error) {
+	if dataConf.DatabaseConfig == nil {
+		return nil, fmt.Errorf("dataconf is missing connection config")
+	}
+	driver, err := driverForConfig(dataConf.DatabaseConfig)
+	if err != nil {
+		return nil, err
+	}
+	requirement := driver.requirement
+	return &requirement, nil
+}
+
 // Generates function to setup database connection
 // func SetupDBConnection() (*sql.DB, error) {...}
 // It'll
@@ -541,7 +877,12 @@ func SetupDBConnectionFunction(dataConf *defs.DataConfig) (*golang.FunctionDef,
 		return nil, fmt.Errorf("dataconf is missing driver")
 	}
 
-	if dbConf.DBName == "" || dbConf.Host == "" || dbConf.Port == 0 || dbConf.UserName == "" || dbConf.Password == "" {
+	driver, err := driverForConfig(dbConf)
+	if err != nil {
+		return nil, err
+	}
+
+	if dbConf.DBName == "" || driver.server && (dbConf.Host == "" || dbConf.Port == 0 || dbConf.UserName == "" || dbConf.Password == "") {
 		return nil, fmt.Errorf("dataconf is missing connection details dbname: [%s], host: [%s], port: [%d], username: [%s], password: [%s]",
 			dbConf.DBName, dbConf.Host, dbConf.Port, dbConf.UserName, dbConf.Password)
 	}
@@ -554,20 +895,18 @@ func SetupDBConnectionFunction(dataConf *defs.DataConfig) (*golang.FunctionDef,
 		return nil, fmt.Errorf("dataconf is missing connection pool config")
 	}
 
-	dsn := fmt.Sprintf("user=%s password=%s dbname=%s port=%d host=%s",
-		dbConf.UserName, dbConf.Password, dbConf.DBName, dbConf.Port, dbConf.Host)
 	fn := golang.FunctionDef{}
 	fn.FunctionCode()
 	returnParams := typeOnlyParamsCE("*sql.DB", "error")
 	return &golang.FunctionDef{
 		Name:    "SetupDBConnection",
-		Imports: []string{"database/sql", "_ github.com/lib/pq", "time"},
+		Imports: []string{"database/sql", "_ " + driver.requirement.Name, "time"},
 		Returns: returnParams,
 		Body: golang.CodeElements{
 			{
 				NewAssign: &golang.NewAssignment{
 					Left:  []string{"driverName", "dsn"},
-					Right: golang.NewLits(dbConf.DriverName, dsn),
+					Right: golang.NewLits(driver.name, driver.dsn(dbConf)),
 				},
 			},
 			{
@@ -628,7 +967,7 @@ func GenerateInitFamilyFunction(modelNameMaps modelNameMappings, varName, family
 		body = append(body, &golang.CodeElement{StructCreation: modelDBStruct})
 	}
 
-	familyKeyValues := golang.KeyValues{}
+	familyKeyValues := golang.KeyValues{{Key: "db", Variable: "db"}}
 	for _, modelNameMap := range modelNameMaps {
 		familyKeyValues = append(familyKeyValues,
 			&golang.KeyValue{Key: modelNameMap.ModelStructName, Variable: golang.ToCamelCase(modelNameMap.ModelStructName)})
@@ -2,8 +2,10 @@ package generator
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
 	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
 )
 
@@ -12,13 +14,13 @@ func TestFindCodeFunction(t *testing.T) {
 	name := "FindUser"
 	attributes := []string{"id", "name"}
 
-	expectedFnCode := `func FindUser(ctx context.Context, db *User_DB, requestParams FindUserParams) (results []User, err error) {
-	stmt := db.preparedCache["FindUser"]
-	values, err := FindUserParseParams(requestParams)
+	expectedFnCode := `func FindUser(ctx context.Context, db *User_DB, requestParams FindUserParams) ([]User, error) {
+	stmt := db.statement(ctx, "FindUser")
+	values, err := FindUserReadParams(requestParams)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := stmt.Query(values...)
+	rows, err := stmt.QueryContext(ctx, values...)
 	if err != nil {
 		return nil, err
 	}
@@ -35,8 +37,8 @@ func TestFindCodeFunction(t *testing.T) {
 	return results, nil
 }`
 
-	expectedImports := map[string]bool{}
-	fn := FindCodeFunction(modelName, "User_DB", name, attributes)
+	expectedImports := map[string]bool{"context": true, "database/sql": true}
+	fn := FindCodeFunction(modelName, "User_DB", name, attributes, nil, nil, false)
 	fnCode, fnImports := fn.FunctionCode()
 	assert.Equal(t, expectedFnCode, fnCode)
 	assert.Equal(t, expectedImports, fnImports)
@@ -51,12 +53,16 @@ func TestUpdateCodeFunction(t *testing.T) {
 	name := "UpdateUser"
 
 	expectedFnCode := `func UpdateUser(ctx context.Context, db *User_DB, requestParams UpdateUserParams) (int64, error) {
-	stmt := db.preparedCache["UpdateUser"]
-	values, err := UpdateUserParseParams(requestParams)
+	err := requestParams.Validate()
 	if err != nil {
 		return int64(0), err
 	}
-	result, err := stmt.Exec(values...)
+	stmt := db.statement(ctx, "UpdateUser")
+	values, err := UpdateUserReadParams(requestParams)
+	if err != nil {
+		return int64(0), err
+	}
+	result, err := stmt.ExecContext(ctx, values...)
 	if err != nil {
 		return int64(0), err
 	}
@@ -67,14 +73,14 @@ func TestUpdateCodeFunction(t *testing.T) {
 	return rowsAffected, nil
 }`
 
-	expectedImports := map[string]bool{}
+	expectedImports := map[string]bool{"context": true, "database/sql": true}
 	fn := UpdateCodeFunction("UpdateUser", "User_DB")
 	fnCode, fnImports := fn.FunctionCode()
 	assert.Equal(t, expectedFnCode, fnCode)
 	assert.Equal(t, expectedImports, fnImports)
 	assert.Equal(t, name, fn.Name)
 	assert.Equal(t, 3, len(fn.Parameters)) // Assuming ctx, db, requestParams
-	assert.Equal(t, 5, len(fn.Body))       // Assuming 6 code elements in the body
+	assert.Equal(t, 6, len(fn.Body))       // Assuming 6 code elements in the body
 	assert.Equal(t, 2, len(fn.Returns))
 	assert.Equal(t, 0, len(fn.Dependencies))
 }
@@ -82,29 +88,33 @@ func TestUpdateCodeFunction(t *testing.T) {
 func TestAddCodeFunction(t *testing.T) {
 	name := "AddUser"
 
-	expectedFnCode := `func AddUser(ctx context.Context, db *User_DB, requestParams AddUserParams) (int64, error) {
-	stmt := db.preparedCache["AddUser"]
-	values, err := AddUserParseParams(requestParams)
+	expectedFnCode := `func AddUser(ctx context.Context, db *User_DB, requestParams AddUserParams) (string, error) {
+	err := requestParams.Validate()
 	if err != nil {
-		return int64(0), err
+		return "", err
 	}
-	var id int64
-	queryErr := stmt.QueryRow(values...).Scan(&id)
+	stmt := db.statement(ctx, "AddUser")
+	values, err := AddUserReadParams(requestParams)
+	if err != nil {
+		return "", err
+	}
+	var id string
+	queryErr := stmt.QueryRowContext(ctx, values...).Scan(&id)
 	if queryErr != nil {
-		return int64(0), queryErr
+		return "", queryErr
 	}
 	return id, nil
 }`
 
-	expectedImports := map[string]bool{}
-	fn := AddCodeFunction(name, "User_DB")
+	expectedImports := map[string]bool{"context": true, "database/sql": true}
+	fn := AddCodeFunction(name, "User_DB", "string")
 	fnCode, fnImports := fn.FunctionCode()
 	// t.Log(fnCode)
 	assert.Equal(t, expectedFnCode, fnCode)
 	assert.Equal(t, expectedImports, fnImports)
 	assert.Equal(t, name, fn.Name)
 	assert.Equal(t, 3, len(fn.Parameters)) // Assuming ctx, db, requestParams
-	assert.Equal(t, 5, len(fn.Body))       // Assuming 5 code elements in the body
+	assert.Equal(t, 6, len(fn.Body))       // Assuming 6 code elements in the body
 	assert.Equal(t, 2, len(fn.Returns))
 	assert.Equal(t, 0, len(fn.Dependencies))
 }
@@ -112,30 +122,34 @@ func TestAddCodeFunction(t *testing.T) {
 func TestAddOrReplaceCodeFunction(t *testing.T) {
 	name := "AddOrReplaceUser"
 
-	expectedFnCode := `func AddOrReplaceUser(ctx context.Context, db *User_DB, requestParams AddOrReplaceUserParams) (int64, bool, error) {
-	stmt := db.preparedCache["AddOrReplaceUser"]
-	values, err := AddOrReplaceUserParseParams(requestParams)
+	expectedFnCode := `func AddOrReplaceUser(ctx context.Context, db *User_DB, requestParams AddOrReplaceUserParams) (string, bool, error) {
+	err := requestParams.Validate()
+	if err != nil {
+		return "", false, err
+	}
+	stmt := db.statement(ctx, "AddOrReplaceUser")
+	values, err := AddOrReplaceUserReadParams(requestParams)
 	if err != nil {
-		return int64(0), false, err
+		return "", false, err
 	}
-	var id int64
+	var id string
 	var inserted bool
-	queryErr := stmt.QueryRow(values...).Scan(&id, &inserted)
+	queryErr := stmt.QueryRowContext(ctx, values...).Scan(&id, &inserted)
 	if queryErr != nil {
-		return int64(0), false, queryErr
+		return "", false, queryErr
 	}
 	return id, inserted, nil
 }`
 
-	expectedImports := map[string]bool{}
+	expectedImports := map[string]bool{"context": true, "database/sql": true}
 
-	fn := AddOrReplaceCodeFunction(name, "User_DB")
+	fn := AddOrReplaceCodeFunction(name, "User_DB", "string")
 	fnCode, fnImports := fn.FunctionCode()
 	assert.Equal(t, expectedFnCode, fnCode)
 	assert.Equal(t, expectedImports, fnImports)
 	assert.Equal(t, name, fn.Name)
 	assert.Equal(t, 3, len(fn.Parameters)) // Assuming ctx, db, requestParams
-	assert.Equal(t, 6, len(fn.Body))       // Assuming 4 code elements in the body
+	assert.Equal(t, 7, len(fn.Body))       // Assuming 7 code elements in the body
 	assert.Equal(t, 3, len(fn.Returns))
 	assert.Equal(t, 0, len(fn.Dependencies))
 }
@@ -144,12 +158,16 @@ func TestDeleteCodeFunction(t *testing.T) {
 	name := "DeleteUser"
 
 	expectedFnCode := `func DeleteUser(ctx context.Context, db *User_DB, requestParams DeleteUserParams) (int64, error) {
-	stmt := db.preparedCache["DeleteUser"]
-	values, err := DeleteUserParseParams(requestParams)
+	err := requestParams.Validate()
 	if err != nil {
 		return int64(0), err
 	}
-	result, err := stmt.Exec(values...)
+	stmt := db.statement(ctx, "DeleteUser")
+	values, err := DeleteUserReadParams(requestParams)
+	if err != nil {
+		return int64(0), err
+	}
+	result, err := stmt.ExecContext(ctx, values...)
 	if err != nil {
 		return int64(0), err
 	}
@@ -160,18 +178,60 @@ func TestDeleteCodeFunction(t *testing.T) {
 	return rowsAffected, nil
 }`
 
-	expectedImports := map[string]bool{}
+	expectedImports := map[string]bool{"context": true, "database/sql": true}
 	fn := DeleteCodeFunction(name, "User_DB")
 	fnCode, fnImports := fn.FunctionCode()
 	assert.Equal(t, expectedFnCode, fnCode)
 	assert.Equal(t, expectedImports, fnImports)
 	assert.Equal(t, name, fn.Name)
 	assert.Equal(t, 3, len(fn.Parameters)) // Assuming ctx, db, param
-	assert.Equal(t, 5, len(fn.Body))       // Assuming 5 code elements in the body
+	assert.Equal(t, 6, len(fn.Body))       // Assuming 6 code elements in the body
 	assert.Equal(t, 2, len(fn.Returns))
 	assert.Equal(t, 0, len(fn.Dependencies))
 }
 
+func TestLockedUpdateCodeFunction(t *testing.T) {
+	fn := LockedUpdateCodeFunction("UpdateProductPrice", "Product_DB")
+	fnCode, _ := fn.FunctionCode()
+	assert.Equal(t, `func UpdateProductPrice(ctx context.Context, db *Product_DB, requestParams UpdateProductPriceParams) (int64, error) {
+	err := requestParams.Validate()
+	if err != nil {
+		return int64(0), err
+	}
+	stmt := db.statement(ctx, "UpdateProductPrice")
+	values, err := UpdateProductPriceReadParams(requestParams)
+	if err != nil {
+		return int64(0), err
+	}
+	var version int64
+	err = stmt.QueryRowContext(ctx, values...).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return int64(0), ErrVersionConflict
+	}
+	if err != nil {
+		return int64(0), err
+	}
+	return version, nil
+}`, fnCode)
+}
+
+func TestWithTimeout(t *testing.T) {
+	fn := DeleteCodeFunction("DeleteUser", "User_DB")
+	assert.NoError(t, withTimeout(fn, &defs.AccessConfig{Name: "DeleteUser", Timeout: "1500ms"}))
+	fnCode, fnImports := fn.FunctionCode()
+	assert.Contains(t, fnCode, `func DeleteUser(ctx context.Context, db *User_DB, requestParams DeleteUserParams) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1500*time.Millisecond)
+	defer cancel()
+	err := requestParams.Validate()`)
+	assert.True(t, fnImports["time"])
+
+	assert.Equal(t, "2*time.Minute", durationExpression(2*time.Minute))
+	assert.Equal(t, "90*time.Second", durationExpression(90*time.Second))
+
+	assert.EqualError(t, withTimeout(fn, &defs.AccessConfig{Name: "DeleteUser", Timeout: "soon"}),
+		`access DeleteUser: invalid timeout: time: invalid duration "soon"`)
+}
+
 func TestReadParamsFunction(t *testing.T) {
 	paramRefs := []defs.ParameterRef{
 		{Name: "age", Index: -1},
@@ -179,34 +239,40 @@ func TestReadParamsFunction(t *testing.T) {
 		{Name: "salary_range", Index: 1},
 		{Name: "department", Index: -1},
 	}
+	params := map[string]*accessParam{
+		"age":          {Type: golang.GoIntType, Operator: "="},
+		"salary_range": {Type: &golang.GoType{Name: "Range[float64]"}, Operator: "BETWEEN"},
+		"department":   {Type: &golang.GoType{Name: "[]string"}, Operator: "IN"},
+	}
 	confName := "config"
 	valuesName := "values"
 	paramsName := "params"
 
 	expectedFnCode := `func configReadParams(params configParams) ([]interface{}, error) {
 	var values []interface{}
-	values = append(values, params.age)
-	values = append(values, params.salary_range.([]interface{})[0])
-	values = append(values, params.salary_range.([]interface{})[1])
-	values = append(values, params.department)
+	values = append(values, params.Age)
+	values = append(values, params.SalaryRange.From)
+	values = append(values, params.SalaryRange.To)
+	values = append(values, pq.Array(params.Department))
 	return values, nil
 }`
 	// Call the function under test
-	resultFunction := ReadParamsFunction(paramRefs, confName, valuesName, paramsName)
-	resultCode, _ := resultFunction.FunctionCode()
+	resultFunction := ReadParamsFunction(paramRefs, params, confName, valuesName, paramsName)
+	resultCode, resultImports := resultFunction.FunctionCode()
 	t.Log(resultFunction.FunctionCode())
 	assert.Equal(t, expectedFnCode, resultCode)
+	assert.Equal(t, map[string]bool{"github.com/lib/pq": true}, resultImports)
 	// Assert the expected output
 	// assert.Equal(t, resultFunction, expectedFunction)
 }
 
 func TestPrepareStmtsFunction(t *testing.T) {
 	queries := []NamedQuery{
-		{"query1", "SELECT * FROM table1 WHERE id = $1 AND name = $2"},
-		{"query2", "INSERT INTO table2 (id, name, age) VALUES ($1, $2, $3)"},
-		{"query3", "UPDATE table3 SET name = $1, age = $2 WHERE id = $3"},
-		{"query4", "DELETE FROM table4 WHERE id = $1"},
-		{"query5", "SELECT * FROM table5"},
+		{Name: "query1", Query: "SELECT * FROM table1 WHERE id = $1 AND name = $2"},
+		{Name: "query2", Query: "INSERT INTO table2 (id, name, age) VALUES ($1, $2, $3)"},
+		{Name: "query3", Query: "UPDATE table3 SET name = $1, age = $2 WHERE id = $3"},
+		{Name: "query4", Query: "DELETE FROM table4 WHERE id = $1"},
+		{Name: "query5", Query: "SELECT * FROM table5"},
 	}
 
 	expectedCode := `func UserPrepareStmts(db *sql.DB) (map[string]*sql.Stmt, error) {
@@ -337,7 +403,7 @@ func TestGenerateNewFamilyFunction(t *testing.T) {
 	if err != nil {
 		return err
 	}
-	stmtMapUser, err := UserPrepareStmt()
+	stmtMapUser, err := UserPrepareStmts(db)
 	if err != nil {
 		return err
 	}
@@ -345,7 +411,7 @@ func TestGenerateNewFamilyFunction(t *testing.T) {
 		db: db,
 		preparedCache: stmtMapUser,
 	}
-	stmtMapProduct, err := ProductPrepareStmt()
+	stmtMapProduct, err := ProductPrepareStmts(db)
 	if err != nil {
 		return err
 	}
@@ -353,7 +419,7 @@ func TestGenerateNewFamilyFunction(t *testing.T) {
 		db: db,
 		preparedCache: stmtMapProduct,
 	}
-	stmtMapOrder, err := OrderPrepareStmt()
+	stmtMapOrder, err := OrderPrepareStmts(db)
 	if err != nil {
 		return err
 	}
@@ -362,6 +428,7 @@ func TestGenerateNewFamilyFunction(t *testing.T) {
 		preparedCache: stmtMapOrder,
 	}
 	RetailDB = &retailDB{
+		db: db,
 		User: user,
 		Product: product,
 		Order: order,
@@ -372,3 +439,13 @@ func TestGenerateNewFamilyFunction(t *testing.T) {
 	resultCode, _ := fn.FunctionCode()
 	assert.Equal(t, expectedCode, resultCode)
 }
+
+func TestFindCodeFunctionSortedKeyset(t *testing.T) {
+	fn := FindCodeFunction("Product", "Product_DB", "ListProducts", []string{"Id", "Price"}, &defs.Pagination{Type: "keyset"},
+		[]defs.OrderBy{{Attribute: "price", Direction: "DESC"}}, false)
+	fnCode, _ := fn.FunctionCode()
+	// The cursor of the next page holds the sort column and the id of the last product
+	assert.Contains(t, fnCode, `		last := results[len(results)-1]
+		page.NextCursor = encodeCursor(map[string]interface{}{"price": last.Price, "id": last.Id})
+`)
+}
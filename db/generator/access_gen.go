@@ -1,7 +1,10 @@
 package generator
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -36,12 +39,17 @@ func GenerateDB(dataConfig *defs.DataConfig) ([]*golang.UnitModule, error) {
 		return nil, fmt.Errorf("dataconf is missing database config")
 	}
 
+	dialect, err := datahelpers.DialectForDriver(dataConfig.DatabaseConfig.DriverName)
+	if err != nil {
+		return nil, err
+	}
+
 	unitModules := make([]*golang.UnitModule, 0)
 	modelNameMaps := make(modelNameMappings, 0)
 	for _, config := range dataConfig.Models {
-		srcFile, modelNameMap, err := Generate(config)
+		srcFile, modelNameMap, err := Generate(config, dataConfig)
 		if err != nil {
-			base.LOG.Error("GenerateDB::Error generating code for model %s: %v", config.Model.Name, err)
+			base.LOG.Error("GenerateDB::Error generating code for model", "model", config.Model.Name, "error", err)
 			return nil, err
 		}
 		modelNameMaps = append(modelNameMaps, modelNameMap)
@@ -61,7 +69,7 @@ func GenerateDB(dataConfig *defs.DataConfig) ([]*golang.UnitModule, error) {
 
 	st, fn, dbvar, err := GenerateFamily(dataConfig, modelNameMaps)
 	if err != nil {
-		base.LOG.Error("GenerateDB::Error generating code for family %s: %v", dataConfig.FamilyName, err)
+		base.LOG.Error("GenerateDB::Error generating code for family", "family", dataConfig.FamilyName, "error", err)
 		return nil, err
 	}
 	unitModules = append(unitModules, &golang.UnitModule{
@@ -70,14 +78,58 @@ func GenerateDB(dataConfig *defs.DataConfig) ([]*golang.UnitModule, error) {
 		Functions:    fn,
 		Variables:    dbvar,
 		Constants:    nil,
+		Types:        append(GenerateReferenceIDTypes(dataConfig), RangeTypeDef()),
 		Imports:      nil,
 		Dependencies: nil,
 	})
 
+	// Migrate(ctx, db) applies the embedded migrations, starting with the DDL of the family
+	migrateUnit, err := GenerateMigrateUnit(dialect, dataConfig)
+	if err != nil {
+		base.LOG.Error("GenerateDB::Error generating migrations", "family", dataConfig.FamilyName, "error", err)
+		return nil, err
+	}
+	unitModules = append(unitModules, migrateUnit)
+
+	// Checks called by the Validate methods of the model and params structs
+	unitModules = append(unitModules, GenerateValidationUnit())
+	// Page sizes and cursors of the paginated finds
+	unitModules = append(unitModules, GeneratePaginationUnit())
+	// Error of the finds called with an unknown sort
+	unitModules = append(unitModules, GenerateSortUnit())
+	// Errors of the rows of batch inserts
+	unitModules = append(unitModules, GenerateBatchUnit(dialect))
+	// Error of the updates and deletes with optimistic_lock called with a stale version
+	unitModules = append(unitModules, GenerateLockUnit())
+	// Actor of the history rows of the models with audit
+	unitModules = append(unitModules, GenerateAuditUnit())
+	// Relay of the events of the accesses with events, which need Postgres (see validateEvents)
+	if dialect.GetName() == "postgres" {
+		unitModules = append(unitModules, GenerateOutboxUnit())
+	}
+	// Statements of the queries with IN lists, see expandsInLists
+	if expandsInLists(dialect) {
+		unitModules = append(unitModules, GenerateInListUnit(dialect))
+	}
+
 	return unitModules, nil
 
 }
 
+// familyDialect is the dialect of the driver of a family, Postgres for a family without a database config
+func familyDialect(family *defs.DataConfig) (datahelpers.Dialect, error) {
+	if family.DatabaseConfig == nil || family.DatabaseConfig.DriverName == "" {
+		return datahelpers.NewPostgresDialect(), nil
+	}
+	return datahelpers.DialectForDriver(family.DatabaseConfig.DriverName)
+}
+
+// returnsRows tells if the writes of a dialect read the columns of the rows they write with RETURNING. The inserts of
+// the other dialects read the ids of their rows with LastInsertId, see Dialect.InsertsID.
+func returnsRows(dialect datahelpers.Dialect) bool {
+	return dialect.FormatReturning(dialect.FormatIdentifier("id")) != ""
+}
+
 func GenerateFamily(dataConf *defs.DataConfig, modelNameMaps modelNameMappings) ([]*golang.StructDef, []*golang.FunctionDef, []*golang.Variable, error) {
 
 	structs := make([]*golang.StructDef, 0)
@@ -97,6 +149,8 @@ func GenerateFamily(dataConf *defs.DataConfig, modelNameMaps modelNameMappings)
 	}
 
 	st := golang.GenStructForDataModel(structName, nameWithTypes, false, false, false)
+	// The connection of the model DBs, which transactions begin on
+	st.Fields = append(st.Fields, &golang.Field{Name: "db", Type: &golang.GoType{Name: "*sql.DB"}})
 	fn, err := GenerateInitFamilyFunction(modelNameMaps, varName, structName)
 	if err != nil {
 		return nil, nil, nil, err
@@ -104,6 +158,13 @@ func GenerateFamily(dataConf *defs.DataConfig, modelNameMaps modelNameMappings)
 	structs = append(structs, st)
 	functions = append(functions, fn)
 
+	txStruct, txFunctions, err := generateFamilyTx(dataConf, modelNameMaps, varName, structName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	structs = append(structs, txStruct)
+	functions = append(functions, txFunctions...)
+
 	fn, err = SetupDBConnectionFunction(dataConf)
 	if err != nil {
 		return nil, nil, nil, err
@@ -119,6 +180,41 @@ func GenerateFamily(dataConf *defs.DataConfig, modelNameMaps modelNameMappings)
 	return structs, functions, varDeclare, nil
 }
 
+// GenerateReferenceIDTypes declares an id type for every model referenced by a relationship,
+// `type CustomerID string`, which the reference fields of the model structs are typed with
+func GenerateReferenceIDTypes(dataConf *defs.DataConfig) []*golang.TypeDef {
+	typeDefs := make([]*golang.TypeDef, 0)
+	declared := map[string]bool{}
+	for i := range dataConf.Models {
+		// Unresolved relationships fail GenerateDB before the family is generated
+		references, _ := dataConf.References(&dataConf.Models[i].Model)
+		for _, reference := range references {
+			if declared[reference.IDTypeName()] {
+				continue
+			}
+			declared[reference.IDTypeName()] = true
+			typeDefs = append(typeDefs, &golang.TypeDef{Name: reference.IDTypeName(), Type: "string"})
+		}
+	}
+	return typeDefs
+}
+
+// Name of the generic type of the params of BETWEEN filters
+const rangeTypeName = "Range"
+
+// RangeTypeDef declares the type of the params of BETWEEN filters, which bind From and To:
+//
+//	type Range[T any] struct {
+//		From T `json:"from"`
+//		To   T `json:"to"`
+//	}
+func RangeTypeDef() *golang.TypeDef {
+	return &golang.TypeDef{
+		Name: rangeTypeName + "[T any]",
+		Type: "struct {\n\tFrom T `json:\"from\"`\n\tTo T `json:\"to\"`\n}",
+	}
+}
+
 func readTypeAndValidations(attributeId int64) (string, *golang.GoType, []*models.Validation, error) {
 	attribute, ok := config.Attributes[attributeId]
 	if !ok {
@@ -140,16 +236,121 @@ func readTypeAndValidations(attributeId int64) (string, *golang.GoType, []*model
 	return attribute.Name, goType, validations, nil
 }
 
+// systemFields are the fields of the system columns of the table of a model, see datahelpers.ModelSystemColumns.
+// Every table has these columns besides the attributes, the model struct needs fields for them so that finds can
+// select the id, version and updated_at of the rows, and the version checked by optimistic locking and the deletion
+// time of soft deleted rows can be read. They lead the fields in the order SchemaBuilder creates the columns, so that
+// the struct reads like the table.
+func systemFields(model *defs.Model) ([]golang.NameWithType, error) {
+	systemColumns := datahelpers.ModelSystemColumns(model)
+	fields := make([]golang.NameWithType, 0, len(systemColumns))
+	for _, column := range systemColumns {
+		goType, err := golang.TranslateToGoType(column.GoType)
+		if err != nil {
+			return nil, err
+		}
+		if column == datahelpers.SoftDeleteColumn {
+			goType = nullableGoType(goType)
+		}
+		fields = append(fields, golang.NameWithType{Name: column.Name, Type: goType})
+	}
+	return fields, nil
+}
+
+// modelFields are the fields of the model struct: the system columns (see systemFields), the attributes, and the
+// foreign key columns of the references, typed with the id type of the target model, a pointer when the column is
+// nullable. Attributes that are not NotNull are nullable columns, see nullableGoType.
+func modelFields(modelConfig *defs.ModelConfig, references []defs.Reference) ([]golang.NameWithType, error) {
+	fields, err := systemFields(&modelConfig.Model)
+	if err != nil {
+		return nil, err
+	}
+	for _, attribute := range modelConfig.Model.Attributes {
+		attrName, goType, _, err := readTypeAndValidations(attribute)
+		if err != nil {
+			return nil, err
+		}
+		if !config.Attributes[attribute].NotNull {
+			goType = nullableGoType(goType)
+		}
+		base.LOG.Debug("Attribute", "attrName", attrName, "goType", goType)
+
+		fields = append(fields, golang.NameWithType{
+			Name: attrName,
+			Type: goType,
+		})
+	}
+	for _, reference := range references {
+		typeName := reference.IDTypeName()
+		if !reference.NotNull {
+			typeName = "*" + typeName
+		}
+		fields = append(fields, golang.NameWithType{Name: reference.Column, Type: &golang.GoType{Name: typeName}})
+	}
+	return fields, nil
+}
+
+// sqlNullTypes are the types of the fields of nullable columns, for the types database/sql has a Null type of
+var sqlNullTypes = map[string]string{
+	"string":  "sql.NullString",
+	"int64":   "sql.NullInt64",
+	"int32":   "sql.NullInt32",
+	"int16":   "sql.NullInt16",
+	"float64": "sql.NullFloat64",
+	"bool":    "sql.NullBool",
+}
+
+// nullableGoType is the type of the field of a nullable column, which rows.Scan can set to NULL: the sql.Null type
+// of goType, a pointer when there is none. Slices, interfaces and imported types (fields of imported types are
+// pointers, see golang.StructCode) take NULL as they are.
+func nullableGoType(goType *golang.GoType) *golang.GoType {
+	if nullType, ok := sqlNullTypes[goType.Name]; ok {
+		return &golang.GoType{Name: nullType}
+	}
+	if goType.Source != "" || strings.HasPrefix(goType.Name, "[]") || strings.HasPrefix(goType.Name, "*") ||
+		goType.Name == "interface{}" || goType.Name == "any" {
+		return goType
+	}
+	return &golang.GoType{Name: "*" + goType.Name}
+}
+
+// nullValueType is the type of the value held by a field of type typeName, the type itself when it's not nullable
+func nullValueType(typeName string) string {
+	for valueType, nullType := range sqlNullTypes {
+		if nullType == typeName {
+			return valueType
+		}
+	}
+	return strings.TrimPrefix(typeName, "*")
+}
+
+// fieldTypes maps the columns of a model to the types of their fields
+func fieldTypes(fields []golang.NameWithType) map[string]*golang.GoType {
+	types := make(map[string]*golang.GoType, len(fields))
+	for _, field := range fields {
+		types[golang.ToSnakeCase(field.Name)] = field.Type
+	}
+	return types
+}
+
 // Generate Model struct for a given model
 //
 //	Example: type Product struct {
-//		Sku         string  `db:"sku"`
-//		ProductName string  `db:"product_name"`
-//		Description string  `db:"description"`
-//		Price       float64 `db:"price"`
+//		Id          string    `db:"id"`
+//		Version     int64     `db:"version"`
+//		UpdatedAt   time.Time `db:"updated_at"`
+//		Sku         string    `db:"sku"`
+//		ProductName string    `db:"product_name"`
+//		Description string    `db:"description"`
+//		Price       float64   `db:"price"`
+//		CategoryId  CategoryID `db:"category_id"`
 //	}
+//
+// The fields are the system columns, the attributes and the foreign key columns of the references, see modelFields.
+// The Validate method of the struct checks the catalog validations of the attributes.
 
-func generateModel(config *defs.ModelConfig) (*modelNameMapping, []*golang.StructDef, []*golang.FunctionDef, error) {
+func generateModel(dialect datahelpers.Dialect, config *defs.ModelConfig, fields []golang.NameWithType, validations *attributeValidations) (
+	*modelNameMapping, []*golang.StructDef, []*golang.FunctionDef, error) {
 
 	models := make([]*golang.StructDef, 0, 1)
 	functions := make([]*golang.FunctionDef, 0, 1)
@@ -159,40 +360,46 @@ func generateModel(config *defs.ModelConfig) (*modelNameMapping, []*golang.Struc
 		ModelDBStructName: golang.ToPascalCase(config.Model.Name) + "_DB",
 	}
 
-	nameWithTypes := make([]golang.NameWithType, 0, 1)
-	for _, attribute := range config.Model.Attributes {
-		attrName, goType, _, err := readTypeAndValidations(attribute)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		base.LOG.Debug("Attribute", "attrName", attrName, "goType", goType)
-
-		nameWithTypes = append(nameWithTypes, golang.NameWithType{
-			Name: attrName,
-			Type: goType,
-		})
+	modelStruct := golang.GenStructForDataModel(modelNameMap.ModelStructName, fields, false, false, true)
+	validateFn, err := validations.ModelValidateMethod(modelNameMap.ModelStructName, &config.Model)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	modelStruct := golang.GenStructForDataModel(modelNameMap.ModelStructName, nameWithTypes, false, false, true)
-
 	dbNameWithTypes := []golang.NameWithType{
 		{Name: "db", Type: &golang.GoType{Name: "*sql.DB"}},
 		{Name: "preparedCache", Type: &golang.GoType{Name: "map[string]*sql.Stmt"}},
 	}
 
 	modelDBStruct, modelDBNewFn := golang.GenStructWithNewFunction(modelNameMap.ModelDBStructName, dbNameWithTypes, true, false, false, false)
+	// The transaction of the model DBs of WithTx, nil outside of transactions
+	modelDBStruct.Fields = append(modelDBStruct.Fields, &golang.Field{Name: "tx", Type: &golang.GoType{Name: "*sql.Tx"}})
 	models = append(models, modelStruct, modelDBStruct)
-	functions = append(functions, modelDBNewFn)
+	statementFn := statementFunction(modelNameMap.ModelDBStructName)
+	if expandsInLists(dialect) {
+		statementFn = inListStatementFunction(modelNameMap.ModelStructName, modelNameMap.ModelDBStructName)
+	}
+	functions = append(functions, validateFn, modelDBNewFn, statementFn, withTxFunction(modelNameMap.ModelDBStructName))
+	if hasInsertChunks(config) {
+		if returnsRows(dialect) {
+			functions = append(functions, queryFunction(modelNameMap.ModelDBStructName))
+		} else {
+			functions = append(functions, execFunction(modelNameMap.ModelDBStructName))
+		}
+	}
 
 	return modelNameMap, models, functions, nil
 }
 
-type AccessFnGenerator func(modelName string, modelDBName string, config []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error)
+// AccessFnGenerator generates the access functions of a model for its access configs, fieldTypes are the types of
+// the model fields keyed by column, see fieldTypes
+type AccessFnGenerator func(dialect datahelpers.Dialect, modelName string, modelDBName string, fieldTypes map[string]*golang.GoType,
+	config []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error)
 
-func genAccessFn(modelName string, modelDBName string, config []defs.AccessConfig, accessFn AccessFnGenerator,
-	allQueries *[]NamedQuery, allFunctions *[]*golang.FunctionDef, allStructs *[]*golang.StructDef) error {
+func genAccessFn(dialect datahelpers.Dialect, modelName string, modelDBName string, fieldTypes map[string]*golang.GoType, config []defs.AccessConfig,
+	accessFn AccessFnGenerator, allQueries *[]NamedQuery, allFunctions *[]*golang.FunctionDef, allStructs *[]*golang.StructDef) error {
 
-	queries, accessFns, structs, err := accessFn(modelName, modelDBName, config)
+	queries, accessFns, structs, err := accessFn(dialect, modelName, modelDBName, fieldTypes, config)
 	if err != nil {
 		return err
 	}
@@ -202,7 +409,9 @@ func genAccessFn(modelName string, modelDBName string, config []defs.AccessConfi
 	return nil
 }
 
-func Generate(config defs.ModelConfig) (*golang.GoSourceFile, *modelNameMapping, error) {
+// Generate generates the model struct and access functions of a model of the family,
+// which resolves its relationships and included models (nil for a model without relationships)
+func Generate(config defs.ModelConfig, family *defs.DataConfig) (*golang.GoSourceFile, *modelNameMapping, error) {
 
 	allQueries := make([]NamedQuery, 0)
 	allFunctions := make([]*golang.FunctionDef, 0)
@@ -210,8 +419,38 @@ func Generate(config defs.ModelConfig) (*golang.GoSourceFile, *modelNameMapping,
 	caser := cases.Title(language.English)
 	modelName := caser.String(config.Model.Name)
 
+	if family == nil {
+		family = &defs.DataConfig{}
+	}
+	dialect, err := familyDialect(family)
+	if err != nil {
+		return nil, nil, err
+	}
+	references, err := family.References(&config.Model)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Catalog validations of the attributes, checked by the Validate methods of the model and params structs
+	validations, err := readAttributeValidations(golang.ToPascalCase(config.Model.Name), &config.Model)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fields, err := modelFields(&config, references)
+	if err != nil {
+		return nil, nil, err
+	}
+	config.Access = replaceAccess(&config.Model, config.Access)
+	if config.Model.SoftDelete {
+		config.Access = softDeleteAccess(config.Access)
+	}
+	if config.Model.Audit {
+		config.Access = auditAccess(config.Access)
+	}
+
 	// Generate Model struct for a given model, for example `type User struct {<fields with db tags>}`
-	modelNameMap, models, fns, err := generateModel(&config)
+	modelNameMap, models, fns, err := generateModel(dialect, &config, fields, validations)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -219,12 +458,24 @@ func Generate(config defs.ModelConfig) (*golang.GoSourceFile, *modelNameMapping,
 	allFunctions = append(allFunctions, fns...)
 
 	// Generate methods for SELECT, UPDATE, INSERT, INSERT OR UPDATE, DELETE for a given model
-	err = geneateAllAccessMethods(config, modelNameMap.ModelStructName, modelNameMap.ModelDBStructName,
-		&allQueries, &allFunctions, &allStructs)
+	err = geneateAllAccessMethods(dialect, config, family, modelNameMap.ModelStructName, modelNameMap.ModelDBStructName,
+		fieldTypes(fields), &allQueries, &allFunctions, &allStructs)
 	if err != nil {
 		base.LOG.Error("Generate::geneateAllAccessMethods", "err", err, "model", modelName, "modelMap", *modelNameMap)
 		return nil, nil, err
 	}
+	for _, accessConfigs := range [][]defs.AccessConfig{config.Access.Find, config.Access.Update, config.Access.Add,
+		config.Access.AddOrReplace, config.Access.Delete, config.Access.Aggregate, config.Access.AddMany} {
+		for i := range accessConfigs {
+			allFunctions = append(allFunctions, validations.ParamsValidateMethod(&accessConfigs[i]))
+		}
+	}
+	if config.Model.SoftDelete {
+		for i := range config.Access.Delete {
+			restore := restoreConfig(&config.Access.Delete[i])
+			allFunctions = append(allFunctions, restoreValidations(validations, &restore).ParamsValidateMethod(&restore))
+		}
+	}
 
 	// PrepareStmt function will prepare all queries for a given model
 	// Make sure allQueries have been populated,
@@ -232,27 +483,45 @@ func Generate(config defs.ModelConfig) (*golang.GoSourceFile, *modelNameMapping,
 	prepareFn := PrepareStmtsFunction(modelName, allQueries)
 	allFunctions = append(allFunctions, prepareFn)
 
+	variables := slices.Clone(validations.patterns)
+	if expandsInLists(dialect) {
+		variables = append(variables, inListStmtsVariable(modelNameMap.ModelStructName, allQueries))
+	}
+
 	goSrc := &golang.GoSourceFile{
 		Package:      "database",
 		Structs:      allStructs,
 		Functions:    allFunctions,
 		InitFunction: nil,
-		Variables:    nil,
+		Variables:    variables,
 		Constants:    nil}
 
 	return goSrc, modelNameMap, nil
 }
 
-// All access methods for a given model (Find, Update, Add, AddOrReplace and Delete),
-// will do query on database with above prepared statements (SELECT, UPDATE, INSERT, INSERT OR UPDATE, DELETE)
-func geneateAllAccessMethods(config defs.ModelConfig, modelName string, modelDBName string,
-	allQueries *[]NamedQuery, allFunctions *[]*golang.FunctionDef, allStructs *[]*golang.StructDef) error {
+// All access methods for a given model (Find, Update, Add, AddOrReplace, Delete, Aggregate and AddMany),
+// will do query on database with above prepared statements (SELECT, UPDATE, INSERT, INSERT OR UPDATE, DELETE, SELECT ... GROUP BY,
+// multi-row INSERT)
+func geneateAllAccessMethods(dialect datahelpers.Dialect, config defs.ModelConfig, family *defs.DataConfig, modelName string, modelDBName string,
+	fieldTypes map[string]*golang.GoType, allQueries *[]NamedQuery, allFunctions *[]*golang.FunctionDef, allStructs *[]*golang.StructDef) error {
+	// Finds with included models need the family to resolve them
+	findConfigs := func(dialect datahelpers.Dialect, modelName string, modelDBName string, fieldTypes map[string]*golang.GoType,
+		findConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
+		return GenerateFindWithIncludesConfigs(dialect, family, &config.Model, modelName, modelDBName, fieldTypes, findConfig)
+	}
+	// Soft delete models keep their deleted rows, see softDeleteAccess for their other accesses
+	deleteConfigs := GenerateDeleteConfigs
+	if config.Model.SoftDelete {
+		deleteConfigs = GenerateSoftDeleteConfigs
+	}
 	accessMethods := []AccessFnGenerator{
-		GenerateFindConfigs,
+		findConfigs,
 		GenerateUpdateConfigs,
 		GenerateAddConfigs,
 		GenerateAddOrReplaceConfigs,
-		GenerateDeleteConfigs,
+		deleteConfigs,
+		GenerateAggregateConfigs,
+		GenerateAddManyConfigs,
 	}
 
 	accessConfigs := [][]defs.AccessConfig{
@@ -261,19 +530,143 @@ func geneateAllAccessMethods(config defs.ModelConfig, modelName string, modelDBN
 		config.Access.Add,
 		config.Access.AddOrReplace,
 		config.Access.Delete,
+		config.Access.Aggregate,
+		config.Access.AddMany,
 	}
 
 	for i, accessMethod := range accessMethods {
-		err := genAccessFn(modelName, modelDBName, accessConfigs[i], accessMethod, allQueries, allFunctions, allStructs)
+		err := genAccessFn(dialect, modelName, modelDBName, fieldTypes, accessConfigs[i], accessMethod, allQueries, allFunctions, allStructs)
 		if err != nil {
 			return err
 		}
 	}
+	// Models with audit read the history of their rows
+	if config.Model.Audit {
+		query, fn, historyStruct := GenerateHistoryAccess(dialect, modelName, modelDBName)
+		*allQueries = append(*allQueries, query)
+		*allFunctions = append(*allFunctions, fn)
+		*allStructs = append(*allStructs, historyStruct)
+	}
 	return nil
 }
 
-func generateParamsStruct(paramRefs []defs.ParameterRef, name string) *golang.StructDef {
+// accessParam is a field of the params struct of an access config
+type accessParam struct {
+	Type *golang.GoType
+	// Operator of the filter comparing the param, empty for the params of set and values
+	Operator string
+	// Import of the element type of list and range params, which are declared without a Source to not become pointers
+	Import string
+	// Decode is the function returning the value bound for the param and an error, decodeCursor for page cursors
+	Decode string
+	// Selects the prepared query instead of being bound, the sort param of a find with sort options
+	Selects bool
+	// Binds one more row than its value, the limit param of a paginated find, see datahelpers.MakeFindQuery
+	PageLimit bool
+}
+
+// pageParams are the params of the page of a paginated find, see datahelpers.MakeFindQuery
+func pageParams(pagination *defs.Pagination) map[string]*accessParam {
+	params := map[string]*accessParam{datahelpers.LimitParam: {Type: golang.GoIntType, PageLimit: true}}
+	switch pagination.Type {
+	case datahelpers.PaginationOffset:
+		params[datahelpers.OffsetParam] = &accessParam{Type: golang.GoIntType}
+	case datahelpers.PaginationKeyset:
+		params[datahelpers.CursorParam] = &accessParam{Type: golang.GoStringType, Decode: decodeCursorFunctionName}
+	}
+	return params
+}
+
+// readAccessParams types the params of an access config from the attributes they filter or set: the type of the
+// attribute field, []T for IN filters and Range[T] for BETWEEN filters. Filters compare values, so the params of
+// nullable attributes are not pointers or sql.Null types.
+func readAccessParams(conf *defs.AccessConfig, fieldTypes map[string]*golang.GoType) (map[string]*accessParam, error) {
+	params := make(map[string]*accessParam)
+	errs := make([]error, 0)
+	types := fieldTypes
+	bind := func(attribute, paramName, operator string) {
+		fieldType, ok := types[golang.ToSnakeCase(attribute)]
+		if !ok {
+			errs = append(errs, fmt.Errorf("access %s: param %s: unknown attribute %s", conf.Name, paramName, attribute))
+			return
+		}
+		typeName := fieldType.Name
+		if operator != "" {
+			typeName = nullValueType(typeName)
+		}
+		switch strings.ToUpper(operator) {
+		case datahelpers.OperatorIn:
+			typeName = "[]" + typeName
+		case datahelpers.OperatorBetween:
+			typeName = fmt.Sprintf("%s[%s]", rangeTypeName, typeName)
+		}
+		if bound, ok := params[paramName]; ok {
+			if bound.Type.Name != typeName {
+				errs = append(errs, fmt.Errorf("access %s: param %s is bound as %s and %s", conf.Name, paramName, bound.Type.Name, typeName))
+			}
+			return
+		}
+		param := &accessParam{Type: &golang.GoType{Name: typeName, Source: fieldType.Source}, Operator: strings.ToUpper(operator)}
+		if typeName != fieldType.Name && typeName != nullValueType(fieldType.Name) {
+			param.Type.Source, param.Import = "", fieldType.Source
+		}
+		params[paramName] = param
+	}
+
+	for _, value := range conf.Values {
+		bind(value.Attribute, value.ParamName, "")
+	}
+	for _, set := range conf.Set {
+		bind(set.Attribute, set.ParamName, "")
+	}
+	var bindFilters func(filters []defs.Filter)
+	bindFilters = func(filters []defs.Filter) {
+		for _, filter := range filters {
+			if filter.ParamName != "" {
+				bind(filter.Attribute, filter.ParamName, filter.Operator)
+			}
+			bindFilters(filter.Conditions)
+		}
+	}
+	bindFilters(conf.Filter)
+	if len(conf.Having) > 0 {
+		// Having filters compare aggregates, typed like the fields of the result
+		fields, err := aggregateFields(conf, fieldTypes)
+		if err != nil {
+			return nil, err
+		}
+		types = make(map[string]*golang.GoType, len(fields))
+		for _, field := range fields {
+			types[field.Name] = field.Type
+		}
+		bindFilters(conf.Having)
+	}
+	if conf.Pagination != nil {
+		for name, param := range pageParams(conf.Pagination) {
+			if _, ok := params[name]; ok {
+				errs = append(errs, fmt.Errorf("access %s: param %s is reserved for pagination", conf.Name, name))
+			}
+			params[name] = param
+		}
+	}
+	if conf.OptimisticLock {
+		if _, ok := params[datahelpers.VersionParam]; ok {
+			errs = append(errs, fmt.Errorf("access %s: param %s is reserved for optimistic_lock", conf.Name, datahelpers.VersionParam))
+		}
+		params[datahelpers.VersionParam] = &accessParam{Type: golang.GoInt64Type}
+	}
+	if len(conf.SortOptions) > 0 {
+		if _, ok := params[datahelpers.SortParam]; ok {
+			errs = append(errs, fmt.Errorf("access %s: param %s is reserved for sort options", conf.Name, datahelpers.SortParam))
+		}
+		params[datahelpers.SortParam] = &accessParam{Type: golang.GoStringType, Selects: true}
+	}
+	return params, errors.Join(errs...)
+}
+
+func generateParamsStruct(paramRefs []defs.ParameterRef, params map[string]*accessParam, name string) *golang.StructDef {
 	nameWithTypes := make([]golang.NameWithType, 0, len(paramRefs))
+	imports := make([]string, 0)
 	uniqueParams := make(map[string]bool)
 	for _, param := range paramRefs {
 		if _, ok := uniqueParams[param.Name]; ok {
@@ -282,11 +675,19 @@ func generateParamsStruct(paramRefs []defs.ParameterRef, name string) *golang.St
 		uniqueParams[param.Name] = true
 		nameWithTypes = append(nameWithTypes, golang.NameWithType{
 			Name: param.Name,
-			Type: golang.GoInterfaceType,
+			Type: params[param.Name].Type,
 		})
+		if params[param.Name].Import != "" {
+			imports = append(imports, params[param.Name].Import)
+		}
+	}
+	if param, ok := params[datahelpers.SortParam]; ok && param.Selects {
+		nameWithTypes = append(nameWithTypes, golang.NameWithType{Name: datahelpers.SortParam, Type: param.Type})
 	}
 
-	return golang.GenStructForDataModel(fmt.Sprintf("%sParams", name), nameWithTypes, true, false, false)
+	paramsStruct := golang.GenStructForDataModel(fmt.Sprintf("%sParams", name), nameWithTypes, true, false, false)
+	paramsStruct.Imports = imports
+	return paramsStruct
 }
 
 func generateRequestStruct(name string, paramStructName string) *golang.StructDef {
@@ -296,8 +697,8 @@ func generateRequestStruct(name string, paramStructName string) *golang.StructDe
 	return golang.GenStructForDataModel(fmt.Sprintf("%sRequest", name), namedWithTypes, true, false, false)
 }
 
-func generateAccessStructs(paramRef []defs.ParameterRef, name string) []*golang.StructDef {
-	paramStruct := generateParamsStruct(paramRef, name)
+func generateAccessStructs(paramRefs []defs.ParameterRef, params map[string]*accessParam, name string) []*golang.StructDef {
+	paramStruct := generateParamsStruct(paramRefs, params, name)
 	reqStruct := generateRequestStruct(name, paramStruct.Name)
 	return []*golang.StructDef{paramStruct, reqStruct}
 }
@@ -323,12 +724,12 @@ func generateAccessStructs(paramRef []defs.ParameterRef, name string) []*golang.
 //		}
 //
 //	    func GetProductByID(ctx context.Context, db *Product_DB, requestParams GetProductByIDParams) (results []Product, err error) {
-//			stmt := db.preparedCache["GetProductByID"]
+//			stmt := db.statement(ctx, "GetProductByID")
 //			values, err := GetProductByIDParseParams(requestParams)
 //			if err != nil {
 //					return nil, err
 //			}
-//			rows, err := stmt.Query(values...)
+//			rows, err := stmt.QueryContext(ctx, values...)
 //			if err != nil {
 //					return nil, err
 //			}
@@ -344,7 +745,8 @@ func generateAccessStructs(paramRef []defs.ParameterRef, name string) []*golang.
 //			}
 //			return results, nil
 //	}
-func GenerateFindConfigs(modelName string, modelDBName string, findConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
+func GenerateFindConfigs(dialect datahelpers.Dialect, modelName string, modelDBName string, fieldTypes map[string]*golang.GoType,
+	findConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
 
 	functions := make([]*golang.FunctionDef, 0, len(findConfig))
 	reqs := make([]*golang.StructDef, 0, len(findConfig))
@@ -352,19 +754,36 @@ func GenerateFindConfigs(modelName string, modelDBName string, findConfig []defs
 
 	for _, conf := range findConfig {
 
-		query, paramRefs := datahelpers.MakeFindQuery(modelName, &conf)
-		queries = append(queries, NamedQuery{Name: conf.Name, Query: query})
-		accessStructs := generateAccessStructs(paramRefs, conf.Name)
+		query, paramRefs := datahelpers.MakeFindQuery(dialect, modelName, &conf)
+		params, err := readAccessParams(&conf, fieldTypes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		rowName, scanned, rowStruct, err := findRow(modelName, &conf, fieldTypes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		queries = append(queries, NamedQuery{Name: conf.Name, Query: query, Expand: inListExpansion(paramRefs)})
+		queries = append(queries, sortQueries(dialect, modelName, &conf)...)
+		accessStructs := generateAccessStructs(paramRefs, params, conf.Name)
 		reqs = append(reqs, accessStructs...)
+		if rowStruct != nil {
+			reqs = append(reqs, rowStruct)
+		}
 
-		paramFn := ReadParamsFunction(paramRefs, conf.Name, "values", "params")
+		paramFn := ReadParamsFunction(paramRefs, params, conf.Name, "values", "params")
 		functions = append(functions, paramFn)
 
-		attributes := conf.Attributes[:]
-		for i := 0; i < len(attributes); i++ {
-			attributes[i] = golang.ToPascalCase(attributes[i])
+		if conf.Pagination != nil {
+			reqs = append(reqs, generatePageStruct(conf.Name, rowName, conf.Pagination))
+		}
+		if len(conf.SortOptions) > 0 {
+			functions = append(functions, SortQueryFunction(&conf))
+		}
+		fn := FindCodeFunction(rowName, modelDBName, conf.Name, scanned, conf.Pagination, conf.OrderBy, len(conf.SortOptions) > 0)
+		if err := withTimeout(fn, &conf); err != nil {
+			return nil, nil, nil, err
 		}
-		fn := FindCodeFunction(modelName, modelDBName, conf.Name, conf.Attributes)
 		functions = append(functions, fn)
 	}
 
@@ -372,11 +791,139 @@ func GenerateFindConfigs(modelName string, modelDBName string, findConfig []defs
 
 }
 
+// projectionStructName is the struct of the rows of a find selecting some of the model attributes, GetUserByEmailRow
+func projectionStructName(name string) string {
+	return name + "Row"
+}
+
+// findRow returns the struct a find scans its rows into, and the fields it scans: one per selected attribute, in the
+// order of the SELECT list (see datahelpers.MakeFindQuery). A find selecting all the model fields scans the model
+// struct, a find selecting some of them (a projection) scans a struct of its own, the returned struct def.
+func findRow(modelName string, conf *defs.AccessConfig, fieldTypes map[string]*golang.GoType) (string, []string, *golang.StructDef, error) {
+	fields := make([]golang.NameWithType, 0, len(conf.Attributes))
+	scanned := make([]string, 0, len(conf.Attributes))
+	selected := make(map[string]bool, len(conf.Attributes))
+	for _, attribute := range conf.Attributes {
+		column := golang.ToSnakeCase(attribute)
+		fieldType, ok := fieldTypes[column]
+		if !ok {
+			return "", nil, nil, fmt.Errorf("access %s: selects unknown attribute %s", conf.Name, attribute)
+		}
+		if selected[column] {
+			return "", nil, nil, fmt.Errorf("access %s: selects %s more than once", conf.Name, attribute)
+		}
+		selected[column] = true
+		fields = append(fields, golang.NameWithType{Name: column, Type: fieldType})
+		scanned = append(scanned, golang.ToPascalCase(column))
+	}
+	if len(selected) == len(fieldTypes) {
+		return modelName, scanned, nil, nil
+	}
+	rowName := projectionStructName(conf.Name)
+	return rowName, scanned, golang.GenStructForDataModel(rowName, fields, false, false, true), nil
+}
+
+// GenerateFindWithIncludesConfigs generates the finders of a model, like GenerateFindConfigs, along with
+// the children of the found models for the configs that include other models.
+// Such a finder runs its query, then one query per included model for the children of all found models,
+// and returns the found models with their children:
+//
+//	type GetUserWithOrdersResult struct {
+//		User   User
+//		Orders []Order
+//	}
+//
+//	func GetUserWithOrders(ctx context.Context, db *User_DB, requestParams GetUserWithOrdersParams) ([]GetUserWithOrdersResult, error) {
+//		...
+//		orderStmt := db.statement(ctx, "GetUserWithOrdersOrder") // SELECT ... FROM order WHERE (1 = 1) AND (user_id = ANY($1))
+//		orderRows, err := orderStmt.QueryContext(ctx, pq.Array(ids))
+//		...
+//	}
+func GenerateFindWithIncludesConfigs(dialect datahelpers.Dialect, family *defs.DataConfig, model *defs.Model, modelName string, modelDBName string,
+	fieldTypes map[string]*golang.GoType, findConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
+
+	functions := make([]*golang.FunctionDef, 0, len(findConfig))
+	reqs := make([]*golang.StructDef, 0, len(findConfig))
+	queries := make([]NamedQuery, 0, len(findConfig))
+
+	for _, conf := range findConfig {
+		if len(conf.Include) == 0 {
+			confQueries, confFunctions, confStructs, err := GenerateFindConfigs(dialect, modelName, modelDBName, fieldTypes, []defs.AccessConfig{conf})
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			queries = append(queries, confQueries...)
+			functions = append(functions, confFunctions...)
+			reqs = append(reqs, confStructs...)
+			continue
+		}
+
+		includes := make([]*defs.IncludedModel, 0, len(conf.Include))
+		for _, include := range conf.Include {
+			included, err := family.ResolveInclude(model, include)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("access %s: %w", conf.Name, err)
+			}
+			includes = append(includes, included)
+		}
+
+		query, paramRefs := datahelpers.MakeFindQuery(dialect, modelName, &conf)
+		params, err := readAccessParams(&conf, fieldTypes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		queries = append(queries, NamedQuery{Name: conf.Name, Query: query, Expand: inListExpansion(paramRefs)})
+		queries = append(queries, sortQueries(dialect, modelName, &conf)...)
+		reqs = append(reqs, generateAccessStructs(paramRefs, params, conf.Name)...)
+		functions = append(functions, ReadParamsFunction(paramRefs, params, conf.Name, "values", "params"))
+		if len(conf.SortOptions) > 0 {
+			functions = append(functions, SortQueryFunction(&conf))
+		}
+
+		rowName, scanned, rowStruct, err := findRow(modelName, &conf, fieldTypes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if rowStruct != nil {
+			reqs = append(reqs, rowStruct)
+		}
+		resultFields := []golang.NameWithType{{Name: modelName, Type: &golang.GoType{Name: rowName}}}
+		expandIds := false
+		for _, included := range includes {
+			childQuery, childParamRefs := datahelpers.MakeFindQuery(dialect, included.Model, &defs.AccessConfig{
+				Attributes:     included.Attributes,
+				Filter:         []defs.Filter{{Attribute: included.Reference.Column, Operator: "IN", ParamName: included.Reference.Column}},
+				ExcludeDeleted: included.SoftDelete,
+			})
+			queries = append(queries, NamedQuery{Name: includeQueryName(conf.Name, included), Query: childQuery,
+				Expand: inListExpansion(childParamRefs)})
+			expandIds = childParamRefs[0].Expand
+			resultFields = append(resultFields, golang.NameWithType{
+				Name: includeFieldName(included),
+				Type: &golang.GoType{Name: "[]" + golang.ToPascalCase(included.Model)},
+			})
+		}
+		reqs = append(reqs, golang.GenStructForDataModel(conf.Name+"Result", resultFields, false, false, false))
+		if conf.Pagination != nil {
+			reqs = append(reqs, generatePageStruct(conf.Name, conf.Name+"Result", conf.Pagination))
+		}
+		fn := FindWithIncludesCodeFunction(modelName, modelDBName, conf.Name,
+			scanned, includes, conf.Pagination, conf.OrderBy, len(conf.SortOptions) > 0, expandIds)
+		if err := withTimeout(fn, &conf); err != nil {
+			return nil, nil, nil, err
+		}
+		functions = append(functions, fn)
+	}
+
+	return queries, functions, reqs, nil
+}
+
 // GenerateUpdateConfigs will generate all UPDATE queries for a given model
 // Similar to GenerateFindConfigs
 // It generates Update function, and one helper function for reading params from request to bind values to query
 // It generates 2 structs for params and request, request is input (arg) to Update function, and params is part of request
-func GenerateUpdateConfigs(modelName string, modelDBName string, updateConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
+func GenerateUpdateConfigs(dialect datahelpers.Dialect, modelName string, modelDBName string, fieldTypes map[string]*golang.GoType,
+	updateConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
 
 	functions := make([]*golang.FunctionDef, 0, len(updateConfig))
 	reqs := make([]*golang.StructDef, 0, len(updateConfig))
@@ -384,15 +931,29 @@ func GenerateUpdateConfigs(modelName string, modelDBName string, updateConfig []
 
 	for _, conf := range updateConfig {
 
-		query, paramRefs := datahelpers.MakeUpdateQuery(modelName, &conf)
-		queries = append(queries, NamedQuery{Name: conf.Name, Query: query})
+		query, paramRefs := datahelpers.MakeUpdateQuery(dialect, modelName, &conf)
+		params, err := readAccessParams(&conf, fieldTypes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		queries = append(queries, NamedQuery{Name: conf.Name, Query: query, Expand: inListExpansion(paramRefs)})
 
-		accessStructs := generateAccessStructs(paramRefs, conf.Name)
+		accessStructs := generateAccessStructs(paramRefs, params, conf.Name)
 		reqs = append(reqs, accessStructs...)
-		paramFn := ReadParamsFunction(paramRefs, conf.Name, "values", "params")
+		paramFn := ReadParamsFunction(paramRefs, params, conf.Name, "values", "params")
 		functions = append(functions, paramFn)
 
 		fn := UpdateCodeFunction(conf.Name, modelDBName)
+		switch {
+		case conf.OptimisticLock && returnsRows(dialect):
+			fn = LockedUpdateCodeFunction(conf.Name, modelDBName)
+		case conf.OptimisticLock:
+			fn = LockedUpdateExecCodeFunction(conf.Name, modelDBName)
+		}
+		withActor(fn, &conf)
+		if err := withTimeout(fn, &conf); err != nil {
+			return nil, nil, nil, err
+		}
 		functions = append(functions, fn)
 	}
 
@@ -404,42 +965,101 @@ func GenerateUpdateConfigs(modelName string, modelDBName string, updateConfig []
 // Similar to GenerateFindConfigs
 // It generates Add(INSERT) function, and one helper function for reading params from request to bind values to query
 // It generates 2 structs for params and request, request is input (arg) to Add function, and params is part of request
-func GenerateAddConfigs(modelName string, modelDBName string, addConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
+func GenerateAddConfigs(dialect datahelpers.Dialect, modelName string, modelDBName string, fieldTypes map[string]*golang.GoType,
+	addConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
 	functions := make([]*golang.FunctionDef, 0, len(addConfig))
 	reqs := make([]*golang.StructDef, 0, len(addConfig))
 	queries := make([]NamedQuery, 0, len(addConfig))
 
 	for _, conf := range addConfig {
-		query, paramRefs := datahelpers.MakeAddQuery(modelName, &conf)
-		queries = append(queries, NamedQuery{Name: conf.Name, Query: query})
-		accessStructs := generateAccessStructs(paramRefs, conf.Name)
+		query, paramRefs := datahelpers.MakeAddQuery(dialect, modelName, &conf)
+		params, err := readAccessParams(&conf, fieldTypes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		queries = append(queries, NamedQuery{Name: conf.Name, Query: query, Expand: inListExpansion(paramRefs)})
+		accessStructs := generateAccessStructs(paramRefs, params, conf.Name)
 		reqs = append(reqs, accessStructs...)
-		paramFn := ReadParamsFunction(paramRefs, conf.Name, "values", "params")
+		paramFn := ReadParamsFunction(paramRefs, params, conf.Name, "values", "params")
 		functions = append(functions, paramFn)
-		fn := AddCodeFunction(conf.Name, modelDBName)
+		fn := AddCodeFunction(conf.Name, modelDBName, fieldTypes["id"].Name)
+		if !returnsRows(dialect) {
+			fn = AddLastInsertIdCodeFunction(conf.Name, modelDBName, fieldTypes["id"].Name)
+		}
+		withActor(fn, &conf)
+		if err := withTimeout(fn, &conf); err != nil {
+			return nil, nil, nil, err
+		}
 		functions = append(functions, fn)
 	}
 	return queries, functions, reqs, nil
 
 }
 
+// replaceAccess returns the accesses of a model with the conflict key of its add_or_replaces, see conflictKey
+func replaceAccess(model *defs.Model, access defs.Access) defs.Access {
+	replaces := make([]defs.AccessConfig, 0, len(access.AddOrReplace))
+	for _, conf := range access.AddOrReplace {
+		conf.ConflictKey = conflictKey(model, &conf)
+		replaces = append(replaces, conf)
+	}
+	access.AddOrReplace = replaces
+	return access
+}
+
+// conflictKey is the unique key whose row an add_or_replace config replaces: the columns of the first unique
+// constraint of the model whose attributes are all values of the config, or the id when it's one of them.
+// Nil when the values hold no unique key, which ValidateDataConfig reports
+func conflictKey(model *defs.Model, conf *defs.AccessConfig) []string {
+	values := map[string]bool{}
+	for _, value := range conf.Values {
+		values[golang.ToSnakeCase(value.Attribute)] = true
+	}
+	for _, constraint := range model.UniqueConstraints {
+		key := make([]string, 0, len(constraint.Attributes))
+		for _, attributeId := range constraint.Attributes {
+			if attribute, ok := config.Attributes[attributeId]; ok && values[golang.ToSnakeCase(attribute.Name)] {
+				key = append(key, golang.ToSnakeCase(attribute.Name))
+			}
+		}
+		if len(key) > 0 && len(key) == len(constraint.Attributes) {
+			return key
+		}
+	}
+	if values["id"] {
+		return []string{"id"}
+	}
+	return nil
+}
+
 // GenerateAddOrReplaceConfigs will generate all INSERT OR UPDATE queries for a given model
 // Similar to GenerateFindConfigs
 // It generates AddOrReplace(INSERT OR UPDATE) function, and one helper function for reading params from request to bind values to query
 // It generates 2 structs for params and request, request is input (arg) to AddOrReplace function, and params is part of request
-func GenerateAddOrReplaceConfigs(modelName string, modelDBName string, addOrReplaceConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
+func GenerateAddOrReplaceConfigs(dialect datahelpers.Dialect, modelName string, modelDBName string, fieldTypes map[string]*golang.GoType,
+	addOrReplaceConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
 	functions := make([]*golang.FunctionDef, 0, len(addOrReplaceConfig))
 	reqs := make([]*golang.StructDef, 0, len(addOrReplaceConfig))
 	queries := make([]NamedQuery, 0, len(addOrReplaceConfig))
 
 	for _, conf := range addOrReplaceConfig {
-		query, paramRefs := datahelpers.MakeAddOrReplaceQuery(modelName, &conf)
-		queries = append(queries, NamedQuery{Name: conf.Name, Query: query})
-		accessStructs := generateAccessStructs(paramRefs, conf.Name)
+		query, paramRefs := datahelpers.MakeAddOrReplaceQuery(dialect, modelName, &conf)
+		params, err := readAccessParams(&conf, fieldTypes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		queries = append(queries, NamedQuery{Name: conf.Name, Query: query, Expand: inListExpansion(paramRefs)})
+		accessStructs := generateAccessStructs(paramRefs, params, conf.Name)
 		reqs = append(reqs, accessStructs...)
-		paramFn := ReadParamsFunction(paramRefs, conf.Name, "values", "params")
+		paramFn := ReadParamsFunction(paramRefs, params, conf.Name, "values", "params")
 		functions = append(functions, paramFn)
-		fn := AddOrReplaceCodeFunction(conf.Name, modelDBName)
+		fn := AddOrReplaceCodeFunction(conf.Name, modelDBName, fieldTypes["id"].Name)
+		if !returnsRows(dialect) {
+			fn = AddOrReplaceLastInsertIdCodeFunction(conf.Name, modelDBName, fieldTypes["id"].Name)
+		}
+		if err := withTimeout(fn, &conf); err != nil {
+			return nil, nil, nil, err
+		}
 		functions = append(functions, fn)
 	}
 	return queries, functions, reqs, nil
@@ -465,12 +1085,12 @@ func GenerateAddOrReplaceConfigs(modelName string, modelDBName string, addOrRepl
 //	}
 //
 //	func DeleteProduct(ctx context.Context, db *sql.DB, requestParams DeleteProductParams) (int64, error) {
-//		stmt := db.preparedCache["DeleteProduct"]
+//		stmt := db.statement(ctx, "DeleteProduct")
 //		values, err := DeleteProductParseParams(requestParams)
 //		if err != nil {
 //				return int64(0), err
 //		}
-//		result, err := stmt.Exec(values...)
+//		result, err := stmt.ExecContext(ctx, values...)
 //		if err != nil {
 //				return int64(0), err
 //		}
@@ -480,19 +1100,31 @@ func GenerateAddOrReplaceConfigs(modelName string, modelDBName string, addOrRepl
 //		}
 //		return rowsAffected, nil
 //	}
-func GenerateDeleteConfigs(modelName string, modelDBName string, deleteConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
+func GenerateDeleteConfigs(dialect datahelpers.Dialect, modelName string, modelDBName string, fieldTypes map[string]*golang.GoType,
+	deleteConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
 	functions := make([]*golang.FunctionDef, 0, len(deleteConfig))
 	reqs := make([]*golang.StructDef, 0, len(deleteConfig))
 	queries := make([]NamedQuery, 0, len(deleteConfig))
 
 	for _, conf := range deleteConfig {
-		query, paramRefs := datahelpers.MakeDeleteQuery(modelName, &conf)
-		queries = append(queries, NamedQuery{Name: conf.Name, Query: query})
-		accessStructs := generateAccessStructs(paramRefs, conf.Name)
+		query, paramRefs := datahelpers.MakeDeleteQuery(dialect, modelName, &conf)
+		params, err := readAccessParams(&conf, fieldTypes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		queries = append(queries, NamedQuery{Name: conf.Name, Query: query, Expand: inListExpansion(paramRefs)})
+		accessStructs := generateAccessStructs(paramRefs, params, conf.Name)
 		reqs = append(reqs, accessStructs...)
-		paramFn := ReadParamsFunction(paramRefs, conf.Name, "values", "params")
+		paramFn := ReadParamsFunction(paramRefs, params, conf.Name, "values", "params")
 		functions = append(functions, paramFn)
 		fn := DeleteCodeFunction(conf.Name, modelDBName)
+		if conf.OptimisticLock {
+			fn = LockedDeleteCodeFunction(conf.Name, modelDBName)
+		}
+		withActor(fn, &conf)
+		if err := withTimeout(fn, &conf); err != nil {
+			return nil, nil, nil, err
+		}
 		functions = append(functions, fn)
 	}
 	return queries, functions, reqs, nil
@@ -512,3 +1144,94 @@ func SetupDatabaseFunction(dataConf defs.DataConfig) (*golang.FunctionDef, error
 	}
 	return fn, nil
 }
+
+// validateIncludes checks the models included by finds, see GenerateFindWithIncludesConfigs
+func validateIncludes(dataConfig *defs.DataConfig, modelConfig *defs.ModelConfig) []error {
+	errs := []error{}
+	modelName := modelConfig.Model.Name
+	for _, accessConfig := range modelConfig.GetAllAccessConfig() {
+		if len(accessConfig.Include) > 0 && !slices.ContainsFunc(modelConfig.Access.Find, func(find defs.AccessConfig) bool {
+			return find.Name == accessConfig.Name
+		}) {
+			errs = append(errs, fmt.Errorf("model %s: access %s includes models, which only finds do", modelName, accessConfig.Name))
+		}
+	}
+
+	for _, accessConfig := range modelConfig.Access.Find {
+		if len(accessConfig.Include) == 0 {
+			continue
+		}
+		// Children are matched to the found models by id
+		if !slices.ContainsFunc(accessConfig.Attributes, func(attr string) bool { return golang.ToSnakeCase(attr) == "id" }) {
+			errs = append(errs, fmt.Errorf("model %s: access %s includes models, which needs id among its attributes", modelName, accessConfig.Name))
+		}
+		included := map[string]bool{}
+		for _, include := range accessConfig.Include {
+			resolved, err := dataConfig.ResolveInclude(&modelConfig.Model, include)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("model %s: access %s: %w", modelName, accessConfig.Name, err))
+				continue
+			}
+			if included[resolved.Model] {
+				errs = append(errs, fmt.Errorf("model %s: access %s includes %s more than once", modelName, accessConfig.Name, resolved.Model))
+			}
+			included[resolved.Model] = true
+
+			childColumns := modelColumns(dataConfig.ModelByName(resolved.Model), dataConfig)
+			for _, attr := range include.Attributes {
+				if !childColumns[golang.ToSnakeCase(attr)] {
+					errs = append(errs, fmt.Errorf("model %s: access %s includes %s with unknown attribute %s",
+						modelName, accessConfig.Name, resolved.Model, attr))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// validateAddOrReplace checks that the add_or_replaces have a unique key to replace the rows of, see conflictKey
+func validateAddOrReplace(modelConfig *defs.ModelConfig) []error {
+	errs := []error{}
+	for _, accessConfig := range modelConfig.Access.AddOrReplace {
+		if conflictKey(&modelConfig.Model, &accessConfig) == nil {
+			errs = append(errs, fmt.Errorf("model %s: access %s has no unique key among its values, expected the attributes of a unique constraint or the id",
+				modelConfig.Model.Name, accessConfig.Name))
+		}
+	}
+	return errs
+}
+
+// ExplainModel returns the named queries generated for a model of the family, exactly as they get prepared
+// by the generated <Model>PrepareStmts function.
+func ExplainModel(modelConfig defs.ModelConfig, family *defs.DataConfig) ([]NamedQuery, error) {
+	references, err := family.References(&modelConfig.Model)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := modelFields(&modelConfig, references)
+	if err != nil {
+		return nil, err
+	}
+	dialect, err := familyDialect(family)
+	if err != nil {
+		return nil, err
+	}
+	modelConfig.Access = replaceAccess(&modelConfig.Model, modelConfig.Access)
+	if modelConfig.Model.SoftDelete {
+		modelConfig.Access = softDeleteAccess(modelConfig.Access)
+	}
+	if modelConfig.Model.Audit {
+		modelConfig.Access = auditAccess(modelConfig.Access)
+	}
+	modelNameMap, _, _, err := generateModel(dialect, &modelConfig, fields, &attributeValidations{})
+	if err != nil {
+		return nil, err
+	}
+	queries := make([]NamedQuery, 0)
+	err = geneateAllAccessMethods(dialect, modelConfig, family, modelNameMap.ModelStructName, modelNameMap.ModelDBStructName,
+		fieldTypes(fields), &queries, &[]*golang.FunctionDef{}, &[]*golang.StructDef{})
+	if err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
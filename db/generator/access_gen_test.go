@@ -1,11 +1,14 @@
 package generator
 
 import (
+	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"stellarsky.ai/platform/codegen/data-service-generator/config"
 	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
 	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
 )
 
@@ -32,12 +35,37 @@ func TestGenerateFindConfigs(t *testing.T) {
 		},
 	}
 
-	queries, functions, structs, err := GenerateFindConfigs("Product", "Product_DB", findConfigs)
+	types := fieldTypes([]golang.NameWithType{
+		{Name: "attr1", Type: golang.GoStringType}, {Name: "attr2", Type: golang.GoInt64Type},
+		{Name: "attr3", Type: golang.GoStringType}, {Name: "attr4", Type: golang.GoFloat64Type},
+		{Name: "attr5", Type: golang.GoStringType}, {Name: "attr6", Type: golang.GoInt64Type},
+		{Name: "attr7", Type: golang.GoTimeType}, {Name: "attr8", Type: golang.GoStringType},
+	})
+	queries, functions, structs, err := GenerateFindConfigs(datahelpers.NewPostgresDialect(), "Product", "Product_DB", types, findConfigs)
 	assert.NoError(t, err)
 	assert.NotNil(t, queries)
 	assert.NotNil(t, functions)
 	assert.NotNil(t, structs)
 
+	// Params are typed from the attributes they filter
+	paramsCode, _ := structs[0].StructCode()
+	assert.Equal(t, "type FindConfig1Params struct {\n"+
+		"\tP1 string\t`json:\"p_1\"`\n"+
+		"\tP2 int64\t`json:\"p_2\"`\n"+
+		"\tP3 string\t`json:\"p_3\"`\n"+
+		"\tP4 float64\t`json:\"p_4\"`\n"+
+		"\tP5 string\t`json:\"p_5\"`\n"+
+		"\tP6 int64\t`json:\"p_6\"`\n"+
+		"\tP7 Range[time.Time]\t`json:\"p_7\"`\n"+
+		"\tP8 []string\t`json:\"p_8\"`\n"+
+		"}\n", paramsCode)
+
+	_, _, _, err = GenerateFindConfigs(datahelpers.NewPostgresDialect(), "Product", "Product_DB", types, []defs.AccessConfig{{
+		Name:   "FindConfig2",
+		Filter: []defs.Filter{{Attribute: "attr1", Operator: "=", ParamName: "p1"}, {Attribute: "attr9", Operator: "=", ParamName: "p9"}},
+	}})
+	assert.EqualError(t, err, "access FindConfig2: param p9: unknown attribute attr9")
+
 	sourceFile := &golang.GoSourceFile{
 		Package:   "database",
 		Structs:   structs,
@@ -48,18 +76,168 @@ func TestGenerateFindConfigs(t *testing.T) {
 	// Add more assertions as needed to validate the output
 }
 
+func TestNullableGoType(t *testing.T) {
+	assert.Equal(t, "sql.NullString", nullableGoType(golang.GoStringType).Name)
+	assert.Equal(t, "sql.NullFloat64", nullableGoType(golang.GoFloat64Type).Name)
+	assert.Equal(t, "*int", nullableGoType(golang.GoIntType).Name)
+	assert.Equal(t, golang.GoTimeType, nullableGoType(golang.GoTimeType))
+	assert.Equal(t, golang.GoInterfaceType, nullableGoType(golang.GoInterfaceType))
+
+	// Values set nullable columns, filters compare with the value type
+	types := map[string]*golang.GoType{"name": nullableGoType(golang.GoStringType), "stock": nullableGoType(golang.GoIntType)}
+	params, err := readAccessParams(&defs.AccessConfig{
+		Name:   "UpdateStock",
+		Set:    []defs.Update{{Attribute: "name", ParamName: "new_name"}, {Attribute: "stock", ParamName: "stock"}},
+		Filter: []defs.Filter{{Attribute: "name", Operator: "in", ParamName: "names"}, {Attribute: "stock", Operator: ">", ParamName: "min_stock"}},
+	}, types)
+	assert.NoError(t, err)
+	assert.Equal(t, "sql.NullString", params["new_name"].Type.Name)
+	assert.Equal(t, "*int", params["stock"].Type.Name)
+	assert.Equal(t, "[]string", params["names"].Type.Name)
+	assert.Equal(t, "int", params["min_stock"].Type.Name)
+}
+
+func TestSystemFields(t *testing.T) {
+	// The fields of the system columns, in the order of the columns of the table
+	fields, err := systemFields(&defs.Model{Name: "Product"})
+	assert.NoError(t, err)
+	assert.Equal(t, []golang.NameWithType{
+		{Name: "id", Type: golang.GoStringType},
+		{Name: "version", Type: golang.GoInt64Type},
+		{Name: "updated_at", Type: golang.GoTimeType},
+	}, fields)
+
+	// Soft delete models have the deletion time of their rows, NULL for the rows that are not deleted
+	fields, err = systemFields(&defs.Model{Name: "Product", SoftDelete: true})
+	assert.NoError(t, err)
+	assert.Len(t, fields, 4)
+	assert.Equal(t, golang.NameWithType{Name: "deleted_at", Type: nullableGoType(golang.GoTimeType)}, fields[3])
+}
+
+func TestAggregateGoType(t *testing.T) {
+	types := map[string]*golang.GoType{
+		"stock": nullableGoType(golang.GoInt32Type), "price": golang.GoFloat64Type, "created_at": golang.GoTimeType,
+	}
+	for aggregate, expected := range map[defs.Aggregate]string{
+		{Function: "COUNT"}:                       "int64",
+		{Function: "sum", Attribute: "stock"}:     "sql.NullInt64",
+		{Function: "SUM", Attribute: "price"}:     "sql.NullFloat64",
+		{Function: "AVG", Attribute: "stock"}:     "sql.NullFloat64",
+		{Function: "MIN", Attribute: "stock"}:     "sql.NullInt32",
+		{Function: "MAX", Attribute: "createdAt"}: golang.GoTimeType.Name,
+	} {
+		aggregateType, err := aggregateGoType(aggregate, types)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, aggregateType.Name, aggregate)
+	}
+
+	// Having params compare the values of the aggregates
+	params, err := readAccessParams(&defs.AccessConfig{
+		Name:       "StockByPrice",
+		GroupBy:    []string{"price"},
+		Aggregates: []defs.Aggregate{{Function: "SUM", Attribute: "stock", Name: "total"}},
+		Having:     []defs.Filter{{Attribute: "total", Operator: ">=", ParamName: "min_total"}},
+	}, types)
+	assert.NoError(t, err)
+	assert.Equal(t, "int64", params["min_total"].Type.Name)
+}
+
+func TestFindRow(t *testing.T) {
+	types := map[string]*golang.GoType{"id": golang.GoStringType, "sku": golang.GoStringType, "price": golang.GoFloat64Type}
+
+	// A projection scans a row struct with the selected fields, in the order of the SELECT list
+	rowName, scanned, rowStruct, err := findRow("Product", &defs.AccessConfig{Name: "ListPrices", Attributes: []string{"price", "sku"}}, types)
+	assert.NoError(t, err)
+	assert.Equal(t, "ListPricesRow", rowName)
+	assert.Equal(t, []string{"Price", "Sku"}, scanned)
+	assert.Equal(t, "ListPricesRow", rowStruct.Name)
+	assert.Len(t, rowStruct.Fields, 2)
+
+	rowName, scanned, rowStruct, err = findRow("Product", &defs.AccessConfig{Name: "ListAll", Attributes: []string{"sku", "id", "price"}}, types)
+	assert.NoError(t, err)
+	assert.Equal(t, "Product", rowName)
+	assert.Equal(t, []string{"Sku", "Id", "Price"}, scanned)
+	assert.Nil(t, rowStruct)
+
+	_, _, _, err = findRow("Product", &defs.AccessConfig{Name: "ListNames", Attributes: []string{"name"}}, types)
+	assert.EqualError(t, err, "access ListNames: selects unknown attribute name")
+}
+
+func TestTxOptions(t *testing.T) {
+	options, err := txOptions(&defs.DatabaseConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, "nil", options)
+
+	options, err = txOptions(&defs.DatabaseConfig{IsolationLevel: "REPEATABLE_READ"})
+	assert.NoError(t, err)
+	assert.Equal(t, "&sql.TxOptions{Isolation: sql.LevelRepeatableRead}", options)
+
+	_, err = txOptions(&defs.DatabaseConfig{IsolationLevel: "snapshot"})
+	assert.EqualError(t, err, `dataconf has isolation level "snapshot", expected read_uncommitted, read_committed, repeatable_read or serializable`)
+}
+
+func TestAddManyChunkRows(t *testing.T) {
+	values := []defs.Update{{Attribute: "sku", ParamName: "sku"}, {Attribute: "price", ParamName: "price"}}
+	assert.Equal(t, defs.DefaultChunkSize, (&defs.AccessConfig{Values: values}).ChunkRows())
+	assert.Equal(t, 250, (&defs.AccessConfig{Values: values, ChunkSize: 250}).ChunkRows())
+	// A chunk binds at most the params of a statement
+	assert.Equal(t, defs.MaxBindParams/2, (&defs.AccessConfig{Values: values, ChunkSize: 100000}).ChunkRows())
+
+	fn := AddManyQueryFunction(datahelpers.NewPostgresDialect(), "Product", &defs.AccessConfig{Name: "AddProducts", Values: values})
+	fnCode, _ := fn.FunctionCode()
+	assert.Equal(t, `func AddProductsQuery(rows int) string {
+	tuples := make([]string, rows)
+	for row := range tuples {
+		tuples[row] = fmt.Sprintf("($%d, $%d)", row*2+1, row*2+2)
+	}
+	return "INSERT INTO \"product\" (\"sku\", \"price\") VALUES " + strings.Join(tuples, ", ") + " RETURNING \"id\""
+}`, fnCode)
+
+	// Positional placeholders are the same for every row, and MySQL returns no ids
+	fn = AddManyQueryFunction(datahelpers.NewMySQLDialect(), "Product", &defs.AccessConfig{Name: "AddProducts", Values: values})
+	fnCode, fnImports := fn.FunctionCode()
+	assert.Equal(t, `func AddProductsQuery(rows int) string {
+	tuples := make([]string, rows)
+	for row := range tuples {
+		tuples[row] = "(?, ?)"
+	}
+	return "INSERT INTO `+"`product` (`sku`, `price`)"+` VALUES " + strings.Join(tuples, ", ")
+}`, fnCode)
+	assert.Equal(t, map[string]bool{"strings": true}, fnImports)
+}
+
+func TestAddManyTimeout(t *testing.T) {
+	values := []defs.Update{{Attribute: "sku", ParamName: "sku"}, {Attribute: "price", ParamName: "price"}}
+	fieldTypes := map[string]*golang.GoType{"sku": golang.GoStringType, "price": golang.GoFloat64Type}
+	_, functions, _, err := GenerateAddManyConfigs(datahelpers.NewPostgresDialect(), "Product", "Product_DB", fieldTypes, []defs.AccessConfig{
+		{Name: "AddProducts", Values: values, Timeout: "5s"},
+		{Name: "ImportProducts", Values: values, Copy: true, Timeout: "30s"},
+	})
+	assert.NoError(t, err)
+
+	// The timeout bounds the whole batch, every chunk or the COPY with its commit
+	for name, timeout := range map[string]string{"AddProducts": "5*time.Second", "ImportProducts": "30*time.Second"} {
+		i := slices.IndexFunc(functions, func(fn *golang.FunctionDef) bool { return fn.Name == name })
+		assert.NotEqual(t, -1, i, name)
+		fnCode, fnImports := functions[i].FunctionCode()
+		assert.Contains(t, fnCode, fmt.Sprintf("\tctx, cancel := context.WithTimeout(ctx, %s)\n\tdefer cancel()\n\tvar rowErrs []error", timeout))
+		assert.True(t, fnImports["time"], name)
+	}
+}
+
 func TestGenerate_Success(t *testing.T) {
 	// Create a sample ModelConfig for testing
 	cfg := defs.ModelConfig{
 		Model: defs.Model{
-			Name:       "Product",
-			Attributes: []int64{2000001, 2000002, 2000003, 2000004},
+			Name:              "Product",
+			Attributes:        []int64{2000001, 2000002, 2000003, 2000004},
+			UniqueConstraints: []defs.UniqueConstraint{{ConstraintName: "product_sku_unique", Attributes: []int64{2000001}}},
 		},
 		Access: defs.Access{
 			Find: []defs.AccessConfig{
 				{
 					Name:       "GetProductByID",
-					Attributes: []string{"id", "name", "price", "quantity"},
+					Attributes: []string{"id", "product_name", "price"},
 					Filter: []defs.Filter{{
 						Attribute: "ID",
 						Operator:  "=",
@@ -79,7 +257,7 @@ func TestGenerate_Success(t *testing.T) {
 					},
 					Set: []defs.Update{
 						{
-							Attribute: "name",
+							Attribute: "product_name",
 							ParamName: "name",
 						},
 					},
@@ -129,7 +307,7 @@ func TestGenerate_Success(t *testing.T) {
 	}
 
 	config.LoadConfig()
-	goSrc, _, err := Generate(cfg)
+	goSrc, _, err := Generate(cfg, nil)
 	t.Log(goSrc.SourceCode())
 
 	assert.NoError(t, err)
@@ -142,7 +320,7 @@ func TestGenerate_ErrorCase(t *testing.T) {
 		// Populate the necessary fields for testing the error case
 	}
 
-	goSrc, _, err := Generate(config)
+	goSrc, _, err := Generate(config, nil)
 
 	assert.Nil(t, err)
 	assert.NotNil(t, goSrc)
@@ -176,6 +354,7 @@ func TestGenerateFamily(t *testing.T) {
 				MaxIdleConns: 5,
 				MaxOpenConns: 10,
 			},
+			IsolationLevel: "serializable",
 		}, // Just to avoid error returned by GenerateDB function
 		Models: []defs.ModelConfig{},
 	}
@@ -189,8 +368,10 @@ func TestGenerateFamily(t *testing.T) {
 	expectedSrcCode := `package database
 
 import (
-	"_ github.com/lib/pq"
+	"context"
 	"database/sql"
+	"errors"
+	_ "github.com/lib/pq"
 	"time"
 )
 
@@ -202,6 +383,15 @@ type ecommerceDb struct {
 	Order     *Order_DB
 	OrderItem *OrderItem_DB
 	UserCart  *UserCart_DB
+	db        *sql.DB
+}
+
+type EcommerceDbTx struct {
+	Product   *Product_DB
+	User      *User_DB
+	Order     *Order_DB
+	OrderItem *OrderItem_DB
+	UserCart  *UserCart_DB
 }
 
 func InitEcommerceDb() error {
@@ -209,7 +399,7 @@ func InitEcommerceDb() error {
 	if err != nil {
 		return err
 	}
-	stmtMapProduct, err := ProductPrepareStmt()
+	stmtMapProduct, err := ProductPrepareStmts(db)
 	if err != nil {
 		return err
 	}
@@ -217,7 +407,7 @@ func InitEcommerceDb() error {
 		db:            db,
 		preparedCache: stmtMapProduct,
 	}
-	stmtMapUser, err := UserPrepareStmt()
+	stmtMapUser, err := UserPrepareStmts(db)
 	if err != nil {
 		return err
 	}
@@ -225,7 +415,7 @@ func InitEcommerceDb() error {
 		db:            db,
 		preparedCache: stmtMapUser,
 	}
-	stmtMapOrder, err := OrderPrepareStmt()
+	stmtMapOrder, err := OrderPrepareStmts(db)
 	if err != nil {
 		return err
 	}
@@ -233,7 +423,7 @@ func InitEcommerceDb() error {
 		db:            db,
 		preparedCache: stmtMapOrder,
 	}
-	stmtMapOrderItem, err := OrderItemPrepareStmt()
+	stmtMapOrderItem, err := OrderItemPrepareStmts(db)
 	if err != nil {
 		return err
 	}
@@ -241,7 +431,7 @@ func InitEcommerceDb() error {
 		db:            db,
 		preparedCache: stmtMapOrderItem,
 	}
-	stmtMapUserCart, err := UserCartPrepareStmt()
+	stmtMapUserCart, err := UserCartPrepareStmts(db)
 	if err != nil {
 		return err
 	}
@@ -250,6 +440,7 @@ func InitEcommerceDb() error {
 		preparedCache: stmtMapUserCart,
 	}
 	EcommerceDb = &ecommerceDb{
+		db:        db,
 		Product:   product,
 		User:      user,
 		Order:     order,
@@ -258,6 +449,30 @@ func InitEcommerceDb() error {
 	}
 	return nil
 }
+func (f *ecommerceDb) WithTx(ctx context.Context, fn func(tx *EcommerceDbTx) error) error {
+	return f.WithTxOptions(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable}, fn)
+}
+func (f *ecommerceDb) WithTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(tx *EcommerceDbTx) error) error {
+	tx, err := f.db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer rollbackOnPanic(tx)
+	err = fn(&EcommerceDbTx{Product: f.Product.withTx(tx), User: f.User.withTx(tx), Order: f.Order.withTx(tx), OrderItem: f.OrderItem.withTx(tx), UserCart: f.UserCart.withTx(tx)})
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+func rollbackOnPanic(tx *sql.Tx) {
+	if recovered := recover(); recovered != nil {
+		_ = tx.Rollback()
+		panic(recovered)
+	}
+}
 func SetupDBConnection() (*sql.DB, error) {
 	driverName, dsn := "postgres", "user=user password=password dbname=ecommerce port=5432 host=localhost"
 	idleConnTimeout, connMaxLifetime := (time.Second * 10), (time.Minute * 30)
@@ -313,8 +528,11 @@ func TestGenerateDB(t *testing.T) {
 		Models: []defs.ModelConfig{
 			{
 				Model: defs.Model{
-					Name:       "User",
-					Attributes: []int64{2000007, 2000008, 2000009, 2000010},
+					ID:                1,
+					Name:              "User",
+					Attributes:        []int64{2000007, 2000008, 2000009, 2000010},
+					UniqueConstraints: []defs.UniqueConstraint{{ConstraintName: "user_email_unique", Attributes: []int64{2000007}}},
+					SoftDelete:        true,
 				},
 				Access: defs.Access{
 					Find: []defs.AccessConfig{
@@ -345,6 +563,19 @@ func TestGenerateDB(t *testing.T) {
 								ParamName: "id",
 							}},
 						},
+						{
+							Name:       "GetUserWithOrders",
+							Attributes: []string{"id", "name", "email"},
+							Filter: []defs.Filter{{
+								Attribute: "email",
+								Operator:  "=",
+								ParamName: "email",
+							}},
+							Include: []defs.Include{{
+								Model:      "Order",
+								Attributes: []string{"id", "order_date", "order_status"},
+							}},
+						},
 					},
 					Update: []defs.AccessConfig{
 						{
@@ -358,6 +589,7 @@ func TestGenerateDB(t *testing.T) {
 								Attribute: "name",
 								ParamName: "name",
 							}},
+							OptimisticLock: true,
 						},
 					},
 					Add: []defs.AccessConfig{
@@ -406,6 +638,7 @@ func TestGenerateDB(t *testing.T) {
 								Operator:  "=",
 								ParamName: "id",
 							}},
+							OptimisticLock: true,
 						},
 					},
 				},
@@ -425,14 +658,48 @@ func TestGenerateDB(t *testing.T) {
 								Operator:  "=",
 								ParamName: "sku",
 							}},
+							Timeout: "2s",
+						},
+						{
+							Name:       "ListProducts",
+							Attributes: []string{"id", "sku", "product_name", "price"},
+							Filter: []defs.Filter{{
+								Attribute: "price",
+								Operator:  "<=",
+								ParamName: "max_price",
+							}},
+							Pagination: &defs.Pagination{Type: "keyset", DefaultLimit: 100},
+						},
+					},
+					AddMany: []defs.AccessConfig{
+						{
+							Name: "AddProducts",
+							Values: []defs.Update{
+								{Attribute: "sku", ParamName: "sku"},
+								{Attribute: "product_name", ParamName: "product_name"},
+								{Attribute: "price", ParamName: "price"},
+							},
+							ChunkSize: 500,
+						},
+						{
+							Name: "ImportProducts",
+							Values: []defs.Update{
+								{Attribute: "sku", ParamName: "sku"},
+								{Attribute: "product_name", ParamName: "product_name"},
+								{Attribute: "price", ParamName: "price"},
+							},
+							Copy:    true,
+							Timeout: "30s",
 						},
 					},
 				},
 			},
 			{
 				Model: defs.Model{
-					Name:       "Order",
-					Attributes: []int64{2000012, 2000013, 2000014, 2000015, 2000016},
+					Name:          "Order",
+					Attributes:    []int64{2000012, 2000013, 2000014, 2000015, 2000016},
+					Relationships: []defs.Relationship{{Type: "BelongsTo", TargetModelID: 1}},
+					Audit:         true,
 				},
 				Access: defs.Access{
 					Find: []defs.AccessConfig{
@@ -445,6 +712,45 @@ func TestGenerateDB(t *testing.T) {
 								ParamName: "order_date",
 							}},
 						},
+						{
+							Name:       "ListOrdersByStatus",
+							Attributes: []string{"id", "order_date", "order_status", "total_amount"},
+							Filter: []defs.Filter{{
+								Attribute: "order_status",
+								Operator:  "=",
+								ParamName: "order_status",
+							}},
+							Pagination: &defs.Pagination{Type: "offset"},
+							OrderBy:    []defs.OrderBy{{Attribute: "order_date", Direction: "DESC"}},
+							SortOptions: []defs.SortOption{{
+								Name:    "total",
+								OrderBy: []defs.OrderBy{{Attribute: "total_amount", Direction: "DESC", Nulls: "LAST"}},
+							}},
+						},
+					},
+					Update: []defs.AccessConfig{
+						{
+							Name:   "UpdateOrderStatus",
+							Set:    []defs.Update{{Attribute: "order_status", ParamName: "order_status"}},
+							Filter: []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}},
+							Events: true,
+						},
+					},
+					Delete: []defs.AccessConfig{
+						{
+							Name:   "DeleteOrder",
+							Filter: []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}},
+						},
+					},
+					Aggregate: []defs.AccessConfig{
+						{
+							Name:       "CountOrdersByStatus",
+							GroupBy:    []string{"order_status"},
+							Aggregates: []defs.Aggregate{{Function: "COUNT"}, {Function: "SUM", Attribute: "total_amount", Name: "revenue"}},
+							Filter:     []defs.Filter{{Attribute: "order_date", Operator: ">=", ParamName: "since"}},
+							Having:     []defs.Filter{{Attribute: "count", Operator: ">=", ParamName: "min_orders"}},
+							OrderBy:    []defs.OrderBy{{Attribute: "revenue", Direction: "DESC"}},
+						},
 					},
 				},
 			},
@@ -454,7 +760,7 @@ func TestGenerateDB(t *testing.T) {
 	unitModules, err := GenerateDB(dataConfig)
 	assert.Nil(t, err)
 	assert.NotNil(t, unitModules)
-	assert.Equal(t, 4, len(unitModules))
+	assert.Equal(t, 12, len(unitModules))
 	t.Log(unitModules)
 
 	for _, unitModule := range unitModules {
@@ -470,3 +776,244 @@ func TestGenerateDB(t *testing.T) {
 	module.GenerateModuleCode("generated")
 
 }
+
+// mysqlDataConfig is a family of the access kinds the mysql driver generates code for
+func mysqlDataConfig() *defs.DataConfig {
+	byId := []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}}
+	userValues := []defs.Update{{Attribute: "name", ParamName: "name"}, {Attribute: "email", ParamName: "email"}}
+	return &defs.DataConfig{
+		FamilyName: "ShopDB",
+		DatabaseConfig: &defs.DatabaseConfig{
+			DriverName:           "mysql",
+			UserName:             "shop",
+			Password:             "secret",
+			Host:                 "localhost",
+			Port:                 3306,
+			DBName:               "shop",
+			ConnectionConfig:     &defs.ConnectionConfig{IdleTimeoutSecs: 10, MaxLifetimeMins: 30},
+			ConnectionPoolConfig: &defs.ConnectionPoolConfig{MaxIdleConns: 5, MaxOpenConns: 10},
+		},
+		Models: []defs.ModelConfig{
+			{
+				Model: defs.Model{
+					ID:                1,
+					Name:              "User",
+					Attributes:        []int64{2000007, 2000008},
+					UniqueConstraints: []defs.UniqueConstraint{{ConstraintName: "user_email_unique", Attributes: []int64{2000007}}},
+					SoftDelete:        true,
+				},
+				Access: defs.Access{
+					Find: []defs.AccessConfig{
+						{
+							Name:       "GetUsersByEmail",
+							Attributes: []string{"id", "name", "email"},
+							Filter:     []defs.Filter{{Attribute: "email", Operator: "IN", ParamName: "emails"}},
+						},
+						{
+							Name:       "GetUserWithOrders",
+							Attributes: []string{"id", "name"},
+							Filter:     byId,
+							Include:    []defs.Include{{Model: "Order", Attributes: []string{"id", "order_status"}}},
+						},
+					},
+					Update: []defs.AccessConfig{{
+						Name:           "UpdateUserName",
+						Set:            userValues[:1],
+						Filter:         byId,
+						OptimisticLock: true,
+					}},
+					Add:          []defs.AccessConfig{{Name: "AddUser", Values: userValues}},
+					AddOrReplace: []defs.AccessConfig{{Name: "AddOrReplaceUser", Values: userValues}},
+					Delete:       []defs.AccessConfig{{Name: "DeleteUser", Filter: byId}},
+				},
+			},
+			{
+				Model: defs.Model{
+					Name:          "Order",
+					Attributes:    []int64{2000012, 2000013, 2000014, 2000015, 2000016},
+					Relationships: []defs.Relationship{{Type: "BelongsTo", TargetModelID: 1}},
+				},
+				Access: defs.Access{
+					Find: []defs.AccessConfig{{
+						Name:       "ListOrdersByStatus",
+						Attributes: []string{"id", "order_date", "order_status"},
+						Filter:     []defs.Filter{{Attribute: "order_status", Operator: "IN", ParamName: "statuses"}},
+						OrderBy:    []defs.OrderBy{{Attribute: "order_date", Direction: "DESC"}},
+						Pagination: &defs.Pagination{Type: "offset"},
+					}},
+					AddMany: []defs.AccessConfig{{
+						Name:      "AddOrders",
+						Values:    []defs.Update{{Attribute: "order_status", ParamName: "order_status"}, {Attribute: "total_amount", ParamName: "total_amount"}},
+						ChunkSize: 100,
+					}},
+					Delete: []defs.AccessConfig{{Name: "DeleteOrder", Filter: byId, OptimisticLock: true}},
+					Aggregate: []defs.AccessConfig{{
+						Name:       "CountOrdersByStatus",
+						GroupBy:    []string{"order_status"},
+						Aggregates: []defs.Aggregate{{Function: "COUNT"}},
+						Filter:     []defs.Filter{{Attribute: "order_status", Operator: "IN", ParamName: "statuses"}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestGenerateDBMySQL(t *testing.T) {
+	config.LoadConfig()
+	dataConfig := mysqlDataConfig()
+	assert.NoError(t, ValidateDataConfig(dataConfig))
+
+	unitModules, err := GenerateDB(dataConfig)
+	assert.NoError(t, err)
+	code := map[string]string{}
+	for _, unitModule := range unitModules {
+		src, _, err := unitModule.GenerateCode("database")
+		assert.NoError(t, err)
+		code[unitModule.Name] = src
+	}
+	// No outbox, the events need Postgres
+	assert.NotContains(t, code, outboxUnitName)
+	for name, src := range code {
+		assert.NotContains(t, src, "github.com/lib/pq", name)
+	}
+	assert.Contains(t, code["ShopDB"], `_ "github.com/go-sql-driver/mysql"`)
+	assert.Contains(t, code["ShopDB"], `driverName, dsn := "mysql", "shop:secret@tcp(localhost:3306)/shop?parseTime=true&multiStatements=true"`)
+
+	// The queries with IN lists are expanded instead of prepared, see inListStatementFunction
+	user := code["user"]
+	assert.Contains(t, user, "var userInListStmts = map[string]inListStmt{\n"+
+		"\t\"GetUsersByEmail\":                      {query: \"SELECT `id`, `name`, `email` FROM `user` WHERE (1 = 1) AND (`email` IN (?)) AND (`deleted_at` IS NULL)\", expand: []bool{true}},\n")
+	assert.Contains(t, user, `func (db *User_DB) statement(ctx context.Context, name string) statementRunner {
+	if inList, ok := userInListStmts[name]; ok {
+		inList.runner = db.db
+		if db.tx != nil {
+			inList.runner = db.tx
+		}
+		return &inList
+	}`)
+	assert.NotContains(t, user, `preparedCache["GetUsersByEmail"]`)
+	assert.Contains(t, user, "\tvalues = append(values, params.Emails)\n")
+	assert.Contains(t, user, "\torderRows, err := orderStmt.QueryContext(ctx, ids)\n")
+
+	// The ids of inserts are their LastInsertId, the version of a locked update follows its version param
+	assert.Contains(t, user, `	result, err := stmt.ExecContext(ctx, values...)
+	if err != nil {
+		return "", err
+	}
+	lastInsertId, err := result.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+	id := strconv.FormatInt(lastInsertId, 10)
+	return id, nil
+}`)
+	assert.Contains(t, user, "\treturn id, rowsAffected == 1, nil\n")
+	assert.Contains(t, user, "\treturn requestParams.Version + 1, nil\n")
+	assert.Contains(t, user, "INSERT INTO `user` (`name`, `email`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `id` = LAST_INSERT_ID(`id`)")
+
+	order := code["order"]
+	assert.Contains(t, order, "\tvalues = append(values, params.Limit+1)\n")
+	assert.Contains(t, order, "ORDER BY `order_date` DESC, `id` DESC LIMIT ? OFFSET ?")
+	assert.Contains(t, order, `		if len(chunk) == 100 {
+			result, err = db.statement(ctx, "AddOrders").ExecContext(ctx, values...)
+		} else {
+			result, err = db.exec(ctx, AddOrdersQuery(len(chunk)), values...)
+		}
+		if err == nil {
+			ids, err = insertedIds(result, ids)
+		}`)
+	assert.Contains(t, code[batchUnitName], "func insertedIds(result sql.Result, ids []string) ([]string, error) {")
+	assert.NotContains(t, code[batchUnitName], "copyRowError")
+
+	inList := code[inListUnitName]
+	assert.Contains(t, inList, `const emptyInList = "SELECT NULL FROM DUAL WHERE FALSE"`)
+	assert.Contains(t, inList, "func expandInLists(query string, expand []bool, args []interface{}) (string, []interface{}) {")
+}
+
+// sqliteDataConfig is the family of mysqlDataConfig in the SQLite database shop.db
+func sqliteDataConfig() *defs.DataConfig {
+	dataConfig := mysqlDataConfig()
+	dataConfig.DatabaseConfig = &defs.DatabaseConfig{
+		DriverName:           "sqlite3",
+		DBName:               "shop.db",
+		ConnectionConfig:     &defs.ConnectionConfig{IdleTimeoutSecs: 10, MaxLifetimeMins: 30},
+		ConnectionPoolConfig: &defs.ConnectionPoolConfig{MaxIdleConns: 5, MaxOpenConns: 10},
+	}
+	return dataConfig
+}
+
+func TestGenerateDBSQLite(t *testing.T) {
+	config.LoadConfig()
+	dataConfig := sqliteDataConfig()
+	assert.NoError(t, ValidateDataConfig(dataConfig))
+
+	unitModules, err := GenerateDB(dataConfig)
+	assert.NoError(t, err)
+	code := map[string]string{}
+	for _, unitModule := range unitModules {
+		src, _, err := unitModule.GenerateCode("database")
+		assert.NoError(t, err)
+		code[unitModule.Name] = src
+	}
+	assert.NotContains(t, code, outboxUnitName)
+	for name, src := range code {
+		assert.NotContains(t, src, "github.com/lib/pq", name)
+	}
+	assert.Contains(t, code["ShopDB"], `_ "github.com/mattn/go-sqlite3"`)
+	assert.Contains(t, code["ShopDB"], `driverName, dsn := "sqlite3", "file:shop.db?_foreign_keys=on"`)
+
+	// The IN lists are expanded and their ?NNN placeholders numbered again
+	user := code["user"]
+	assert.Contains(t, user, `"GetUsersByEmail":                      {query: "SELECT \"id\", \"name\", \"email\" FROM \"user\" WHERE (1 = 1) AND (\"email\" IN (?1)) AND (\"deleted_at\" IS NULL)", expand: []bool{true}},`)
+	assert.Contains(t, code[inListUnitName], `const emptyInList = ""`)
+	assert.Contains(t, code[inListUnitName], `expanded.WriteString("?" + strconv.Itoa(len(values)))`)
+
+	// A replace increments the version of the row, the rows of version 1 were inserted
+	assert.Contains(t, user, `ON CONFLICT (\"email\") WHERE (\"deleted_at\" IS NULL) DO UPDATE SET \"name\" = excluded.\"name\", \"email\" = excluded.\"email\", `+
+		`\"version\" = \"version\" + 1 RETURNING \"id\", (\"version\" = 1) AS inserted")`)
+	assert.Contains(t, user, "\tqueryErr := stmt.QueryRowContext(ctx, values...).Scan(&id, &inserted)\n")
+
+	// The ids of batches are returned, as with Postgres
+	order := code["order"]
+	assert.Contains(t, order, `		tuples[row] = fmt.Sprintf("(?%d, ?%d)", row*2+1, row*2+2)`)
+	assert.Contains(t, order, "\t\t\tchunkRows, err = db.query(ctx, AddOrdersQuery(len(chunk)), values...)\n")
+	assert.Contains(t, code[batchUnitName], "func scanIds(rows *sql.Rows, ids []string) ([]string, error) {")
+}
+
+func TestExplainModel(t *testing.T) {
+	config.LoadConfig()
+
+	queries, err := ExplainModel(validDataConfig().Models[0], validDataConfig())
+	assert.NoError(t, err)
+	assert.Equal(t, []NamedQuery{
+		{Name: "GetUserByEmail", Query: `SELECT "id", "name", "email" FROM "user" WHERE (1 = 1) AND ("email" = $1)`},
+		{Name: "UpdateUserName", Query: `UPDATE "user" SET "name" = $1 WHERE (1 = 1) AND ("id" = $2)`},
+	}, queries)
+
+	// Soft delete models leave their deleted rows out, and set their deletion time instead of deleting them
+	dataConfig := validDataConfig()
+	dataConfig.Models[0].Model.SoftDelete = true
+	dataConfig.Models[0].Access.Delete = []defs.AccessConfig{{
+		Name:   "DeleteUser",
+		Filter: []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}},
+	}}
+	queries, err = ExplainModel(dataConfig.Models[0], dataConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, []NamedQuery{
+		{Name: "GetUserByEmail", Query: `SELECT "id", "name", "email" FROM "user" WHERE (1 = 1) AND ("email" = $1) AND ("deleted_at" IS NULL)`},
+		{Name: "GetUserByEmailIncludeDeleted", Query: `SELECT "id", "name", "email" FROM "user" WHERE (1 = 1) AND ("email" = $1)`},
+		{Name: "UpdateUserName", Query: `UPDATE "user" SET "name" = $1 WHERE (1 = 1) AND ("id" = $2) AND ("deleted_at" IS NULL)`},
+		{Name: "DeleteUser", Query: `UPDATE "user" SET "deleted_at" = NOW() WHERE (1 = 1) AND ("id" = $1) AND ("deleted_at" IS NULL)`},
+		{Name: "RestoreUser", Query: `UPDATE "user" SET "deleted_at" = NULL WHERE (1 = 1) AND ("id" = $1) AND ("deleted_at" IS NOT NULL)`},
+	}, queries)
+
+	// Models with audit write history rows and read them with their history access
+	dataConfig.Models[0].Model.Audit = true
+	queries, err = ExplainModel(dataConfig.Models[0], dataConfig)
+	assert.NoError(t, err)
+	assert.Len(t, queries, 6)
+	assert.Contains(t, queries[4].Query, `WITH old_rows AS (SELECT * FROM "user" WHERE (1 = 1) AND ("id" = $1) AND ("deleted_at" IS NOT NULL) FOR UPDATE)`)
+	assert.Equal(t, NamedQuery{Name: "FindUserHistory",
+		Query: `SELECT "id", "row_id", "operation", "old_values", "new_values", "actor", "changed_at" FROM "user_history" WHERE "row_id" = $1 ORDER BY "id"`}, queries[5])
+}
@@ -0,0 +1,550 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+// Name of the unit holding the helpers of the batch inserts
+const batchUnitName = "batch"
+
+// addManyQueryFunctionName is the function making the INSERT of a chunk of rows, AddProductsQuery
+func addManyQueryFunctionName(name string) string {
+	return name + "Query"
+}
+
+// hasInsertChunks tells if a model has add_many configs inserting chunks of rows, which query through the model DB
+func hasInsertChunks(config *defs.ModelConfig) bool {
+	for _, conf := range config.Access.AddMany {
+		if !conf.Copy {
+			return true
+		}
+	}
+	return false
+}
+
+// AddManyQueryFunction generates the function making the INSERT of a chunk of rows of an add_many config, see
+// datahelpers.MakeAddManyQuery. The statement of full chunks is prepared, the last chunk of a call is queried.
+// The placeholders of dialects numbering them are numbered by row, the tuples of the others are all alike:
+//
+//	func AddProductsQuery(rows int) string {
+//		tuples := make([]string, rows)
+//		for row := range tuples {
+//			tuples[row] = fmt.Sprintf("($%d, $%d)", row*2+1, row*2+2)
+//		}
+//		return "INSERT INTO product (sku, price) VALUES " + strings.Join(tuples, ", ") + " RETURNING id"
+//	}
+func AddManyQueryFunction(dialect datahelpers.Dialect, modelName string, conf *defs.AccessConfig) *golang.FunctionDef {
+	insert, returning := datahelpers.MakeAddManyQueryParts(dialect, modelName, conf)
+	query := fmt.Sprintf(`%q + strings.Join(tuples, ", ")`, insert+" ")
+	if returning != "" {
+		query += fmt.Sprintf(" + %q", " "+returning)
+	}
+	numbered := dialect.GetPlaceholder(1) != dialect.GetPlaceholder(2)
+	placeholders := make([]string, 0, len(conf.Values))
+	params := make([]string, 0, len(conf.Values))
+	for i := range conf.Values {
+		if !numbered {
+			placeholders = append(placeholders, dialect.GetPlaceholder(i+1))
+			continue
+		}
+		placeholders = append(placeholders, strings.TrimSuffix(dialect.GetPlaceholder(1), "1")+"%d")
+		params = append(params, fmt.Sprintf("row*%d+%d", len(conf.Values), i+1))
+	}
+	tuple := fmt.Sprintf("%q", "("+strings.Join(placeholders, ", ")+")")
+	imports := []string{"strings"}
+	if numbered {
+		tuple = fmt.Sprintf("fmt.Sprintf(%s, %s)", tuple, strings.Join(params, ", "))
+		imports = []string{"fmt", "strings"}
+	}
+	return &golang.FunctionDef{
+		Name:       addManyQueryFunctionName(conf.Name),
+		Parameters: []*golang.Parameter{{Name: "rows", Type: golang.GoIntType}},
+		Returns:    typeOnlyParamsCE("string"),
+		Imports:    imports,
+		Body: golang.CodeElements{
+			{NewAssign: &golang.NewAssignment{Left: "tuples", Right: "make([]string, rows)"}},
+			{Iterate: &golang.IterateElement{
+				Variables: []string{"row"},
+				RangeOn:   &golang.CodeElement{Literal: "tuples"},
+				Body:      golang.CodeElements{{Assign: &golang.Assignment{Left: "tuples[row]", Right: tuple}}},
+			}},
+			returnValuesCE(query),
+		},
+	}
+}
+
+// validateRowsCE validates the rows of a batch before any is inserted, the failed rows are joined as *RowError:
+//
+//	var rowErrs []error
+//	for i, row := range rows {
+//		if err := row.Validate(); err != nil {
+//			rowErrs = append(rowErrs, &RowError{Row: i, Err: err})
+//		}
+//	}
+//	if len(rowErrs) > 0 {
+//		return nil, errors.Join(rowErrs...)
+//	}
+func validateRowsCE(rowsName string, zero string) golang.CodeElements {
+	return golang.CodeElements{
+		{Variable: createVarCE("rowErrs", "[]error")},
+		{Iterate: &golang.IterateElement{
+			Variables: []string{"i", "row"},
+			RangeOn:   &golang.CodeElement{Literal: rowsName},
+			Body: golang.CodeElements{
+				{If: &golang.IfElement{
+					Condition: "err := row.Validate(); err != nil",
+					Then:      golang.CodeElements{{FunctionCall: appendCE("rowErrs", "&RowError{Row: i, Err: err}")}},
+				}},
+			},
+		}},
+		{If: &golang.IfElement{
+			Condition: "len(rowErrs) > 0",
+			Then:      golang.CodeElements{returnValuesCE(zero, "errors.Join(rowErrs...)")},
+		}},
+	}
+}
+
+// readRowCE binds the values of a row, a row failing to bind is returned as a *RowError
+func readRowCE(name, valuesName, rowIndex, zero string) golang.CodeElements {
+	return golang.CodeElements{
+		{NewAssign: &golang.NewAssignment{Left: []string{valuesName, "err"}, Right: fmt.Sprintf("%sReadParams(row)", name)}},
+		{If: &golang.IfElement{
+			Condition: "err != nil",
+			Then:      golang.CodeElements{returnValuesCE(zero, fmt.Sprintf("&RowError{Row: %s, Err: err}", rowIndex))},
+		}},
+	}
+}
+
+// AddManyCodeFunction generates the batch insert of an add_many config, returning the ids of the rows in the order of
+// the rows. The rows are validated first, then inserted chunkRows at a time by multi-row INSERTs (see
+// AddManyQueryFunction), in the transaction of the model DB or in a transaction of their own, so that the rows are
+// all inserted or none is. Failed validations and binds are returned per row as *RowError, the database reports no
+// row of a failed INSERT, so its error tells the rows of the chunk:
+//
+//	func AddProducts(ctx context.Context, db *Product_DB, rows []AddProductsParams) ([]string, error) {
+//		... // validateRowsCE
+//		owned := db.tx == nil
+//		if owned {
+//			tx, err := db.db.BeginTx(ctx, nil)
+//			if err != nil {
+//				return nil, err
+//			}
+//			defer tx.Rollback()
+//			db = db.withTx(tx)
+//		}
+//		ids := make([]string, 0, len(rows))
+//		for start := 0; start < len(rows); start += 1000 {
+//			chunk := rows[start:min(start+1000, len(rows))]
+//			values := make([]interface{}, 0, len(chunk)*2)
+//			for i, row := range chunk {
+//				... // readRowCE
+//				values = append(values, rowValues...)
+//			}
+//			var chunkRows *sql.Rows
+//			var err error
+//			if len(chunk) == 1000 {
+//				chunkRows, err = db.statement(ctx, "AddProducts").QueryContext(ctx, values...)
+//			} else {
+//				chunkRows, err = db.query(ctx, AddProductsQuery(len(chunk)), values...)
+//			}
+//			if err == nil {
+//				ids, err = scanIds(chunkRows, ids)
+//			}
+//			if err != nil {
+//				return nil, fmt.Errorf("rows %d to %d: %w", start, start+len(chunk)-1, err)
+//			}
+//		}
+//		if owned {
+//			if err := db.tx.Commit(); err != nil {
+//				return nil, err
+//			}
+//		}
+//		return ids, nil
+//	}
+//
+// Dialects without RETURNING execute the chunks and read their ids with insertedIds instead, see returnsRows.
+func AddManyCodeFunction(name, modelDBName string, values, chunkRows int, returnsRows bool) *golang.FunctionDef {
+	fnReturns := typeOnlyParamsCE("[]string", "error")
+	codeElems := validateRowsCE("rows", "nil")
+	chunkBody := golang.CodeElements{
+		{NewAssign: &golang.NewAssignment{Left: "chunk", Right: fmt.Sprintf("rows[start:min(start+%d, len(rows))]", chunkRows)}},
+		{NewAssign: &golang.NewAssignment{Left: "values", Right: fmt.Sprintf("make([]interface{}, 0, len(chunk)*%d)", values)}},
+		{Iterate: &golang.IterateElement{
+			Variables: []string{"i", "row"},
+			RangeOn:   &golang.CodeElement{Literal: "chunk"},
+			Body: append(readRowCE(name, "rowValues", "start + i", "nil"),
+				&golang.CodeElement{FunctionCall: appendCE("values", "rowValues...")}),
+		}},
+	}
+	chunkResult, chunkResultType, stmtFunction, dbFunction, readIds := "chunkRows", "*sql.Rows", "QueryContext", queryFunctionName, "scanIds"
+	if !returnsRows {
+		chunkResult, chunkResultType, stmtFunction, dbFunction, readIds = "result", "sql.Result", "ExecContext", execFunctionName, "insertedIds"
+	}
+	chunkBody = append(chunkBody,
+		&golang.CodeElement{Variable: createVarCE(chunkResult, chunkResultType)},
+		&golang.CodeElement{Variable: createVarCE("err", "error")},
+		&golang.CodeElement{If: &golang.IfElement{
+			Condition: fmt.Sprintf("len(chunk) == %d", chunkRows),
+			Then: golang.CodeElements{{FunctionCall: &golang.FunctionCall{
+				Output:   []string{chunkResult, "err"},
+				Receiver: fmt.Sprintf("db.%s(ctx, %q)", statementFunctionName, name),
+				Function: stmtFunction,
+				Args:     []string{"ctx", "values..."},
+			}}},
+			Else: golang.CodeElements{{FunctionCall: &golang.FunctionCall{
+				Output:   []string{chunkResult, "err"},
+				Receiver: "db",
+				Function: dbFunction,
+				Args:     []string{"ctx", fmt.Sprintf("%s(len(chunk))", addManyQueryFunctionName(name)), "values..."},
+			}}},
+		}},
+		&golang.CodeElement{If: &golang.IfElement{
+			Condition: "err == nil",
+			Then: golang.CodeElements{{FunctionCall: &golang.FunctionCall{
+				Output:   []string{"ids", "err"},
+				Function: readIds,
+				Args:     []string{chunkResult, "ids"},
+			}}},
+		}},
+		&golang.CodeElement{If: &golang.IfElement{
+			Condition: "err != nil",
+			Then:      golang.CodeElements{returnValuesCE("nil", `fmt.Errorf("rows %d to %d: %w", start, start+len(chunk)-1, err)`)},
+		}},
+	)
+	codeElems = append(codeElems,
+		&golang.CodeElement{NewAssign: &golang.NewAssignment{Left: "owned", Right: "db.tx == nil"}},
+		&golang.CodeElement{If: &golang.IfElement{
+			Condition: "owned",
+			Then: golang.CodeElements{
+				{FunctionCall: &golang.FunctionCall{
+					NewOutput:    []string{"tx", "err"},
+					Receiver:     "db.db",
+					Function:     "BeginTx",
+					Args:         []string{"ctx", "nil"},
+					ErrorHandler: &golang.ErrorHandler{ErrorFunctionReturns: fnReturns},
+				}},
+				{FunctionCall: &golang.FunctionCall{Receiver: "tx", Function: "Rollback", Defer: true}},
+				{Assign: &golang.Assignment{Left: "db", Right: fmt.Sprintf("db.%s(tx)", withTxFunctionName)}},
+			},
+		}},
+		&golang.CodeElement{NewAssign: &golang.NewAssignment{Left: "ids", Right: "make([]string, 0, len(rows))"}},
+		&golang.CodeElement{RepeatLoop: &golang.RepeatLoopElement{
+			Init:      []*golang.CodeElement{{NewAssign: &golang.NewAssignment{Left: "start", Right: "0"}}},
+			Condition: &golang.CodeElement{Literal: "start < len(rows)"},
+			Step:      []*golang.CodeElement{{Literal: fmt.Sprintf("start += %d", chunkRows)}},
+			Body:      chunkBody,
+		}},
+		&golang.CodeElement{If: &golang.IfElement{
+			Condition: "owned",
+			Then: golang.CodeElements{{If: &golang.IfElement{
+				Condition: "err := db.tx.Commit(); err != nil",
+				Then:      golang.CodeElements{returnValuesCE("nil", "err")},
+			}}},
+		}},
+		returnResultNilCE("ids"),
+	)
+	return &golang.FunctionDef{
+		Name:       name,
+		Parameters: []*golang.Parameter{ctxParamCE("ctx"), modelDBParamCE("db", modelDBName), rowsParamCE(name)},
+		Body:       codeElems,
+		Returns:    fnReturns,
+		Imports:    []string{"context", "database/sql", "errors", "fmt"},
+	}
+}
+
+// AddManyCopyCodeFunction generates the batch insert of an add_many config copying its rows with COPY, returning the
+// number of rows. COPY returns no ids. The rows are validated first, then copied in the transaction of the model DB,
+// or in a transaction of their own. The driver buffers the rows, so the error of a row comes from the Exec of a later
+// row, the flush or the close: copyRowError maps it back to its row by the line of the COPY Postgres reports it on,
+// an error on no line, like a failed commit, fails the whole COPY:
+//
+//	func ImportProducts(ctx context.Context, db *Product_DB, rows []ImportProductsParams) (int64, error) {
+//		... // validateRowsCE
+//		tx, owned := db.tx, db.tx == nil
+//		if owned {
+//			var err error
+//			tx, err = db.db.BeginTx(ctx, nil)
+//			if err != nil {
+//				return int64(0), err
+//			}
+//			defer tx.Rollback()
+//		}
+//		stmt, err := tx.PrepareContext(ctx, pq.CopyIn("product", "sku", "price"))
+//		...
+//		for i, row := range rows {
+//			... // readRowCE
+//			if _, err := stmt.ExecContext(ctx, values...); err != nil {
+//				return int64(0), copyRowError(err)
+//			}
+//		}
+//		... // flush and close the COPY, commit an owned transaction
+//		return int64(len(rows)), nil
+//	}
+func AddManyCopyCodeFunction(modelName, modelDBName string, conf *defs.AccessConfig) *golang.FunctionDef {
+	fnReturns := typeOnlyParamsCE("int64", "error")
+	copyArgs := []string{fmt.Sprintf("%q", golang.ToSnakeCase(modelName))}
+	for _, value := range conf.Values {
+		copyArgs = append(copyArgs, fmt.Sprintf("%q", golang.ToSnakeCase(value.Attribute)))
+	}
+	zero := "int64(0)"
+	errorHandler := &golang.ErrorHandler{ErrorFunctionReturns: fnReturns}
+	codeElems := validateRowsCE("rows", zero)
+	codeElems = append(codeElems,
+		&golang.CodeElement{NewAssign: &golang.NewAssignment{Left: []string{"tx", "owned"}, Right: "db.tx, db.tx == nil"}},
+		&golang.CodeElement{If: &golang.IfElement{
+			Condition: "owned",
+			Then: golang.CodeElements{
+				{Variable: createVarCE("err", "error")},
+				{FunctionCall: &golang.FunctionCall{
+					Output:       []string{"tx", "err"},
+					Receiver:     "db.db",
+					Function:     "BeginTx",
+					Args:         []string{"ctx", "nil"},
+					ErrorHandler: errorHandler,
+				}},
+				{FunctionCall: &golang.FunctionCall{Receiver: "tx", Function: "Rollback", Defer: true}},
+			},
+		}},
+		&golang.CodeElement{FunctionCall: &golang.FunctionCall{
+			NewOutput:        []string{"stmt", "err"},
+			Receiver:         "tx",
+			Function:         "PrepareContext",
+			Args:             []string{"ctx", fmt.Sprintf("pq.CopyIn(%s)", strings.Join(copyArgs, ", "))},
+			ErrorHandler:     errorHandler,
+			CleanningHandler: &golang.CleanningHandler{Receiver: "stmt", Function: "Close"},
+		}},
+		&golang.CodeElement{Iterate: &golang.IterateElement{
+			Variables: []string{"i", "row"},
+			RangeOn:   &golang.CodeElement{Literal: "rows"},
+			Body: append(readRowCE(conf.Name, "values", "i", zero),
+				&golang.CodeElement{If: &golang.IfElement{
+					Condition: "_, err := stmt.ExecContext(ctx, values...); err != nil",
+					Then:      golang.CodeElements{returnValuesCE(zero, "copyRowError(err)")},
+				}}),
+		}},
+		// The COPY is flushed by an Exec without values
+		&golang.CodeElement{If: &golang.IfElement{
+			Condition: "_, err := stmt.ExecContext(ctx); err != nil",
+			Then:      golang.CodeElements{returnValuesCE(zero, "copyRowError(err)")},
+		}},
+		&golang.CodeElement{If: &golang.IfElement{
+			Condition: "err := stmt.Close(); err != nil",
+			Then:      golang.CodeElements{returnValuesCE(zero, "copyRowError(err)")},
+		}},
+		&golang.CodeElement{If: &golang.IfElement{
+			Condition: "owned",
+			Then: golang.CodeElements{{If: &golang.IfElement{
+				Condition: "err := tx.Commit(); err != nil",
+				Then:      golang.CodeElements{returnValuesCE(zero, "err")},
+			}}},
+		}},
+		returnResultNilCE("int64(len(rows))"),
+	)
+	return &golang.FunctionDef{
+		Name:       conf.Name,
+		Parameters: []*golang.Parameter{ctxParamCE("ctx"), modelDBParamCE("db", modelDBName), rowsParamCE(conf.Name)},
+		Body:       codeElems,
+		Returns:    fnReturns,
+		Imports:    []string{"context", "errors", "github.com/lib/pq"},
+	}
+}
+
+// rowsParamCE is the rows param of a batch insert, the params of each row
+func rowsParamCE(name string) *golang.Parameter {
+	return &golang.Parameter{Name: "rows", Type: &golang.GoType{Name: fmt.Sprintf("[]%sParams", name)}}
+}
+
+// GenerateAddManyConfigs generates the batch inserts of a model, like GenerateAddConfigs, taking the params of many
+// rows: see AddManyCodeFunction for the multi-row INSERTs and AddManyCopyCodeFunction for COPY
+func GenerateAddManyConfigs(dialect datahelpers.Dialect, modelName string, modelDBName string, fieldTypes map[string]*golang.GoType,
+	addManyConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
+	functions := make([]*golang.FunctionDef, 0, len(addManyConfig))
+	reqs := make([]*golang.StructDef, 0, len(addManyConfig))
+	queries := make([]NamedQuery, 0, len(addManyConfig))
+
+	for _, conf := range addManyConfig {
+		// A row binds its values like an add
+		_, paramRefs := datahelpers.MakeAddQuery(dialect, modelName, &conf)
+		params, err := readAccessParams(&conf, fieldTypes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		reqs = append(reqs, generateAccessStructs(paramRefs, params, conf.Name)...)
+		functions = append(functions, ReadParamsFunction(paramRefs, params, conf.Name, "values", "params"))
+
+		var fn *golang.FunctionDef
+		if conf.Copy {
+			fn = AddManyCopyCodeFunction(modelName, modelDBName, &conf)
+		} else {
+			chunkRows := conf.ChunkRows()
+			// The statement of full chunks is made by the query function of the config, see ExplainModel for its query
+			queries = append(queries, NamedQuery{
+				Name:       conf.Name,
+				Query:      datahelpers.MakeAddManyQuery(dialect, modelName, &conf, chunkRows),
+				Expression: fmt.Sprintf("%s(%d)", addManyQueryFunctionName(conf.Name), chunkRows),
+			})
+			functions = append(functions, AddManyQueryFunction(dialect, modelName, &conf))
+			fn = AddManyCodeFunction(conf.Name, modelDBName, len(conf.Values), chunkRows, returnsRows(dialect))
+		}
+		if err := withTimeout(fn, &conf); err != nil {
+			return nil, nil, nil, err
+		}
+		functions = append(functions, fn)
+	}
+	return queries, functions, reqs, nil
+}
+
+// GenerateBatchUnit generates the error of the rows of batch inserts, which tells the rows failing validation, binding
+// or COPY, and the read of the ids of inserted chunks: scanIds when the dialect returns them, insertedIds when it
+// doesn't (see returnsRows). COPY is Postgres only, see validateAddMany.
+func GenerateBatchUnit(dialect datahelpers.Dialect) *golang.UnitModule {
+	receiver := &golang.Receiver{Name: "e", Type: &golang.GoType{Name: "RowError"}}
+	unit := &golang.UnitModule{
+		Name: batchUnitName,
+		Structs: []*golang.StructDef{golang.GenStructForDataModel("RowError", []golang.NameWithType{
+			{Name: "row", Type: golang.GoIntType},
+			{Name: "err", Type: &golang.GoType{Name: "error"}},
+		}, false, false, false)},
+		Functions: []*golang.FunctionDef{
+			{
+				Name:     "Error",
+				Receiver: receiver,
+				Returns:  typeOnlyParamsCE("string"),
+				Imports:  []string{"fmt"},
+				Body:     golang.CodeElements{returnValuesCE(`fmt.Sprintf("row %d: %v", e.Row, e.Err)`)},
+			},
+			{
+				Name:     "Unwrap",
+				Receiver: receiver,
+				Returns:  typeOnlyParamsCE("error"),
+				Body:     golang.CodeElements{returnValuesCE("e.Err")},
+			},
+		},
+	}
+	if !returnsRows(dialect) {
+		unit.Functions = append(unit.Functions, insertedIdsFunction())
+		return unit
+	}
+	unit.Functions = append(unit.Functions, scanIdsFunction())
+	if dialect.GetName() == "postgres" {
+		unit.Variables = []*golang.Variable{{
+			Names:  "copyLinePattern",
+			Type:   "*regexp.Regexp",
+			Values: "regexp.MustCompile(`, line (\\d+)`)",
+		}}
+		unit.Functions = append(unit.Functions, copyRowErrorFunction())
+	}
+	return unit
+}
+
+// scanIds appends the ids returned by the INSERT of a chunk, and closes its rows
+func scanIdsFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name: "scanIds",
+		Parameters: []*golang.Parameter{
+			{Name: "rows", Type: &golang.GoType{Name: "*sql.Rows"}},
+			{Name: "ids", Type: &golang.GoType{Name: "[]string"}},
+		},
+		Returns: typeOnlyParamsCE("[]string", "error"),
+		Imports: []string{"database/sql"},
+		Body: golang.CodeElements{
+			{FunctionCall: &golang.FunctionCall{Receiver: "rows", Function: "Close", Defer: true}},
+			{RepeatCond: &golang.RepeatByCondition{
+				Condition: &golang.CodeElement{FunctionCall: &golang.FunctionCall{Receiver: "rows", Function: "Next"}},
+				Body: golang.CodeElements{
+					{Variable: createVarCE("id", "string")},
+					{If: &golang.IfElement{
+						Condition: "err := rows.Scan(&id); err != nil",
+						Then:      golang.CodeElements{returnValuesCE("nil", "err")},
+					}},
+					{FunctionCall: appendCE("ids", "id")},
+				},
+			}},
+			returnValuesCE("ids", "rows.Err()"),
+		},
+	}
+}
+
+// copyRowError maps the error of a COPY to the row it was reported on. Postgres tells the line of the COPY, counted
+// from 1, in the context of the error, like "COPY product, line 3, column price: ...", other errors are returned as is:
+//
+//	func copyRowError(err error) error {
+//		var pqErr *pq.Error
+//		if !errors.As(err, &pqErr) {
+//			return err
+//		}
+//		match := copyLinePattern.FindStringSubmatch(pqErr.Where)
+//		if match == nil {
+//			return err
+//		}
+//		line, _ := strconv.Atoi(match[1])
+//		return &RowError{Row: line - 1, Err: err}
+//	}
+func copyRowErrorFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:       "copyRowError",
+		Parameters: []*golang.Parameter{{Name: "err", Type: &golang.GoType{Name: "error"}}},
+		Returns:    typeOnlyParamsCE("error"),
+		Imports:    []string{"errors", "github.com/lib/pq", "regexp", "strconv"},
+		Body: golang.CodeElements{
+			{Variable: createVarCE("pqErr", "*pq.Error")},
+			{If: &golang.IfElement{
+				Condition: "!errors.As(err, &pqErr)",
+				Then:      golang.CodeElements{returnValuesCE("err")},
+			}},
+			{NewAssign: &golang.NewAssignment{Left: "match", Right: "copyLinePattern.FindStringSubmatch(pqErr.Where)"}},
+			{If: &golang.IfElement{
+				Condition: "match == nil",
+				Then:      golang.CodeElements{returnValuesCE("err")},
+			}},
+			// The pattern matches digits only
+			{NewAssign: &golang.NewAssignment{Left: []string{"line", "_"}, Right: "strconv.Atoi(match[1])"}},
+			returnValuesCE("&RowError{Row: line - 1, Err: err}"),
+		},
+	}
+}
+
+// validateAddMany checks the batch inserts, see GenerateAddManyConfigs
+func validateAddMany(modelConfig *defs.ModelConfig, dialect datahelpers.Dialect) []error {
+	errs := []error{}
+	modelName := modelConfig.Model.Name
+	for _, accessConfig := range modelConfig.GetAllAccessConfig() {
+		if (accessConfig.ChunkSize != 0 || accessConfig.Copy) && !slices.ContainsFunc(modelConfig.Access.AddMany, func(addMany defs.AccessConfig) bool {
+			return addMany.Name == accessConfig.Name
+		}) {
+			errs = append(errs, fmt.Errorf("model %s: access %s has chunk_size or copy, which only add_many accesses have", modelName, accessConfig.Name))
+		}
+	}
+	for _, accessConfig := range modelConfig.Access.AddMany {
+		if len(accessConfig.Values) == 0 {
+			errs = append(errs, fmt.Errorf("model %s: access %s inserts no values", modelName, accessConfig.Name))
+		}
+		if len(accessConfig.Filter) > 0 || len(accessConfig.Set) > 0 || len(accessConfig.Attributes) > 0 {
+			errs = append(errs, fmt.Errorf("model %s: access %s filters, sets or selects attributes, which add_many accesses don't", modelName, accessConfig.Name))
+		}
+		switch {
+		case accessConfig.Copy && dialect.GetName() != "postgres":
+			errs = append(errs, fmt.Errorf("model %s: access %s copies its rows, which needs the postgres driver", modelName, accessConfig.Name))
+		case !accessConfig.Copy && !returnsRows(dialect) && slices.ContainsFunc(accessConfig.Values, func(value defs.Update) bool {
+			return golang.ToSnakeCase(value.Attribute) == "id"
+		}):
+			// The ids of the rows are counted from the LastInsertId of their chunk, see insertedIdsFunction
+			errs = append(errs, fmt.Errorf("model %s: access %s inserts the id, which the database assigns to the batch inserts of the %s driver",
+				modelName, accessConfig.Name, dialect.GetName()))
+		case accessConfig.ChunkSize < 0:
+			errs = append(errs, fmt.Errorf("model %s: access %s has a negative chunk_size", modelName, accessConfig.Name))
+		case accessConfig.Copy && accessConfig.ChunkSize > 0:
+			errs = append(errs, fmt.Errorf("model %s: access %s copies its rows, which have no chunk_size", modelName, accessConfig.Name))
+		case accessConfig.ChunkSize*len(accessConfig.Values) > defs.MaxBindParams:
+			errs = append(errs, fmt.Errorf("model %s: access %s binds %d params per chunk, above the %d of a statement",
+				modelName, accessConfig.Name, accessConfig.ChunkSize*len(accessConfig.Values), defs.MaxBindParams))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,186 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+// aggregateResultName is the struct of a row of an aggregate, CountOrdersByStatusResult
+func aggregateResultName(name string) string {
+	return name + "Result"
+}
+
+// aggregateGoType is the type of the field of an aggregate in the result. COUNT is an int64, SUM is a nullable int64
+// or float64, AVG a nullable float64, and MIN and MAX have the type of their attribute: all but COUNT are NULL for
+// the aggregates of no rows.
+func aggregateGoType(aggregate defs.Aggregate, fieldTypes map[string]*golang.GoType) (*golang.GoType, error) {
+	function := strings.ToUpper(aggregate.Function)
+	if function == datahelpers.AggregateCount {
+		return golang.GoInt64Type, nil
+	}
+	fieldType, ok := fieldTypes[golang.ToSnakeCase(aggregate.Attribute)]
+	if !ok {
+		return nil, fmt.Errorf("aggregate %s: unknown attribute %s", aggregate.ResultName(), aggregate.Attribute)
+	}
+	valueType := &golang.GoType{Name: nullValueType(fieldType.Name), Source: fieldType.Source}
+	switch function {
+	case datahelpers.AggregateSum:
+		if strings.HasPrefix(valueType.Name, "int") {
+			return &golang.GoType{Name: "sql.NullInt64"}, nil
+		}
+		return nullableGoType(valueType), nil
+	case datahelpers.AggregateAvg:
+		return &golang.GoType{Name: "sql.NullFloat64"}, nil
+	case datahelpers.AggregateMin, datahelpers.AggregateMax:
+		return nullableGoType(valueType), nil
+	}
+	return nil, fmt.Errorf("aggregate %s: unknown function %s", aggregate.ResultName(), aggregate.Function)
+}
+
+// aggregateFields are the fields of the result of an aggregate access: the group by attributes, then the aggregates
+func aggregateFields(conf *defs.AccessConfig, fieldTypes map[string]*golang.GoType) ([]golang.NameWithType, error) {
+	fields := make([]golang.NameWithType, 0, len(conf.GroupBy)+len(conf.Aggregates))
+	for _, attribute := range conf.GroupBy {
+		fieldType, ok := fieldTypes[golang.ToSnakeCase(attribute)]
+		if !ok {
+			return nil, fmt.Errorf("access %s: groups by unknown attribute %s", conf.Name, attribute)
+		}
+		fields = append(fields, golang.NameWithType{Name: golang.ToSnakeCase(attribute), Type: fieldType})
+	}
+	for _, aggregate := range conf.Aggregates {
+		aggregateType, err := aggregateGoType(aggregate, fieldTypes)
+		if err != nil {
+			return nil, fmt.Errorf("access %s: %w", conf.Name, err)
+		}
+		fields = append(fields, golang.NameWithType{Name: golang.ToSnakeCase(aggregate.ResultName()), Type: aggregateType})
+	}
+	return fields, nil
+}
+
+// GenerateAggregateConfigs generates the aggregates of a model, like GenerateFindConfigs, returning a result per
+// group instead of the found models:
+//
+//	type CountOrdersByStatusResult struct {
+//		OrderStatus string `json:"order_status"`
+//		Count       int64  `json:"count"`
+//	}
+//
+//	func CountOrdersByStatus(ctx context.Context, db *Order_DB, requestParams CountOrdersByStatusParams) ([]CountOrdersByStatusResult, error) {
+//		stmt := db.statement(ctx, "CountOrdersByStatus") // SELECT order_status, COUNT(*) AS count FROM order ... GROUP BY order_status
+//		...
+//	}
+func GenerateAggregateConfigs(dialect datahelpers.Dialect, modelName string, modelDBName string, fieldTypes map[string]*golang.GoType,
+	aggregateConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
+
+	functions := make([]*golang.FunctionDef, 0, len(aggregateConfig))
+	reqs := make([]*golang.StructDef, 0, len(aggregateConfig))
+	queries := make([]NamedQuery, 0, len(aggregateConfig))
+
+	for _, conf := range aggregateConfig {
+		query, paramRefs := datahelpers.MakeAggregateQuery(dialect, modelName, &conf)
+		params, err := readAccessParams(&conf, fieldTypes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		fields, err := aggregateFields(&conf, fieldTypes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		queries = append(queries, NamedQuery{Name: conf.Name, Query: query, Expand: inListExpansion(paramRefs)})
+		reqs = append(reqs, generateAccessStructs(paramRefs, params, conf.Name)...)
+		reqs = append(reqs, golang.GenStructForDataModel(aggregateResultName(conf.Name), fields, false, false, true))
+		functions = append(functions, ReadParamsFunction(paramRefs, params, conf.Name, "values", "params"))
+
+		scanned := make([]string, 0, len(fields))
+		for _, field := range fields {
+			scanned = append(scanned, golang.ToPascalCase(field.Name))
+		}
+		// An aggregate scans its rows like a find scans the found models
+		fn := FindCodeFunction(aggregateResultName(conf.Name), modelDBName, conf.Name, scanned, nil, nil, false)
+		if err := withTimeout(fn, &conf); err != nil {
+			return nil, nil, nil, err
+		}
+		functions = append(functions, fn)
+	}
+
+	return queries, functions, reqs, nil
+}
+
+// aggregateColumns are the snake case columns of the results of an aggregate, its group by attributes and aggregates
+func aggregateColumns(accessConfig *defs.AccessConfig) map[string]bool {
+	columns := map[string]bool{}
+	for _, attribute := range accessConfig.GroupBy {
+		columns[golang.ToSnakeCase(attribute)] = true
+	}
+	for _, aggregate := range accessConfig.Aggregates {
+		columns[golang.ToSnakeCase(aggregate.ResultName())] = true
+	}
+	return columns
+}
+
+// validateAggregates checks the aggregate access configs, see GenerateAggregateConfigs
+func validateAggregates(dataConfig *defs.DataConfig, modelConfig *defs.ModelConfig) []error {
+	errs := []error{}
+	modelName := modelConfig.Model.Name
+	for _, accessConfig := range modelConfig.GetAllAccessConfig() {
+		if (len(accessConfig.Aggregates) > 0 || len(accessConfig.GroupBy) > 0 || len(accessConfig.Having) > 0) &&
+			!slices.ContainsFunc(modelConfig.Access.Aggregate, func(aggregate defs.AccessConfig) bool {
+				return aggregate.Name == accessConfig.Name
+			}) {
+			errs = append(errs, fmt.Errorf("model %s: access %s aggregates, which only aggregate accesses do", modelName, accessConfig.Name))
+		}
+	}
+
+	columns := modelColumns(modelConfig, dataConfig)
+	for _, accessConfig := range modelConfig.Access.Aggregate {
+		if len(accessConfig.Aggregates) == 0 {
+			errs = append(errs, fmt.Errorf("model %s: access %s has no aggregates", modelName, accessConfig.Name))
+		}
+		if len(accessConfig.Attributes) > 0 || accessConfig.Pagination != nil || len(accessConfig.Include) > 0 {
+			errs = append(errs, fmt.Errorf("model %s: access %s selects attributes, pages or includes models, which aggregates don't", modelName, accessConfig.Name))
+		}
+		resultNames := map[string]bool{}
+		for _, attribute := range accessConfig.GroupBy {
+			if !columns[golang.ToSnakeCase(attribute)] {
+				errs = append(errs, fmt.Errorf("model %s: access %s groups by unknown attribute %s", modelName, accessConfig.Name, attribute))
+			}
+			resultNames[golang.ToSnakeCase(attribute)] = true
+		}
+		for _, aggregate := range accessConfig.Aggregates {
+			function := strings.ToUpper(aggregate.Function)
+			if !slices.Contains(aggregateFunctions, function) {
+				errs = append(errs, fmt.Errorf("model %s: access %s has aggregate function %q, expected one of %s",
+					modelName, accessConfig.Name, aggregate.Function, strings.Join(aggregateFunctions, ", ")))
+			}
+			if aggregate.Attribute == "" && function != datahelpers.AggregateCount {
+				errs = append(errs, fmt.Errorf("model %s: access %s has %s aggregate without attribute", modelName, accessConfig.Name, function))
+			} else if aggregate.Attribute != "" && !columns[golang.ToSnakeCase(aggregate.Attribute)] {
+				errs = append(errs, fmt.Errorf("model %s: access %s aggregates unknown attribute %s", modelName, accessConfig.Name, aggregate.Attribute))
+			}
+			name := golang.ToSnakeCase(aggregate.ResultName())
+			if resultNames[name] {
+				errs = append(errs, fmt.Errorf("model %s: access %s has result %s more than once", modelName, accessConfig.Name, name))
+			}
+			resultNames[name] = true
+		}
+		// Having filters compare aggregates, by their names
+		aggregateNames := map[string]bool{}
+		for _, aggregate := range accessConfig.Aggregates {
+			aggregateNames[aggregate.ResultName()] = true
+		}
+		checkAggregate := func(accessName, name string) {
+			if !aggregateNames[name] {
+				errs = append(errs, fmt.Errorf("model %s: access %s has having filter on unknown aggregate %s", modelName, accessName, name))
+			}
+		}
+		for _, filter := range accessConfig.Having {
+			errs = append(errs, validateFilter(modelName, accessConfig.Name, filter, checkAggregate)...)
+		}
+	}
+	return errs
+}
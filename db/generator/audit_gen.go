@@ -0,0 +1,178 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+// Name of the unit holding the actor of the writes of the models with audit
+const auditUnitName = "audit"
+
+// historyAccessName is the access reading the history of a row of a model with audit, FindProductHistory
+func historyAccessName(modelName string) string {
+	return "Find" + modelName + "History"
+}
+
+// historyStructName is the struct of the history rows of a model with audit, ProductHistory
+func historyStructName(modelName string) string {
+	return modelName + "History"
+}
+
+// auditAccess returns the accesses of a model with audit: its updates, adds and deletes (and the restores of soft
+// delete models) write the history rows of the rows they change, see datahelpers.MakeUpdateQuery
+func auditAccess(access defs.Access) defs.Access {
+	audit := func(configs []defs.AccessConfig) []defs.AccessConfig {
+		audited := make([]defs.AccessConfig, 0, len(configs))
+		for _, conf := range configs {
+			conf.Audit = true
+			audited = append(audited, conf)
+		}
+		return audited
+	}
+	access.Update = audit(access.Update)
+	access.Add = audit(access.Add)
+	access.Delete = audit(access.Delete)
+	return access
+}
+
+// withActor binds the actor of the context after the params of a write of a model with audit, the actor of its
+// history rows:
+//
+//	values, err := UpdateProductPriceReadParams(requestParams)
+//	...
+//	values = append(values, ActorFromContext(ctx))
+func withActor(fn *golang.FunctionDef, conf *defs.AccessConfig) {
+	if !conf.Audit {
+		return
+	}
+	for i, elem := range fn.Body {
+		if elem.FunctionCall != nil && elem.FunctionCall.Function == conf.Name+"ReadParams" {
+			fn.Body = slices.Insert(fn.Body, i+1, &golang.CodeElement{Assign: &golang.Assignment{
+				Left:  "values",
+				Right: "append(values, ActorFromContext(ctx))",
+			}})
+			return
+		}
+	}
+}
+
+// historyFields are the fields of the history rows, see datahelpers.BuildCreateHistoryTable. The old values of
+// added rows and the new values of deleted rows are NULL, as is the actor of writes without one.
+func historyFields() []golang.NameWithType {
+	jsonType := &golang.GoType{Name: "json.RawMessage", Source: "encoding/json"}
+	return []golang.NameWithType{
+		{Name: "id", Type: golang.GoInt64Type},
+		{Name: "row_id", Type: golang.GoStringType},
+		{Name: "operation", Type: golang.GoStringType},
+		{Name: "old_values", Type: jsonType},
+		{Name: "new_values", Type: jsonType},
+		{Name: "actor", Type: &golang.GoType{Name: "sql.NullString"}},
+		{Name: "changed_at", Type: golang.GoTimeType},
+	}
+}
+
+// GenerateHistoryAccess generates the access reading the history of a row of a model with audit, oldest first:
+//
+//	func FindProductHistory(ctx context.Context, db *Product_DB, id string) ([]ProductHistory, error) {
+//		stmt := db.statement(ctx, "FindProductHistory") // SELECT ... FROM "product_history" WHERE "row_id" = $1 ORDER BY "id"
+//		rows, err := stmt.QueryContext(ctx, id)
+//		...
+//		return results, nil
+//	}
+func GenerateHistoryAccess(dialect datahelpers.Dialect, modelName string, modelDBName string) (NamedQuery, *golang.FunctionDef, *golang.StructDef) {
+	name := historyAccessName(modelName)
+	structName := historyStructName(modelName)
+	fields := historyFields()
+	attributes := make([]string, 0, len(fields))
+	for _, field := range fields {
+		attributes = append(attributes, golang.ToPascalCase(field.Name))
+	}
+	resultsTypeName := "[]" + structName
+	fnReturns := typeOnlyParamsCE(resultsTypeName, "error")
+	fn := &golang.FunctionDef{
+		Name: name,
+		Parameters: []*golang.Parameter{
+			ctxParamCE("ctx"),
+			{Name: "db", Type: &golang.GoType{Name: "*" + modelDBName}},
+			{Name: "id", Type: golang.GoStringType},
+		},
+		Returns: fnReturns,
+		Imports: []string{"context", "database/sql"},
+		Body: golang.CodeElements{
+			{FunctionCall: lookupStmtCE(name, "db", "stmt")},
+			{FunctionCall: &golang.FunctionCall{
+				NewOutput:        []string{"rows", "err"},
+				Receiver:         "stmt",
+				Function:         "QueryContext",
+				Args:             []string{"ctx", "id"},
+				ErrorHandler:     &golang.ErrorHandler{ErrorFunctionReturns: fnReturns},
+				CleanningHandler: &golang.CleanningHandler{Receiver: "rows", Function: "Close"},
+			}},
+			{Variable: createVarCE("results", resultsTypeName)},
+			{RepeatCond: scanResultsCE(structName, attributes, "results")},
+			returnValuesCE("results", "nil"),
+		},
+	}
+	query := NamedQuery{Name: name, Query: datahelpers.MakeHistoryQuery(dialect, modelName)}
+	return query, fn, golang.GenStructForDataModel(structName, fields, true, false, true)
+}
+
+// GenerateAuditUnit generates the actor of the history rows written by the models with audit, which callers set on
+// the context of their writes:
+//
+//	ctx = WithActor(ctx, "alice")
+//	_, err := UpdateProductPrice(ctx, db, requestParams)
+func GenerateAuditUnit() *golang.UnitModule {
+	contextType := &golang.GoType{Name: "context.Context"}
+	return &golang.UnitModule{
+		Name:  auditUnitName,
+		Types: []*golang.TypeDef{{Name: "actorKey", Type: "struct{}"}},
+		Functions: []*golang.FunctionDef{
+			{
+				Name:       "WithActor",
+				Parameters: []*golang.Parameter{ctxParamCE("ctx"), {Name: "actor", Type: golang.GoStringType}},
+				Returns:    []*golang.Parameter{{Type: contextType}},
+				Imports:    []string{"context"},
+				Body:       golang.CodeElements{returnValuesCE("context.WithValue(ctx, actorKey{}, actor)")},
+			},
+			{
+				Name:       "ActorFromContext",
+				Parameters: []*golang.Parameter{ctxParamCE("ctx")},
+				Returns:    typeOnlyParamsCE("string"),
+				Imports:    []string{"context"},
+				Body: golang.CodeElements{
+					{NewAssign: &golang.NewAssignment{Left: "actor, _", Right: "ctx.Value(actorKey{}).(string)"}},
+					returnValuesCE("actor"),
+				},
+			},
+		},
+	}
+}
+
+// validateAudit checks the models with audit, whose history is written with Postgres JSONB by the statements of their
+// updates, adds and deletes, see datahelpers.MakeUpdateQuery
+func validateAudit(modelConfig *defs.ModelConfig, dialect datahelpers.Dialect) []error {
+	if !modelConfig.Model.Audit {
+		return nil
+	}
+	errs := []error{}
+	modelName := modelConfig.Model.Name
+	if dialect.GetName() != "postgres" {
+		errs = append(errs, fmt.Errorf("model %s: has audit, which needs the postgres driver", modelName))
+	}
+	// The history rows are written by the CTEs of single row writes, the upsert of an add_or_replace doesn't tell the
+	// old row it replaces and the chunks and COPY of an add_many write none
+	for _, accessConfig := range modelConfig.Access.AddOrReplace {
+		errs = append(errs, fmt.Errorf("model %s: access %s is an add_or_replace, audit doesn't record the rows it replaces",
+			modelName, accessConfig.Name))
+	}
+	for _, accessConfig := range modelConfig.Access.AddMany {
+		errs = append(errs, fmt.Errorf("model %s: access %s is an add_many, audit doesn't record the rows of batch inserts",
+			modelName, accessConfig.Name))
+	}
+	return errs
+}
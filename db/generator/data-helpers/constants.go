@@ -12,6 +12,55 @@ const (
 	OperatorLike              = "LIKE"
 )
 
+// Pagination types of find access configs, see defs.Pagination
+const (
+	PaginationOffset = "offset"
+	PaginationKeyset = "keyset"
+)
+
+// Params binding the page of a paginated find, they follow the params of the filters
+const (
+	LimitParam  = "limit"
+	OffsetParam = "offset"
+	CursorParam = "cursor"
+)
+
+// Where the NULLs of an order_by attribute sort, see defs.OrderBy
+const (
+	NullsFirst = "FIRST"
+	NullsLast  = "LAST"
+)
+
+// Functions of the aggregates of aggregate access configs, see defs.Aggregate
+const (
+	AggregateCount = "COUNT"
+	AggregateSum   = "SUM"
+	AggregateAvg   = "AVG"
+	AggregateMin   = "MIN"
+	AggregateMax   = "MAX"
+)
+
+// SortParam selects one of the sort options of a find
+const SortParam = "sort"
+
+// VersionColumn is the system column counting the updates of a row, VersionParam binds the version an access with
+// optimistic_lock expects the row to have. It follows the params of the filters.
+const (
+	VersionColumn = "version"
+	VersionParam  = "version"
+)
+
+// OutboxTable is the table of the events of the accesses with events, see defs.DataConfig.HasEvents
+const OutboxTable = "outbox_events"
+
+// Operations of the history rows of the models with audit, see auditInsert
+const (
+	AuditAdd     = "add"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+)
+
 const (
 	LogicalAnd = "AND"
 	LogicalOr  = "OR"
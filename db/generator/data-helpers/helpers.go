@@ -45,10 +45,12 @@ func prepareAndCacheQuery(model *ModelDB, queryName string, query string, paramM
 // Need to build different prepared statements for different access methods, find, update, add, add_or_replace, delete
 // Each prepared statement will have different parameter mappings,
 func PrepareStatements(model *ModelDB, access *defs.Access) error {
+	// The statements are prepared with lib/pq, see NewPreparedStmtBuilder
+	dialect := NewPostgresDialect()
 
 	for _, accessConfig := range access.Find {
 		filter := accessConfig.Filter
-		preparedQuery, paramMap := PrepareFilters(filter)
+		preparedQuery, paramMap := PrepareFilters(dialect, filter)
 		err := prepareAndCacheQuery(model, accessConfig.Name, preparedQuery, paramMap)
 		if err != nil {
 			return err
@@ -57,7 +59,7 @@ func PrepareStatements(model *ModelDB, access *defs.Access) error {
 
 	for _, accessConfig := range access.Update {
 		filter := accessConfig.Filter
-		preparedQuery, paramMap := PrepareFilters(filter)
+		preparedQuery, paramMap := PrepareFilters(dialect, filter)
 		err := prepareAndCacheQuery(model, accessConfig.Name, preparedQuery, paramMap)
 		if err != nil {
 			return err
@@ -66,7 +68,7 @@ func PrepareStatements(model *ModelDB, access *defs.Access) error {
 
 	for _, accessConfig := range access.Add {
 		filter := accessConfig.Filter
-		preparedQuery, paramMap := PrepareFilters(filter)
+		preparedQuery, paramMap := PrepareFilters(dialect, filter)
 		err := prepareAndCacheQuery(model, accessConfig.Name, preparedQuery, paramMap)
 		if err != nil {
 			return err
@@ -75,7 +77,7 @@ func PrepareStatements(model *ModelDB, access *defs.Access) error {
 
 	for _, accessConfig := range access.AddOrReplace {
 		filter := accessConfig.Filter
-		preparedQuery, paramMap := PrepareFilters(filter)
+		preparedQuery, paramMap := PrepareFilters(dialect, filter)
 		err := prepareAndCacheQuery(model, accessConfig.Name, preparedQuery, paramMap)
 		if err != nil {
 			return err
@@ -84,7 +86,7 @@ func PrepareStatements(model *ModelDB, access *defs.Access) error {
 
 	for _, accessConfig := range access.Delete {
 		filter := accessConfig.Filter
-		preparedQuery, paramMap := PrepareFilters(filter)
+		preparedQuery, paramMap := PrepareFilters(dialect, filter)
 		err := prepareAndCacheQuery(model, accessConfig.Name, preparedQuery, paramMap)
 		if err != nil {
 			return err
@@ -120,6 +122,14 @@ func GetPostgresType(typeId int64) string {
 	return config.PostgresTypeMaps[typeId].MappedType
 }
 
+func GetMySQLType(typeId int64) string {
+	return config.MySQLTypeMaps[typeId].MappedType
+}
+
+func GetSQLiteType(typeId int64) string {
+	return config.SQLiteTypeMaps[typeId].MappedType
+}
+
 func GetValidations(validationIds []int64) []*models.Validation {
 	validations := []*models.Validation{}
 
@@ -179,3 +189,34 @@ func PostgresToGoType(pgType string) string {
 	}
 	return goType
 }
+
+// GetTypeIdForPostgresType is the reverse of GetPostgresType: the type id mapped to postgresType,
+// the lowest one when several types share a database type (TEXT for text, long_text, email ...)
+func GetTypeIdForPostgresType(postgresType string) (int64, bool) {
+	return reverseTypeMapping(config.PostgresTypeMaps, postgresType)
+}
+
+func reverseTypeMapping(typeMaps map[int64]models.TypeMapping, databaseType string) (int64, bool) {
+	normalized := NormalizeDatabaseType(databaseType)
+	var typeId int64
+	for id, typeMap := range typeMaps {
+		if NormalizeDatabaseType(typeMap.MappedType) == normalized && (typeId == 0 || id < typeId) {
+			typeId = id
+		}
+	}
+	return typeId, typeId != 0
+}
+
+var databaseTypeSpaceRegex = regexp.MustCompile(`\s*([(),])\s*|\s+`)
+
+// NormalizeDatabaseType upper cases a database type and drops the optional whitespace,
+// "numeric( 33, 18 )" and "NUMERIC(33,18)" are the same type
+func NormalizeDatabaseType(databaseType string) string {
+	normalized := databaseTypeSpaceRegex.ReplaceAllStringFunc(strings.TrimSpace(databaseType), func(match string) string {
+		if trimmed := strings.TrimSpace(match); trimmed != "" {
+			return trimmed
+		}
+		return " "
+	})
+	return strings.ToUpper(normalized)
+}
@@ -0,0 +1,108 @@
+package datahelpers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MySQLDialect targets MySQL 8 (go-sql-driver/mysql): backtick identifiers, ? placeholders,
+// AUTO_INCREMENT ids read back with LAST_INSERT_ID and IN lists expanded to one placeholder per element.
+// It has no RETURNING, the generated inserts read their ids with sql.Result.LastInsertId.
+type MySQLDialect struct {
+	BaseDialect
+}
+
+func NewMySQLDialect() *MySQLDialect {
+	return &MySQLDialect{BaseDialect: BaseDialect{name: "mysql"}}
+}
+
+func (d *MySQLDialect) DatabaseType(typeId int64) (string, error) {
+	mysqlType := GetMySQLType(typeId)
+	if mysqlType == "" {
+		return "", fmt.Errorf("type %d has no mysql type mapping", typeId)
+	}
+	return mysqlType, nil
+}
+
+var mysqlSystemColumns = map[string]string{
+	"id":         "BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY",
+	"version":    "INT NOT NULL DEFAULT 1",
+	"updated_at": "TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6)",
+	"deleted_at": "TIMESTAMP(6) NULL",
+}
+
+func (d *MySQLDialect) SystemColumnDefinition(column string) string {
+	return mysqlSystemColumns[column]
+}
+
+// Ids are AUTO_INCREMENT, see sql.Result.LastInsertId
+func (d *MySQLDialect) InsertsID() bool {
+	return false
+}
+
+func (d *MySQLDialect) FormatReturning(columns ...string) string {
+	return ""
+}
+
+func (d *MySQLDialect) FormatIn(attr, placeholder string, negate bool) (string, bool) {
+	operator := OperatorIN
+	if negate {
+		operator = OperatorNOTIN
+	}
+	return fmt.Sprintf("%s %s (%s)", attr, operator, placeholder), true
+}
+
+// id = LAST_INSERT_ID(id) makes LastInsertId return the id of the replaced row as well,
+// RowsAffected is 1 for an insert, 2 for an update and 0 for a replace changing nothing
+// MySQL updates the row of any unique key the values conflict on, it has no conflict target
+func (d *MySQLDialect) FormatUpsert(target string, columns []string, bind func(i int) string) string {
+	id := d.FormatIdentifier("id")
+	updateClauses := []string{fmt.Sprintf("%s = LAST_INSERT_ID(%s)", id, id)}
+	for _, column := range columns {
+		updateClauses = append(updateClauses, fmt.Sprintf("%s = %s(%s)", column, KeywordVALUES, column))
+	}
+	return fmt.Sprintf("%s DUPLICATE KEY %s %s", KeywordON, KeywordUPDATE, strings.Join(updateClauses, ", "))
+}
+
+// Microseconds, as the updated_at column
+func (d *MySQLDialect) CurrentTimestamp() string {
+	return "NOW(6)"
+}
+
+// MySQL requires index names
+func (d *MySQLDialect) IndexName(table string, columns []string, isUnique bool) string {
+	return indexName(table, columns, isUnique)
+}
+
+// MySQL has no partial indexes, the where condition is ignored
+func (d *MySQLDialect) FormatCreateIndex(table string, columns []string, isUnique bool, where string) string {
+	indexType := KeywordINDEX
+	if isUnique {
+		indexType = KeywordUNIQUE + " " + KeywordINDEX
+	}
+	return fmt.Sprintf("%s %s %s %s %s (%s);", KeywordCREATE, indexType, d.FormatIdentifier(d.IndexName(table, columns, isUnique)), KeywordON,
+		d.FormatIdentifier(table), formatIdentifiers(d, columns))
+}
+
+func (d *MySQLDialect) FormatDropIndex(table, indexName string) string {
+	return fmt.Sprintf("%s %s %s %s %s;", KeywordDROP, KeywordINDEX, d.FormatIdentifier(indexName), KeywordON, d.FormatIdentifier(table))
+}
+
+func (d *MySQLDialect) FormatAlterColumnType(table, column, columnType string) (string, error) {
+	return fmt.Sprintf("%s %s %s MODIFY %s %s %s;", KeywordALTER, KeywordTABLE, d.FormatIdentifier(table),
+		KeywordCOLUMN, d.FormatIdentifier(column), columnType), nil
+}
+
+// Matches the BIGINT AUTO_INCREMENT id
+func (d *MySQLDialect) ReferenceColumnType() string {
+	return "BIGINT"
+}
+
+func (d *MySQLDialect) FormatAddForeignKey(table, constraint, definition string) (string, error) {
+	return formatAddForeignKey(d, table, constraint, definition), nil
+}
+
+func (d *MySQLDialect) FormatDropForeignKey(table, constraint string) (string, error) {
+	return fmt.Sprintf("%s %s %s %s %s %s;", KeywordALTER, KeywordTABLE, d.FormatIdentifier(table),
+		KeywordDROP, KeywordFOREIGNKEY, d.FormatIdentifier(constraint)), nil
+}
@@ -0,0 +1,294 @@
+package datahelpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+func TestMySQLPreparedStmts(t *testing.T) {
+	t.Run("Find", func(t *testing.T) {
+		psb := NewPreparedStmtBuilderWithDialect(NewMySQLDialect(), "users", defs.AccessConfig{
+			Attributes: []string{"id", "name", "email"},
+			Filter: []defs.Filter{
+				{Attribute: "email", Operator: "=", ParamName: "email"},
+				{Operator: "OR", Conditions: []defs.Filter{
+					{Attribute: "age", Operator: "BETWEEN", ParamName: "age_range"},
+					{Attribute: "status", Operator: "IN", ParamName: "statuses"},
+					{Attribute: "role", Operator: "NOT IN", ParamName: "roles"},
+				}},
+			},
+		})
+		query, params := psb.BuildFindPreparedStmt()
+		assert.Equal(t, "SELECT `id`, `name`, `email` FROM `users` WHERE (`email` = ? AND "+
+			"((`age` BETWEEN ? AND ?) OR `status` IN (?) OR `role` NOT IN (?)))", query)
+		assert.Equal(t, []defs.ParameterRef{
+			{Name: "email", Index: -1},
+			{Name: "age_range", Index: 0},
+			{Name: "age_range", Index: 1},
+			{Name: "statuses", Index: -1, Expand: true},
+			{Name: "roles", Index: -1, Expand: true},
+		}, params)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		psb := NewPreparedStmtBuilderWithDialect(NewMySQLDialect(), "products", defs.AccessConfig{
+			Set:              []defs.Update{{Attribute: "price", ParamName: "new_price"}},
+			Autoincrement:    []string{"version"},
+			CaptureTimestamp: []string{"updated_at"},
+			Filter:           []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "product_id"}},
+		})
+		query, params := psb.BuildUpdatePreparedStmt()
+		assert.Equal(t, "UPDATE `products` SET `price` = ?, `version` = `version` + 1, `updated_at` = NOW(6) WHERE (`id` = ?)", query)
+		assert.Equal(t, []defs.ParameterRef{
+			{Name: "new_price", Index: -1},
+			{Name: "product_id", Index: -1},
+		}, params)
+	})
+
+	t.Run("Add", func(t *testing.T) {
+		psb := NewPreparedStmtBuilderWithDialect(NewMySQLDialect(), "products", defs.AccessConfig{
+			Values: []defs.Update{
+				{Attribute: "name", ParamName: "product_name"},
+				{Attribute: "price", ParamName: "product_price"},
+			},
+		})
+		query, params := psb.BuildAddPreparedStmt()
+		assert.Equal(t, "INSERT INTO `products` (`name`, `price`) VALUES (?, ?)", query)
+		assert.Equal(t, []defs.ParameterRef{
+			{Name: "product_name", Index: -1},
+			{Name: "product_price", Index: -1},
+		}, params)
+	})
+
+	t.Run("AddOrReplace", func(t *testing.T) {
+		psb := NewPreparedStmtBuilderWithDialect(NewMySQLDialect(), "inventory", defs.AccessConfig{
+			Values: []defs.Update{
+				{Attribute: "product_id", ParamName: "product_id"},
+				{Attribute: "quantity", ParamName: "quantity"},
+			},
+		})
+		query, params := psb.BuildAddOrReplacePreparedStmt()
+		assert.Equal(t, "INSERT INTO `inventory` (`product_id`, `quantity`) VALUES (?, ?) "+
+			"ON DUPLICATE KEY UPDATE `id` = LAST_INSERT_ID(`id`), `product_id` = VALUES(`product_id`), `quantity` = VALUES(`quantity`)", query)
+		assert.Equal(t, []defs.ParameterRef{
+			{Name: "product_id", Index: -1},
+			{Name: "quantity", Index: -1},
+		}, params)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		psb := NewPreparedStmtBuilderWithDialect(NewMySQLDialect(), "orders", defs.AccessConfig{
+			Filter: []defs.Filter{
+				{Attribute: "status", Operator: "=", ParamName: "order_status"},
+				{Attribute: "created_at", Operator: "<", ParamName: "cutoff_date"},
+			},
+		})
+		query, params := psb.BuildDeletePreparedStmt()
+		assert.Equal(t, "DELETE FROM `orders` WHERE (`status` = ? AND `created_at` < ?)", query)
+		assert.Equal(t, []defs.ParameterRef{
+			{Name: "order_status", Index: -1},
+			{Name: "cutoff_date", Index: -1},
+		}, params)
+	})
+}
+
+// The queries of the access kinds of the generated code, see generator.GenerateDB
+func TestMySQLAccessQueries(t *testing.T) {
+	dialect := NewMySQLDialect()
+	byId := []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}}
+	values := []defs.Update{{Attribute: "sku", ParamName: "sku"}, {Attribute: "price", ParamName: "price"}}
+	idRef, versionRef := defs.ParameterRef{Name: "id", Index: -1}, defs.ParameterRef{Name: VersionParam, Index: -1}
+	valueRefs := []defs.ParameterRef{{Name: "sku", Index: -1}, {Name: "price", Index: -1}}
+
+	tests := []struct {
+		name     string
+		make     func() (string, []defs.ParameterRef)
+		expected string
+		params   []defs.ParameterRef
+	}{
+		{
+			name: "find",
+			make: func() (string, []defs.ParameterRef) {
+				return MakeFindQuery(dialect, "Product", &defs.AccessConfig{
+					Attributes: []string{"id", "sku"},
+					Filter: []defs.Filter{
+						{Attribute: "sku", Operator: "IN", ParamName: "skus"},
+						{Attribute: "price", Operator: "BETWEEN", ParamName: "price_range"},
+					},
+					OrderBy:    []defs.OrderBy{{Attribute: "price", Direction: "DESC"}},
+					Pagination: &defs.Pagination{Type: PaginationOffset},
+				})
+			},
+			expected: "SELECT `id`, `sku` FROM `product` WHERE (1 = 1) AND (`sku` IN (?) AND (`price` BETWEEN ? AND ?)) " +
+				"ORDER BY `price` DESC, `id` DESC LIMIT ? OFFSET ?",
+			params: []defs.ParameterRef{
+				{Name: "skus", Index: -1, Expand: true},
+				{Name: "price_range", Index: 0},
+				{Name: "price_range", Index: 1},
+				{Name: LimitParam, Index: -1},
+				{Name: OffsetParam, Index: -1},
+			},
+		},
+		{
+			// The children of a find with includes, selected by the ids of the found models
+			name: "include",
+			make: func() (string, []defs.ParameterRef) {
+				return MakeFindQuery(dialect, "OrderItem", &defs.AccessConfig{
+					Attributes:     []string{"id", "order_id"},
+					Filter:         []defs.Filter{{Attribute: "order_id", Operator: "IN", ParamName: "order_id"}},
+					ExcludeDeleted: true,
+				})
+			},
+			expected: "SELECT `id`, `order_id` FROM `order_item` WHERE (1 = 1) AND (`order_id` IN (?)) AND (`deleted_at` IS NULL)",
+			params:   []defs.ParameterRef{{Name: "order_id", Index: -1, Expand: true}},
+		},
+		{
+			name: "aggregate",
+			make: func() (string, []defs.ParameterRef) {
+				return MakeAggregateQuery(dialect, "Order", &defs.AccessConfig{
+					GroupBy:    []string{"orderStatus"},
+					Aggregates: []defs.Aggregate{{Function: "count"}, {Function: "SUM", Attribute: "total_amount", Name: "revenue"}},
+					Filter:     []defs.Filter{{Attribute: "user_id", Operator: "IN", ParamName: "user_ids"}},
+					Having:     []defs.Filter{{Attribute: "count", Operator: ">=", ParamName: "min_orders"}},
+					OrderBy:    []defs.OrderBy{{Attribute: "revenue", Direction: "DESC"}},
+				})
+			},
+			expected: "SELECT `order_status`, COUNT(*) AS `count`, SUM(`total_amount`) AS `revenue` FROM `order` " +
+				"WHERE (1 = 1) AND (`user_id` IN (?)) GROUP BY `order_status` HAVING (COUNT(*) >= ?) ORDER BY `revenue` DESC",
+			params: []defs.ParameterRef{{Name: "user_ids", Index: -1, Expand: true}, {Name: "min_orders", Index: -1}},
+		},
+		{
+			name: "update",
+			make: func() (string, []defs.ParameterRef) {
+				return MakeUpdateQuery(dialect, "Product", &defs.AccessConfig{Set: values[1:], Filter: byId})
+			},
+			expected: "UPDATE `product` SET `price` = ? WHERE (1 = 1) AND (`id` = ?)",
+			params:   []defs.ParameterRef{valueRefs[1], idRef},
+		},
+		{
+			// No RETURNING, the new version follows the version param
+			name: "locked update",
+			make: func() (string, []defs.ParameterRef) {
+				return MakeUpdateQuery(dialect, "Product", &defs.AccessConfig{Set: values[1:], Filter: byId, OptimisticLock: true})
+			},
+			expected: "UPDATE `product` SET `price` = ?, `version` = `version` + 1 WHERE (1 = 1) AND (`id` = ?) AND (`version` = ?)",
+			params:   []defs.ParameterRef{valueRefs[1], idRef, versionRef},
+		},
+		{
+			// No RETURNING, the id is read with LastInsertId
+			name: "add",
+			make: func() (string, []defs.ParameterRef) {
+				return MakeAddQuery(dialect, "Product", &defs.AccessConfig{Values: values})
+			},
+			expected: "INSERT INTO `product` (`sku`, `price`) VALUES (?, ?)",
+			params:   valueRefs,
+		},
+		{
+			name: "add_or_replace",
+			make: func() (string, []defs.ParameterRef) {
+				return MakeAddOrReplaceQuery(dialect, "Product", &defs.AccessConfig{Values: values, ConflictKey: []string{"sku"}})
+			},
+			expected: "INSERT INTO `product` (`sku`, `price`) VALUES (?, ?) " +
+				"ON DUPLICATE KEY UPDATE `id` = LAST_INSERT_ID(`id`), `sku` = VALUES(`sku`), `price` = VALUES(`price`)",
+			params: valueRefs,
+		},
+		{
+			name: "delete",
+			make: func() (string, []defs.ParameterRef) {
+				return MakeDeleteQuery(dialect, "Product", &defs.AccessConfig{Filter: byId})
+			},
+			expected: "DELETE FROM `product` WHERE (1 = 1) AND (`id` = ?)",
+			params:   []defs.ParameterRef{idRef},
+		},
+		{
+			name: "locked delete",
+			make: func() (string, []defs.ParameterRef) {
+				return MakeDeleteQuery(dialect, "Product", &defs.AccessConfig{Filter: byId, OptimisticLock: true})
+			},
+			expected: "DELETE FROM `product` WHERE (1 = 1) AND (`id` = ?) AND (`version` = ?)",
+			params:   []defs.ParameterRef{idRef, versionRef},
+		},
+		{
+			name: "soft delete",
+			make: func() (string, []defs.ParameterRef) {
+				return MakeSoftDeleteQuery(dialect, "Product", &defs.AccessConfig{Filter: byId})
+			},
+			expected: "UPDATE `product` SET `deleted_at` = NOW(6) WHERE (1 = 1) AND (`id` = ?) AND (`deleted_at` IS NULL)",
+			params:   []defs.ParameterRef{idRef},
+		},
+		{
+			name: "restore",
+			make: func() (string, []defs.ParameterRef) {
+				return MakeRestoreQuery(dialect, "Product", &defs.AccessConfig{Filter: byId})
+			},
+			expected: "UPDATE `product` SET `deleted_at` = NULL WHERE (1 = 1) AND (`id` = ?) AND (`deleted_at` IS NOT NULL)",
+			params:   []defs.ParameterRef{idRef},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, params := tt.make()
+			assert.Equal(t, tt.expected, query)
+			assert.Equal(t, tt.params, params)
+		})
+	}
+
+	t.Run("add_many", func(t *testing.T) {
+		addConfig := &defs.AccessConfig{Values: values}
+		assert.Equal(t, "INSERT INTO `product` (`sku`, `price`) VALUES (?, ?), (?, ?), (?, ?)", MakeAddManyQuery(dialect, "Product", addConfig, 3))
+		insert, returning := MakeAddManyQueryParts(dialect, "Product", addConfig)
+		assert.Equal(t, "INSERT INTO `product` (`sku`, `price`) VALUES", insert)
+		assert.Empty(t, returning)
+	})
+}
+
+func TestMySQLBuildCreateTable(t *testing.T) {
+	config.LoadConfig()
+
+	sb := NewSchemaBuilder(NewMySQLDialect(), "shop", defs.DataConfig{})
+	model := &defs.ModelConfig{
+		Model: defs.Model{
+			Name:       "products",
+			Attributes: []int64{2000001, 2000002, 2000003},
+			Indexes: []defs.ModelIndex{
+				{IndexName: "idx_sku_product_name", Attributes: []int64{2000001, 2000002}},
+			},
+			UniqueConstraints: []defs.UniqueConstraint{
+				{ConstraintName: "uq_sku", Attributes: []int64{2000001}},
+			},
+		},
+		Access: defs.Access{
+			Find: []defs.AccessConfig{{
+				Filter: []defs.Filter{{Attribute: "product_name", Operator: "="}},
+			}},
+		},
+	}
+	expected := "CREATE TABLE `products` (\n" +
+		"	`id` BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,\n" +
+		"	`version` INT NOT NULL DEFAULT 1,\n" +
+		"	`updated_at` TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),\n" +
+		"	`sku` VARCHAR(255) NOT NULL,\n" +
+		"	`product_name` VARCHAR(255) NOT NULL,\n" +
+		"	`description` TEXT\n" +
+		");\n\n" +
+		"CREATE INDEX `idx_products_product_name` ON `products` (`product_name`);\n" +
+		"CREATE INDEX `idx_products_sku_product_name` ON `products` (`sku`, `product_name`);\n" +
+		"CREATE UNIQUE INDEX `uidx_products_sku` ON `products` (`sku`);\n"
+	assert.Equal(t, expected, sb.BuildCreateTable(model))
+}
+
+func TestDialectForDriver(t *testing.T) {
+	for driver, name := range map[string]string{
+		"postgres": "postgres", "pgx": "postgres", "mysql": "mysql", "sqlite": "sqlite", "sqlite3": "sqlite",
+	} {
+		dialect, err := DialectForDriver(driver)
+		assert.NoError(t, err)
+		assert.Equal(t, name, dialect.GetName())
+	}
+
+	_, err := DialectForDriver("oracle")
+	assert.EqualError(t, err, `no SQL dialect for driver "oracle"`)
+}
@@ -2,6 +2,7 @@ package datahelpers
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"stellarsky.ai/platform/codegen/data-service-generator/base"
@@ -9,46 +10,67 @@ import (
 	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
 )
 
-func applyTransformation(attribute string, transformation string) string {
+// The queries of the access configs quote their identifiers with the dialect, so that tables and columns named
+// after reserved words (user, order) can be queried, and bind their params to the placeholders of the dialect
+
+// column is the formatted identifier of the column of an attribute, * for all the columns
+func column(dialect Dialect, attribute string) string {
+	if attribute == "*" {
+		return attribute
+	}
+	return dialect.FormatIdentifier(golang.ToSnakeCase(attribute))
+}
+
+// columns are the formatted identifiers of the columns of attributes
+func columns(dialect Dialect, attributes []string) []string {
+	formatted := make([]string, 0, len(attributes))
+	for _, attribute := range attributes {
+		formatted = append(formatted, column(dialect, attribute))
+	}
+	return formatted
+}
+
+func applyTransformation(dialect Dialect, attribute string, transformation string) string {
 	if transformation == "" {
-		return golang.ToSnakeCase(attribute)
+		return column(dialect, attribute)
 	}
-	return fmt.Sprintf("%s(%s)", transformation, golang.ToSnakeCase(attribute))
+	return fmt.Sprintf("%s(%s)", transformation, column(dialect, attribute))
 }
 
-func makePreparedCounter(counter *uint32) string {
-	result := fmt.Sprintf("$%d", *counter)
+func makePreparedCounter(dialect Dialect, counter *uint32) string {
+	result := dialect.GetPlaceholder(int(*counter))
 	*counter += 1
 	return result
 }
 
-func buildPrepareStmt(filter defs.Filter, counter *uint32, paramsMap *[]defs.ParameterRef) string {
-	attribute := applyTransformation(filter.Attribute, filter.Transformation)
+func buildPrepareStmt(dialect Dialect, filter defs.Filter, counter *uint32, paramsMap *[]defs.ParameterRef) string {
+	attribute := applyTransformation(dialect, filter.Attribute, filter.Transformation)
 	switch strings.ToUpper(filter.Operator) {
 	case OperatorEquals, OperatorNotEquals, OperatorLessThan, OperatorLessThanEquals, OperatorGreaterThan, OperatorGreaterThanEquals:
-		result := fmt.Sprintf("%s %s %v", attribute, filter.Operator, makePreparedCounter(counter))
+		result := fmt.Sprintf("%s %s %v", attribute, filter.Operator, makePreparedCounter(dialect, counter))
 		*paramsMap = append(*paramsMap, defs.ParameterRef{
 			Name:  filter.ParamName,
 			Index: -1,
 		})
 		return result
 	case OperatorBetween:
-		lowEnd := makePreparedCounter(counter)
-		highEnd := makePreparedCounter(counter)
+		lowEnd := makePreparedCounter(dialect, counter)
+		highEnd := makePreparedCounter(dialect, counter)
 		*paramsMap = append(*paramsMap, defs.ParameterRef{Name: filter.ParamName, Index: 0}, defs.ParameterRef{Name: filter.ParamName, Index: 1})
 		result := fmt.Sprintf("(%s BETWEEN %v AND %v)", attribute, lowEnd, highEnd)
 		return result
 	case OperatorIn:
-		result := fmt.Sprintf("%s = ANY(%s)", attribute, makePreparedCounter(counter))
+		result, expand := dialect.FormatIn(attribute, makePreparedCounter(dialect, counter), false)
 		*paramsMap = append(*paramsMap, defs.ParameterRef{
-			Name:  filter.ParamName,
-			Index: -1,
+			Name:   filter.ParamName,
+			Index:  -1,
+			Expand: expand,
 		})
 		return result
 	case LogicalAnd, LogicalOr, LogicalNot:
 		subConditions := make([]string, len(filter.Conditions))
 		for i, sub := range filter.Conditions {
-			subConditions[i] = buildPrepareStmt(sub, counter, paramsMap)
+			subConditions[i] = buildPrepareStmt(dialect, sub, counter, paramsMap)
 		}
 		if filter.Operator == LogicalNot {
 			return fmt.Sprintf("NOT(%s)", subConditions[0])
@@ -59,54 +81,56 @@ func buildPrepareStmt(filter defs.Filter, counter *uint32, paramsMap *[]defs.Par
 	}
 }
 
-func argsClause(updates []defs.Update, counter *uint32, paramsMap *[]defs.ParameterRef) string {
+func argsClause(dialect Dialect, updates []defs.Update, counter *uint32, paramsMap *[]defs.ParameterRef) string {
 	var values []string
 	for i := range updates {
-		values = append(values, fmt.Sprintf("$%d", *counter))
+		values = append(values, makePreparedCounter(dialect, counter))
 		*paramsMap = append(*paramsMap, defs.ParameterRef{
 			Name:  updates[i].ParamName,
 			Index: -1,
 		})
-		*counter += 1
 	}
 	return strings.Join(values, ", ")
 }
 
-func setClause(updates []defs.Update, counter *uint32, paramsMap *[]defs.ParameterRef) string {
+func setClause(dialect Dialect, updates []defs.Update, counter *uint32, paramsMap *[]defs.ParameterRef) string {
 	var clauses []string
 	for _, update := range updates {
-		clauses = append(clauses, fmt.Sprintf("%s = $%d", golang.ToSnakeCase(update.Attribute), *counter))
+		clauses = append(clauses, fmt.Sprintf("%s = %s", column(dialect, update.Attribute), makePreparedCounter(dialect, counter)))
 		*paramsMap = append(*paramsMap, defs.ParameterRef{
 			Name:  update.ParamName,
 			Index: -1,
 		})
-		*counter += 1
 	}
 	return strings.Join(clauses, ", ")
 }
 
-func autoincrementClause(attributes []string) string {
+func autoincrementClause(dialect Dialect, attributes []string) string {
 	var clauses []string
 	for _, attribute := range attributes {
-		attr := golang.ToSnakeCase(attribute)
+		attr := column(dialect, attribute)
 		clauses = append(clauses, fmt.Sprintf("%s = %s + 1", attr, attr))
 	}
 	return strings.Join(clauses, ", ")
 }
 
-func captureTimestampClause(attributes []string) string {
+func captureTimestampClause(dialect Dialect, attributes []string) string {
 	var clauses []string
 	for _, attribute := range attributes {
-		attr := golang.ToSnakeCase(attribute)
-		clauses = append(clauses, fmt.Sprintf("%s = NOW()", attr))
+		clauses = append(clauses, fmt.Sprintf("%s = %s", column(dialect, attribute), dialect.CurrentTimestamp()))
 	}
 	return strings.Join(clauses, ", ")
 }
 
-func prepareFilters(filters []defs.Filter, counter *uint32, paramsMap *[]defs.ParameterRef) string {
+// notDeletedCondition is NotDeletedCondition on the formatted deleted_at column
+func notDeletedCondition(dialect Dialect) string {
+	return fmt.Sprintf("(%s IS NULL)", column(dialect, SoftDeleteColumn.Name))
+}
+
+func prepareFilters(dialect Dialect, filters []defs.Filter, counter *uint32, paramsMap *[]defs.ParameterRef) string {
 	conditions := make([]string, len(filters))
 	for i, filter := range filters {
-		conditions[i] = buildPrepareStmt(filter, counter, paramsMap)
+		conditions[i] = buildPrepareStmt(dialect, filter, counter, paramsMap)
 	}
 	result := strings.Join(conditions, " AND ")
 	base.LOG.Debug("Prepared filters for", "input filters", filters, "result", result, "paramsMap", paramsMap)
@@ -116,25 +140,25 @@ func prepareFilters(filters []defs.Filter, counter *uint32, paramsMap *[]defs.Pa
 	return fmt.Sprintf("(%s)", result)
 }
 
-// Returns a prepared condition in Postgresql format, example "column = $1 AND column2 = $2"
+// Returns a prepared condition in the format of the dialect, example "column" = $1 AND "column2" = $2 for Postgres
 // and returns id to param name mapping in array, example ["", "column" "column2"], keeping first string empty to start with $1
-func PrepareFilters(filters []defs.Filter) (string, []defs.ParameterRef) {
+func PrepareFilters(dialect Dialect, filters []defs.Filter) (string, []defs.ParameterRef) {
 	counter := uint32(1)
 	paramsMap := make([]defs.ParameterRef, 0)
-	result := prepareFilters(filters, &counter, &paramsMap)
+	result := prepareFilters(dialect, filters, &counter, &paramsMap)
 	return result, paramsMap
 }
 
 // Given AccessConfig, for update we need to generate the prepared statement
 // We need to generate the set clause part and where clause part
-func PrepareUpdateStmt(updateConfig *defs.AccessConfig) (string, string, []defs.ParameterRef) {
+func PrepareUpdateStmt(dialect Dialect, updateConfig *defs.AccessConfig) (string, string, []defs.ParameterRef) {
 	counter := uint32(1)
 	paramsMap := make([]defs.ParameterRef, 0)
 
-	setClause := setClause(updateConfig.Set, &counter, &paramsMap)
-	autoincClause := autoincrementClause(updateConfig.Autoincrement)
-	captureClause := captureTimestampClause(updateConfig.CaptureTimestamp)
-	whereClause := prepareFilters(updateConfig.Filter, &counter, &paramsMap)
+	setClause := setClause(dialect, updateConfig.Set, &counter, &paramsMap)
+	autoincClause := autoincrementClause(dialect, updateConfig.Autoincrement)
+	captureClause := captureTimestampClause(dialect, updateConfig.CaptureTimestamp)
+	whereClause := prepareFilters(dialect, updateConfig.Filter, &counter, &paramsMap)
 
 	base.LOG.Debug("Prepared update stmt for", "updateConfig", updateConfig, "setClause", setClause,
 		"autoincClause", autoincClause, "captureClause", captureClause, "whereClause", whereClause, "paramsMap", paramsMap)
@@ -155,68 +179,450 @@ func PrepareUpdateStmt(updateConfig *defs.AccessConfig) (string, string, []defs.
 	return "", whereClause, paramsMap
 }
 
-func PrepareAddStmt(addConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
+func PrepareAddStmt(dialect Dialect, addConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
 	counter := uint32(1)
 	paramsMap := make([]defs.ParameterRef, 0)
-	insertClause := argsClause(addConfig.Values, &counter, &paramsMap)
+	insertClause := argsClause(dialect, addConfig.Values, &counter, &paramsMap)
 	return insertClause, paramsMap
 }
 
-func PrepareAddOrReplaceStmt(addConfig *defs.AccessConfig) (string, string, []defs.ParameterRef) {
+// PrepareAddOrReplaceStmt returns the values of an add_or_replace config, and the upsert replacing the row of its
+// conflict key with them, see Dialect.FormatUpsert
+func PrepareAddOrReplaceStmt(dialect Dialect, addConfig *defs.AccessConfig) (string, string, []defs.ParameterRef) {
 	counter := uint32(1)
 	paramsMap := make([]defs.ParameterRef, 0)
-	insertClause := argsClause(addConfig.Values, &counter, &paramsMap)
-	setClause := setClause(addConfig.Values, &counter, &paramsMap)
-	return insertClause, setClause, paramsMap
+	insertClause := argsClause(dialect, addConfig.Values, &counter, &paramsMap)
+	upsert := dialect.FormatUpsert(ConflictTarget(dialect, addConfig), valueColumns(dialect, addConfig), func(i int) string {
+		paramsMap = append(paramsMap, defs.ParameterRef{Name: addConfig.Values[i].ParamName, Index: -1})
+		return makePreparedCounter(dialect, &counter)
+	})
+	return insertClause, upsert, paramsMap
+}
+
+// ConflictTarget is the conflict target of an add_or_replace config, its conflict key in parentheses, the id when it
+// has none. The unique indexes of soft delete models are partial (see SchemaBuilder.modelIndexes), their condition
+// follows the key so that the index is the arbiter of the conflict:
+//
+//	("sku") WHERE ("deleted_at" IS NULL)
+func ConflictTarget(dialect Dialect, addConfig *defs.AccessConfig) string {
+	key := addConfig.ConflictKey
+	if len(key) == 0 {
+		key = []string{"id"}
+	}
+	target := fmt.Sprintf("(%s)", strings.Join(columns(dialect, key), ", "))
+	if addConfig.ExcludeDeleted {
+		target += " WHERE " + notDeletedCondition(dialect)
+	}
+	return target
 }
 
-func PrepareDeleteStmt(deleteConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
+func PrepareDeleteStmt(dialect Dialect, deleteConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
 	counter := uint32(1)
 	paramsMap := make([]defs.ParameterRef, 0)
-	whereClause := prepareFilters(deleteConfig.Filter, &counter, &paramsMap)
+	whereClause := prepareFilters(dialect, deleteConfig.Filter, &counter, &paramsMap)
 	return whereClause, paramsMap
 }
 
-func MakeFindQuery(table string, accessConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
-	filterClause, paramsMap := PrepareFilters(accessConfig.Filter)
-	whereClause := fmt.Sprintf("(1 = 1) AND %s", filterClause)
-	attrClause := strings.Join(golang.ToSnakeCaseArray(accessConfig.Attributes), ", ")
+func MakeFindQuery(dialect Dialect, table string, accessConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
+	filterClause, paramsMap := PrepareFilters(dialect, accessConfig.Filter)
+	conditions := []string{"(1 = 1)"}
+	if filterClause != "" {
+		conditions = append(conditions, filterClause)
+	}
+	if accessConfig.ExcludeDeleted {
+		conditions = append(conditions, notDeletedCondition(dialect))
+	}
+	orderClause := MakeOrderByClause(dialect, accessConfig.OrderBy, accessConfig.Pagination != nil)
+	pageClause := ""
+	if accessConfig.Pagination != nil {
+		counter := uint32(len(paramsMap) + 1)
+		var cursorCondition string
+		cursorCondition, pageClause = paginate(dialect, table, accessConfig.OrderBy, accessConfig.Pagination, &counter, &paramsMap)
+		if cursorCondition != "" {
+			conditions = append(conditions, cursorCondition)
+		}
+	}
+	whereClause := strings.Join(conditions, " AND ")
+	attrClause := strings.Join(columns(dialect, accessConfig.Attributes), ", ")
 	base.LOG.Info("Making find query for", "table", table, "attributes", accessConfig.Attributes, "whereClause", whereClause, "paramsMap", paramsMap)
-	tableClause := golang.ToSnakeCase(table)
-	return fmt.Sprintf("SELECT %s FROM %s WHERE %s", attrClause, tableClause, whereClause), paramsMap
+	tableClause := column(dialect, table)
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s%s%s", attrClause, tableClause, whereClause, orderClause, pageClause), paramsMap
+}
+
+// MakeOrderByClause returns the ORDER BY clause of a find, empty for an unordered find. Paginated finds end their
+// ordering with id, in the direction of the last ordering, so that rows with equal values keep their page.
+func MakeOrderByClause(dialect Dialect, orderBy []defs.OrderBy, paginated bool) string {
+	terms := make([]string, 0, len(orderBy)+1)
+	orderedById := false
+	direction := ""
+	for _, order := range orderBy {
+		orderedById = orderedById || golang.ToSnakeCase(order.Attribute) == "id"
+		direction = strings.ToUpper(order.Direction)
+		term := column(dialect, order.Attribute)
+		if direction != "" {
+			term += " " + direction
+		}
+		if order.Nulls != "" {
+			term += " NULLS " + strings.ToUpper(order.Nulls)
+		}
+		terms = append(terms, term)
+	}
+	if paginated && !orderedById {
+		if direction == KeywordDESC {
+			terms = append(terms, column(dialect, "id")+" "+KeywordDESC)
+		} else {
+			terms = append(terms, column(dialect, "id"))
+		}
+	}
+	if len(terms) == 0 {
+		return ""
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+// KeysetColumns are the columns of the cursor of a keyset page, the order_by columns of the find followed by id,
+// which the rows of the next page follow in the order of the find
+func KeysetColumns(orderBy []defs.OrderBy) []string {
+	columns := make([]string, 0, len(orderBy)+1)
+	for _, order := range orderBy {
+		column := golang.ToSnakeCase(order.Attribute)
+		columns = append(columns, column)
+		if column == "id" {
+			return columns
+		}
+	}
+	return append(columns, "id")
+}
+
+// paginate returns the condition on the cursor of a keyset page, and the LIMIT clause of the page.
+// A page reads one row more than its limit, which tells whether there is a next page: the limit param is bound plus
+// one (see ReadParamsFunction), MySQL takes no expression in LIMIT.
+// The cursor is a JSON object of the KeysetColumns of the last row of the previous page, NULL for the first page.
+// Its values are typed by the columns of table, and compared to the row of the columns in the direction of the find:
+//
+//	($3::jsonb IS NULL OR ("price", "id") > (SELECT prev."price", prev."id" FROM jsonb_populate_record(NULL::"product", $3::jsonb) AS prev))
+func paginate(dialect Dialect, table string, orderBy []defs.OrderBy, pagination *defs.Pagination, counter *uint32, paramsMap *[]defs.ParameterRef) (string, string) {
+	cursorCondition := ""
+	if pagination.Type == PaginationKeyset {
+		cursor := makePreparedCounter(dialect, counter)
+		keyset := columns(dialect, KeysetColumns(orderBy))
+		row, previous := keyset[0], "prev."+keyset[0]
+		if len(keyset) > 1 {
+			row = fmt.Sprintf("(%s)", strings.Join(keyset, ", "))
+			previous = "prev." + strings.Join(keyset, ", prev.")
+		}
+		operator := ">"
+		if len(orderBy) > 0 && strings.ToUpper(orderBy[0].Direction) == KeywordDESC {
+			operator = "<"
+		}
+		cursorCondition = fmt.Sprintf("(%s::jsonb IS NULL OR %s %s (SELECT %s FROM jsonb_populate_record(NULL::%s, %s::jsonb) AS prev))",
+			cursor, row, operator, previous, column(dialect, table), cursor)
+		*paramsMap = append(*paramsMap, defs.ParameterRef{Name: CursorParam, Index: -1})
+	}
+	pageClause := fmt.Sprintf(" LIMIT %s", makePreparedCounter(dialect, counter))
+	*paramsMap = append(*paramsMap, defs.ParameterRef{Name: LimitParam, Index: -1})
+	if pagination.Type == PaginationOffset {
+		pageClause += fmt.Sprintf(" OFFSET %s", makePreparedCounter(dialect, counter))
+		*paramsMap = append(*paramsMap, defs.ParameterRef{Name: OffsetParam, Index: -1})
+	}
+	return cursorCondition, pageClause
+}
+
+// MakeAggregateQuery returns the query of an aggregate access config, the group by attributes followed by the
+// aggregates of the filtered rows:
+//
+//	SELECT "order_status", COUNT(*) AS "count" FROM "order" WHERE (1 = 1) AND ("user_id" = $1) GROUP BY "order_status"
+//	HAVING (COUNT(*) >= $2) ORDER BY "count" DESC
+//
+// The params of the having filters follow the params of the filters.
+func MakeAggregateQuery(dialect Dialect, table string, accessConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
+	counter := uint32(1)
+	paramsMap := make([]defs.ParameterRef, 0)
+	conditions := []string{"(1 = 1)"}
+	if filterClause := prepareFilters(dialect, accessConfig.Filter, &counter, &paramsMap); filterClause != "" {
+		conditions = append(conditions, filterClause)
+	}
+	if accessConfig.ExcludeDeleted {
+		conditions = append(conditions, notDeletedCondition(dialect))
+	}
+
+	groupColumns := columns(dialect, accessConfig.GroupBy)
+	selectTerms := slices.Clone(groupColumns)
+	expressions := make(map[string]defs.Aggregate, len(accessConfig.Aggregates))
+	for _, aggregate := range accessConfig.Aggregates {
+		selectTerms = append(selectTerms, fmt.Sprintf("%s AS %s", AggregateExpression(dialect, aggregate), column(dialect, aggregate.ResultName())))
+		expressions[aggregate.ResultName()] = aggregate
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(selectTerms, ", "), column(dialect, table),
+		strings.Join(conditions, " AND "))
+	if len(groupColumns) > 0 {
+		query += " GROUP BY " + strings.Join(groupColumns, ", ")
+	}
+	// Aggregates can't be referred to by their alias in HAVING, the having filters compare their expressions
+	if havingClause := prepareFilters(dialect, havingFilters(accessConfig.Having, expressions), &counter, &paramsMap); havingClause != "" {
+		query += " HAVING " + havingClause
+	}
+	query += MakeOrderByClause(dialect, accessConfig.OrderBy, false)
+	base.LOG.Info("Making aggregate query for", "table", table, "query", query, "paramsMap", paramsMap)
+	return query, paramsMap
+}
+
+// AggregateExpression is the SQL expression of an aggregate, SUM("total_amount") or COUNT(*)
+func AggregateExpression(dialect Dialect, aggregate defs.Aggregate) string {
+	attribute := aggregate.Attribute
+	if attribute == "" {
+		attribute = "*"
+	}
+	return applyTransformation(dialect, attribute, strings.ToUpper(aggregate.Function))
 }
 
-func MakeUpdateQuery(table string, updateConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
-	setClause, filterClause, paramsMap := PrepareUpdateStmt(updateConfig)
+// havingFilters are the having filters on the expressions of the aggregates they name
+func havingFilters(having []defs.Filter, aggregates map[string]defs.Aggregate) []defs.Filter {
+	filters := make([]defs.Filter, 0, len(having))
+	for _, filter := range having {
+		if aggregate, ok := aggregates[filter.Attribute]; ok {
+			filter.Attribute = aggregate.Attribute
+			if filter.Attribute == "" {
+				filter.Attribute = "*"
+			}
+			filter.Transformation = strings.ToUpper(aggregate.Function)
+		}
+		filter.Conditions = havingFilters(filter.Conditions, aggregates)
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+// MakeUpdateQuery returns the query of an update config. An update with optimistic_lock increments the version of
+// the rows having the version param and returns their new version:
+//
+//	UPDATE "product" SET "price" = $1, "version" = "version" + 1 WHERE (1 = 1) AND ("id" = $2) AND ("version" = $3)
+//	RETURNING "version"
+//
+// The writes of the models with audit and the writes with events add rows for the rows they change, see writeQuery.
+func MakeUpdateQuery(dialect Dialect, table string, updateConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
+	setClause, filterClause, paramsMap := PrepareUpdateStmt(dialect, updateConfig)
 	whereClause := fmt.Sprintf("(1 = 1) AND %s", filterClause)
-	tableClause := golang.ToSnakeCase(table)
-	return fmt.Sprintf("UPDATE %s SET %s WHERE %s", tableClause, setClause, whereClause), paramsMap
+	returning := ""
+	if updateConfig.OptimisticLock {
+		if !slices.ContainsFunc(updateConfig.Autoincrement, func(attribute string) bool { return golang.ToSnakeCase(attribute) == VersionColumn }) {
+			setClause += ", " + autoincrementClause(dialect, []string{VersionColumn})
+		}
+		whereClause += " AND " + versionCondition(dialect, &paramsMap)
+		returning = VersionColumn
+	}
+	if updateConfig.ExcludeDeleted {
+		whereClause += " AND " + notDeletedCondition(dialect)
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", column(dialect, table), setClause, whereClause)
+	return writeQuery(dialect, table, AuditUpdate, query, whereClause, returning, updateConfig, paramsMap), paramsMap
 }
 
-func MakeAddQuery(table string, addConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
-	insertClause, paramsMap := PrepareAddStmt(addConfig)
-	attributes := make([]string, 0, len(addConfig.Attributes))
-	for _, attr := range addConfig.Values {
-		attributes = append(attributes, golang.ToSnakeCase(attr.Attribute))
+// returningClause is the RETURNING clause of the column a write returns, empty when it returns none or the dialect has
+// no RETURNING (the ids of its inserts are read with LastInsertId)
+func returningClause(dialect Dialect, returning string) string {
+	if returning == "" {
+		return ""
+	}
+	if clause := dialect.FormatReturning(column(dialect, returning)); clause != "" {
+		return " " + clause
+	}
+	return ""
+}
+
+// HistoryTable is the table of the history rows of a model with audit, product_history
+func HistoryTable(table string) string {
+	return golang.ToSnakeCase(table) + "_history"
+}
+
+// MakeHistoryQuery returns the query of the history rows of a row of a model with audit, in the order they were
+// written:
+//
+//	SELECT "id", "row_id", "operation", "old_values", "new_values", "actor", "changed_at" FROM "product_history"
+//	WHERE "row_id" = $1 ORDER BY "id"
+func MakeHistoryQuery(dialect Dialect, table string) string {
+	counter := uint32(1)
+	historyColumns := columns(dialect, []string{"id", "row_id", "operation", "old_values", "new_values", "actor", "changed_at"})
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s ORDER BY %s", strings.Join(historyColumns, ", "),
+		column(dialect, HistoryTable(table)), column(dialect, "row_id"), makePreparedCounter(dialect, &counter), column(dialect, "id"))
+}
+
+// writeQuery returns the query of a write, write followed by its RETURNING clause. The writes of the models with
+// audit and the writes with events add the history rows (see auditInsert) and the outbox events (see eventInsert) of
+// the rows they change in the same statement, so that they are written in the transaction of the write:
+//
+//	WITH old_rows AS (SELECT * FROM "product" WHERE (1 = 1) AND ("id" = $2) FOR UPDATE),
+//	changed AS (UPDATE "product" SET "price" = $1 WHERE (1 = 1) AND ("id" = $2) RETURNING *),
+//	history AS (INSERT INTO "product_history" ... FROM changed JOIN old_rows ON old_rows."id" = changed."id")
+//	INSERT INTO "outbox_events" ("model", "access_name", "row_id", "payload")
+//	SELECT 'Product', 'UpdateProductPrice', changed."id", to_jsonb(changed) FROM changed
+//
+// The returning columns are selected from the changed rows, a write returning none ends with its last insert, which
+// adds one row per changed row. where selects the rows an update changes, empty for inserts and deletes.
+func writeQuery(dialect Dialect, table, operation, write, where, returning string, conf *defs.AccessConfig, paramsMap []defs.ParameterRef) string {
+	if !conf.Audit && !conf.Events {
+		return write + returningClause(dialect, returning)
 	}
-	tableClause := golang.ToSnakeCase(table)
-	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING id", tableClause, strings.Join(attributes, ", "), insertClause), paramsMap
+	ctes := []string{}
+	inserts := []struct{ name, query string }{}
+	if conf.Audit {
+		oldRows, insert := auditInsert(dialect, table, operation, where, paramsMap)
+		if oldRows != "" {
+			ctes = append(ctes, oldRows)
+		}
+		inserts = append(inserts, struct{ name, query string }{"history", insert})
+	}
+	if conf.Events {
+		inserts = append(inserts, struct{ name, query string }{"events", eventInsert(dialect, table, conf.Name)})
+	}
+	ctes = append(ctes, fmt.Sprintf("changed AS (%s RETURNING *)", write))
+	last := ""
+	if returning == "" {
+		last = inserts[len(inserts)-1].query
+		inserts = inserts[:len(inserts)-1]
+	} else {
+		last = fmt.Sprintf("SELECT %s FROM changed", column(dialect, returning))
+	}
+	for _, insert := range inserts {
+		ctes = append(ctes, fmt.Sprintf("%s AS (%s)", insert.name, insert.query))
+	}
+	return fmt.Sprintf("WITH %s %s", strings.Join(ctes, ", "), last)
 }
 
-func MakeAddOrReplaceQuery(table string, addConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
-	insertClause, setClause, paramsMap := PrepareAddOrReplaceStmt(addConfig)
-	attributes := make([]string, 0, len(addConfig.Attributes))
+// auditInsert returns the insert of the history rows of the rows a write of a model with audit changes, along with
+// the old_rows query locking and reading the rows an update changes before it, empty when where is empty. The rows of
+// inserts and deletes have no old or no new values. The actor is bound to the param following paramsMap, NULL when
+// it's empty.
+func auditInsert(dialect Dialect, table, operation, where string, paramsMap []defs.ParameterRef) (string, string) {
+	counter := uint32(len(paramsMap) + 1)
+	id := column(dialect, "id")
+	oldRows := ""
+	oldValues, newValues, rows := "NULL", "to_jsonb(changed)", "changed"
+	switch {
+	case where != "":
+		oldRows = fmt.Sprintf("old_rows AS (SELECT * FROM %s WHERE %s FOR UPDATE)", column(dialect, table), where)
+		oldValues, rows = "to_jsonb(old_rows)", fmt.Sprintf("changed JOIN old_rows ON old_rows.%s = changed.%s", id, id)
+	case operation == AuditDelete:
+		oldValues, newValues = "to_jsonb(changed)", "NULL"
+	}
+	historyColumns := columns(dialect, []string{"row_id", "operation", "old_values", "new_values", "actor"})
+	insert := fmt.Sprintf("INSERT INTO %s (%s) SELECT changed.%s, '%s', %s, %s, NULLIF(%s, '') FROM %s",
+		column(dialect, HistoryTable(table)), strings.Join(historyColumns, ", "), id, operation, oldValues, newValues,
+		makePreparedCounter(dialect, &counter), rows)
+	return oldRows, insert
+}
+
+// eventInsert returns the insert of the outbox events of the rows a write with events changes, their payload is the
+// values of the rows after the write, before it for deletes
+func eventInsert(dialect Dialect, model, accessName string) string {
+	outboxColumns := columns(dialect, []string{"model", "access_name", "row_id", "payload"})
+	return fmt.Sprintf("INSERT INTO %s (%s) SELECT '%s', '%s', changed.%s, to_jsonb(changed) FROM changed",
+		column(dialect, OutboxTable), strings.Join(outboxColumns, ", "), model, accessName, column(dialect, "id"))
+}
+
+// versionCondition is the condition of an access with optimistic_lock on the version of the rows, bound to the
+// param following paramsMap
+func versionCondition(dialect Dialect, paramsMap *[]defs.ParameterRef) string {
+	counter := uint32(len(*paramsMap) + 1)
+	condition := fmt.Sprintf("(%s = %s)", column(dialect, VersionColumn), makePreparedCounter(dialect, &counter))
+	*paramsMap = append(*paramsMap, defs.ParameterRef{Name: VersionParam, Index: -1})
+	return condition
+}
+
+// valueColumns are the formatted columns of the values of an add config
+func valueColumns(dialect Dialect, addConfig *defs.AccessConfig) []string {
+	attributes := make([]string, 0, len(addConfig.Values))
 	for _, attr := range addConfig.Values {
-		attributes = append(attributes, golang.ToSnakeCase(attr.Attribute))
+		attributes = append(attributes, column(dialect, attr.Attribute))
 	}
-	tableClause := golang.ToSnakeCase(table)
-	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT DO UPDATE SET %s RETURNING id, (xmax = 0)",
-		tableClause, strings.Join(attributes, ", "), insertClause, setClause), paramsMap
+	return attributes
 }
 
-func MakeDeleteQuery(table string, deleteConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
-	filterClause, paramsMap := PrepareDeleteStmt(deleteConfig)
+func MakeAddQuery(dialect Dialect, table string, addConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
+	insertClause, paramsMap := PrepareAddStmt(dialect, addConfig)
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", column(dialect, table), strings.Join(valueColumns(dialect, addConfig), ", "), insertClause)
+	return writeQuery(dialect, table, AuditAdd, query, "", "id", addConfig, paramsMap), paramsMap
+}
+
+// MakeAddManyQueryParts returns the INSERT of an add_many config without its rows, and its RETURNING clause
+func MakeAddManyQueryParts(dialect Dialect, table string, addConfig *defs.AccessConfig) (string, string) {
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES", column(dialect, table), strings.Join(valueColumns(dialect, addConfig), ", ")),
+		strings.TrimSpace(returningClause(dialect, "id"))
+}
+
+// MakeAddManyQuery makes the INSERT of rows rows of an add_many config, the values of the row r are bound to
+// $r*n+1 to $r*n+n for the n values of the config:
+//
+//	INSERT INTO "product" ("sku", "price") VALUES ($1, $2), ($3, $4) RETURNING "id"
+func MakeAddManyQuery(dialect Dialect, table string, addConfig *defs.AccessConfig, rows int) string {
+	insert, returning := MakeAddManyQueryParts(dialect, table, addConfig)
+	counter := uint32(1)
+	tuples := make([]string, 0, rows)
+	for row := 0; row < rows; row++ {
+		paramsMap := make([]defs.ParameterRef, 0, len(addConfig.Values))
+		tuples = append(tuples, fmt.Sprintf("(%s)", argsClause(dialect, addConfig.Values, &counter, &paramsMap)))
+	}
+	query := fmt.Sprintf("%s %s", insert, strings.Join(tuples, ", "))
+	if returning != "" {
+		query += " " + returning
+	}
+	return query
+}
+
+// MakeAddOrReplaceQuery returns the query of an add_or_replace config, which inserts a row or replaces the row of
+// its conflict key (see ConflictTarget), and returns its id and whether it was inserted:
+//
+//	INSERT INTO "product" ("sku", "price") VALUES ($1, $2) ON CONFLICT ("sku") DO UPDATE SET "sku" = $3, "price" = $4
+//	RETURNING "id", (xmax = 0) AS inserted
+func MakeAddOrReplaceQuery(dialect Dialect, table string, addConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
+	insertClause, upsert, paramsMap := PrepareAddOrReplaceStmt(dialect, addConfig)
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) %s",
+		column(dialect, table), strings.Join(valueColumns(dialect, addConfig), ", "), insertClause, upsert), paramsMap
+}
+
+func MakeDeleteQuery(dialect Dialect, table string, deleteConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
+	filterClause, paramsMap := PrepareDeleteStmt(dialect, deleteConfig)
+	whereClause := fmt.Sprintf("(1 = 1) AND %s", filterClause)
+	if deleteConfig.OptimisticLock {
+		whereClause += " AND " + versionCondition(dialect, &paramsMap)
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", column(dialect, table), whereClause)
+	return writeQuery(dialect, table, AuditDelete, query, "", "", deleteConfig, paramsMap), paramsMap
+}
+
+// MakeSoftDeleteQuery returns the query of a delete config of a soft delete model, which sets the deletion time of
+// the filtered rows that are not deleted yet instead of deleting them:
+//
+//	UPDATE "product" SET "deleted_at" = NOW() WHERE (1 = 1) AND ("id" = $1) AND ("deleted_at" IS NULL)
+//
+// A delete with optimistic_lock increments the version of the rows having the version param, like an update.
+func MakeSoftDeleteQuery(dialect Dialect, table string, deleteConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
+	filterClause, paramsMap := PrepareDeleteStmt(dialect, deleteConfig)
+	setClause := fmt.Sprintf("%s = %s", column(dialect, SoftDeleteColumn.Name), dialect.CurrentTimestamp())
 	whereClause := fmt.Sprintf("(1 = 1) AND %s", filterClause)
-	tableClause := golang.ToSnakeCase(table)
-	return fmt.Sprintf("DELETE FROM %s WHERE %s", tableClause, whereClause), paramsMap
+	if deleteConfig.OptimisticLock {
+		setClause += ", " + autoincrementClause(dialect, []string{VersionColumn})
+		whereClause += " AND " + versionCondition(dialect, &paramsMap)
+	}
+	whereClause += " AND " + notDeletedCondition(dialect)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", column(dialect, table), setClause, whereClause)
+	return writeQuery(dialect, table, AuditDelete, query, whereClause, "", deleteConfig, paramsMap), paramsMap
+}
+
+// MakeRestoreQuery returns the query restoring the rows soft deleted by a delete config, see MakeSoftDeleteQuery:
+//
+//	UPDATE "product" SET "deleted_at" = NULL WHERE (1 = 1) AND ("id" = $1) AND ("deleted_at" IS NOT NULL)
+//
+// A restore with optimistic_lock increments the version of the rows, so that the versions read before they were
+// restored are stale, it doesn't check the version.
+func MakeRestoreQuery(dialect Dialect, table string, deleteConfig *defs.AccessConfig) (string, []defs.ParameterRef) {
+	filterClause, paramsMap := PrepareDeleteStmt(dialect, deleteConfig)
+	deletedAt := column(dialect, SoftDeleteColumn.Name)
+	setClause := fmt.Sprintf("%s = NULL", deletedAt)
+	if deleteConfig.OptimisticLock {
+		setClause += ", " + autoincrementClause(dialect, []string{VersionColumn})
+	}
+	whereClause := fmt.Sprintf("(1 = 1) AND %s AND (%s IS NOT NULL)", filterClause, deletedAt)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", column(dialect, table), setClause, whereClause)
+	return writeQuery(dialect, table, AuditRestore, query, whereClause, "", deleteConfig, paramsMap), paramsMap
 }
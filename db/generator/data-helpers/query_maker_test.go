@@ -36,7 +36,7 @@ func filterData(startCounter uint32) ([]defs.Filter, string, []defs.ParameterRef
 		},
 	}
 
-	expected := fmt.Sprintf("(age >= $%d AND status = ANY($%d) AND ((salary BETWEEN $%d AND $%d) OR position = $%d OR (deparment = $%d AND experience >= $%d AND (CHAR_LENGTH(name) > $%d AND DATE(created_at) = $%d))) AND NOT(terminated = $%d))",
+	expected := fmt.Sprintf(`("age" >= $%d AND "status" = ANY($%d) AND (("salary" BETWEEN $%d AND $%d) OR "position" = $%d OR ("deparment" = $%d AND "experience" >= $%d AND (CHAR_LENGTH("name") > $%d AND DATE("created_at") = $%d))) AND NOT("terminated" = $%d))`,
 		startCounter, startCounter+1, startCounter+2, startCounter+3, startCounter+4, startCounter+5, startCounter+6, startCounter+7, startCounter+8, startCounter+9)
 
 	paramsMap := []defs.ParameterRef{
@@ -68,7 +68,7 @@ func valueMapData(startCounter uint32) ([]defs.Update, string, []defs.ParameterR
 		{Attribute: "terminated", ParamName: "terminated"},
 	}
 
-	expected := fmt.Sprintf("age = $%d, status = $%d, salary = $%d, position = $%d, department = $%d, experience = $%d, name = $%d, created_at = $%d, terminated = $%d",
+	expected := fmt.Sprintf(`"age" = $%d, "status" = $%d, "salary" = $%d, "position" = $%d, "department" = $%d, "experience" = $%d, "name" = $%d, "created_at" = $%d, "terminated" = $%d`,
 		startCounter, startCounter+1, startCounter+2, startCounter+3, startCounter+4, startCounter+5, startCounter+6, startCounter+7, startCounter+8)
 
 	paramsMap := []defs.ParameterRef{
@@ -109,7 +109,7 @@ func TestPrepareFilters(t *testing.T) {
 		{Attribute: "created_at", Transformation: "DATE", Operator: "=", ParamName: "created_at"},
 	}
 
-	expected := "(age >= $1 AND status = ANY($2) AND ((salary BETWEEN $3 AND $4) OR position = $5 OR (department = $6 AND experience >= $7)) AND NOT(terminated = $8) AND CHAR_LENGTH(name) > $9 AND DATE(created_at) = $10)"
+	expected := `("age" >= $1 AND "status" = ANY($2) AND (("salary" BETWEEN $3 AND $4) OR "position" = $5 OR ("department" = $6 AND "experience" >= $7)) AND NOT("terminated" = $8) AND CHAR_LENGTH("name") > $9 AND DATE("created_at") = $10)`
 	expectedParamsMap := []defs.ParameterRef{
 		{Index: -1, Name: "age"},
 		{Index: -1, Name: "status"},
@@ -122,7 +122,7 @@ func TestPrepareFilters(t *testing.T) {
 		{Index: -1, Name: "name"},
 		{Index: -1, Name: "created_at"},
 	}
-	result, paramsMap := PrepareFilters(filters)
+	result, paramsMap := PrepareFilters(NewPostgresDialect(), filters)
 
 	fmt.Printf("ParamsMap: %v\n", paramsMap)
 	if result != expected {
@@ -139,7 +139,7 @@ func TestPrepareFilters(t *testing.T) {
 
 	// Test case: complex filter
 	complexFilters, complexExpected, complexExpectedParams := filterData(uint32(1))
-	complexQuery, complexParams := PrepareFilters(complexFilters)
+	complexQuery, complexParams := PrepareFilters(NewPostgresDialect(), complexFilters)
 	if complexQuery != complexExpected {
 		t.Errorf("Expected '%s', but got '%s'", complexExpected, complexQuery)
 	}
@@ -151,7 +151,7 @@ func TestPrepareFilters(t *testing.T) {
 func TestPrepareUpdateStmt(t *testing.T) {
 	// Test case: empty AccessConfig
 	updateConfig := &defs.AccessConfig{}
-	setClause, whereClause, paramsMap := PrepareUpdateStmt(updateConfig)
+	setClause, whereClause, paramsMap := PrepareUpdateStmt(NewPostgresDialect(), updateConfig)
 	if setClause != "" || whereClause != "" || len(paramsMap) != 0 {
 		t.Errorf("Set clause should be empty, where clause should be empty, and params map should be empty")
 	}
@@ -183,9 +183,9 @@ func TestPrepareUpdateStmt(t *testing.T) {
 		Autoincrement:    []string{"attribute5", "attribute6"},
 		CaptureTimestamp: []string{"attribute7", "attribute8"},
 	}
-	setClause, whereClause, paramsMap = PrepareUpdateStmt(updateConfig)
-	expectedSetClause := "attribute_3 = $1, attribute_4 = $2, attribute_5 = attribute_5 + 1, attribute_6 = attribute_6 + 1, attribute_7 = NOW(), attribute_8 = NOW()"
-	expectedWhereClause := "(attribute_1 = $3 AND attribute_2 > $4)"
+	setClause, whereClause, paramsMap = PrepareUpdateStmt(NewPostgresDialect(), updateConfig)
+	expectedSetClause := `"attribute_3" = $1, "attribute_4" = $2, "attribute_5" = "attribute_5" + 1, "attribute_6" = "attribute_6" + 1, "attribute_7" = NOW(), "attribute_8" = NOW()`
+	expectedWhereClause := `("attribute_1" = $3 AND "attribute_2" > $4)`
 	expectedParamsMap := []defs.ParameterRef{
 		{
 			Name:  "param3",
@@ -215,6 +215,62 @@ func TestPrepareUpdateStmt(t *testing.T) {
 	}
 }
 
+func TestMakeFindQuery(t *testing.T) {
+	findConfig := &defs.AccessConfig{
+		Attributes: []string{"id", "sku"},
+		Filter:     []defs.Filter{{Attribute: "price", Operator: "BETWEEN", ParamName: "price_range"}},
+	}
+	query, params := MakeFindQuery(NewPostgresDialect(), "Product", findConfig)
+	assert.Equal(t, `SELECT "id", "sku" FROM "product" WHERE (1 = 1) AND (("price" BETWEEN $1 AND $2))`, query)
+	assert.Equal(t, []defs.ParameterRef{{Name: "price_range", Index: 0}, {Name: "price_range", Index: 1}}, params)
+
+	findConfig.Pagination = &defs.Pagination{Type: PaginationKeyset}
+	query, params = MakeFindQuery(NewPostgresDialect(), "Product", findConfig)
+	assert.Equal(t, `SELECT "id", "sku" FROM "product" WHERE (1 = 1) AND (("price" BETWEEN $1 AND $2)) AND `+
+		`($3::jsonb IS NULL OR "id" > (SELECT prev."id" FROM jsonb_populate_record(NULL::"product", $3::jsonb) AS prev)) ORDER BY "id" LIMIT $4`, query)
+	assert.Equal(t, []defs.ParameterRef{{Name: "price_range", Index: 0}, {Name: "price_range", Index: 1},
+		{Name: CursorParam, Index: -1}, {Name: LimitParam, Index: -1}}, params)
+
+	query, params = MakeFindQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{
+		Attributes: []string{"sku"},
+		Pagination: &defs.Pagination{Type: PaginationOffset},
+	})
+	assert.Equal(t, `SELECT "sku" FROM "product" WHERE (1 = 1) ORDER BY "id" LIMIT $1 OFFSET $2`, query)
+	assert.Equal(t, []defs.ParameterRef{{Name: LimitParam, Index: -1}, {Name: OffsetParam, Index: -1}}, params)
+
+	query, _ = MakeFindQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{
+		Attributes: []string{"sku"},
+		OrderBy:    []defs.OrderBy{{Attribute: "price", Direction: "desc", Nulls: NullsLast}, {Attribute: "productName"}},
+		Pagination: &defs.Pagination{Type: PaginationOffset},
+	})
+	assert.Equal(t, `SELECT "sku" FROM "product" WHERE (1 = 1) ORDER BY "price" DESC NULLS LAST, "product_name", "id" LIMIT $1 OFFSET $2`, query)
+
+	// Sorted keyset pages follow the sort columns and the id of the last row, in the direction of the find
+	query, params = MakeFindQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{
+		Attributes: []string{"id", "price", "createdAt"},
+		OrderBy:    []defs.OrderBy{{Attribute: "price", Direction: "desc"}, {Attribute: "createdAt", Direction: "DESC"}},
+		Pagination: &defs.Pagination{Type: PaginationKeyset},
+	})
+	assert.Equal(t, `SELECT "id", "price", "created_at" FROM "product" WHERE (1 = 1) AND ($1::jsonb IS NULL OR ("price", "created_at", "id") < `+
+		`(SELECT prev."price", prev."created_at", prev."id" FROM jsonb_populate_record(NULL::"product", $1::jsonb) AS prev)) `+
+		`ORDER BY "price" DESC, "created_at" DESC, "id" DESC LIMIT $2`, query)
+	assert.Equal(t, []defs.ParameterRef{{Name: CursorParam, Index: -1}, {Name: LimitParam, Index: -1}}, params)
+}
+
+func TestKeysetColumns(t *testing.T) {
+	assert.Equal(t, []string{"id"}, KeysetColumns(nil))
+	assert.Equal(t, []string{"price", "id"}, KeysetColumns([]defs.OrderBy{{Attribute: "price"}}))
+	assert.Equal(t, []string{"sku", "id"}, KeysetColumns([]defs.OrderBy{{Attribute: "sku"}, {Attribute: "id"}, {Attribute: "price"}}))
+}
+
+func TestMakeOrderByClause(t *testing.T) {
+	assert.Equal(t, "", MakeOrderByClause(NewPostgresDialect(), nil, false))
+	assert.Equal(t, ` ORDER BY "id"`, MakeOrderByClause(NewPostgresDialect(), nil, true))
+	assert.Equal(t, ` ORDER BY "price" DESC`, MakeOrderByClause(NewPostgresDialect(), []defs.OrderBy{{Attribute: "price", Direction: "DESC"}}, false))
+	assert.Equal(t, ` ORDER BY "id" DESC`, MakeOrderByClause(NewPostgresDialect(), []defs.OrderBy{{Attribute: "id", Direction: "DESC"}}, true))
+	assert.Equal(t, ` ORDER BY "price" DESC, "id" DESC`, MakeOrderByClause(NewPostgresDialect(), []defs.OrderBy{{Attribute: "price", Direction: "desc"}}, true))
+}
+
 func TestMakeUpdateQuery(t *testing.T) {
 	table := "test_table"
 
@@ -225,11 +281,11 @@ func TestMakeUpdateQuery(t *testing.T) {
 		Filter: filter,
 	}
 
-	expectedQuery := fmt.Sprintf("UPDATE test_table SET %v WHERE (1 = 1) AND %v", expectedSetClause, expectedFilterClause)
+	expectedQuery := fmt.Sprintf(`UPDATE "test_table" SET %v WHERE (1 = 1) AND %v`, expectedSetClause, expectedFilterClause)
 	expectedParams := []defs.ParameterRef{}
 	expectedParams = append(expectedParams, expectedUpdateParams...)
 	expectedParams = append(expectedParams, expectedFilterParams...)
-	query, params := MakeUpdateQuery(table, updateConfig)
+	query, params := MakeUpdateQuery(NewPostgresDialect(), table, updateConfig)
 	assert.Equal(t, expectedQuery, query)
 	assert.Equal(t, expectedParams, params)
 }
@@ -240,10 +296,10 @@ func TestMakeAddQuery(t *testing.T) {
 	addConfig := &defs.AccessConfig{
 		Values: []defs.Update{{Attribute: "attr1", ParamName: "p1"}, {Attribute: "attr2", ParamName: "p2"}},
 	}
-	expectedQuery := "INSERT INTO test_table (attr_1, attr_2) VALUES ($1, $2) RETURNING id"
+	expectedQuery := `INSERT INTO "test_table" ("attr_1", "attr_2") VALUES ($1, $2) RETURNING "id"`
 	expectedParams := []defs.ParameterRef{{Name: "p1", Index: -1}, {Name: "p2", Index: -1}}
 
-	query, params := MakeAddQuery(table, addConfig)
+	query, params := MakeAddQuery(NewPostgresDialect(), table, addConfig)
 	assert.Equal(t, expectedQuery, query)
 	assert.Equal(t, expectedParams, params)
 }
@@ -251,14 +307,21 @@ func TestMakeAddQuery(t *testing.T) {
 func TestMakeAddOrReplaceQuery(t *testing.T) {
 	table := "test_table"
 	addConfig := &defs.AccessConfig{
-		Values: []defs.Update{{Attribute: "attr1", ParamName: "p1"}, {Attribute: "attr2", ParamName: "p2"}},
+		Values:      []defs.Update{{Attribute: "attr1", ParamName: "p1"}, {Attribute: "attr2", ParamName: "p2"}},
+		ConflictKey: []string{"attr_1"},
 	}
-	expectedQuery := "INSERT INTO test_table (attr_1, attr_2) VALUES ($1, $2) ON CONFLICT DO UPDATE SET attr_1 = $3, attr_2 = $4 RETURNING id, (xmax = 0)"
+	expectedQuery := `INSERT INTO "test_table" ("attr_1", "attr_2") VALUES ($1, $2) ON CONFLICT ("attr_1") DO UPDATE SET "attr_1" = $3, "attr_2" = $4 RETURNING "id", (xmax = 0) AS inserted`
 	expectedParams := []defs.ParameterRef{{Name: "p1", Index: -1}, {Name: "p2", Index: -1}, {Name: "p1", Index: -1}, {Name: "p2", Index: -1}}
 
-	query, params := MakeAddOrReplaceQuery(table, addConfig)
+	query, params := MakeAddOrReplaceQuery(NewPostgresDialect(), table, addConfig)
 	assert.Equal(t, expectedQuery, query)
 	assert.Equal(t, expectedParams, params)
+
+	// The unique indexes of soft delete models are partial, the conflict target has their condition
+	addConfig.ExcludeDeleted = true
+	query, _ = MakeAddOrReplaceQuery(NewPostgresDialect(), table, addConfig)
+	assert.Equal(t, `INSERT INTO "test_table" ("attr_1", "attr_2") VALUES ($1, $2) ON CONFLICT ("attr_1") WHERE ("deleted_at" IS NULL) `+
+		`DO UPDATE SET "attr_1" = $3, "attr_2" = $4 RETURNING "id", (xmax = 0) AS inserted`, query)
 }
 
 func TestMakeDeleteQuery(t *testing.T) {
@@ -277,10 +340,203 @@ func TestMakeDeleteQuery(t *testing.T) {
 			},
 		},
 	}
-	expectedQuery := "DELETE FROM test_table WHERE (1 = 1) AND (attr_1 = $1 AND attr_2 > $2)"
+	expectedQuery := `DELETE FROM "test_table" WHERE (1 = 1) AND ("attr_1" = $1 AND "attr_2" > $2)`
 	expectedParams := []defs.ParameterRef{{Name: "p1", Index: -1}, {Name: "p2", Index: -1}}
 
-	query, params := MakeDeleteQuery(table, deleteConfig)
+	query, params := MakeDeleteQuery(NewPostgresDialect(), table, deleteConfig)
 	assert.Equal(t, expectedQuery, query)
 	assert.Equal(t, expectedParams, params)
 }
+
+// User and order are reserved words, the queries of models named after them must quote their tables
+func TestQueriesQuoteReservedWords(t *testing.T) {
+	dialect := NewPostgresDialect()
+	byID := []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}}
+	values := []defs.Update{{Attribute: "user_id", ParamName: "user_id"}, {Attribute: "total", ParamName: "total"}}
+
+	tests := []struct {
+		name     string
+		query    func() (string, []defs.ParameterRef)
+		expected string
+	}{
+		{"find", func() (string, []defs.ParameterRef) {
+			return MakeFindQuery(dialect, "User", &defs.AccessConfig{Attributes: []string{"id", "name"}, Filter: byID})
+		}, `SELECT "id", "name" FROM "user" WHERE (1 = 1) AND ("id" = $1)`},
+		{"add", func() (string, []defs.ParameterRef) {
+			return MakeAddQuery(dialect, "Order", &defs.AccessConfig{Values: values})
+		}, `INSERT INTO "order" ("user_id", "total") VALUES ($1, $2) RETURNING "id"`},
+		{"update", func() (string, []defs.ParameterRef) {
+			return MakeUpdateQuery(dialect, "Order", &defs.AccessConfig{Set: values[1:], Filter: byID})
+		}, `UPDATE "order" SET "total" = $1 WHERE (1 = 1) AND ("id" = $2)`},
+		{"delete", func() (string, []defs.ParameterRef) {
+			return MakeDeleteQuery(dialect, "User", &defs.AccessConfig{Filter: byID})
+		}, `DELETE FROM "user" WHERE (1 = 1) AND ("id" = $1)`},
+		{"add or replace", func() (string, []defs.ParameterRef) {
+			return MakeAddOrReplaceQuery(dialect, "Order", &defs.AccessConfig{Values: values, ConflictKey: []string{"user_id"}})
+		}, `INSERT INTO "order" ("user_id", "total") VALUES ($1, $2) ON CONFLICT ("user_id") DO UPDATE SET "user_id" = $3, "total" = $4 ` +
+			`RETURNING "id", (xmax = 0) AS inserted`},
+		{"audited update", func() (string, []defs.ParameterRef) {
+			return MakeUpdateQuery(dialect, "Order", &defs.AccessConfig{Set: values[1:], Filter: byID, Audit: true})
+		}, `WITH old_rows AS (SELECT * FROM "order" WHERE (1 = 1) AND ("id" = $2) FOR UPDATE), ` +
+			`changed AS (UPDATE "order" SET "total" = $1 WHERE (1 = 1) AND ("id" = $2) RETURNING *) ` +
+			`INSERT INTO "order_history" ("row_id", "operation", "old_values", "new_values", "actor") SELECT changed."id", 'update', ` +
+			`to_jsonb(old_rows), to_jsonb(changed), NULLIF($3, '') FROM changed JOIN old_rows ON old_rows."id" = changed."id"`},
+		{"keyset find", func() (string, []defs.ParameterRef) {
+			return MakeFindQuery(dialect, "Order", &defs.AccessConfig{Attributes: []string{"id"}, Pagination: &defs.Pagination{Type: PaginationKeyset}})
+		}, `SELECT "id" FROM "order" WHERE (1 = 1) AND ($1::jsonb IS NULL OR "id" > ` +
+			`(SELECT prev."id" FROM jsonb_populate_record(NULL::"order", $1::jsonb) AS prev)) ORDER BY "id" LIMIT $2`},
+		{"history", func() (string, []defs.ParameterRef) {
+			return MakeHistoryQuery(dialect, "Order"), nil
+		}, `SELECT "id", "row_id", "operation", "old_values", "new_values", "actor", "changed_at" FROM "order_history" WHERE "row_id" = $1 ORDER BY "id"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := tt.query()
+			assert.Equal(t, tt.expected, query)
+		})
+	}
+}
+
+func TestMakeAggregateQuery(t *testing.T) {
+	aggregateConfig := &defs.AccessConfig{
+		GroupBy:    []string{"orderStatus"},
+		Aggregates: []defs.Aggregate{{Function: "count"}, {Function: "SUM", Attribute: "total_amount", Name: "revenue"}},
+		Filter:     []defs.Filter{{Attribute: "user_id", Operator: "=", ParamName: "user_id"}},
+		Having:     []defs.Filter{{Attribute: "count", Operator: ">=", ParamName: "min_orders"}},
+		OrderBy:    []defs.OrderBy{{Attribute: "revenue", Direction: "DESC"}},
+	}
+	query, params := MakeAggregateQuery(NewPostgresDialect(), "Order", aggregateConfig)
+	assert.Equal(t, `SELECT "order_status", COUNT(*) AS "count", SUM("total_amount") AS "revenue" FROM "order" WHERE (1 = 1) AND ("user_id" = $1) `+
+		`GROUP BY "order_status" HAVING (COUNT(*) >= $2) ORDER BY "revenue" DESC`, query)
+	assert.Equal(t, []defs.ParameterRef{{Name: "user_id", Index: -1}, {Name: "min_orders", Index: -1}}, params)
+
+	query, params = MakeAggregateQuery(NewPostgresDialect(), "Order", &defs.AccessConfig{Aggregates: []defs.Aggregate{{Function: "AVG", Attribute: "totalAmount"}}})
+	assert.Equal(t, `SELECT AVG("total_amount") AS "avg_total_amount" FROM "order" WHERE (1 = 1)`, query)
+	assert.Empty(t, params)
+}
+
+func TestMakeAddManyQuery(t *testing.T) {
+	addConfig := &defs.AccessConfig{
+		Values: []defs.Update{{Attribute: "sku", ParamName: "sku"}, {Attribute: "unitPrice", ParamName: "price"}},
+	}
+	assert.Equal(t, `INSERT INTO "order_item" ("sku", "unit_price") VALUES ($1, $2), ($3, $4), ($5, $6) RETURNING "id"`,
+		MakeAddManyQuery(NewPostgresDialect(), "OrderItem", addConfig, 3))
+
+	insert, returning := MakeAddManyQueryParts(NewPostgresDialect(), "OrderItem", addConfig)
+	assert.Equal(t, `INSERT INTO "order_item" ("sku", "unit_price") VALUES`, insert)
+	assert.Equal(t, `RETURNING "id"`, returning)
+}
+
+func TestMakeSoftDeleteQueries(t *testing.T) {
+	filter := []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}}
+	query, paramsMap := MakeSoftDeleteQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Filter: filter})
+	assert.Equal(t, `UPDATE "product" SET "deleted_at" = NOW() WHERE (1 = 1) AND ("id" = $1) AND ("deleted_at" IS NULL)`, query)
+	assert.Equal(t, []defs.ParameterRef{{Name: "id", Index: -1}}, paramsMap)
+
+	query, paramsMap = MakeRestoreQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Filter: filter})
+	assert.Equal(t, `UPDATE "product" SET "deleted_at" = NULL WHERE (1 = 1) AND ("id" = $1) AND ("deleted_at" IS NOT NULL)`, query)
+	assert.Equal(t, []defs.ParameterRef{{Name: "id", Index: -1}}, paramsMap)
+
+	// Finds, updates and aggregates of soft delete models leave the deleted rows out
+	query, _ = MakeFindQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Attributes: []string{"sku"}, Filter: filter, ExcludeDeleted: true,
+		Pagination: &defs.Pagination{Type: PaginationOffset}})
+	assert.Equal(t, `SELECT "sku" FROM "product" WHERE (1 = 1) AND ("id" = $1) AND ("deleted_at" IS NULL) ORDER BY "id" LIMIT $2 OFFSET $3`, query)
+
+	query, _ = MakeUpdateQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Filter: filter, Set: []defs.Update{{Attribute: "sku", ParamName: "sku"}},
+		ExcludeDeleted: true})
+	assert.Equal(t, `UPDATE "product" SET "sku" = $1 WHERE (1 = 1) AND ("id" = $2) AND ("deleted_at" IS NULL)`, query)
+
+	query, _ = MakeAggregateQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Aggregates: []defs.Aggregate{{Function: "COUNT"}}, ExcludeDeleted: true})
+	assert.Equal(t, `SELECT COUNT(*) AS "count" FROM "product" WHERE (1 = 1) AND ("deleted_at" IS NULL)`, query)
+}
+
+func TestMakeOptimisticLockQueries(t *testing.T) {
+	filter := []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}}
+	versionRefs := []defs.ParameterRef{{Name: "id", Index: -1}, {Name: VersionParam, Index: -1}}
+
+	query, paramsMap := MakeUpdateQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Filter: filter, Set: []defs.Update{{Attribute: "price", ParamName: "price"}},
+		OptimisticLock: true})
+	assert.Equal(t, `UPDATE "product" SET "price" = $1, "version" = "version" + 1 WHERE (1 = 1) AND ("id" = $2) AND ("version" = $3) RETURNING "version"`, query)
+	assert.Equal(t, []defs.ParameterRef{{Name: "price", Index: -1}, {Name: "id", Index: -1}, {Name: VersionParam, Index: -1}}, paramsMap)
+
+	// The version is incremented once when autoincrement lists it too
+	query, _ = MakeUpdateQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Filter: filter, Set: []defs.Update{{Attribute: "price", ParamName: "price"}},
+		Autoincrement: []string{"version"}, OptimisticLock: true, ExcludeDeleted: true})
+	assert.Equal(t, `UPDATE "product" SET "price" = $1, "version" = "version" + 1 WHERE (1 = 1) AND ("id" = $2) AND ("version" = $3) AND ("deleted_at" IS NULL) RETURNING "version"`, query)
+
+	query, paramsMap = MakeDeleteQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Filter: filter, OptimisticLock: true})
+	assert.Equal(t, `DELETE FROM "product" WHERE (1 = 1) AND ("id" = $1) AND ("version" = $2)`, query)
+	assert.Equal(t, versionRefs, paramsMap)
+
+	query, paramsMap = MakeSoftDeleteQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Filter: filter, OptimisticLock: true})
+	assert.Equal(t, `UPDATE "product" SET "deleted_at" = NOW(), "version" = "version" + 1 WHERE (1 = 1) AND ("id" = $1) AND ("version" = $2) AND ("deleted_at" IS NULL)`, query)
+	assert.Equal(t, versionRefs, paramsMap)
+
+	// Restores increment the version without checking it
+	query, paramsMap = MakeRestoreQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Filter: filter, OptimisticLock: true})
+	assert.Equal(t, `UPDATE "product" SET "deleted_at" = NULL, "version" = "version" + 1 WHERE (1 = 1) AND ("id" = $1) AND ("deleted_at" IS NOT NULL)`, query)
+	assert.Equal(t, []defs.ParameterRef{{Name: "id", Index: -1}}, paramsMap)
+}
+
+func TestMakeAuditQueries(t *testing.T) {
+	filter := []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}}
+	history := `INSERT INTO "product_history" ("row_id", "operation", "old_values", "new_values", "actor") SELECT changed."id"`
+
+	// The actor is bound after the params of the access, it's not one of them
+	query, paramsMap := MakeUpdateQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Filter: filter, Set: []defs.Update{{Attribute: "price", ParamName: "price"}},
+		Audit: true})
+	assert.Equal(t, `WITH old_rows AS (SELECT * FROM "product" WHERE (1 = 1) AND ("id" = $2) FOR UPDATE), `+
+		`changed AS (UPDATE "product" SET "price" = $1 WHERE (1 = 1) AND ("id" = $2) RETURNING *) `+
+		history+", 'update', to_jsonb(old_rows), to_jsonb(changed), NULLIF($3, '') FROM changed JOIN old_rows ON old_rows.\"id\" = changed.\"id\"", query)
+	assert.Equal(t, []defs.ParameterRef{{Name: "price", Index: -1}, {Name: "id", Index: -1}}, paramsMap)
+
+	// Writes returning columns select them from the changed rows
+	query, _ = MakeUpdateQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Filter: filter, Set: []defs.Update{{Attribute: "price", ParamName: "price"}},
+		OptimisticLock: true, Audit: true})
+	assert.Equal(t, `WITH old_rows AS (SELECT * FROM "product" WHERE (1 = 1) AND ("id" = $2) AND ("version" = $3) FOR UPDATE), `+
+		`changed AS (UPDATE "product" SET "price" = $1, "version" = "version" + 1 WHERE (1 = 1) AND ("id" = $2) AND ("version" = $3) RETURNING *), `+
+		"history AS ("+history+", 'update', to_jsonb(old_rows), to_jsonb(changed), NULLIF($4, '') FROM changed JOIN old_rows ON old_rows.\"id\" = changed.\"id\") "+
+		`SELECT "version" FROM changed`, query)
+
+	query, _ = MakeAddQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Values: []defs.Update{{Attribute: "sku", ParamName: "sku"}}, Audit: true})
+	assert.Equal(t, `WITH changed AS (INSERT INTO "product" ("sku") VALUES ($1) RETURNING *), `+
+		"history AS ("+history+`, 'add', NULL, to_jsonb(changed), NULLIF($2, '') FROM changed) SELECT "id" FROM changed`, query)
+
+	query, _ = MakeDeleteQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Filter: filter, Audit: true})
+	assert.Equal(t, `WITH changed AS (DELETE FROM "product" WHERE (1 = 1) AND ("id" = $1) RETURNING *) `+
+		history+", 'delete', to_jsonb(changed), NULL, NULLIF($2, '') FROM changed", query)
+
+	query, _ = MakeRestoreQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Filter: filter, Audit: true})
+	assert.Equal(t, `WITH old_rows AS (SELECT * FROM "product" WHERE (1 = 1) AND ("id" = $1) AND ("deleted_at" IS NOT NULL) FOR UPDATE), `+
+		`changed AS (UPDATE "product" SET "deleted_at" = NULL WHERE (1 = 1) AND ("id" = $1) AND ("deleted_at" IS NOT NULL) RETURNING *) `+
+		history+", 'restore', to_jsonb(old_rows), to_jsonb(changed), NULLIF($2, '') FROM changed JOIN old_rows ON old_rows.\"id\" = changed.\"id\"", query)
+}
+
+func TestMakeEventQueries(t *testing.T) {
+	filter := []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}}
+	history := `INSERT INTO "product_history" ("row_id", "operation", "old_values", "new_values", "actor") SELECT changed."id"`
+	events := `INSERT INTO "outbox_events" ("model", "access_name", "row_id", "payload") SELECT 'Product', `
+
+	query, paramsMap := MakeUpdateQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Name: "UpdateProductPrice", Filter: filter,
+		Set: []defs.Update{{Attribute: "price", ParamName: "price"}}, Events: true})
+	assert.Equal(t, `WITH changed AS (UPDATE "product" SET "price" = $1 WHERE (1 = 1) AND ("id" = $2) RETURNING *) `+
+		events+`'UpdateProductPrice', changed."id", to_jsonb(changed) FROM changed`, query)
+	assert.Equal(t, []defs.ParameterRef{{Name: "price", Index: -1}, {Name: "id", Index: -1}}, paramsMap)
+
+	query, _ = MakeAddQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Name: "AddProduct", Values: []defs.Update{{Attribute: "sku", ParamName: "sku"}}, Events: true})
+	assert.Equal(t, `WITH changed AS (INSERT INTO "product" ("sku") VALUES ($1) RETURNING *), `+
+		"events AS ("+events+`'AddProduct', changed."id", to_jsonb(changed) FROM changed) SELECT "id" FROM changed`, query)
+
+	// The history rows and the events of a model with audit are both written by the statement
+	query, _ = MakeDeleteQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Name: "DeleteProduct", Filter: filter, Audit: true, Events: true})
+	assert.Equal(t, `WITH changed AS (DELETE FROM "product" WHERE (1 = 1) AND ("id" = $1) RETURNING *), `+
+		"history AS ("+history+`, 'delete', to_jsonb(changed), NULL, NULLIF($2, '') FROM changed) `+
+		events+`'DeleteProduct', changed."id", to_jsonb(changed) FROM changed`, query)
+	// The soft delete and the restore of a row add its events with the deletion time they set and clear
+	query, _ = MakeSoftDeleteQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Name: "DeleteProduct", Filter: filter, Events: true})
+	assert.Equal(t, `WITH changed AS (UPDATE "product" SET "deleted_at" = NOW() WHERE (1 = 1) AND ("id" = $1) AND ("deleted_at" IS NULL) RETURNING *) `+
+		events+`'DeleteProduct', changed."id", to_jsonb(changed) FROM changed`, query)
+	query, _ = MakeRestoreQuery(NewPostgresDialect(), "Product", &defs.AccessConfig{Name: "RestoreProduct", Filter: filter, Events: true})
+	assert.Equal(t, `WITH changed AS (UPDATE "product" SET "deleted_at" = NULL WHERE (1 = 1) AND ("id" = $1) AND ("deleted_at" IS NOT NULL) RETURNING *) `+
+		events+`'RestoreProduct', changed."id", to_jsonb(changed) FROM changed`, query)
+}
@@ -9,6 +9,7 @@ import (
 	"stellarsky.ai/platform/codegen/data-service-generator/config"
 	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
 	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/models"
 )
 
 // Dialect represents a specific SQL dialect
@@ -17,6 +18,38 @@ type Dialect interface {
 	FormatIdentifier(name string) string
 	GetPlaceholder(index int) string
 	DatabaseType(typeId int64) (string, error)
+	// SystemColumnDefinition is the type and constraints of one of the SystemColumns
+	SystemColumnDefinition(column string) string
+	// InsertsID is true when ids are generated by the application (UUIDV7) and inserted,
+	// false when the database assigns them and they're read back with sql.Result.LastInsertId
+	InsertsID() bool
+	// FormatReturning returns the clause that reads columns of the inserted row, empty when the dialect has none
+	FormatReturning(columns ...string) string
+	// FormatIn returns attr [NOT] IN the list bound to placeholder. expand is true when the placeholder has to be
+	// expanded to one placeholder per element before execution, see defs.ParameterRef.Expand
+	FormatIn(attr, placeholder string, negate bool) (clause string, expand bool)
+	// FormatUpsert returns what follows INSERT ... VALUES (...) in add_or_replace, columns are the formatted value columns.
+	// target is the conflict target, the formatted unique key in parentheses and the condition of its partial index, see
+	// ConflictTarget. bind returns a new placeholder bound to the value of columns[i]
+	FormatUpsert(target string, columns []string, bind func(i int) string) string
+	// CurrentTimestamp is the expression capture_timestamp sets columns to
+	CurrentTimestamp() string
+	// IndexName is the name of the index FormatCreateIndex creates on columns of table (names, not formatted)
+	IndexName(table string, columns []string, isUnique bool) string
+	// FormatCreateIndex returns the CREATE INDEX statement on columns of table (names, not formatted). where is the
+	// condition of a partial index, empty for an index of all rows, and ignored by dialects without partial indexes
+	FormatCreateIndex(table string, columns []string, isUnique bool, where string) string
+	// FormatDropIndex returns the DROP INDEX statement of an index of table, see IndexName
+	FormatDropIndex(table, indexName string) string
+	// FormatAlterColumnType returns the statement changing the type of a column, an error when the dialect can't
+	FormatAlterColumnType(table, column, columnType string) (string, error)
+	// ReferenceColumnType is the type of foreign key columns, which reference the id system column
+	ReferenceColumnType() string
+	// FormatAddForeignKey returns the statement adding a foreign key constraint (FOREIGN KEY ... REFERENCES ...)
+	// to an existing table, an error when the dialect can't
+	FormatAddForeignKey(table, constraint, definition string) (string, error)
+	// FormatDropForeignKey returns the statement dropping a foreign key constraint, an error when the dialect can't
+	FormatDropForeignKey(table, constraint string) (string, error)
 }
 
 // BaseDialect implements common functionality for all dialects
@@ -70,24 +103,154 @@ func (q *BaseQuery) Dialect() Dialect {
 
 type PostgresDialect struct {
 	BaseDialect
-	placeholderIndex int
 }
 
 func NewPostgresDialect() *PostgresDialect {
 	return &PostgresDialect{BaseDialect: BaseDialect{name: "postgres"}}
 }
 
-func (d *PostgresDialect) GetPlaceholder(index int) string {
-	d.placeholderIndex++
-	return fmt.Sprintf("$%d", d.placeholderIndex)
+// Postgres quotes identifiers with double quotes, backticks are a syntax error
+func (d *PostgresDialect) FormatIdentifier(name string) string {
+	return fmt.Sprintf(`"%s"`, strcase.ToSnake(name))
 }
 
-func (d *PostgresDialect) ResetPlaceholderIndex() {
-	d.placeholderIndex = 0
+func (d *PostgresDialect) GetPlaceholder(index int) string {
+	return fmt.Sprintf("$%d", index)
 }
 
 func (d *PostgresDialect) DatabaseType(typeId int64) (string, error) {
-	return GetPostgresType(typeId), nil
+	pgType := GetPostgresType(typeId)
+	if pgType == "" {
+		return "", fmt.Errorf("type %d has no postgres type mapping", typeId)
+	}
+	return pgType, nil
+}
+
+var postgresSystemColumns = map[string]string{
+	"id":         "UUID PRIMARY KEY DEFAULT gen_random_uuid()",
+	"version":    "INTEGER NOT NULL DEFAULT 1",
+	"updated_at": "TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP",
+	"deleted_at": "TIMESTAMP WITH TIME ZONE",
+}
+
+func (d *PostgresDialect) SystemColumnDefinition(column string) string {
+	return postgresSystemColumns[column]
+}
+
+func (d *PostgresDialect) InsertsID() bool {
+	return true
+}
+
+func (d *PostgresDialect) FormatReturning(columns ...string) string {
+	return fmt.Sprintf("%s %s", KeywordRETURNING, strings.Join(columns, ", "))
+}
+
+// IN binds the whole list to one array parameter
+func (d *PostgresDialect) FormatIn(attr, placeholder string, negate bool) (string, bool) {
+	if negate {
+		return fmt.Sprintf("%s %s ALL(%s)", attr, OperatorNotEqual, placeholder), false
+	}
+	return fmt.Sprintf("%s %s ANY(%s)", attr, OperatorEqual, placeholder), false
+}
+
+// The conflicting row is set from the values bound again, inserted tells an insert from an update
+// The rows inserted rather than updated have no xmax, the transaction deleting the old version of an updated row
+func (d *PostgresDialect) FormatUpsert(target string, columns []string, bind func(i int) string) string {
+	updateClauses := make([]string, 0, len(columns))
+	for i, column := range columns {
+		updateClauses = append(updateClauses, fmt.Sprintf("%s = %s", column, bind(i)))
+	}
+	return fmt.Sprintf("%s %s %s %s %s %s %s %s",
+		KeywordON, KeywordCONFLICT, target, KeywordDO, KeywordUPDATE, KeywordSET,
+		strings.Join(updateClauses, ", "),
+		d.FormatReturning(d.FormatIdentifier("id"), "(xmax = 0) AS inserted"))
+}
+
+func (d *PostgresDialect) CurrentTimestamp() string {
+	return "NOW()"
+}
+
+// The name Postgres picks for an unnamed index (<table>_<columns>_idx), ignoring its truncation
+// of names over 63 bytes and the numbering of clashing names
+func (d *PostgresDialect) IndexName(table string, columns []string, isUnique bool) string {
+	return strings.Join(append(append([]string{table}, columns...), "idx"), "_")
+}
+
+// Postgres names indexes itself, see IndexName
+func (d *PostgresDialect) FormatCreateIndex(table string, columns []string, isUnique bool, where string) string {
+	indexType := KeywordINDEX
+	if isUnique {
+		indexType = KeywordUNIQUE + " " + KeywordINDEX
+	}
+	return fmt.Sprintf("%s %s %s %s (%s)%s;", KeywordCREATE, indexType, KeywordON, d.FormatIdentifier(table), formatIdentifiers(d, columns),
+		indexCondition(where))
+}
+
+// indexCondition is the WHERE clause of a partial index, empty for an index of all rows
+func indexCondition(where string) string {
+	if where == "" {
+		return ""
+	}
+	return fmt.Sprintf(" %s %s", KeywordWHERE, where)
+}
+
+func formatIdentifiers(d Dialect, names []string) string {
+	formatted := make([]string, 0, len(names))
+	for _, name := range names {
+		formatted = append(formatted, d.FormatIdentifier(name))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// indexName names an index for dialects that require names, from the table and the columns
+func indexName(table string, columns []string, isUnique bool) string {
+	prefix := "idx"
+	if isUnique {
+		prefix = "uidx"
+	}
+	return strings.Join(append([]string{prefix, table}, columns...), "_")
+}
+
+func (d *PostgresDialect) FormatDropIndex(table, indexName string) string {
+	return fmt.Sprintf("%s %s %s;", KeywordDROP, KeywordINDEX, d.FormatIdentifier(indexName))
+}
+
+func (d *PostgresDialect) FormatAlterColumnType(table, column, columnType string) (string, error) {
+	column = d.FormatIdentifier(column)
+	return fmt.Sprintf("%s %s %s %s %s %s TYPE %s USING %s::%s;", KeywordALTER, KeywordTABLE, d.FormatIdentifier(table),
+		KeywordALTER, KeywordCOLUMN, column, columnType, column, columnType), nil
+}
+
+func (d *PostgresDialect) ReferenceColumnType() string {
+	return "UUID"
+}
+
+func (d *PostgresDialect) FormatAddForeignKey(table, constraint, definition string) (string, error) {
+	return formatAddForeignKey(d, table, constraint, definition), nil
+}
+
+func (d *PostgresDialect) FormatDropForeignKey(table, constraint string) (string, error) {
+	return fmt.Sprintf("%s %s %s %s %s %s;", KeywordALTER, KeywordTABLE, d.FormatIdentifier(table),
+		KeywordDROP, KeywordCONSTRAINT, d.FormatIdentifier(constraint)), nil
+}
+
+func formatAddForeignKey(d Dialect, table, constraint, definition string) string {
+	return fmt.Sprintf("%s %s %s %s %s %s %s;", KeywordALTER, KeywordTABLE, d.FormatIdentifier(table),
+		KeywordADD, KeywordCONSTRAINT, d.FormatIdentifier(constraint), definition)
+}
+
+// DialectForDriver returns the dialect of a DatabaseConfig.DriverName
+func DialectForDriver(driverName string) (Dialect, error) {
+	switch driverName {
+	case "postgres", "pgx":
+		return NewPostgresDialect(), nil
+	case "mysql":
+		return NewMySQLDialect(), nil
+	case "sqlite", "sqlite3":
+		return NewSQLiteDialect(), nil
+	default:
+		return nil, fmt.Errorf("no SQL dialect for driver %q", driverName)
+	}
 }
 
 type PreparedStmtBuilder struct {
@@ -99,8 +262,12 @@ type PreparedStmtBuilder struct {
 }
 
 func NewPreparedStmtBuilder(modelName string, accessConfig defs.AccessConfig) *PreparedStmtBuilder {
+	return NewPreparedStmtBuilderWithDialect(NewPostgresDialect(), modelName, accessConfig)
+}
+
+func NewPreparedStmtBuilderWithDialect(dialect Dialect, modelName string, accessConfig defs.AccessConfig) *PreparedStmtBuilder {
 	return &PreparedStmtBuilder{
-		dialect:          NewPostgresDialect(),
+		dialect:          dialect,
 		modelName:        modelName,
 		accessConfig:     accessConfig,
 		placeholderIndex: 0,
@@ -158,12 +325,10 @@ func (psb *PreparedStmtBuilder) buildPreparedStmtFilterClause(filter defs.Filter
 	case OperatorEqual, OperatorNotEqual, OperatorGreaterThan, OperatorLessThan, OperatorGreaterThanOrEqual, OperatorLessThanOrEqual:
 		psb.addParam(filter.ParamName, -1)
 		return fmt.Sprintf("%s %s %s", attr, filter.Operator, psb.getNextPlaceholder())
-	case OperatorIN:
-		psb.addParam(filter.ParamName, -1)
-		return fmt.Sprintf("%s %s ANY(%s)", attr, OperatorEqual, psb.getNextPlaceholder())
-	case OperatorNOTIN:
-		psb.addParam(filter.ParamName, -1)
-		return fmt.Sprintf("%s %s ALL(%s)", attr, OperatorNotEqual, psb.getNextPlaceholder())
+	case OperatorIN, OperatorNOTIN:
+		clause, expand := psb.dialect.FormatIn(attr, psb.getNextPlaceholder(), filter.Operator == OperatorNOTIN)
+		psb.params = append(psb.params, defs.ParameterRef{Name: filter.ParamName, Index: -1, Expand: expand})
+		return clause
 	case OperatorLIKE, OperatorNOTLIKE:
 		psb.addParam(filter.ParamName, -1)
 		return fmt.Sprintf("%s %s %s", attr, filter.Operator, psb.getNextPlaceholder())
@@ -264,7 +429,7 @@ func (psb *PreparedStmtBuilder) BuildUpdatePreparedStmt() (string, []defs.Parame
 
 	// Capture timestamp columns
 	for _, attr := range psb.accessConfig.CaptureTimestamp {
-		setClauses = append(setClauses, fmt.Sprintf("%s = NOW()", psb.dialect.FormatIdentifier(attr)))
+		setClauses = append(setClauses, fmt.Sprintf("%s = %s", psb.dialect.FormatIdentifier(attr), psb.dialect.CurrentTimestamp()))
 	}
 
 	query.WriteString(strings.Join(setClauses, ", "))
@@ -284,9 +449,11 @@ func (psb *PreparedStmtBuilder) BuildAddPreparedStmt() (string, []defs.Parameter
 	attributes := make([]string, 0, len(psb.accessConfig.Values))
 	values := make([]string, 0, len(psb.accessConfig.Values))
 
-	attributes = append(attributes, psb.dialect.FormatIdentifier("id"))
-	values = append(values, psb.getNextPlaceholder())
-	psb.addFuncParam("UUIDV7")
+	if psb.dialect.InsertsID() {
+		attributes = append(attributes, psb.dialect.FormatIdentifier("id"))
+		values = append(values, psb.getNextPlaceholder())
+		psb.addFuncParam("UUIDV7")
+	}
 
 	for _, update := range psb.accessConfig.Values {
 		attributes = append(attributes, psb.dialect.FormatIdentifier(update.Attribute))
@@ -294,13 +461,15 @@ func (psb *PreparedStmtBuilder) BuildAddPreparedStmt() (string, []defs.Parameter
 		psb.addParam(update.ParamName, -1)
 	}
 
-	query.WriteString(fmt.Sprintf("%s %s %s (%s) %s (%s) %s id",
+	query.WriteString(fmt.Sprintf("%s %s %s (%s) %s (%s)",
 		KeywordINSERT, KeywordINTO,
 		psb.dialect.FormatIdentifier(psb.modelName),
 		strings.Join(attributes, ", "),
 		KeywordVALUES,
-		strings.Join(values, ", "),
-		KeywordRETURNING))
+		strings.Join(values, ", ")))
+	if returning := psb.dialect.FormatReturning("id"); returning != "" {
+		query.WriteString(" " + returning)
+	}
 
 	return query.String(), psb.params
 }
@@ -311,43 +480,75 @@ func (psb *PreparedStmtBuilder) BuildAddOrReplacePreparedStmt() (string, []defs.
 
 	attributes := make([]string, 0, len(psb.accessConfig.Values))
 	values := make([]string, 0, len(psb.accessConfig.Values))
-	updateClauses := make([]string, 0, len(psb.accessConfig.Values))
+	valueColumns := make([]string, 0, len(psb.accessConfig.Values))
 
-	attributes = append(attributes, psb.dialect.FormatIdentifier("id"))
-	values = append(values, psb.getNextPlaceholder())
-	psb.addFuncParam("UUIDV7")
+	if psb.dialect.InsertsID() {
+		attributes = append(attributes, psb.dialect.FormatIdentifier("id"))
+		values = append(values, psb.getNextPlaceholder())
+		psb.addFuncParam("UUIDV7")
+	}
 	for _, update := range psb.accessConfig.Values {
 		attr := psb.dialect.FormatIdentifier(update.Attribute)
 		placeholder := psb.getNextPlaceholder()
 
 		attributes = append(attributes, attr)
+		valueColumns = append(valueColumns, attr)
 		values = append(values, placeholder)
 		psb.addParam(update.ParamName, -1)
 	}
 
-	for _, update := range psb.accessConfig.Values {
-		attr := psb.dialect.FormatIdentifier(update.Attribute)
-		placeholder := psb.getNextPlaceholder()
-		updateClauses = append(updateClauses, fmt.Sprintf("%s = %s", attr, placeholder))
-		psb.addParam(update.ParamName, -1)
-	}
+	upsert := psb.dialect.FormatUpsert(ConflictTarget(psb.dialect, &psb.accessConfig), valueColumns, func(i int) string {
+		psb.addParam(psb.accessConfig.Values[i].ParamName, -1)
+		return psb.getNextPlaceholder()
+	})
 
-	query.WriteString(fmt.Sprintf("%s %s %s (%s) %s (%s) %s %s %s %s %s %s %s id, (xmax = 0) AS inserted",
+	query.WriteString(fmt.Sprintf("%s %s %s (%s) %s (%s) %s",
 		KeywordINSERT, KeywordINTO, psb.dialect.FormatIdentifier(psb.modelName),
 		strings.Join(attributes, ", "),
 		KeywordVALUES,
 		strings.Join(values, ", "),
-		KeywordON, KeywordCONFLICT, KeywordDO, KeywordUPDATE, KeywordSET,
-		strings.Join(updateClauses, ", "),
-		KeywordRETURNING))
+		upsert))
 
 	return query.String(), psb.params
 }
 
+// SystemColumn is a column every table has besides the model attributes,
+// its type and constraints come from Dialect.SystemColumnDefinition
+type SystemColumn struct {
+	Name   string
+	GoType string // field type in the generated model struct
+}
+
+// SystemColumns is the one column set of all tables, SchemaBuilder creates them ahead of the attribute columns
+// and the generated model structs lead with the same fields
+var SystemColumns = []SystemColumn{
+	{Name: "id", GoType: "string"},
+	{Name: "version", GoType: "int64"},
+	{Name: "updated_at", GoType: "time.Time"},
+}
+
+// SoftDeleteColumn is the deletion time of the rows of the models with soft_delete, NULL for the rows that are not
+// deleted. It follows the SystemColumns of their tables, see ModelSystemColumns.
+var SoftDeleteColumn = SystemColumn{Name: "deleted_at", GoType: "time.Time"}
+
+// NotDeletedCondition is the condition of the rows of a soft delete model that are not deleted
+const NotDeletedCondition = "(deleted_at IS NULL)"
+
+// ModelSystemColumns are the system columns of the table of a model, SystemColumns and the SoftDeleteColumn of the
+// models with soft_delete
+func ModelSystemColumns(model *defs.Model) []SystemColumn {
+	if !model.SoftDelete {
+		return SystemColumns
+	}
+	return append(slices.Clip(SystemColumns), SoftDeleteColumn)
+}
+
 type SchemaBuilder struct {
 	dialect      Dialect
 	databaseName string
 	dataConfig   defs.DataConfig
+	// attributes overrides the catalog (config.Attributes) attribute ids are resolved against
+	attributes map[int64]models.AttributeRow
 }
 
 func NewSchemaBuilder(dialect Dialect, databaseName string, config defs.DataConfig) *SchemaBuilder {
@@ -358,6 +559,22 @@ func NewSchemaBuilder(dialect Dialect, databaseName string, config defs.DataConf
 	}
 }
 
+// WithAttributes returns a copy of the builder that resolves attribute ids against attributes instead of the loaded catalog
+func (sb *SchemaBuilder) WithAttributes(attributes map[int64]models.AttributeRow) *SchemaBuilder {
+	copied := *sb
+	copied.attributes = attributes
+	return &copied
+}
+
+func (sb *SchemaBuilder) attribute(attrId int64) (models.AttributeRow, bool) {
+	if sb.attributes != nil {
+		attribute, ok := sb.attributes[attrId]
+		return attribute, ok
+	}
+	attribute, ok := config.Attributes[attrId]
+	return attribute, ok
+}
+
 func (sb *SchemaBuilder) BuildCreateDatabase() string {
 	return fmt.Sprintf("CREATE DATABASE %s;", sb.dialect.FormatIdentifier(sb.dataConfig.FamilyName))
 }
@@ -373,10 +590,9 @@ func (sb *SchemaBuilder) BuildGrantPermissions() string {
 }
 
 func (sb *SchemaBuilder) BuildCreateTable(model *defs.ModelConfig) string {
-	columns := []string{
-		fmt.Sprintf("%s`id` UUID PRIMARY KEY DEFAULT gen_random_uuid()", golang.Indent),
-		fmt.Sprintf("%s`version` INTEGER NOT NULL DEFAULT 1", golang.Indent),
-		fmt.Sprintf("%s`updated_at` TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP", golang.Indent),
+	columns := []string{}
+	for _, column := range ModelSystemColumns(&model.Model) {
+		columns = append(columns, fmt.Sprintf("%s%s %s", golang.Indent, sb.dialect.FormatIdentifier(column.Name), sb.dialect.SystemColumnDefinition(column.Name)))
 	}
 
 	// Add columns from ModelConfig
@@ -385,41 +601,170 @@ func (sb *SchemaBuilder) BuildCreateTable(model *defs.ModelConfig) string {
 		columns = append(columns, fmt.Sprintf("%s%s %s", golang.Indent, sb.dialect.FormatIdentifier(attrName), attrType))
 	}
 
+	// Foreign key columns follow the attributes, their constraints close the column list
+	references := sb.references(model)
+	for _, reference := range references {
+		columns = append(columns, golang.Indent+sb.referenceColumnDefinition(reference))
+	}
+	for _, reference := range references {
+		columns = append(columns, fmt.Sprintf("%s%s %s %s", golang.Indent, KeywordCONSTRAINT,
+			sb.dialect.FormatIdentifier(ForeignKeyName(strcase.ToSnake(model.Name), reference.Column)), sb.foreignKeyDefinition(reference)))
+	}
+
 	// Create table SQL
 	createTableSQL := fmt.Sprintf("CREATE TABLE %s (\n%s\n);",
 		sb.dialect.FormatIdentifier(model.Name),
 		strings.Join(columns, ",\n"))
 
-	// Add indexes based on filters
+	indexSQL := sb.generateIndexSQL(model.Name, sb.modelIndexes(model))
+	if model.Model.Audit {
+		return createTableSQL + "\n\n" + indexSQL + "\n\n" + sb.BuildCreateHistoryTable(model) + "\n"
+	}
+	return createTableSQL + "\n\n" + indexSQL + "\n"
+}
+
+// historyColumns are the columns of the history table of a model with audit, see BuildCreateHistoryTable. Audit
+// uses JSONB and to_jsonb, so it's supported by Postgres only.
+var historyColumns = []struct{ name, definition string }{
+	{"id", "BIGSERIAL PRIMARY KEY"},
+	{"row_id", "UUID NOT NULL"},
+	{"operation", "TEXT NOT NULL"},
+	{"old_values", "JSONB"},
+	{"new_values", "JSONB"},
+	{"actor", "TEXT"},
+	{"changed_at", "TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP"},
+}
+
+// BuildCreateHistoryTable returns the CREATE TABLE and CREATE INDEX statements of the history table of a model with
+// audit (see HistoryTable), which has a row for each row a write of the model changed, with its values before and
+// after the write. Rows have no foreign key to the model, so that the history of deleted rows is kept.
+func (sb *SchemaBuilder) BuildCreateHistoryTable(model *defs.ModelConfig) string {
+	table := HistoryTable(model.Name)
+	columns := make([]string, 0, len(historyColumns))
+	for _, column := range historyColumns {
+		columns = append(columns, fmt.Sprintf("%s%s %s", golang.Indent, sb.dialect.FormatIdentifier(column.name), column.definition))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n);\n\n%s", sb.dialect.FormatIdentifier(table), strings.Join(columns, ",\n"),
+		sb.dialect.FormatCreateIndex(table, []string{"row_id"}, false, ""))
+}
+
+// outboxColumns are the columns of the outbox table of the accesses with events, see BuildCreateOutboxTable
+var outboxColumns = []struct{ name, definition string }{
+	{"id", "BIGSERIAL PRIMARY KEY"},
+	{"model", "TEXT NOT NULL"},
+	{"access_name", "TEXT NOT NULL"},
+	{"row_id", "UUID NOT NULL"},
+	{"payload", "JSONB"},
+	{"created_at", "TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP"},
+	{"sent_at", "TIMESTAMP WITH TIME ZONE"},
+}
+
+// BuildCreateOutboxTable returns the CREATE TABLE and CREATE INDEX statements of the outbox table (see OutboxTable),
+// which has an event for each row a write with events changed, in the order of the writes. The events that are not
+// sent yet are indexed, so that the relay finds them without reading the sent ones.
+func (sb *SchemaBuilder) BuildCreateOutboxTable() string {
+	columns := make([]string, 0, len(outboxColumns))
+	for _, column := range outboxColumns {
+		columns = append(columns, fmt.Sprintf("%s%s %s", golang.Indent, sb.dialect.FormatIdentifier(column.name), column.definition))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n);\n\n%s", sb.dialect.FormatIdentifier(OutboxTable), strings.Join(columns, ",\n"),
+		sb.dialect.FormatCreateIndex(OutboxTable, []string{"id"}, false, "sent_at IS NULL"))
+}
+
+// modelIndexes are the indexes of a table: one per filtered attribute and foreign key column, one per ordering of
+// the sorted finds, and the model indexes and unique constraints. The unique indexes of a soft delete model are
+// partial indexes of the rows that are not deleted, so that deleted rows don't hold on to their unique values.
+func (sb *SchemaBuilder) modelIndexes(model *defs.ModelConfig) map[string]indexItem {
 	seenIndexes := sb.generateIndexesFromFilters(model.GetAllFilters())
+	for _, find := range model.Access.Find {
+		sb.generateIndexesFromOrderings(&find, seenIndexes)
+	}
+	for _, reference := range sb.references(model) {
+		if _, ok := seenIndexes[reference.Column]; !ok {
+			seenIndexes[reference.Column] = sb.newIndexItem([]string{reference.Column}, false)
+		}
+	}
 	sb.generateIndexes(model.Model.GetIndexes(), seenIndexes)
-	indexSQL := sb.generateIndexSQL(model.Name, seenIndexes)
-	return createTableSQL + "\n\n" + indexSQL + "\n"
+	if model.Model.SoftDelete {
+		for name, item := range seenIndexes {
+			if item.isUnique {
+				item.where = NotDeletedCondition
+				seenIndexes[name] = item
+			}
+		}
+	}
+	return seenIndexes
+}
+
+// references resolves the relationships of a model against the family of the builder,
+// relationships that don't resolve are left out, ValidateDataConfig reports them
+func (sb *SchemaBuilder) references(model *defs.ModelConfig) []defs.Reference {
+	references, _ := sb.dataConfig.References(&model.Model)
+	return references
+}
+
+// ForeignKeyName is the name of the constraint of a foreign key column, fk_<table>_<column>
+func ForeignKeyName(table, column string) string {
+	return strings.Join([]string{"fk", table, column}, "_")
+}
+
+func (sb *SchemaBuilder) referenceColumnDefinition(reference defs.Reference) string {
+	definition := fmt.Sprintf("%s %s", sb.dialect.FormatIdentifier(reference.Column), sb.dialect.ReferenceColumnType())
+	if reference.NotNull {
+		definition += " NOT " + KeywordNULL
+	}
+	return definition
+}
+
+// foreignKeyDefinition is the FOREIGN KEY ... REFERENCES ... clause of a reference, with its referential actions
+func (sb *SchemaBuilder) foreignKeyDefinition(reference defs.Reference) string {
+	definition := fmt.Sprintf("%s (%s) %s %s (%s)", KeywordFOREIGNKEY, sb.dialect.FormatIdentifier(reference.Column),
+		KeywordREFERENCES, sb.dialect.FormatIdentifier(reference.TargetModel), sb.dialect.FormatIdentifier("id"))
+	if reference.OnDelete != "" {
+		definition += fmt.Sprintf(" %s %s %s", KeywordON, KeywordDELETE, reference.OnDelete)
+	}
+	if reference.OnUpdate != "" {
+		definition += fmt.Sprintf(" %s %s %s", KeywordON, KeywordUPDATE, reference.OnUpdate)
+	}
+	return definition
 }
 
 func (sb *SchemaBuilder) mapAttributeTypeToSQL(attrId int64) (string, string) {
 	// Map attribute types to SQL types
 	// Implement this based on your specific type mappings
-	attribute, ok := config.Attributes[attrId]
+	attribute, ok := sb.attribute(attrId)
 	if !ok {
 		return "", ""
 	}
 	attrType, _ := sb.dialect.DatabaseType(attribute.TypeId)
-	return attribute.Name, attrType
+	return attribute.Name, attrType + ColumnConstraints(attribute)
 
 }
 
+// ColumnConstraints are the NOT NULL and DEFAULT an attribute declares for its column, with a leading space
+func ColumnConstraints(attribute models.AttributeRow) string {
+	constraints := ""
+	if attribute.NotNull {
+		constraints += " NOT " + KeywordNULL
+	}
+	if attribute.Default != "" {
+		constraints += fmt.Sprintf(" %s %s", KeywordDEFAULT, attribute.Default)
+	}
+	return constraints
+}
+
 type indexItem struct {
-	joinedAttrs string // e.g. "attr1, attr2, attr3"
-	isUnique    bool   // e.g. "UNIQUE INDEX" or "INDEX"
+	columns  []string // snake case, e.g. ["attr1", "attr2"]
+	isUnique bool     // e.g. "UNIQUE INDEX" or "INDEX"
+	where    string   // condition of a partial index, e.g. "(deleted_at IS NULL)"
 }
 
 func (sb *SchemaBuilder) newIndexItem(attrs []string, isUnique bool) indexItem {
-	snakeAttrs := []string{}
+	columns := []string{}
 	for _, attr := range attrs {
-		snakeAttrs = append(snakeAttrs, sb.dialect.FormatIdentifier(strcase.ToSnake(attr)))
+		columns = append(columns, strcase.ToSnake(attr))
 	}
-	return indexItem{joinedAttrs: strings.Join(snakeAttrs, ", "), isUnique: isUnique}
+	return indexItem{columns: columns, isUnique: isUnique}
 }
 
 func (sb *SchemaBuilder) generateIndexesFromFilters(allFilters []defs.Filter) map[string]indexItem {
@@ -451,12 +796,46 @@ func (sb *SchemaBuilder) generateIndexesFromFilters(allFilters []defs.Filter) ma
 	return seenIndexes
 }
 
+// generateIndexesFromOrderings adds the composite indexes of the orderings of a sorted find: the attributes the find
+// filters for equality, then the ordered attributes, so the database reads the rows in order instead of sorting them
+func (sb *SchemaBuilder) generateIndexesFromOrderings(find *defs.AccessConfig, seenIndexes map[string]indexItem) {
+	equalities := []string{}
+	for _, filter := range find.Filter {
+		if filter.Attribute != "" && filter.Operator == OperatorEquals && !slices.Contains(equalities, filter.Attribute) {
+			equalities = append(equalities, filter.Attribute)
+		}
+	}
+	for _, ordering := range find.Orderings() {
+		attrs := slices.Clone(equalities)
+		for _, order := range ordering {
+			if !slices.Contains(attrs, order.Attribute) {
+				attrs = append(attrs, order.Attribute)
+			}
+		}
+		item := sb.newIndexItem(attrs, false)
+		if !hasIndexOn(seenIndexes, item.columns) {
+			seenIndexes[strings.Join(item.columns, ", ")] = item
+		}
+	}
+}
+
+// hasIndexOn tells whether one of the indexes is on exactly the columns
+func hasIndexOn(indexes map[string]indexItem, columns []string) bool {
+	for _, item := range indexes {
+		if slices.Equal(item.columns, columns) {
+			return true
+		}
+	}
+	return false
+}
+
 func (sb *SchemaBuilder) generateIndexes(allIndexes []defs.Index, seenIndexes map[string]indexItem) {
 
 	for _, index := range allIndexes {
 		indexAttrs := make([]string, len(index.Attributes))
 		for i, attr := range index.Attributes {
-			attrName := config.Attributes[attr].Name
+			attribute, _ := sb.attribute(attr)
+			attrName := attribute.Name
 			indexAttrs[i] = strcase.ToSnake(attrName)
 		}
 		indexAttr := strings.Join(indexAttrs, ", ")
@@ -471,15 +850,7 @@ func (sb *SchemaBuilder) generateIndexes(allIndexes []defs.Index, seenIndexes ma
 func (sb *SchemaBuilder) generateIndexSQL(modelName string, indexItemMap map[string]indexItem) string {
 	var indexSQLs []string
 	for _, item := range indexItemMap {
-		indexType := "INDEX"
-		if item.isUnique {
-			indexType = "UNIQUE INDEX"
-		}
-		indexSQL := fmt.Sprintf("CREATE %s ON %s (%s);",
-			indexType,
-			sb.dialect.FormatIdentifier(modelName),
-			item.joinedAttrs)
-		indexSQLs = append(indexSQLs, indexSQL)
+		indexSQLs = append(indexSQLs, sb.dialect.FormatCreateIndex(strcase.ToSnake(modelName), item.columns, item.isUnique, item.where))
 	}
 	slices.Sort(indexSQLs)
 	return strings.Join(indexSQLs, "\n")
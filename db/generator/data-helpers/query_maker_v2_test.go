@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"stellarsky.ai/platform/codegen/data-service-generator/config"
 	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/models"
 )
 
 func TestBuildDeletePreparedStmt(t *testing.T) {
@@ -15,7 +16,7 @@ func TestBuildDeletePreparedStmt(t *testing.T) {
 			dialect:   &PostgresDialect{},
 		}
 		query, params := psb.BuildDeletePreparedStmt()
-		assert.Equal(t, "DELETE FROM `test_table`", query)
+		assert.Equal(t, "DELETE FROM \"test_table\"", query)
 		assert.Empty(t, params)
 	})
 
@@ -30,7 +31,7 @@ func TestBuildDeletePreparedStmt(t *testing.T) {
 			},
 		}
 		query, params := psb.BuildDeletePreparedStmt()
-		assert.Equal(t, "DELETE FROM `users` WHERE (`id` = $1)", query)
+		assert.Equal(t, "DELETE FROM \"users\" WHERE (\"id\" = $1)", query)
 		assert.Equal(t, []defs.ParameterRef{{Name: "user_id", Index: -1}}, params)
 	})
 
@@ -46,7 +47,7 @@ func TestBuildDeletePreparedStmt(t *testing.T) {
 			},
 		}
 		query, params := psb.BuildDeletePreparedStmt()
-		assert.Equal(t, "DELETE FROM `orders` WHERE (`status` = $1 AND `created_at` < $2)", query)
+		assert.Equal(t, "DELETE FROM \"orders\" WHERE (\"status\" = $1 AND \"created_at\" < $2)", query)
 		assert.Equal(t, []defs.ParameterRef{
 			{Name: "order_status", Index: -1},
 			{Name: "date", Index: -1},
@@ -71,7 +72,7 @@ func TestBuildDeletePreparedStmt(t *testing.T) {
 			},
 		}
 		query, params := psb.BuildDeletePreparedStmt()
-		assert.Equal(t, "DELETE FROM `products` WHERE ((`category` = $1 OR `price` > $2) AND `is_active` = $3)", query)
+		assert.Equal(t, "DELETE FROM \"products\" WHERE ((\"category\" = $1 OR \"price\" > $2) AND \"is_active\" = $3)", query)
 		assert.Equal(t, []defs.ParameterRef{
 			{Name: "category", Index: -1},
 			{Name: "min_price", Index: -1},
@@ -86,7 +87,7 @@ func TestBuildFindPreparedStmt(t *testing.T) {
 			dialect:   &PostgresDialect{},
 		}
 		query, params := psb.BuildFindPreparedStmt()
-		assert.Equal(t, "SELECT * FROM `users`", query)
+		assert.Equal(t, "SELECT * FROM \"users\"", query)
 		assert.Empty(t, params)
 	})
 
@@ -99,7 +100,7 @@ func TestBuildFindPreparedStmt(t *testing.T) {
 			},
 		}
 		query, params := psb.BuildFindPreparedStmt()
-		assert.Equal(t, "SELECT `id`, `name`, `price` FROM `products`", query)
+		assert.Equal(t, "SELECT \"id\", \"name\", \"price\" FROM \"products\"", query)
 		assert.Empty(t, params)
 	})
 
@@ -114,7 +115,7 @@ func TestBuildFindPreparedStmt(t *testing.T) {
 			},
 		}
 		query, params := psb.BuildFindPreparedStmt()
-		assert.Equal(t, "SELECT * FROM `orders` WHERE (`status` = $1)", query)
+		assert.Equal(t, "SELECT * FROM \"orders\" WHERE (\"status\" = $1)", query)
 		assert.Equal(t, []defs.ParameterRef{{Name: "order_status", Index: -1}}, params)
 	})
 
@@ -131,7 +132,7 @@ func TestBuildFindPreparedStmt(t *testing.T) {
 			},
 		}
 		query, params := psb.BuildFindPreparedStmt()
-		assert.Equal(t, "SELECT `id`, `name`, `department` FROM `employees` WHERE (`age` > $1 AND `salary` < $2)", query)
+		assert.Equal(t, "SELECT \"id\", \"name\", \"department\" FROM \"employees\" WHERE (\"age\" > $1 AND \"salary\" < $2)", query)
 		assert.Equal(t, []defs.ParameterRef{
 			{Name: "min_age", Index: -1},
 			{Name: "max_salary", Index: -1},
@@ -156,7 +157,7 @@ func TestBuildFindPreparedStmt(t *testing.T) {
 			},
 		}
 		query, params := psb.BuildFindPreparedStmt()
-		assert.Equal(t, "SELECT * FROM `customers` WHERE ((`country` = $1 OR `total_purchases` > $2) AND `is_active` = $3)", query)
+		assert.Equal(t, "SELECT * FROM \"customers\" WHERE ((\"country\" = $1 OR \"total_purchases\" > $2) AND \"is_active\" = $3)", query)
 		assert.Equal(t, []defs.ParameterRef{
 			{Name: "country", Index: -1},
 			{Name: "min_purchases", Index: -1},
@@ -194,7 +195,7 @@ func TestBuildFindPreparedStmt(t *testing.T) {
 			},
 		}
 		query, params := psb.BuildFindPreparedStmt()
-		assert.Equal(t, "SELECT `id`, `name`, `price`, `category` FROM `products` WHERE (((`price` BETWEEN $1 AND $2) AND (`category` = ANY($3) OR `name` LIKE $4)) AND (NOT(`is_discontinued` = $5)))", query)
+		assert.Equal(t, "SELECT \"id\", \"name\", \"price\", \"category\" FROM \"products\" WHERE (((\"price\" BETWEEN $1 AND $2) AND (\"category\" = ANY($3) OR \"name\" LIKE $4)) AND (NOT(\"is_discontinued\" = $5)))", query)
 		assert.Equal(t, []defs.ParameterRef{
 			{Name: "price_range", Index: 0},
 			{Name: "price_range", Index: 1},
@@ -224,7 +225,7 @@ func TestBuildUpdatePreparedStmt(t *testing.T) {
 			},
 		}
 		query, params := psb.BuildUpdatePreparedStmt()
-		assert.Equal(t, "UPDATE `users` SET `name` = $1, `email` = $2, `version` = `version` + 1, `updated_at` = NOW() WHERE (`id` = $3)", query)
+		assert.Equal(t, "UPDATE \"users\" SET \"name\" = $1, \"email\" = $2, \"version\" = \"version\" + 1, \"updated_at\" = NOW() WHERE (\"id\" = $3)", query)
 		assert.Equal(t, []defs.ParameterRef{
 			{Name: "new_name", Index: -1},
 			{Name: "new_email", Index: -1},
@@ -246,7 +247,7 @@ func TestBuildAddPreparedStmt(t *testing.T) {
 			},
 		}
 		query, params := psb.BuildAddPreparedStmt()
-		assert.Equal(t, "INSERT INTO `products` (`id`, `name`, `price`) VALUES ($1, $2, $3) RETURNING id", query)
+		assert.Equal(t, "INSERT INTO \"products\" (\"id\", \"name\", \"price\") VALUES ($1, $2, $3) RETURNING id", query)
 		assert.Equal(t, []defs.ParameterRef{
 			{FuncName: "UUIDV7"},
 			{Name: "product_name", Index: -1},
@@ -265,11 +266,12 @@ func TestBuildAddOrReplacePreparedStmt(t *testing.T) {
 					{Attribute: "product_id", ParamName: "pid"},
 					{Attribute: "quantity", ParamName: "qty"},
 				},
+				ConflictKey: []string{"product_id"},
 			},
 		}
 		query, params := psb.BuildAddOrReplacePreparedStmt()
 		assert.Equal(t,
-			"INSERT INTO `inventory` (`id`, `product_id`, `quantity`) VALUES ($1, $2, $3) ON CONFLICT DO UPDATE SET `product_id` = $4, `quantity` = $5 RETURNING id, (xmax = 0) AS inserted",
+			"INSERT INTO \"inventory\" (\"id\", \"product_id\", \"quantity\") VALUES ($1, $2, $3) ON CONFLICT (\"product_id\") DO UPDATE SET \"product_id\" = $4, \"quantity\" = $5 RETURNING \"id\", (xmax = 0) AS inserted",
 			query)
 		assert.Equal(t, []defs.ParameterRef{
 			{FuncName: "UUIDV7"},
@@ -292,10 +294,10 @@ func TestBuildCreateTable(t *testing.T) {
 			},
 		}
 		result := sb.BuildCreateTable(model)
-		expected := "CREATE TABLE `test_table` (\n" +
-			"	`id` UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
-			"	`version` INTEGER NOT NULL DEFAULT 1,\n" +
-			"	`updated_at` TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP\n" +
+		expected := "CREATE TABLE \"test_table\" (\n" +
+			"	\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
+			"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+			"	\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP\n" +
 			");\n\n\n"
 
 		assert.Equal(t, expected, result)
@@ -312,18 +314,57 @@ func TestBuildCreateTable(t *testing.T) {
 			},
 		}
 		result := sb.BuildCreateTable(model)
-		expected := "CREATE TABLE `products` (\n" +
-			"	`id` UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
-			"	`version` INTEGER NOT NULL DEFAULT 1,\n" +
-			"	`updated_at` TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
-			"	`sku` TEXT,\n" +
-			"	`product_name` TEXT,\n" +
-			"	`description` TEXT\n" +
+		expected := "CREATE TABLE \"products\" (\n" +
+			"	\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
+			"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+			"	\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+			"	\"sku\" TEXT NOT NULL,\n" +
+			"	\"product_name\" TEXT NOT NULL,\n" +
+			"	\"description\" TEXT\n" +
 			");\n\n\n"
 
 		assert.Equal(t, expected, result)
 	})
 
+	t.Run("ModelWithNotNullAndDefaults", func(t *testing.T) {
+		sb := NewSchemaBuilder(&PostgresDialect{}, "", defs.DataConfig{}).WithAttributes(map[int64]models.AttributeRow{
+			1: {UniqueID: models.UniqueID{ID: 1, Name: "sku"}, TypeId: 1000001, NotNull: true},
+			2: {UniqueID: models.UniqueID{ID: 2, Name: "stock"}, TypeId: 1000004, NotNull: true, Default: "0"},
+			3: {UniqueID: models.UniqueID{ID: 3, Name: "status"}, TypeId: 1000001, Default: "'new'"},
+		})
+		model := &defs.ModelConfig{Model: defs.Model{Name: "products", Attributes: []int64{1, 2, 3}}}
+		expected := "CREATE TABLE \"products\" (\n" +
+			"	\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
+			"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+			"	\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+			"	\"sku\" TEXT NOT NULL,\n" +
+			"	\"stock\" INTEGER NOT NULL DEFAULT 0,\n" +
+			"	\"status\" TEXT DEFAULT 'new'\n" +
+			");\n\n\n"
+		assert.Equal(t, expected, sb.BuildCreateTable(model))
+	})
+
+	t.Run("ModelWithAudit", func(t *testing.T) {
+		sb := NewSchemaBuilder(&PostgresDialect{}, "", defs.DataConfig{})
+		model := &defs.ModelConfig{Model: defs.Model{Name: "products", Audit: true}}
+		expected := "CREATE TABLE \"products\" (\n" +
+			"	\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
+			"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+			"	\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP\n" +
+			");\n\n\n\n" +
+			"CREATE TABLE \"products_history\" (\n" +
+			"	\"id\" BIGSERIAL PRIMARY KEY,\n" +
+			"	\"row_id\" UUID NOT NULL,\n" +
+			"	\"operation\" TEXT NOT NULL,\n" +
+			"	\"old_values\" JSONB,\n" +
+			"	\"new_values\" JSONB,\n" +
+			"	\"actor\" TEXT,\n" +
+			"	\"changed_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP\n" +
+			");\n\n" +
+			"CREATE INDEX ON \"products_history\" (\"row_id\");\n"
+		assert.Equal(t, expected, sb.BuildCreateTable(model))
+	})
+
 	t.Run("ModelWithAttributesAndIndexes", func(t *testing.T) {
 		sb := &SchemaBuilder{
 			dialect: &PostgresDialect{},
@@ -332,10 +373,7 @@ func TestBuildCreateTable(t *testing.T) {
 			Model: defs.Model{
 				Name:       "products",
 				Attributes: []int64{2000001, 2000002, 2000003},
-				Indexes: []struct {
-					IndexName  string  `yaml:"index_name"`
-					Attributes []int64 `yaml:"attributes"`
-				}{
+				Indexes: []defs.ModelIndex{
 					{
 						IndexName:  "idx_product_name",
 						Attributes: []int64{2000001},
@@ -348,16 +386,16 @@ func TestBuildCreateTable(t *testing.T) {
 			},
 		}
 		result := sb.BuildCreateTable(model)
-		expected := "CREATE TABLE `products` (\n" +
-			"	`id` UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
-			"	`version` INTEGER NOT NULL DEFAULT 1,\n" +
-			"	`updated_at` TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
-			"	`sku` TEXT,\n" +
-			"	`product_name` TEXT,\n" +
-			"	`description` TEXT\n" +
+		expected := "CREATE TABLE \"products\" (\n" +
+			"	\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
+			"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+			"	\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+			"	\"sku\" TEXT NOT NULL,\n" +
+			"	\"product_name\" TEXT NOT NULL,\n" +
+			"	\"description\" TEXT\n" +
 			");\n\n" +
-			"CREATE INDEX ON `products` (`product_name`);\n" +
-			"CREATE INDEX ON `products` (`sku`);\n"
+			"CREATE INDEX ON \"products\" (\"product_name\");\n" +
+			"CREATE INDEX ON \"products\" (\"sku\");\n"
 
 		assert.Equal(t, expected, result)
 	})
@@ -382,16 +420,49 @@ func TestBuildCreateTable(t *testing.T) {
 			},
 		}
 		result := sb.BuildCreateTable(model)
-		expected := "CREATE TABLE `orders` (\n" +
-			"	`id` UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
-			"	`version` INTEGER NOT NULL DEFAULT 1,\n" +
-			"	`updated_at` TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
-			"	`sku` TEXT,\n" +
-			"	`product_name` TEXT,\n" +
-			"	`description` TEXT\n" +
+		expected := "CREATE TABLE \"orders\" (\n" +
+			"	\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
+			"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+			"	\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+			"	\"sku\" TEXT NOT NULL,\n" +
+			"	\"product_name\" TEXT NOT NULL,\n" +
+			"	\"description\" TEXT\n" +
+			");\n\n" +
+			"CREATE INDEX ON \"orders\" (\"product_name\");\n" +
+			"CREATE INDEX ON \"orders\" (\"sku\");\n"
+		assert.Equal(t, expected, result)
+	})
+	t.Run("ModelWithSortedFinds", func(t *testing.T) {
+		sb := &SchemaBuilder{
+			dialect: &PostgresDialect{},
+		}
+		model := &defs.ModelConfig{
+			Model: defs.Model{
+				Name:       "orders",
+				Attributes: []int64{2000001, 2000002, 2000003},
+			},
+			Access: defs.Access{
+				Find: []defs.AccessConfig{{
+					Filter:      []defs.Filter{{Attribute: "sku", Operator: "="}},
+					OrderBy:     []defs.OrderBy{{Attribute: "product_name", Direction: "DESC"}},
+					SortOptions: []defs.SortOption{{Name: "description", OrderBy: []defs.OrderBy{{Attribute: "description"}}}},
+				}, {
+					OrderBy: []defs.OrderBy{{Attribute: "sku"}},
+				}},
+			},
+		}
+		result := sb.BuildCreateTable(model)
+		expected := "CREATE TABLE \"orders\" (\n" +
+			"	\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
+			"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+			"	\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+			"	\"sku\" TEXT NOT NULL,\n" +
+			"	\"product_name\" TEXT NOT NULL,\n" +
+			"	\"description\" TEXT\n" +
 			");\n\n" +
-			"CREATE INDEX ON `orders` (`product_name`);\n" +
-			"CREATE INDEX ON `orders` (`sku`);\n"
+			"CREATE INDEX ON \"orders\" (\"sku\");\n" +
+			"CREATE INDEX ON \"orders\" (\"sku\", \"description\");\n" +
+			"CREATE INDEX ON \"orders\" (\"sku\", \"product_name\");\n"
 		assert.Equal(t, expected, result)
 	})
 	t.Run("ModelWithAttributesAndFiltersAndIndexes", func(t *testing.T) {
@@ -402,10 +473,7 @@ func TestBuildCreateTable(t *testing.T) {
 			Model: defs.Model{
 				Name:       "orders",
 				Attributes: []int64{2000001, 2000002, 2000003},
-				Indexes: []struct {
-					IndexName  string  `yaml:"index_name"`
-					Attributes []int64 `yaml:"attributes"`
-				}{
+				Indexes: []defs.ModelIndex{
 					{
 						IndexName:  "idx_sku_product_name",
 						Attributes: []int64{2000001, 2000002},
@@ -427,18 +495,55 @@ func TestBuildCreateTable(t *testing.T) {
 			},
 		}
 		result := sb.BuildCreateTable(model)
-		expected := "CREATE TABLE `orders` (\n" +
-			"	`id` UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
-			"	`version` INTEGER NOT NULL DEFAULT 1,\n" +
-			"	`updated_at` TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
-			"	`sku` TEXT,\n" +
-			"	`product_name` TEXT,\n" +
-			"	`description` TEXT\n" +
+		expected := "CREATE TABLE \"orders\" (\n" +
+			"	\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
+			"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+			"	\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+			"	\"sku\" TEXT NOT NULL,\n" +
+			"	\"product_name\" TEXT NOT NULL,\n" +
+			"	\"description\" TEXT\n" +
 			");\n\n" +
-			"CREATE INDEX ON `orders` (`product_name`);\n" +
-			"CREATE INDEX ON `orders` (`product_name`, `description`);\n" +
-			"CREATE INDEX ON `orders` (`sku`);\n" +
-			"CREATE INDEX ON `orders` (`sku`, `product_name`);\n"
+			"CREATE INDEX ON \"orders\" (\"product_name\");\n" +
+			"CREATE INDEX ON \"orders\" (\"product_name\", \"description\");\n" +
+			"CREATE INDEX ON \"orders\" (\"sku\");\n" +
+			"CREATE INDEX ON \"orders\" (\"sku\", \"product_name\");\n"
 		assert.Equal(t, expected, result)
 	})
+	t.Run("ModelWithRelationships", func(t *testing.T) {
+		dataConfig := defs.DataConfig{Models: []defs.ModelConfig{
+			{Model: defs.Model{ID: 1, Name: "Customer"}},
+			{Model: defs.Model{ID: 2, Name: "Order", Attributes: []int64{2000001}, Relationships: []defs.Relationship{
+				{Type: "BelongsTo", TargetModelID: 1, OnUpdate: "cascade"},
+				{Type: "Refers", TargetModelID: 1, Name: "referrer", OnDelete: "SET NULL"},
+			}}},
+			{Model: defs.Model{ID: 3, Name: "OrderLine", Relationships: []defs.Relationship{
+				{Type: "Child", TargetModelID: 2},
+			}}},
+		}}
+		sb := NewSchemaBuilder(&PostgresDialect{}, "", dataConfig)
+		expected := "CREATE TABLE \"order\" (\n" +
+			"	\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
+			"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+			"	\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+			"	\"sku\" TEXT NOT NULL,\n" +
+			"	\"customer_id\" UUID NOT NULL,\n" +
+			"	\"referrer_id\" UUID,\n" +
+			"	CONSTRAINT \"fk_order_customer_id\" FOREIGN KEY (\"customer_id\") REFERENCES \"customer\" (\"id\") ON UPDATE CASCADE,\n" +
+			"	CONSTRAINT \"fk_order_referrer_id\" FOREIGN KEY (\"referrer_id\") REFERENCES \"customer\" (\"id\") ON DELETE SET NULL\n" +
+			");\n\n" +
+			"CREATE INDEX ON \"order\" (\"customer_id\");\n" +
+			"CREATE INDEX ON \"order\" (\"referrer_id\");\n"
+		assert.Equal(t, expected, sb.BuildCreateTable(&dataConfig.Models[1]))
+
+		sb = NewSchemaBuilder(NewSQLiteDialect(), "", dataConfig)
+		expected = "CREATE TABLE \"order_line\" (\n" +
+			"	\"id\" TEXT PRIMARY KEY NOT NULL DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89AB', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),\n" +
+			"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+			"	\"updated_at\" TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),\n" +
+			"	\"order_id\" TEXT NOT NULL,\n" +
+			"	CONSTRAINT \"fk_order_line_order_id\" FOREIGN KEY (\"order_id\") REFERENCES \"order\" (\"id\") ON DELETE CASCADE\n" +
+			");\n\n" +
+			"CREATE INDEX \"idx_order_line_order_id\" ON \"order_line\" (\"order_id\");\n"
+		assert.Equal(t, expected, sb.BuildCreateTable(&dataConfig.Models[2]))
+	})
 }
@@ -0,0 +1,446 @@
+package datahelpers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/models"
+)
+
+// SchemaSnapshot is a data config along with the attribute catalog it was written against
+type SchemaSnapshot struct {
+	DataConfig defs.DataConfig
+	// Attributes resolves the attribute ids of the models, the loaded catalog (config.Attributes) when nil
+	Attributes map[int64]models.AttributeRow
+}
+
+// Migration holds the statements taking a schema from one snapshot to the next (Up) and back (Down), in execution order
+type Migration struct {
+	Up   []string
+	Down []string
+}
+
+func (m *Migration) IsEmpty() bool {
+	return len(m.Up) == 0
+}
+
+func (m *Migration) UpSQL() string {
+	return joinStatements(m.Up)
+}
+
+func (m *Migration) DownSQL() string {
+	return joinStatements(m.Down)
+}
+
+func joinStatements(statements []string) string {
+	if len(statements) == 0 {
+		return ""
+	}
+	return strings.Join(statements, "\n\n") + "\n"
+}
+
+// Phases of a migration, up runs them in this order and down undoes them in the reverse order.
+// Foreign keys and indexes are dropped first and created last, so that they never refer to missing
+// columns or stale table names.
+const (
+	phaseDropForeignKeys = iota
+	phaseDropIndexes
+	phaseDropTables
+	phaseRenameTables
+	phaseRenameColumns
+	phaseDropColumns
+	phaseAddColumns
+	phaseAlterColumns
+	phaseCreateTables
+	phaseAddForeignKeys
+	phaseCreateIndexes
+	phaseCount
+)
+
+type migrationStep struct {
+	up   string
+	down string
+}
+
+type schemaDiff struct {
+	dialect Dialect
+	from    *SchemaBuilder
+	to      *SchemaBuilder
+	phases  [phaseCount][]migrationStep
+	errs    []error
+}
+
+// DiffSchemas compares two snapshots of a family and returns the migration between them.
+//
+// Models are matched by their numeric id (by name when the id is 0), so a renamed model renames its table,
+// and attributes are matched by id, so a renamed attribute renames its column. Attribute names and types are
+// looked up in the catalog of each snapshot, a type with a different database type in dialect alters the column.
+// Indexes (filters, model indexes and unique constraints, as in BuildCreateTable) are matched by dialect.IndexName.
+// Foreign keys are matched by column, new tables are created after the tables they reference.
+// Columns added to existing tables can't be NOT NULL without a DEFAULT (or NOT NULL references), which would fail on
+// tables with rows, they are added with a DEFAULT or as nullable columns.
+func DiffSchemas(dialect Dialect, from, to SchemaSnapshot) (*Migration, error) {
+	diff := &schemaDiff{
+		dialect: dialect,
+		from:    NewSchemaBuilder(dialect, "", from.DataConfig).WithAttributes(from.Attributes),
+		to:      NewSchemaBuilder(dialect, "", to.DataConfig).WithAttributes(to.Attributes),
+	}
+
+	fromModels, err := modelsByKey(from.DataConfig.Models)
+	if err != nil {
+		return nil, fmt.Errorf("from: %w", err)
+	}
+	toModels, err := modelsByKey(to.DataConfig.Models)
+	if err != nil {
+		return nil, fmt.Errorf("to: %w", err)
+	}
+
+	fromOrder, err := from.DataConfig.ModelsInReferenceOrder()
+	if err != nil {
+		return nil, fmt.Errorf("from: %w", err)
+	}
+	toOrder, err := to.DataConfig.ModelsInReferenceOrder()
+	if err != nil {
+		return nil, fmt.Errorf("to: %w", err)
+	}
+
+	// Tables referencing others are dropped first
+	for i := len(fromOrder) - 1; i >= 0; i-- {
+		model := fromOrder[i]
+		if _, ok := toModels[modelKey(model.Model)]; !ok {
+			diff.dropTable(model)
+		}
+	}
+	for _, model := range toOrder {
+		if previous, ok := fromModels[modelKey(model.Model)]; ok {
+			diff.alterTable(previous, model)
+		} else {
+			diff.createTable(model)
+		}
+	}
+	// The outbox table comes and goes with the accesses with events, see BuildCreateOutboxTable
+	if hasEvents := to.DataConfig.HasEvents(); hasEvents != from.DataConfig.HasEvents() {
+		if hasEvents {
+			diff.add(phaseCreateTables, diff.to.BuildCreateOutboxTable(), diff.dropTableSQL(OutboxTable))
+		} else {
+			diff.add(phaseDropTables, diff.dropTableSQL(OutboxTable), diff.from.BuildCreateOutboxTable())
+		}
+	}
+	if err := errors.Join(diff.errs...); err != nil {
+		return nil, err
+	}
+
+	migration := &Migration{Up: []string{}, Down: []string{}}
+	for phase := 0; phase < phaseCount; phase++ {
+		for _, step := range diff.phases[phase] {
+			migration.Up = append(migration.Up, step.up)
+		}
+	}
+	for phase := phaseCount - 1; phase >= 0; phase-- {
+		steps := diff.phases[phase]
+		for i := len(steps) - 1; i >= 0; i-- {
+			migration.Down = append(migration.Down, steps[i].down)
+		}
+	}
+	return migration, nil
+}
+
+func modelKey(model defs.Model) string {
+	if model.ID != 0 {
+		return fmt.Sprintf("id:%d", model.ID)
+	}
+	return "name:" + strcase.ToSnake(model.Name)
+}
+
+func modelsByKey(modelConfigs []defs.ModelConfig) (map[string]*defs.ModelConfig, error) {
+	byKey := make(map[string]*defs.ModelConfig, len(modelConfigs))
+	for i := range modelConfigs {
+		key := modelKey(modelConfigs[i].Model)
+		if other, ok := byKey[key]; ok {
+			return nil, fmt.Errorf("models %s and %s have the same id %d", other.Model.Name, modelConfigs[i].Model.Name, other.Model.ID)
+		}
+		byKey[key] = &modelConfigs[i]
+	}
+	return byKey, nil
+}
+
+func (d *schemaDiff) add(phase int, up, down string) {
+	d.phases[phase] = append(d.phases[phase], migrationStep{up: up, down: down})
+}
+
+func (d *schemaDiff) createTable(model *defs.ModelConfig) {
+	if err := d.checkAttributes(d.to, model); err != nil {
+		d.errs = append(d.errs, err)
+		return
+	}
+	d.add(phaseCreateTables, strings.TrimSpace(d.to.BuildCreateTable(model)), d.dropTablesSQL(model))
+}
+
+func (d *schemaDiff) dropTable(model *defs.ModelConfig) {
+	if err := d.checkAttributes(d.from, model); err != nil {
+		d.errs = append(d.errs, err)
+		return
+	}
+	d.add(phaseDropTables, d.dropTablesSQL(model), strings.TrimSpace(d.from.BuildCreateTable(model)))
+}
+
+func (d *schemaDiff) dropTableSQL(table string) string {
+	return fmt.Sprintf("DROP TABLE %s;", d.dialect.FormatIdentifier(table))
+}
+
+// dropTablesSQL drops the tables BuildCreateTable creates for a model, its table and the history table of audit
+func (d *schemaDiff) dropTablesSQL(model *defs.ModelConfig) string {
+	if model.Model.Audit {
+		return d.dropTableSQL(HistoryTable(model.Name)) + "\n\n" + d.dropTableSQL(model.Name)
+	}
+	return d.dropTableSQL(model.Name)
+}
+
+// checkAttributes makes sure BuildCreateTable finds a column name and type for every attribute of the model
+func (d *schemaDiff) checkAttributes(sb *SchemaBuilder, model *defs.ModelConfig) error {
+	errs := []error{}
+	for _, attrId := range model.Attributes {
+		if _, _, _, err := d.column(sb, model, attrId); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// column returns the column name, database type and constraints (see ColumnConstraints) of an attribute,
+// resolved against the catalog of sb
+func (d *schemaDiff) column(sb *SchemaBuilder, model *defs.ModelConfig, attrId int64) (string, string, string, error) {
+	attribute, ok := sb.attribute(attrId)
+	if !ok {
+		return "", "", "", fmt.Errorf("model %s: attribute %d not found in catalog", model.Name, attrId)
+	}
+	columnType, err := d.dialect.DatabaseType(attribute.TypeId)
+	if err != nil {
+		return "", "", "", fmt.Errorf("model %s: attribute %d (%s): %w", model.Name, attrId, attribute.Name, err)
+	}
+	return strcase.ToSnake(attribute.Name), columnType, ColumnConstraints(attribute), nil
+}
+
+func (d *schemaDiff) alterTable(previous, model *defs.ModelConfig) {
+	fromTable := strcase.ToSnake(previous.Name)
+	table := strcase.ToSnake(model.Name)
+	formattedTable := d.dialect.FormatIdentifier(table)
+	if fromTable != table {
+		d.add(phaseRenameTables,
+			fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", d.dialect.FormatIdentifier(fromTable), formattedTable),
+			fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", formattedTable, d.dialect.FormatIdentifier(fromTable)))
+		if previous.Model.Audit && model.Model.Audit {
+			fromHistory, history := d.dialect.FormatIdentifier(HistoryTable(fromTable)), d.dialect.FormatIdentifier(HistoryTable(table))
+			d.add(phaseRenameTables,
+				fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", fromHistory, history),
+				fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", history, fromHistory))
+		}
+	}
+
+	for _, attrId := range previous.Attributes {
+		if slices.Contains(model.Attributes, attrId) {
+			continue
+		}
+		name, columnType, constraints, err := d.column(d.from, previous, attrId)
+		if err != nil {
+			d.errs = append(d.errs, err)
+			continue
+		}
+		d.add(phaseDropColumns,
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", formattedTable, d.dialect.FormatIdentifier(name)),
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s%s;", formattedTable, d.dialect.FormatIdentifier(name), columnType, constraints))
+	}
+
+	for _, attrId := range model.Attributes {
+		name, columnType, constraints, err := d.column(d.to, model, attrId)
+		if err != nil {
+			d.errs = append(d.errs, err)
+			continue
+		}
+		if !slices.Contains(previous.Attributes, attrId) {
+			// Rows of the table would have no value for the column
+			if attribute, _ := d.to.attribute(attrId); attribute.NotNull && attribute.Default == "" {
+				d.errs = append(d.errs, fmt.Errorf("model %s: new column %s is NOT NULL without a DEFAULT, which fails on a table with rows", model.Name, name))
+				continue
+			}
+			d.add(phaseAddColumns,
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s%s;", formattedTable, d.dialect.FormatIdentifier(name), columnType, constraints),
+				fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", formattedTable, d.dialect.FormatIdentifier(name)))
+			continue
+		}
+
+		fromName, fromType, fromConstraints, err := d.column(d.from, previous, attrId)
+		if err != nil {
+			d.errs = append(d.errs, err)
+			continue
+		}
+		if fromConstraints != constraints {
+			d.errs = append(d.errs, fmt.Errorf("model %s: column %s changes NOT NULL or DEFAULT, which is not supported", model.Name, name))
+			continue
+		}
+		if fromName != name {
+			d.add(phaseRenameColumns,
+				fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;", formattedTable, d.dialect.FormatIdentifier(fromName), d.dialect.FormatIdentifier(name)),
+				fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;", formattedTable, d.dialect.FormatIdentifier(name), d.dialect.FormatIdentifier(fromName)))
+		}
+		if fromType != columnType {
+			up, upErr := d.dialect.FormatAlterColumnType(table, name, columnType)
+			down, downErr := d.dialect.FormatAlterColumnType(table, name, fromType)
+			if err := errors.Join(upErr, downErr); err != nil {
+				d.errs = append(d.errs, fmt.Errorf("model %s: %w", model.Name, err))
+				continue
+			}
+			d.add(phaseAlterColumns, up, down)
+		}
+	}
+
+	// The deleted_at column comes and goes with soft_delete, see ModelSystemColumns
+	if previous.Model.SoftDelete != model.Model.SoftDelete {
+		column := d.dialect.FormatIdentifier(SoftDeleteColumn.Name)
+		addColumn := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", formattedTable, column, d.dialect.SystemColumnDefinition(SoftDeleteColumn.Name))
+		dropColumn := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", formattedTable, column)
+		if model.Model.SoftDelete {
+			d.add(phaseAddColumns, addColumn, dropColumn)
+		} else {
+			d.add(phaseDropColumns, dropColumn, addColumn)
+		}
+	}
+
+	// The history table comes and goes with audit, see BuildCreateHistoryTable
+	if previous.Model.Audit != model.Model.Audit {
+		if model.Model.Audit {
+			d.add(phaseCreateTables, d.to.BuildCreateHistoryTable(model), d.dropTableSQL(HistoryTable(table)))
+		} else {
+			d.add(phaseDropTables, d.dropTableSQL(HistoryTable(fromTable)), d.from.BuildCreateHistoryTable(previous))
+		}
+	}
+
+	d.diffReferences(previous, model)
+	d.diffIndexes(fromTable, d.from.modelIndexes(previous), table, d.to.modelIndexes(model))
+}
+
+// diffReferences adds and drops the foreign key columns of a table, a constraint that changes
+// (referential actions, a renamed table) is dropped and added again
+func (d *schemaDiff) diffReferences(previous, model *defs.ModelConfig) {
+	fromTable := strcase.ToSnake(previous.Name)
+	table := strcase.ToSnake(model.Name)
+	fromReferences, fromErr := d.from.dataConfig.References(&previous.Model)
+	references, err := d.to.dataConfig.References(&model.Model)
+	if err := errors.Join(fromErr, err); err != nil {
+		d.errs = append(d.errs, err)
+		return
+	}
+	byColumn := make(map[string]defs.Reference, len(references))
+	for _, reference := range references {
+		byColumn[reference.Column] = reference
+	}
+	fromByColumn := make(map[string]defs.Reference, len(fromReferences))
+	for _, reference := range fromReferences {
+		fromByColumn[reference.Column] = reference
+	}
+
+	for _, fromReference := range fromReferences {
+		reference, ok := byColumn[fromReference.Column]
+		if ok && d.from.foreignKeyDefinition(fromReference) == d.to.foreignKeyDefinition(reference) && fromTable == table {
+			continue
+		}
+		d.add(phaseDropForeignKeys, d.dropForeignKey(d.from, fromTable, fromReference), d.addForeignKey(d.from, fromTable, fromReference))
+		if !ok {
+			// Columns are dropped once the table is renamed
+			formattedTable := d.dialect.FormatIdentifier(table)
+			d.add(phaseDropColumns,
+				fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", formattedTable, d.dialect.FormatIdentifier(fromReference.Column)),
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", formattedTable, d.from.referenceColumnDefinition(fromReference)))
+		}
+	}
+	for _, reference := range references {
+		fromReference, ok := fromByColumn[reference.Column]
+		if ok && d.from.foreignKeyDefinition(fromReference) == d.to.foreignKeyDefinition(reference) && fromTable == table {
+			continue
+		}
+		if !ok {
+			if reference.NotNull {
+				d.errs = append(d.errs, fmt.Errorf("model %s: new reference column %s is NOT NULL, which fails on a table with rows", model.Name, reference.Column))
+				continue
+			}
+			formattedTable := d.dialect.FormatIdentifier(table)
+			d.add(phaseAddColumns,
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", formattedTable, d.to.referenceColumnDefinition(reference)),
+				fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", formattedTable, d.dialect.FormatIdentifier(reference.Column)))
+		} else if fromReference.NotNull != reference.NotNull {
+			d.errs = append(d.errs, fmt.Errorf("model %s: reference column %s changes nullability, which is not supported", model.Name, reference.Column))
+			continue
+		}
+		d.add(phaseAddForeignKeys, d.addForeignKey(d.to, table, reference), d.dropForeignKey(d.to, table, reference))
+	}
+}
+
+func (d *schemaDiff) addForeignKey(sb *SchemaBuilder, table string, reference defs.Reference) string {
+	statement, err := d.dialect.FormatAddForeignKey(table, ForeignKeyName(table, reference.Column), sb.foreignKeyDefinition(reference))
+	if err != nil {
+		d.errs = append(d.errs, err)
+	}
+	return statement
+}
+
+func (d *schemaDiff) dropForeignKey(sb *SchemaBuilder, table string, reference defs.Reference) string {
+	statement, err := d.dialect.FormatDropForeignKey(table, ForeignKeyName(table, reference.Column))
+	if err != nil {
+		d.errs = append(d.errs, err)
+	}
+	return statement
+}
+
+func (d *schemaDiff) diffIndexes(fromTable string, fromIndexes map[string]indexItem, table string, indexes map[string]indexItem) {
+	fromByName := d.indexesByName(fromTable, fromIndexes)
+	byName := d.indexesByName(table, indexes)
+
+	for _, name := range sortedKeys(fromByName) {
+		if _, ok := byName[name]; ok {
+			continue
+		}
+		item := fromByName[name]
+		d.add(phaseDropIndexes,
+			d.dialect.FormatDropIndex(fromTable, d.dialect.IndexName(fromTable, item.columns, item.isUnique)),
+			d.dialect.FormatCreateIndex(fromTable, item.columns, item.isUnique, item.where))
+	}
+	for _, name := range sortedKeys(byName) {
+		if _, ok := fromByName[name]; ok {
+			continue
+		}
+		item := byName[name]
+		d.add(phaseCreateIndexes,
+			d.dialect.FormatCreateIndex(table, item.columns, item.isUnique, item.where),
+			d.dialect.FormatDropIndex(table, d.dialect.IndexName(table, item.columns, item.isUnique)))
+	}
+}
+
+// indexesByName keys indexes by their name in the dialect, along with uniqueness and the condition of partial
+// indexes for dialects naming them alike
+func (d *schemaDiff) indexesByName(table string, indexes map[string]indexItem) map[string]indexItem {
+	byName := make(map[string]indexItem, len(indexes))
+	for _, item := range indexes {
+		key := d.dialect.IndexName(table, item.columns, item.isUnique)
+		if item.isUnique {
+			key += " unique"
+		}
+		if item.where != "" {
+			key += " where " + item.where
+		}
+		byName[key] = item
+	}
+	return byName
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
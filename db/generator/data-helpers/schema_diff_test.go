@@ -0,0 +1,297 @@
+package datahelpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/models"
+)
+
+func catalogAttribute(id int64, name string, typeId int64) models.AttributeRow {
+	return models.AttributeRow{UniqueID: models.UniqueID{ID: id, Name: name}, TypeId: typeId}
+}
+
+func diffSnapshots() (SchemaSnapshot, SchemaSnapshot) {
+	from := SchemaSnapshot{
+		Attributes: map[int64]models.AttributeRow{
+			1: catalogAttribute(1, "sku", 1000001),
+			2: catalogAttribute(2, "name", 1000001),
+			3: catalogAttribute(3, "price", 1000003),
+			4: catalogAttribute(4, "legacy", 1000001),
+		},
+		DataConfig: defs.DataConfig{Models: []defs.ModelConfig{
+			{
+				Model: defs.Model{ID: 1, Name: "Product", Attributes: []int64{1, 2, 3, 4},
+					UniqueConstraints: []defs.UniqueConstraint{{ConstraintName: "uq_sku", Attributes: []int64{1}}}},
+				Access: defs.Access{Find: []defs.AccessConfig{{Filter: []defs.Filter{{Attribute: "name", Operator: "="}}}}},
+			},
+			{Model: defs.Model{ID: 2, Name: "Review", Attributes: []int64{1}}},
+		}},
+	}
+	to := SchemaSnapshot{
+		Attributes: map[int64]models.AttributeRow{
+			1: catalogAttribute(1, "sku", 1000001),
+			2: catalogAttribute(2, "title", 1000001),
+			3: catalogAttribute(3, "price", 1000005),
+			5: catalogAttribute(5, "stock", 1000004),
+		},
+		DataConfig: defs.DataConfig{Models: []defs.ModelConfig{
+			{
+				Model: defs.Model{ID: 1, Name: "Item", Attributes: []int64{1, 2, 3, 5},
+					UniqueConstraints: []defs.UniqueConstraint{{ConstraintName: "uq_sku", Attributes: []int64{1}}}},
+				Access: defs.Access{Find: []defs.AccessConfig{{Filter: []defs.Filter{{Attribute: "title", Operator: "="}}}}},
+			},
+			{Model: defs.Model{ID: 3, Name: "Stock", Attributes: []int64{5}}},
+		}},
+	}
+	return from, to
+}
+
+func TestDiffSchemas(t *testing.T) {
+	config.LoadConfig()
+
+	t.Run("Postgres", func(t *testing.T) {
+		from, to := diffSnapshots()
+		migration, err := DiffSchemas(NewPostgresDialect(), from, to)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"DROP INDEX \"product_name_idx\";",
+			"DROP INDEX \"product_sku_idx\";",
+			"DROP TABLE \"review\";",
+			"ALTER TABLE \"product\" RENAME TO \"item\";",
+			"ALTER TABLE \"item\" RENAME COLUMN \"name\" TO \"title\";",
+			"ALTER TABLE \"item\" DROP COLUMN \"legacy\";",
+			"ALTER TABLE \"item\" ADD COLUMN \"stock\" INTEGER;",
+			"ALTER TABLE \"item\" ALTER COLUMN \"price\" TYPE NUMERIC(33,18) USING \"price\"::NUMERIC(33,18);",
+			"CREATE TABLE \"stock\" (\n" +
+				"	\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
+				"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+				"	\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+				"	\"stock\" INTEGER\n" +
+				");",
+			"CREATE UNIQUE INDEX ON \"item\" (\"sku\");",
+			"CREATE INDEX ON \"item\" (\"title\");",
+		}, migration.Up)
+		assert.Equal(t, []string{
+			"DROP INDEX \"item_title_idx\";",
+			"DROP INDEX \"item_sku_idx\";",
+			"DROP TABLE \"stock\";",
+			"ALTER TABLE \"item\" ALTER COLUMN \"price\" TYPE DECIMAL USING \"price\"::DECIMAL;",
+			"ALTER TABLE \"item\" DROP COLUMN \"stock\";",
+			"ALTER TABLE \"item\" ADD COLUMN \"legacy\" TEXT;",
+			"ALTER TABLE \"item\" RENAME COLUMN \"title\" TO \"name\";",
+			"ALTER TABLE \"item\" RENAME TO \"product\";",
+			"CREATE TABLE \"review\" (\n" +
+				"	\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
+				"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+				"	\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+				"	\"sku\" TEXT\n" +
+				");",
+			"CREATE UNIQUE INDEX ON \"product\" (\"sku\");",
+			"CREATE INDEX ON \"product\" (\"name\");",
+		}, migration.Down)
+	})
+
+	t.Run("MySQL", func(t *testing.T) {
+		from, to := diffSnapshots()
+		from.DataConfig.Models, to.DataConfig.Models = from.DataConfig.Models[:1], to.DataConfig.Models[:1]
+		to.DataConfig.Models[0].Model.Name = "Product"
+		to.Attributes[2] = catalogAttribute(2, "name", 1000001)
+		to.DataConfig.Models[0].Access.Find[0].Filter[0].Attribute = "name"
+
+		migration, err := DiffSchemas(NewMySQLDialect(), from, to)
+		assert.NoError(t, err)
+		assert.Equal(t, "ALTER TABLE `product` DROP COLUMN `legacy`;\n\n"+
+			"ALTER TABLE `product` ADD COLUMN `stock` INT;\n\n"+
+			"ALTER TABLE `product` MODIFY COLUMN `price` DECIMAL(33,18);\n", migration.UpSQL())
+		assert.Equal(t, "ALTER TABLE `product` MODIFY COLUMN `price` DECIMAL(38,18);\n\n"+
+			"ALTER TABLE `product` DROP COLUMN `stock`;\n\n"+
+			"ALTER TABLE `product` ADD COLUMN `legacy` VARCHAR(255);\n", migration.DownSQL())
+	})
+
+	t.Run("IndexChanges", func(t *testing.T) {
+		from, _ := diffSnapshots()
+		from.DataConfig.Models = from.DataConfig.Models[:1]
+		to := SchemaSnapshot{Attributes: from.Attributes, DataConfig: defs.DataConfig{Models: []defs.ModelConfig{from.DataConfig.Models[0]}}}
+		to.DataConfig.Models[0].Model.UniqueConstraints = nil
+		to.DataConfig.Models[0].Model.Indexes = []defs.ModelIndex{{IndexName: "idx_sku_price", Attributes: []int64{1, 3}}}
+
+		migration, err := DiffSchemas(NewMySQLDialect(), from, to)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"DROP INDEX `uidx_product_sku` ON `product`;",
+			"CREATE INDEX `idx_product_sku_price` ON `product` (`sku`, `price`);",
+		}, migration.Up)
+		assert.Equal(t, []string{
+			"DROP INDEX `idx_product_sku_price` ON `product`;",
+			"CREATE UNIQUE INDEX `uidx_product_sku` ON `product` (`sku`);",
+		}, migration.Down)
+	})
+
+	t.Run("SoftDelete", func(t *testing.T) {
+		from, _ := diffSnapshots()
+		from.DataConfig.Models = from.DataConfig.Models[:1]
+		to := SchemaSnapshot{Attributes: from.Attributes, DataConfig: defs.DataConfig{Models: []defs.ModelConfig{from.DataConfig.Models[0]}}}
+		to.DataConfig.Models[0].Model.SoftDelete = true
+
+		migration, err := DiffSchemas(NewPostgresDialect(), from, to)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"DROP INDEX \"product_sku_idx\";",
+			"ALTER TABLE \"product\" ADD COLUMN \"deleted_at\" TIMESTAMP WITH TIME ZONE;",
+			"CREATE UNIQUE INDEX ON \"product\" (\"sku\") WHERE (deleted_at IS NULL);",
+		}, migration.Up)
+		assert.Equal(t, []string{
+			"DROP INDEX \"product_sku_idx\";",
+			"ALTER TABLE \"product\" DROP COLUMN \"deleted_at\";",
+			"CREATE UNIQUE INDEX ON \"product\" (\"sku\");",
+		}, migration.Down)
+	})
+
+	t.Run("Audit", func(t *testing.T) {
+		from, _ := diffSnapshots()
+		from.DataConfig.Models = from.DataConfig.Models[:1]
+		to := SchemaSnapshot{Attributes: from.Attributes, DataConfig: defs.DataConfig{Models: []defs.ModelConfig{from.DataConfig.Models[0]}}}
+		to.DataConfig.Models[0].Model.Audit = true
+
+		migration, err := DiffSchemas(NewPostgresDialect(), from, to)
+		assert.NoError(t, err)
+		assert.Len(t, migration.Up, 1)
+		assert.Contains(t, migration.Up[0], "CREATE TABLE \"product_history\" (")
+		assert.Contains(t, migration.Up[0], "CREATE INDEX ON \"product_history\" (\"row_id\");")
+		assert.Equal(t, []string{"DROP TABLE \"product_history\";"}, migration.Down)
+
+		// A renamed table renames its history table, a new table is created along with it
+		to.DataConfig.Models[0].Model.Name = "Item"
+		to.DataConfig.Models = append(to.DataConfig.Models, defs.ModelConfig{Model: defs.Model{ID: 3, Name: "Stock", Audit: true}})
+		from.DataConfig.Models[0].Model.Audit = true
+		migration, err = DiffSchemas(NewPostgresDialect(), from, to)
+		assert.NoError(t, err)
+		assert.Contains(t, migration.Up, "ALTER TABLE \"product_history\" RENAME TO \"item_history\";")
+		assert.Contains(t, migration.Down, "ALTER TABLE \"item_history\" RENAME TO \"product_history\";")
+		assert.Contains(t, migration.Down, "DROP TABLE \"stock_history\";\n\nDROP TABLE \"stock\";")
+	})
+
+	t.Run("Events", func(t *testing.T) {
+		from, _ := diffSnapshots()
+		from.DataConfig.Models = from.DataConfig.Models[:1]
+		to := SchemaSnapshot{Attributes: from.Attributes, DataConfig: defs.DataConfig{Models: []defs.ModelConfig{from.DataConfig.Models[0]}}}
+		to.DataConfig.Models[0].Access.Delete = []defs.AccessConfig{{Name: "DeleteProduct", Events: true}}
+
+		migration, err := DiffSchemas(NewPostgresDialect(), from, to)
+		assert.NoError(t, err)
+		assert.Len(t, migration.Up, 1)
+		assert.Contains(t, migration.Up[0], "CREATE TABLE \"outbox_events\" (")
+		assert.Contains(t, migration.Up[0], "CREATE INDEX ON \"outbox_events\" (\"id\") WHERE sent_at IS NULL;")
+		assert.Equal(t, []string{"DROP TABLE \"outbox_events\";"}, migration.Down)
+
+		// The outbox is dropped with the last access with events
+		migration, err = DiffSchemas(NewPostgresDialect(), to, from)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"DROP TABLE \"outbox_events\";"}, migration.Up)
+	})
+
+	t.Run("References", func(t *testing.T) {
+		attributes := map[int64]models.AttributeRow{1: catalogAttribute(1, "sku", 1000001)}
+		from := SchemaSnapshot{Attributes: attributes, DataConfig: defs.DataConfig{Models: []defs.ModelConfig{
+			{Model: defs.Model{ID: 1, Name: "Customer"}},
+			{Model: defs.Model{ID: 2, Name: "Order", Relationships: []defs.Relationship{
+				{Type: "BelongsTo", TargetModelID: 1},
+				{Type: "Refers", TargetModelID: 1, Name: "referrer", OnDelete: "SET NULL"},
+			}}},
+		}}}
+		// Line is listed before the order it references and is created after it
+		to := SchemaSnapshot{Attributes: attributes, DataConfig: defs.DataConfig{Models: []defs.ModelConfig{
+			{Model: defs.Model{ID: 3, Name: "Line", Attributes: []int64{1}, Relationships: []defs.Relationship{{Type: "Child", TargetModelID: 2}}}},
+			{Model: defs.Model{ID: 1, Name: "Customer"}},
+			{Model: defs.Model{ID: 2, Name: "Order", Relationships: []defs.Relationship{
+				{Type: "BelongsTo", TargetModelID: 1, OnDelete: "RESTRICT"},
+			}}},
+		}}}
+
+		migration, err := DiffSchemas(NewPostgresDialect(), from, to)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"ALTER TABLE \"order\" DROP CONSTRAINT \"fk_order_customer_id\";",
+			"ALTER TABLE \"order\" DROP CONSTRAINT \"fk_order_referrer_id\";",
+			"DROP INDEX \"order_referrer_id_idx\";",
+			"ALTER TABLE \"order\" DROP COLUMN \"referrer_id\";",
+			"CREATE TABLE \"line\" (\n" +
+				"	\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
+				"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+				"	\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+				"	\"sku\" TEXT,\n" +
+				"	\"order_id\" UUID NOT NULL,\n" +
+				"	CONSTRAINT \"fk_line_order_id\" FOREIGN KEY (\"order_id\") REFERENCES \"order\" (\"id\") ON DELETE CASCADE\n" +
+				");\n\n" +
+				"CREATE INDEX ON \"line\" (\"order_id\");",
+			"ALTER TABLE \"order\" ADD CONSTRAINT \"fk_order_customer_id\" FOREIGN KEY (\"customer_id\") REFERENCES \"customer\" (\"id\") ON DELETE RESTRICT;",
+		}, migration.Up)
+		assert.Equal(t, []string{
+			"ALTER TABLE \"order\" DROP CONSTRAINT \"fk_order_customer_id\";",
+			"DROP TABLE \"line\";",
+			"ALTER TABLE \"order\" ADD COLUMN \"referrer_id\" UUID;",
+			"CREATE INDEX ON \"order\" (\"referrer_id\");",
+			"ALTER TABLE \"order\" ADD CONSTRAINT \"fk_order_referrer_id\" FOREIGN KEY (\"referrer_id\") REFERENCES \"customer\" (\"id\") ON DELETE SET NULL;",
+			"ALTER TABLE \"order\" ADD CONSTRAINT \"fk_order_customer_id\" FOREIGN KEY (\"customer_id\") REFERENCES \"customer\" (\"id\");",
+		}, migration.Down)
+
+		to.DataConfig.Models[1].Model.Relationships = []defs.Relationship{{Type: "BelongsTo", TargetModelID: 3}}
+		_, err = DiffSchemas(NewPostgresDialect(), from, to)
+		assert.EqualError(t, err, "to: relationships form a cycle: Line -> Order -> Customer -> Line")
+	})
+
+	t.Run("NoChanges", func(t *testing.T) {
+		from, _ := diffSnapshots()
+		migration, err := DiffSchemas(NewPostgresDialect(), from, from)
+		assert.NoError(t, err)
+		assert.True(t, migration.IsEmpty())
+		assert.Equal(t, "", migration.UpSQL())
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		from, to := diffSnapshots()
+		to.Attributes[3] = catalogAttribute(3, "price", 1000004)
+		_, err := DiffSchemas(NewSQLiteDialect(), from, to)
+		assert.EqualError(t, err, "model Item: sqlite can't change the type of column item.price to INTEGER in place\n"+
+			"sqlite can't change the type of column item.price to REAL in place")
+
+		from, to = diffSnapshots()
+		delete(to.Attributes, 5)
+		to.DataConfig.Models = append(to.DataConfig.Models, defs.ModelConfig{Model: defs.Model{ID: 3, Name: "Duplicate"}})
+		_, err = DiffSchemas(NewPostgresDialect(), from, to)
+		assert.EqualError(t, err, "to: models Stock and Duplicate have the same id 3")
+
+		to.DataConfig.Models = to.DataConfig.Models[:2]
+		_, err = DiffSchemas(NewPostgresDialect(), from, to)
+		assert.EqualError(t, err, "model Item: attribute 5 not found in catalog\nmodel Stock: attribute 5 not found in catalog")
+
+		from, to = diffSnapshots()
+		sku := to.Attributes[1]
+		sku.NotNull = true
+		to.Attributes[1] = sku
+		_, err = DiffSchemas(NewPostgresDialect(), from, to)
+		assert.EqualError(t, err, "model Item: column sku changes NOT NULL or DEFAULT, which is not supported")
+
+		// New NOT NULL columns need a DEFAULT for the rows of the table, the columns of a new table don't
+		from, to = diffSnapshots()
+		stock := to.Attributes[5]
+		stock.NotNull = true
+		to.Attributes[5] = stock
+		_, err = DiffSchemas(NewPostgresDialect(), from, to)
+		assert.EqualError(t, err, "model Item: new column stock is NOT NULL without a DEFAULT, which fails on a table with rows")
+
+		stock.Default = "0"
+		to.Attributes[5] = stock
+		_, err = DiffSchemas(NewPostgresDialect(), from, to)
+		assert.NoError(t, err)
+
+		from, to = diffSnapshots()
+		to.DataConfig.Models[1].Model.ID = 2
+		to.DataConfig.Models[1].Model.Relationships = []defs.Relationship{{Type: "Child", TargetModelID: 1}}
+		_, err = DiffSchemas(NewPostgresDialect(), from, to)
+		assert.EqualError(t, err, "model Stock: new reference column item_id is NOT NULL, which fails on a table with rows")
+	})
+}
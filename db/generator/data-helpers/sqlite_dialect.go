@@ -0,0 +1,125 @@
+package datahelpers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// SQLiteDialect targets SQLite 3.35+ (mattn/go-sqlite3, RETURNING, ON CONFLICT DO UPDATE without a conflict target):
+// double quoted identifiers, ?NNN placeholders, types by storage class (TEXT, INTEGER, REAL, BLOB) but for dates and
+// timestamps, and IN lists expanded to one placeholder per element.
+type SQLiteDialect struct {
+	BaseDialect
+}
+
+func NewSQLiteDialect() *SQLiteDialect {
+	return &SQLiteDialect{BaseDialect: BaseDialect{name: "sqlite"}}
+}
+
+func (d *SQLiteDialect) FormatIdentifier(name string) string {
+	return fmt.Sprintf(`"%s"`, strcase.ToSnake(name))
+}
+
+func (d *SQLiteDialect) GetPlaceholder(index int) string {
+	return fmt.Sprintf("?%d", index)
+}
+
+func (d *SQLiteDialect) DatabaseType(typeId int64) (string, error) {
+	sqliteType := GetSQLiteType(typeId)
+	if sqliteType == "" {
+		return "", fmt.Errorf("type %d has no sqlite type mapping", typeId)
+	}
+	return sqliteType, nil
+}
+
+// Timestamps are stored as the text go-sqlite3 writes times as, so that they compare with bound times, in columns
+// declared TIMESTAMP, which go-sqlite3 scans into time.Time
+const sqliteNow = "strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')"
+
+// A random (version 4) UUID, as gen_random_uuid() of Postgres
+const sqliteRandomUUID = "lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || " +
+	"substr('89AB', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))"
+
+var sqliteSystemColumns = map[string]string{
+	"id":         "TEXT PRIMARY KEY NOT NULL DEFAULT (" + sqliteRandomUUID + ")",
+	"version":    "INTEGER NOT NULL DEFAULT 1",
+	"updated_at": "TIMESTAMP NOT NULL DEFAULT (" + sqliteNow + ")",
+	"deleted_at": "TIMESTAMP",
+}
+
+func (d *SQLiteDialect) SystemColumnDefinition(column string) string {
+	return sqliteSystemColumns[column]
+}
+
+// Ids are UUID text, as with Postgres
+func (d *SQLiteDialect) InsertsID() bool {
+	return true
+}
+
+func (d *SQLiteDialect) FormatReturning(columns ...string) string {
+	return fmt.Sprintf("%s %s", KeywordRETURNING, strings.Join(columns, ", "))
+}
+
+func (d *SQLiteDialect) FormatIn(attr, placeholder string, negate bool) (string, bool) {
+	operator := OperatorIN
+	if negate {
+		operator = OperatorNOTIN
+	}
+	return fmt.Sprintf("%s %s (%s)", attr, operator, placeholder), true
+}
+
+// The conflicting row is set from the excluded (proposed) row, so values aren't bound twice. Replacing a row increments
+// its version, inserted tells an inserted row (version 1) from a replaced one
+func (d *SQLiteDialect) FormatUpsert(target string, columns []string, bind func(i int) string) string {
+	updateClauses := make([]string, 0, len(columns)+1)
+	for _, column := range columns {
+		updateClauses = append(updateClauses, fmt.Sprintf("%s = excluded.%s", column, column))
+	}
+	updateClauses = append(updateClauses, autoincrementClause(d, []string{VersionColumn}))
+	return fmt.Sprintf("%s %s %s %s %s %s %s %s",
+		KeywordON, KeywordCONFLICT, target, KeywordDO, KeywordUPDATE, KeywordSET,
+		strings.Join(updateClauses, ", "),
+		d.FormatReturning(d.FormatIdentifier("id"), fmt.Sprintf("(%s = 1) AS inserted", d.FormatIdentifier(VersionColumn))))
+}
+
+func (d *SQLiteDialect) CurrentTimestamp() string {
+	return sqliteNow
+}
+
+// SQLite requires index names
+func (d *SQLiteDialect) IndexName(table string, columns []string, isUnique bool) string {
+	return indexName(table, columns, isUnique)
+}
+
+func (d *SQLiteDialect) FormatCreateIndex(table string, columns []string, isUnique bool, where string) string {
+	indexType := KeywordINDEX
+	if isUnique {
+		indexType = KeywordUNIQUE + " " + KeywordINDEX
+	}
+	return fmt.Sprintf("%s %s %s %s %s (%s)%s;", KeywordCREATE, indexType, d.FormatIdentifier(d.IndexName(table, columns, isUnique)), KeywordON,
+		d.FormatIdentifier(table), formatIdentifiers(d, columns), indexCondition(where))
+}
+
+func (d *SQLiteDialect) FormatDropIndex(table, indexName string) string {
+	return fmt.Sprintf("%s %s %s;", KeywordDROP, KeywordINDEX, d.FormatIdentifier(indexName))
+}
+
+// SQLite has no ALTER COLUMN, changing a type takes rebuilding the table
+func (d *SQLiteDialect) FormatAlterColumnType(table, column, columnType string) (string, error) {
+	return "", fmt.Errorf("sqlite can't change the type of column %s.%s to %s in place", table, column, columnType)
+}
+
+func (d *SQLiteDialect) ReferenceColumnType() string {
+	return "TEXT"
+}
+
+// SQLite has no ALTER TABLE ... ADD CONSTRAINT, foreign keys are only created with their table
+func (d *SQLiteDialect) FormatAddForeignKey(table, constraint, definition string) (string, error) {
+	return "", fmt.Errorf("sqlite can't add foreign key %s to table %s in place", constraint, table)
+}
+
+func (d *SQLiteDialect) FormatDropForeignKey(table, constraint string) (string, error) {
+	return "", fmt.Errorf("sqlite can't drop foreign key %s of table %s in place", constraint, table)
+}
@@ -0,0 +1,127 @@
+package datahelpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+func TestSQLitePreparedStmts(t *testing.T) {
+	t.Run("Find", func(t *testing.T) {
+		psb := NewPreparedStmtBuilderWithDialect(NewSQLiteDialect(), "users", defs.AccessConfig{
+			Attributes: []string{"id", "name"},
+			Filter: []defs.Filter{
+				{Attribute: "email", Operator: "=", ParamName: "email"},
+				{Attribute: "age", Operator: "BETWEEN", ParamName: "age_range"},
+				{Attribute: "status", Operator: "IN", ParamName: "statuses"},
+			},
+		})
+		query, params := psb.BuildFindPreparedStmt()
+		assert.Equal(t, `SELECT "id", "name" FROM "users" WHERE ("email" = ?1 AND ("age" BETWEEN ?2 AND ?3) AND "status" IN (?4))`, query)
+		assert.Equal(t, []defs.ParameterRef{
+			{Name: "email", Index: -1},
+			{Name: "age_range", Index: 0},
+			{Name: "age_range", Index: 1},
+			{Name: "statuses", Index: -1, Expand: true},
+		}, params)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		psb := NewPreparedStmtBuilderWithDialect(NewSQLiteDialect(), "products", defs.AccessConfig{
+			Set:              []defs.Update{{Attribute: "price", ParamName: "new_price"}},
+			Autoincrement:    []string{"version"},
+			CaptureTimestamp: []string{"updated_at"},
+			Filter:           []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "product_id"}},
+		})
+		query, params := psb.BuildUpdatePreparedStmt()
+		assert.Equal(t, `UPDATE "products" SET "price" = ?1, "version" = "version" + 1, `+
+			`"updated_at" = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now') WHERE ("id" = ?2)`, query)
+		assert.Equal(t, []defs.ParameterRef{
+			{Name: "new_price", Index: -1},
+			{Name: "product_id", Index: -1},
+		}, params)
+	})
+
+	t.Run("Add", func(t *testing.T) {
+		psb := NewPreparedStmtBuilderWithDialect(NewSQLiteDialect(), "products", defs.AccessConfig{
+			Values: []defs.Update{
+				{Attribute: "name", ParamName: "product_name"},
+				{Attribute: "price", ParamName: "product_price"},
+			},
+		})
+		query, params := psb.BuildAddPreparedStmt()
+		assert.Equal(t, `INSERT INTO "products" ("id", "name", "price") VALUES (?1, ?2, ?3) RETURNING id`, query)
+		assert.Equal(t, []defs.ParameterRef{
+			{FuncName: "UUIDV7"},
+			{Name: "product_name", Index: -1},
+			{Name: "product_price", Index: -1},
+		}, params)
+	})
+
+	t.Run("AddOrReplace", func(t *testing.T) {
+		psb := NewPreparedStmtBuilderWithDialect(NewSQLiteDialect(), "inventory", defs.AccessConfig{
+			Values: []defs.Update{
+				{Attribute: "product_id", ParamName: "product_id"},
+				{Attribute: "quantity", ParamName: "quantity"},
+			},
+			ConflictKey: []string{"product_id"},
+		})
+		query, params := psb.BuildAddOrReplacePreparedStmt()
+		assert.Equal(t, `INSERT INTO "inventory" ("id", "product_id", "quantity") VALUES (?1, ?2, ?3) `+
+			`ON CONFLICT ("product_id") DO UPDATE SET "product_id" = excluded."product_id", "quantity" = excluded."quantity", `+
+			`"version" = "version" + 1 RETURNING "id", ("version" = 1) AS inserted`, query)
+		assert.Equal(t, []defs.ParameterRef{
+			{FuncName: "UUIDV7"},
+			{Name: "product_id", Index: -1},
+			{Name: "quantity", Index: -1},
+		}, params)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		psb := NewPreparedStmtBuilderWithDialect(NewSQLiteDialect(), "orders", defs.AccessConfig{
+			Filter: []defs.Filter{
+				{Attribute: "status", Operator: "NOT IN", ParamName: "statuses"},
+				{Attribute: "created_at", Operator: "<", ParamName: "cutoff_date"},
+			},
+		})
+		query, params := psb.BuildDeletePreparedStmt()
+		assert.Equal(t, `DELETE FROM "orders" WHERE ("status" NOT IN (?1) AND "created_at" < ?2)`, query)
+		assert.Equal(t, []defs.ParameterRef{
+			{Name: "statuses", Index: -1, Expand: true},
+			{Name: "cutoff_date", Index: -1},
+		}, params)
+	})
+}
+
+func TestSQLiteBuildCreateTable(t *testing.T) {
+	config.LoadConfig()
+
+	sb := NewSchemaBuilder(NewSQLiteDialect(), "shop", defs.DataConfig{})
+	model := &defs.ModelConfig{
+		Model: defs.Model{
+			Name:       "products",
+			Attributes: []int64{2000001, 2000002, 2000003},
+			UniqueConstraints: []defs.UniqueConstraint{
+				{ConstraintName: "uq_sku", Attributes: []int64{2000001}},
+			},
+		},
+		Access: defs.Access{
+			Find: []defs.AccessConfig{{
+				Filter: []defs.Filter{{Attribute: "product_name", Operator: "="}},
+			}},
+		},
+	}
+	expected := "CREATE TABLE \"products\" (\n" +
+		"	\"id\" TEXT PRIMARY KEY NOT NULL DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89AB', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),\n" +
+		"	\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+		"	\"updated_at\" TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),\n" +
+		"	\"sku\" TEXT NOT NULL,\n" +
+		"	\"product_name\" TEXT NOT NULL,\n" +
+		"	\"description\" TEXT\n" +
+		");\n\n" +
+		"CREATE INDEX \"idx_products_product_name\" ON \"products\" (\"product_name\");\n" +
+		"CREATE UNIQUE INDEX \"uidx_products_sku\" ON \"products\" (\"sku\");\n"
+	assert.Equal(t, expected, sb.BuildCreateTable(model))
+}
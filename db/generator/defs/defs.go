@@ -1,54 +1,88 @@
 package defs
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"stellarsky.ai/platform/codegen/data-service-generator/config"
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
 	"stellarsky.ai/platform/codegen/data-service-generator/db/models"
 )
 
 type ParameterRef struct {
-	Name     string        `yaml:"name"`
-	Index    int32         `yaml:"index"`
-	FuncName string        `yaml:"func_name"`
-	FuncArgs []interface{} `yaml:"func_args"`
+	Name  string `yaml:"name" json:"name"`
+	Index int32  `yaml:"index" json:"index"`
+	// Expand marks a list bound to a single placeholder that has to be expanded to one placeholder per element by the
+	// code executing the query (MySQL and SQLite), the generated code runs such queries through an inListStmt
+	Expand   bool          `yaml:"expand" json:"expand"`
+	FuncName string        `yaml:"func_name" json:"func_name"`
+	FuncArgs []interface{} `yaml:"func_args" json:"func_args"`
 }
 
 type Filter struct {
-	Attribute      string   `yaml:"attribute,omitempty"`
-	Transformation string   `yaml:"transformation,omitempty"`
-	Operator       string   `yaml:"operator"`
-	ParamName      string   `yaml:"param_name,omitempty"`
-	Conditions     []Filter `yaml:"conditions,omitempty"`
+	Attribute      string   `yaml:"attribute,omitempty" json:"attribute,omitempty"`
+	Transformation string   `yaml:"transformation,omitempty" json:"transformation,omitempty"`
+	Operator       string   `yaml:"operator" json:"operator"`
+	ParamName      string   `yaml:"param_name,omitempty" json:"param_name,omitempty"`
+	Conditions     []Filter `yaml:"conditions,omitempty" json:"conditions,omitempty"`
 }
 
 type Model struct {
-	ID                int     `yaml:"id"`
-	Namespace         string  `yaml:"namespace"`
-	Family            string  `yaml:"family"`
-	Name              string  `yaml:"name"`
-	Attributes        []int64 `yaml:"attributes"`
-	UniqueConstraints []struct {
-		ConstraintName string  `yaml:"constraint_name"`
-		Attributes     []int64 `yaml:"attributes"`
-	} `yaml:"unique_constraints"`
-	Indexes []struct {
-		IndexName  string  `yaml:"index_name"`
-		Attributes []int64 `yaml:"attributes"`
-	} `yaml:"indexes"`
+	ID                int                `yaml:"id" json:"id"`
+	Namespace         string             `yaml:"namespace" json:"namespace"`
+	Family            string             `yaml:"family" json:"family"`
+	Name              string             `yaml:"name" json:"name"`
+	Attributes        []int64            `yaml:"attributes" json:"attributes"`
+	UniqueConstraints []UniqueConstraint `yaml:"unique_constraints" json:"unique_constraints"`
+	Indexes           []ModelIndex       `yaml:"indexes" json:"indexes"`
+	Relationships     []Relationship     `yaml:"relationships,omitempty" json:"relationships,omitempty"`
+	// SoftDelete keeps deleted rows with their deletion time in a deleted_at column: deletes set it, the other
+	// accesses leave the deleted rows out, and finds have IncludeDeleted variants that don't
+	SoftDelete bool `yaml:"soft_delete,omitempty" json:"soft_delete,omitempty"`
+	// Audit keeps the history of the rows in a <model>_history table: the writes of the model add a row with the old
+	// and new values of each row they change, and a Find<Model>History access reads them
+	Audit bool `yaml:"audit,omitempty" json:"audit,omitempty"`
+}
+
+// Relationship of a model to another model of the family. Child, BelongsTo and Refers put a foreign key
+// column referencing the id of the target model on the table of the model, see DataConfig.References.
+// Children is the parent side of Child and adds no column.
+type Relationship struct {
+	Type          string `yaml:"type" json:"type"`
+	TargetModelID int    `yaml:"target_model_id" json:"target_model_id"`
+	// Name of the reference, the foreign key column is <name>_id. The target model name when empty,
+	// required when a model has more than one reference to the same target
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// OnDelete and OnUpdate are the referential actions (CASCADE, RESTRICT, SET NULL, SET DEFAULT, NO ACTION).
+	// Child deletes default to CASCADE, the database default (NO ACTION) applies otherwise
+	OnDelete string `yaml:"on_delete,omitempty" json:"on_delete,omitempty"`
+	OnUpdate string `yaml:"on_update,omitempty" json:"on_update,omitempty"`
+}
+
+type UniqueConstraint struct {
+	ConstraintName string  `yaml:"constraint_name" json:"constraint_name"`
+	Attributes     []int64 `yaml:"attributes" json:"attributes"`
+}
+
+type ModelIndex struct {
+	IndexName  string  `yaml:"index_name" json:"index_name"`
+	Attributes []int64 `yaml:"attributes" json:"attributes"`
 }
 
 type Access struct {
-	Find         []AccessConfig `yaml:"find"`
-	Update       []AccessConfig `yaml:"update"`
-	Add          []AccessConfig `yaml:"add"`
-	AddOrReplace []AccessConfig `yaml:"add_or_replace"`
-	Delete       []AccessConfig `yaml:"delete"`
+	Find         []AccessConfig `yaml:"find" json:"find"`
+	Update       []AccessConfig `yaml:"update" json:"update"`
+	Add          []AccessConfig `yaml:"add" json:"add"`
+	AddOrReplace []AccessConfig `yaml:"add_or_replace" json:"add_or_replace"`
+	Delete       []AccessConfig `yaml:"delete" json:"delete"`
+	Aggregate    []AccessConfig `yaml:"aggregate,omitempty" json:"aggregate,omitempty"`
+	AddMany      []AccessConfig `yaml:"add_many,omitempty" json:"add_many,omitempty"`
 }
 
 type ModelConfig struct {
-	Model  `yaml:"model"`
-	Access `yaml:"access"`
+	Model  `yaml:"model" json:"model"`
+	Access `yaml:"access" json:"access"`
 }
 
 func (m *ModelConfig) GetAllAccessConfig() []AccessConfig {
@@ -58,6 +92,8 @@ func (m *ModelConfig) GetAllAccessConfig() []AccessConfig {
 	accessConfig = append(accessConfig, m.Access.Add...)
 	accessConfig = append(accessConfig, m.Access.AddOrReplace...)
 	accessConfig = append(accessConfig, m.Access.Delete...)
+	accessConfig = append(accessConfig, m.Access.Aggregate...)
+	accessConfig = append(accessConfig, m.Access.AddMany...)
 	return accessConfig
 }
 
@@ -82,9 +118,9 @@ func (m *ModelConfig) GetAttributes() ([]*models.AttributeRow, error) {
 }
 
 type Index struct {
-	IndexName  string  `yaml:"index_name"`
-	Attributes []int64 `yaml:"attributes"`
-	IsUnique   bool    `yaml:"is_unique"`
+	IndexName  string  `yaml:"index_name" json:"index_name"`
+	Attributes []int64 `yaml:"attributes" json:"attributes"`
+	IsUnique   bool    `yaml:"is_unique" json:"is_unique"`
 }
 
 func (m *Model) GetIndexes() []Index {
@@ -108,57 +144,396 @@ func (m *Model) GetIndexes() []Index {
 }
 
 type Parameter struct {
-	Param string `yaml:"param"`
+	Param string `yaml:"param" json:"param"`
 }
 
 type Request struct {
-	Parameters []Parameter `yaml:"parameters"`
+	Parameters []Parameter `yaml:"parameters" json:"parameters"`
 }
 
 type AccessConfig struct {
-	Name             string   `yaml:"name"`
-	Request          *Request `yaml:"request,omitempty"`
-	Attributes       []string `yaml:"attributes,omitempty"`
-	Filter           []Filter `yaml:"filter,omitempty"`
-	Set              []Update `yaml:"set,omitempty"`
-	Autoincrement    []string `yaml:"autoincrement,omitempty"`
-	CaptureTimestamp []string `yaml:"capture_timestamp,omitempty"`
-	Values           []Update `yaml:"values,omitempty"`
+	Name             string   `yaml:"name" json:"name"`
+	Request          *Request `yaml:"request,omitempty" json:"request,omitempty"`
+	Attributes       []string `yaml:"attributes,omitempty" json:"attributes,omitempty"`
+	Filter           []Filter `yaml:"filter,omitempty" json:"filter,omitempty"`
+	Set              []Update `yaml:"set,omitempty" json:"set,omitempty"`
+	Autoincrement    []string `yaml:"autoincrement,omitempty" json:"autoincrement,omitempty"`
+	CaptureTimestamp []string `yaml:"capture_timestamp,omitempty" json:"capture_timestamp,omitempty"`
+	Values           []Update `yaml:"values,omitempty" json:"values,omitempty"`
+	// Include loads the children of the found models along with them (find only)
+	Include []Include `yaml:"include,omitempty" json:"include,omitempty"`
+	// Pagination returns the found models a page at a time (find only)
+	Pagination *Pagination `yaml:"pagination,omitempty" json:"pagination,omitempty"`
+	// OrderBy sorts the found models (find only)
+	OrderBy []OrderBy `yaml:"order_by,omitempty" json:"order_by,omitempty"`
+	// SortOptions are the other orderings a caller can select by name with the sort param (find only)
+	SortOptions []SortOption `yaml:"sort_options,omitempty" json:"sort_options,omitempty"`
+	// Aggregates are computed over the filtered models, per group of the GroupBy attributes (aggregate only)
+	Aggregates []Aggregate `yaml:"aggregates,omitempty" json:"aggregates,omitempty"`
+	GroupBy    []string    `yaml:"group_by,omitempty" json:"group_by,omitempty"`
+	// Having filters the groups, the attributes of its filters are the names of aggregates (aggregate only)
+	Having []Filter `yaml:"having,omitempty" json:"having,omitempty"`
+	// Timeout bounds the statements of the access, a Go duration like 500ms or 2s, unbounded when empty
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// ChunkSize is the number of rows inserted by a statement, see ChunkRows. The chunks are inserted in one
+	// transaction, a failed chunk fails all the rows (add_many only)
+	ChunkSize int `yaml:"chunk_size,omitempty" json:"chunk_size,omitempty"`
+	// Copy inserts the rows with COPY instead of multi-row INSERTs, which is faster but returns no ids (add_many only)
+	Copy bool `yaml:"copy,omitempty" json:"copy,omitempty"`
+	// OptimisticLock updates or deletes the rows only when they have the version param, and increments their version.
+	// Updates return the new version, both fail with ErrVersionConflict when no row has it (update and delete only)
+	OptimisticLock bool `yaml:"optimistic_lock,omitempty" json:"optimistic_lock,omitempty"`
+	// Events adds an event with the values of each row the access changes to the outbox, in the statement of the
+	// access, see DataConfig.HasEvents (update, add and delete only)
+	Events bool `yaml:"events,omitempty" json:"events,omitempty"`
+	// ExcludeDeleted leaves the soft deleted rows out of the query, set by the generator on the finds, updates,
+	// aggregates and add_or_replaces of the models with soft_delete, see Model.SoftDelete
+	ExcludeDeleted bool `yaml:"-" json:"-"`
+	// Audit writes the history rows of the changed rows along with the query, set by the generator on the writes of
+	// the models with audit, see Model.Audit
+	Audit bool `yaml:"-" json:"-"`
+	// ConflictKey is the unique key whose row an add_or_replace replaces, the columns of a unique constraint of the
+	// model or the id, set by the generator on the add_or_replace configs
+	ConflictKey []string `yaml:"-" json:"-"`
+}
+
+// Rows of the chunks of add_many configs: a statement binds at most MaxBindParams params
+const (
+	DefaultChunkSize = 1000
+	MaxBindParams    = 65535
+)
+
+// ChunkRows returns the number of rows inserted by a statement of an add_many config, the chunk size defaulted to
+// DefaultChunkSize and bounded by the rows of MaxBindParams params
+func (a *AccessConfig) ChunkRows() int {
+	chunkSize := a.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	return max(1, min(chunkSize, MaxBindParams/max(1, len(a.Values))))
+}
+
+// Aggregate is a value computed by an aggregate access over the models it filters
+type Aggregate struct {
+	// Function is COUNT, SUM, AVG, MIN or MAX
+	Function string `yaml:"function" json:"function"`
+	// Attribute is the aggregated attribute, COUNT without an attribute counts the models
+	Attribute string `yaml:"attribute,omitempty" json:"attribute,omitempty"`
+	// Name is the field of the aggregate in the result, see ResultName
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+}
+
+// ResultName is the name of the aggregate, or the function and the attribute in snake case, count or sum_total_amount
+func (a Aggregate) ResultName() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	name := strings.ToLower(a.Function)
+	if a.Attribute != "" {
+		name += "_" + golang.ToSnakeCase(a.Attribute)
+	}
+	return name
+}
+
+// OrderBy sorts the models found by an attribute
+type OrderBy struct {
+	Attribute string `yaml:"attribute" json:"attribute"`
+	// Direction is ASC (the default) or DESC
+	Direction string `yaml:"direction,omitempty" json:"direction,omitempty"`
+	// Nulls is FIRST or LAST, the default of the database when empty
+	Nulls string `yaml:"nulls,omitempty" json:"nulls,omitempty"`
+}
+
+// SortOption is an ordering a caller selects at runtime in place of the order_by of the access config.
+// Only the orderings listed by the access config can be selected, each is a prepared query of its own.
+type SortOption struct {
+	Name    string    `yaml:"name" json:"name"`
+	OrderBy []OrderBy `yaml:"order_by" json:"order_by"`
+}
+
+// Orderings returns the order_by of the access config followed by the order_by of its sort options
+func (a *AccessConfig) Orderings() [][]OrderBy {
+	orderings := make([][]OrderBy, 0, len(a.SortOptions)+1)
+	if len(a.OrderBy) > 0 {
+		orderings = append(orderings, a.OrderBy)
+	}
+	for _, option := range a.SortOptions {
+		orderings = append(orderings, option.OrderBy)
+	}
+	return orderings
+}
+
+// Pagination pages the results of a find. Pages end their ordering with id, so that rows with equal values keep
+// their page. Keyset pages are ordered by the order_by of the find, in one direction, by attributes the find reads
+// and that are not NULL, and they have no sort options.
+type Pagination struct {
+	// Type is offset (LIMIT and OFFSET params) or keyset (a cursor param, the order_by columns and the id of the last
+	// row of the previous page)
+	Type string `yaml:"type" json:"type"`
+	// DefaultLimit is the page size of requests without a limit, MaxLimit caps the limit of requests
+	DefaultLimit int `yaml:"default_limit,omitempty" json:"default_limit,omitempty"`
+	MaxLimit     int `yaml:"max_limit,omitempty" json:"max_limit,omitempty"`
+}
+
+// Page sizes of pagination configs without default_limit or max_limit
+const (
+	DefaultPageLimit = 50
+	DefaultMaxLimit  = 1000
+)
+
+// Limits returns the default and the max page size, defaulted to DefaultPageLimit and DefaultMaxLimit
+func (p *Pagination) Limits() (int, int) {
+	defaultLimit, maxLimit := p.DefaultLimit, p.MaxLimit
+	if maxLimit <= 0 {
+		maxLimit = DefaultMaxLimit
+	}
+	if defaultLimit <= 0 {
+		defaultLimit = min(DefaultPageLimit, maxLimit)
+	}
+	return defaultLimit, maxLimit
+}
+
+// Include names a child model loaded by a find, in one batched query for all the found models.
+// The child model references the found model with a Child, BelongsTo or Refers relationship.
+type Include struct {
+	Model string `yaml:"model" json:"model"`
+	// Attributes of the child model to load, the reference column is always loaded
+	Attributes []string `yaml:"attributes" json:"attributes"`
 }
 
 type Update struct {
-	Attribute string `yaml:"attribute"`
-	ParamName string `yaml:"param_name"`
+	Attribute string `yaml:"attribute" json:"attribute"`
+	ParamName string `yaml:"param_name" json:"param_name"`
 }
 
 type Attribute struct {
-	Attribute string `yaml:"attribute"`
+	Attribute string `yaml:"attribute" json:"attribute"`
 }
 
 type ConnectionConfig struct {
-	IdleTimeoutSecs int `yaml:"idle_timeout_secs"`
-	MaxLifetimeMins int `yaml:"conn_max_lifetime_mins"`
+	IdleTimeoutSecs int `yaml:"idle_timeout_secs" json:"idle_timeout_secs"`
+	MaxLifetimeMins int `yaml:"conn_max_lifetime_mins" json:"conn_max_lifetime_mins"`
 }
 
 type ConnectionPoolConfig struct {
-	MaxIdleConns int `yaml:"max_idle_conns"`
-	MaxOpenConns int `yaml:"max_open_conns"`
+	MaxIdleConns int `yaml:"max_idle_conns" json:"max_idle_conns"`
+	MaxOpenConns int `yaml:"max_open_conns" json:"max_open_conns"`
 }
 
 type DatabaseConfig struct {
-	DriverName           string                `yaml:"driver_name"`
-	DBConfigId           string                `yaml:"db_config_id"`
-	UserName             string                `yaml:"user_name"`
-	Password             string                `yaml:"password"`
-	Host                 string                `yaml:"host"`
-	Port                 int                   `yaml:"port"`
-	DBName               string                `yaml:"db_name"`
-	ConnectionConfig     *ConnectionConfig     `yaml:"conn_config,omitempty"`
-	ConnectionPoolConfig *ConnectionPoolConfig `yaml:"conn_pool_config,omitempty"`
+	DriverName           string                `yaml:"driver_name" json:"driver_name"`
+	DBConfigId           string                `yaml:"db_config_id" json:"db_config_id"`
+	UserName             string                `yaml:"user_name" json:"user_name"`
+	Password             string                `yaml:"password" json:"password"`
+	Host                 string                `yaml:"host" json:"host"`
+	Port                 int                   `yaml:"port" json:"port"`
+	DBName               string                `yaml:"db_name" json:"db_name"`
+	ConnectionConfig     *ConnectionConfig     `yaml:"conn_config,omitempty" json:"conn_config,omitempty"`
+	ConnectionPoolConfig *ConnectionPoolConfig `yaml:"conn_pool_config,omitempty" json:"conn_pool_config,omitempty"`
+	// IsolationLevel of the transactions of WithTx: read_uncommitted, read_committed, repeatable_read or serializable,
+	// the default of the database when empty
+	IsolationLevel string `yaml:"isolation_level,omitempty" json:"isolation_level,omitempty"`
 }
 
 type DataConfig struct {
-	FamilyName     string          `yaml:"family_name"`
-	Models         []ModelConfig   `yaml:"models"`
-	DatabaseConfig *DatabaseConfig `yaml:"connection_config,omitempty"`
+	FamilyName     string          `yaml:"family_name" json:"family_name"`
+	Models         []ModelConfig   `yaml:"models" json:"models"`
+	DatabaseConfig *DatabaseConfig `yaml:"connection_config,omitempty" json:"connection_config,omitempty"`
+}
+
+// Reference is a foreign key column of a model, a relationship resolved against the models of its family
+type Reference struct {
+	RelationType models.RelationType
+	// TargetModel is the name of the referenced model
+	TargetModel string
+	// Column is the snake case foreign key column, <name>_id
+	Column string
+	// NotNull is true for Child and BelongsTo, unless deleting the target sets the column to NULL
+	NotNull  bool
+	OnDelete string
+	OnUpdate string
+}
+
+// IDTypeName is the Go type generated for ids of the target model, CustomerID for references to Customer
+func (r *Reference) IDTypeName() string {
+	return golang.ToPascalCase(r.TargetModel) + "ID"
+}
+
+var referentialActions = map[string]bool{"CASCADE": true, "RESTRICT": true, "SET NULL": true, "SET DEFAULT": true, "NO ACTION": true}
+
+func referentialAction(action string) (string, error) {
+	normalized := strings.ToUpper(strings.Join(strings.Fields(action), " "))
+	if normalized != "" && !referentialActions[normalized] {
+		return "", fmt.Errorf("unknown referential action %q, expected CASCADE, RESTRICT, SET NULL, SET DEFAULT or NO ACTION", action)
+	}
+	return normalized, nil
+}
+
+// HasEvents tells whether an access of the family has events, whose outbox table is created along with the tables of
+// the models
+func (d *DataConfig) HasEvents() bool {
+	for i := range d.Models {
+		for _, accessConfig := range d.Models[i].GetAllAccessConfig() {
+			if accessConfig.Events {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ModelByID returns the model with the given id, nil when the family has none
+func (d *DataConfig) ModelByID(id int) *ModelConfig {
+	for i := range d.Models {
+		if d.Models[i].Model.ID == id {
+			return &d.Models[i]
+		}
+	}
+	return nil
+}
+
+// References resolves the relationships of a model that put a foreign key column on its table,
+// in the order they are declared. Children relationships are skipped.
+func (d *DataConfig) References(model *Model) ([]Reference, error) {
+	references := []Reference{}
+	columns := map[string]bool{}
+	errs := []error{}
+	for i, relationship := range model.Relationships {
+		relationType, err := models.ParseRelationType(relationship.Type)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("model %s: relationships[%d]: %w", model.Name, i, err))
+			continue
+		}
+		if relationType == models.Children {
+			continue
+		}
+		target := d.ModelByID(relationship.TargetModelID)
+		if target == nil || relationship.TargetModelID == 0 {
+			errs = append(errs, fmt.Errorf("model %s: relationships[%d]: target model %d not found", model.Name, i, relationship.TargetModelID))
+			continue
+		}
+
+		reference := Reference{RelationType: relationType, TargetModel: target.Model.Name}
+		name := relationship.Name
+		if name == "" {
+			name = target.Model.Name
+		}
+		reference.Column = golang.ToSnakeCase(name) + "_id"
+		if columns[reference.Column] {
+			errs = append(errs, fmt.Errorf("model %s: relationships[%d]: column %s is already a reference, name the relationship",
+				model.Name, i, reference.Column))
+			continue
+		}
+		columns[reference.Column] = true
+
+		onDelete, deleteErr := referentialAction(relationship.OnDelete)
+		onUpdate, updateErr := referentialAction(relationship.OnUpdate)
+		if err := errors.Join(deleteErr, updateErr); err != nil {
+			errs = append(errs, fmt.Errorf("model %s: relationships[%d]: %w", model.Name, i, err))
+			continue
+		}
+		if onDelete == "" && relationType == models.Child {
+			onDelete = "CASCADE"
+		}
+		reference.OnDelete, reference.OnUpdate = onDelete, onUpdate
+		reference.NotNull = relationType != models.Referes && onDelete != "SET NULL" && onUpdate != "SET NULL"
+		references = append(references, reference)
+	}
+	return references, errors.Join(errs...)
+}
+
+// ModelsInReferenceOrder orders the models so that each follows the models it references, which is the order
+// their tables can be created in. Models keep their order otherwise. A model may reference itself, other cycles are an error.
+func (d *DataConfig) ModelsInReferenceOrder() ([]*ModelConfig, error) {
+	ordered := make([]*ModelConfig, 0, len(d.Models))
+	// 1 while the references of a model are visited, 2 once it is ordered
+	state := make(map[*ModelConfig]int, len(d.Models))
+	var visit func(model *ModelConfig, path []string) error
+	visit = func(model *ModelConfig, path []string) error {
+		switch state[model] {
+		case 1:
+			return fmt.Errorf("relationships form a cycle: %s", strings.Join(append(path, model.Model.Name), " -> "))
+		case 2:
+			return nil
+		}
+		state[model] = 1
+		for _, relationship := range model.Model.Relationships {
+			relationType, err := models.ParseRelationType(relationship.Type)
+			if err != nil || relationType == models.Children || relationship.TargetModelID == model.Model.ID {
+				continue
+			}
+			if target := d.ModelByID(relationship.TargetModelID); target != nil {
+				if err := visit(target, append(path, model.Model.Name)); err != nil {
+					return err
+				}
+			}
+		}
+		state[model] = 2
+		ordered = append(ordered, model)
+		return nil
+	}
+	for i := range d.Models {
+		if err := visit(&d.Models[i], nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// IncludedModel is an Include resolved against the models of the family
+type IncludedModel struct {
+	// Model is the name of the child model
+	Model string
+	// Reference is the reference of the child model to the found model
+	Reference Reference
+	// Attributes are the attributes of the include, followed by the reference column when they don't list it
+	Attributes []string
+	// SoftDelete is true when the child model has soft_delete, its deleted rows are not included
+	SoftDelete bool
+}
+
+// ModelByName returns the model with the given name, compared in pascal case, nil when the family has none
+func (d *DataConfig) ModelByName(name string) *ModelConfig {
+	for i := range d.Models {
+		if golang.ToPascalCase(d.Models[i].Model.Name) == golang.ToPascalCase(name) {
+			return &d.Models[i]
+		}
+	}
+	return nil
+}
+
+// ResolveInclude finds the reference of the included model to the parent model
+func (d *DataConfig) ResolveInclude(parent *Model, include Include) (*IncludedModel, error) {
+	child := d.ModelByName(include.Model)
+	if child == nil {
+		return nil, fmt.Errorf("included model %s not found", include.Model)
+	}
+	references, err := d.References(&child.Model)
+	if err != nil {
+		return nil, err
+	}
+	var found []Reference
+	for _, reference := range references {
+		if golang.ToPascalCase(reference.TargetModel) == golang.ToPascalCase(parent.Name) {
+			found = append(found, reference)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("included model %s has no relationship to %s", child.Model.Name, parent.Name)
+	case 1:
+	default:
+		return nil, fmt.Errorf("included model %s references %s more than once", child.Model.Name, parent.Name)
+	}
+
+	included := &IncludedModel{Model: child.Model.Name, Reference: found[0], SoftDelete: child.Model.SoftDelete}
+	included.Attributes = append(included.Attributes, include.Attributes...)
+	hasColumn := false
+	for _, attribute := range include.Attributes {
+		hasColumn = hasColumn || golang.ToSnakeCase(attribute) == found[0].Column
+	}
+	if !hasColumn {
+		included.Attributes = append(included.Attributes, found[0].Column)
+	}
+	return included, nil
 }
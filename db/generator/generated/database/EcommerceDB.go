@@ -1,17 +1,33 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	_ "github.com/lib/pq"
 	"time"
 )
 
 var EcommerceDb *ecommerceDb
 
+type UserID string
+
+type Range[T any] struct {
+	From T `json:"from"`
+	To   T `json:"to"`
+}
+
 type ecommerceDb struct {
 	User    *User_DB
 	Product *Product_DB
 	Order   *Order_DB
+	db      *sql.DB
+}
+
+type EcommerceDbTx struct {
+	User    *User_DB
+	Product *Product_DB
+	Order   *Order_DB
 }
 
 func InitEcommerceDb() error {
@@ -44,14 +60,39 @@ func InitEcommerceDb() error {
 		preparedCache: stmtMapOrder,
 	}
 	EcommerceDb = &ecommerceDb{
+		db:      db,
 		User:    user,
 		Product: product,
 		Order:   order,
 	}
 	return nil
 }
+func (f *ecommerceDb) WithTx(ctx context.Context, fn func(tx *EcommerceDbTx) error) error {
+	return f.WithTxOptions(ctx, nil, fn)
+}
+func (f *ecommerceDb) WithTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(tx *EcommerceDbTx) error) error {
+	tx, err := f.db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer rollbackOnPanic(tx)
+	err = fn(&EcommerceDbTx{User: f.User.withTx(tx), Product: f.Product.withTx(tx), Order: f.Order.withTx(tx)})
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+func rollbackOnPanic(tx *sql.Tx) {
+	if recovered := recover(); recovered != nil {
+		_ = tx.Rollback()
+		panic(recovered)
+	}
+}
 func SetupDBConnection() (*sql.DB, error) {
-	driverName, dsn := "postgres", "user=user password=password dbname=ecommerce port=5432 host=localhost"
+	driverName, dsn := "postgres", "user=test_gen_user password=test_gen_password dbname=test_gen_ecommerce port=5432 host=localhost"
 	idleConnTimeout, connMaxLifetime := (time.Second * 10), (time.Minute * 30)
 	idleConns, maxOpenConns := 5, 10
 	db, err := sql.Open(driverName, dsn)
@@ -0,0 +1,16 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActorFromContext(t *testing.T) {
+	// Writes without an actor record a NULL actor
+	assert.Equal(t, "", ActorFromContext(context.Background()))
+
+	ctx := WithActor(context.Background(), "alice")
+	assert.Equal(t, "alice", ActorFromContext(ctx))
+}
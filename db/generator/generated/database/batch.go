@@ -0,0 +1,47 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"regexp"
+	"strconv"
+)
+
+var copyLinePattern *regexp.Regexp = regexp.MustCompile(`, line (\d+)`)
+
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+func scanIds(rows *sql.Rows, ids []string) ([]string, error) {
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+func copyRowError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	match := copyLinePattern.FindStringSubmatch(pqErr.Where)
+	if match == nil {
+		return err
+	}
+	line, _ := strconv.Atoi(match[1])
+	return &RowError{Row: line - 1, Err: err}
+}
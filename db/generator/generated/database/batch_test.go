@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddProductsRowErrors(t *testing.T) {
+	rows := []AddProductsParams{{Sku: "SKU-1", ProductName: "Lamp", Price: 10}, {ProductName: "Desk"}, {Sku: "SKU-3"}}
+
+	// Rows are validated before any is inserted, no database is needed
+	_, err := AddProducts(context.Background(), &Product_DB{}, rows)
+	var rowErr *RowError
+	assert.ErrorAs(t, err, &rowErr)
+	assert.Equal(t, 1, rowErr.Row)
+	assert.EqualError(t, err, "row 1: validation failed: sku is required\nrow 2: validation failed: product_name is required")
+
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+}
+
+func TestAddProductsQuery(t *testing.T) {
+	assert.Equal(t, `INSERT INTO "product" ("sku", "product_name", "price") VALUES ($1, $2, $3), ($4, $5, $6) RETURNING "id"`, AddProductsQuery(2))
+
+	// A row error wraps the error of its row
+	errInsert := errors.New("insert failed")
+	assert.ErrorIs(t, &RowError{Row: 0, Err: errInsert}, errInsert)
+}
+
+func TestCopyRowError(t *testing.T) {
+	// Postgres reports the line of the COPY, counted from 1, whichever Exec the driver returns the error from
+	copyErr := &pq.Error{Message: "invalid input syntax for type numeric", Where: `COPY product, line 3, column price: "abc"`}
+	err := copyRowError(copyErr)
+	var rowErr *RowError
+	assert.ErrorAs(t, err, &rowErr)
+	assert.Equal(t, 2, rowErr.Row)
+	assert.ErrorIs(t, err, copyErr)
+
+	// Errors on no line fail the whole COPY
+	connErr := errors.New("connection reset")
+	assert.Same(t, connErr, copyRowError(connErr))
+	assert.Equal(t, error(&pq.Error{Message: "canceling statement due to statement timeout"}),
+		copyRowError(&pq.Error{Message: "canceling statement due to statement timeout"}))
+}
@@ -0,0 +1,7 @@
+package database
+
+import (
+	"errors"
+)
+
+var ErrVersionConflict = errors.New("version conflict")
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const createSchemaMigrationsQuery = "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT PRIMARY KEY, applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+
+const selectSchemaMigrationsQuery = "SELECT version, applied_at FROM schema_migrations"
+
+const insertSchemaMigrationQuery = "INSERT INTO schema_migrations (version) VALUES ($1)"
+
+const deleteSchemaMigrationQuery = "DELETE FROM schema_migrations WHERE version = $1"
+
+type SchemaMigration struct {
+	Version   int64
+	Name      string
+	Up        string
+	Down      string
+	AppliedAt *time.Time
+}
+
+func readMigration(upPath string) (*SchemaMigration, error) {
+	name := strings.TrimSuffix(path.Base(upPath), ".up.sql")
+	version, err := strconv.ParseInt(strings.SplitN(name, "_", 2)[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("migration %s: %w", upPath, err)
+	}
+	up, err := migrationFiles.ReadFile(upPath)
+	if err != nil {
+		return nil, err
+	}
+	down, err := migrationFiles.ReadFile(strings.TrimSuffix(upPath, ".up.sql") + ".down.sql")
+	if err != nil {
+		return nil, err
+	}
+	migration := &SchemaMigration{
+		Version: version,
+		Name:    name,
+		Up:      string(up),
+		Down:    string(down),
+	}
+	return migration, nil
+}
+func loadMigrations() ([]*SchemaMigration, error) {
+	upPaths, err := fs.Glob(migrationFiles, "migrations/*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []*SchemaMigration
+	for _, upPath := range upPaths {
+		migration, err := readMigration(upPath)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+func appliedMigrations(ctx context.Context, db *sql.DB) (map[int64]*time.Time, error) {
+	_, err := db.ExecContext(ctx, createSchemaMigrationsQuery)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, selectSchemaMigrationsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int64]*time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		scanErr := rows.Scan(&version, &appliedAt)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		applied[version] = &appliedAt
+	}
+	return applied, rows.Err()
+}
+func runMigration(ctx context.Context, db *sql.DB, script string, query string, version int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, script)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.ExecContext(ctx, query, version)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+func Migrate(ctx context.Context, db *sql.DB) error {
+	migrations, err := MigrationStatus(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if migration.AppliedAt != nil {
+			continue
+		}
+		err = runMigration(ctx, db, migration.Up, insertSchemaMigrationQuery, migration.Version)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", migration.Name, err)
+		}
+	}
+	return nil
+}
+func MigrateDown(ctx context.Context, db *sql.DB) error {
+	migrations, err := MigrationStatus(ctx, db)
+	if err != nil {
+		return err
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.AppliedAt == nil {
+			continue
+		}
+		err = runMigration(ctx, db, migration.Down, deleteSchemaMigrationQuery, migration.Version)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", migration.Name, err)
+		}
+		return nil
+	}
+	return nil
+}
+func MigrationStatus(ctx context.Context, db *sql.DB) ([]*SchemaMigration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	for _, migration := range migrations {
+		migration.AppliedAt = applied[migration.Version]
+	}
+	return migrations, nil
+}
@@ -0,0 +1,24 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+	for i, migration := range migrations {
+		if i > 0 {
+			assert.Less(t, migrations[i-1].Version, migration.Version)
+		}
+		// Postgres rejects backtick quoted identifiers, the embedded scripts quote them with double quotes
+		assert.NotContains(t, migration.Up, "`", migration.Name)
+		assert.NotContains(t, migration.Down, "`", migration.Name)
+	}
+	assert.Equal(t, "0001_init", migrations[0].Name)
+	assert.Contains(t, migrations[0].Up, "CREATE TABLE \"user\" (\n\t\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),")
+	assert.Contains(t, migrations[0].Down, "DROP TABLE \"user\";")
+}
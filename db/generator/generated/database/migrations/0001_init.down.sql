@@ -0,0 +1,9 @@
+DROP TABLE "outbox_events";
+
+DROP TABLE "order_history";
+
+DROP TABLE "order";
+
+DROP TABLE "product";
+
+DROP TABLE "user";
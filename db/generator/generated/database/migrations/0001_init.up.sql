@@ -0,0 +1,72 @@
+CREATE TABLE "user" (
+	"id" UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	"version" INTEGER NOT NULL DEFAULT 1,
+	"updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	"deleted_at" TIMESTAMP WITH TIME ZONE,
+	"email" TEXT NOT NULL,
+	"name" TEXT NOT NULL,
+	"shipping_address" TEXT,
+	"billing_address" TEXT
+);
+
+CREATE INDEX ON "user" ("email");
+CREATE INDEX ON "user" ("id");
+CREATE INDEX ON "user" ("name");
+CREATE UNIQUE INDEX ON "user" ("email") WHERE (deleted_at IS NULL);
+
+CREATE TABLE "product" (
+	"id" UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	"version" INTEGER NOT NULL DEFAULT 1,
+	"updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	"sku" TEXT NOT NULL,
+	"product_name" TEXT NOT NULL,
+	"description" TEXT,
+	"price" NUMERIC(33,18) NOT NULL
+);
+
+CREATE INDEX ON "product" ("price");
+CREATE INDEX ON "product" ("sku");
+
+CREATE TABLE "order" (
+	"id" UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	"version" INTEGER NOT NULL DEFAULT 1,
+	"updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	"order_date" DATE NOT NULL,
+	"order_status" TEXT NOT NULL DEFAULT 'pending',
+	"payment_method" TEXT,
+	"total_amount" NUMERIC(33,18) NOT NULL,
+	"rating" NUMERIC(5,2),
+	"user_id" UUID NOT NULL,
+	CONSTRAINT "fk_order_user_id" FOREIGN KEY ("user_id") REFERENCES "user" ("id")
+);
+
+CREATE INDEX ON "order" ("id");
+CREATE INDEX ON "order" ("order_date");
+CREATE INDEX ON "order" ("order_status");
+CREATE INDEX ON "order" ("order_status", "order_date");
+CREATE INDEX ON "order" ("order_status", "total_amount");
+CREATE INDEX ON "order" ("user_id");
+
+CREATE TABLE "order_history" (
+	"id" BIGSERIAL PRIMARY KEY,
+	"row_id" UUID NOT NULL,
+	"operation" TEXT NOT NULL,
+	"old_values" JSONB,
+	"new_values" JSONB,
+	"actor" TEXT,
+	"changed_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX ON "order_history" ("row_id");
+
+CREATE TABLE "outbox_events" (
+	"id" BIGSERIAL PRIMARY KEY,
+	"model" TEXT NOT NULL,
+	"access_name" TEXT NOT NULL,
+	"row_id" UUID NOT NULL,
+	"payload" JSONB,
+	"created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	"sent_at" TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX ON "outbox_events" ("id") WHERE sent_at IS NULL;
@@ -3,56 +3,147 @@ package database
 import (
 	"context"
 	"database/sql"
-	_ "github.com/lib/pq"
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
 type Order struct {
-	OrderDate     *time.Time `db:"order_date"`
-	OrderStatus   string     `db:"order_status"`
-	PaymentMethod string     `db:"payment_method"`
-	TotalAmount   float64    `db:"total_amount"`
-	Rating        float64    `db:"rating"`
+	Id            string          `db:"id"`
+	Version       int64           `db:"version"`
+	UpdatedAt     *time.Time      `db:"updated_at"`
+	OrderDate     *time.Time      `db:"order_date"`
+	OrderStatus   string          `db:"order_status"`
+	PaymentMethod sql.NullString  `db:"payment_method"`
+	TotalAmount   float64         `db:"total_amount"`
+	Rating        sql.NullFloat64 `db:"rating"`
+	UserId        UserID          `db:"user_id"`
 }
 
 type Order_DB struct {
 	db            *sql.DB
 	preparedCache map[string]*sql.Stmt
+	tx            *sql.Tx
 }
 
 type GetOrderByIDParams struct {
-	OrderDate interface{} `json:"order_date"`
+	OrderDate *time.Time `json:"order_date"`
 }
 
 type GetOrderByIDRequest struct {
 	Params GetOrderByIDParams `json:"params"`
 }
 
+type GetOrderByIDRow struct {
+	OrderDate     *time.Time     `db:"order_date"`
+	OrderStatus   string         `db:"order_status"`
+	PaymentMethod sql.NullString `db:"payment_method"`
+	TotalAmount   float64        `db:"total_amount"`
+}
+
+type ListOrdersByStatusParams struct {
+	OrderStatus string `json:"order_status"`
+	Limit       int    `json:"limit"`
+	Offset      int    `json:"offset"`
+	Sort        string `json:"sort"`
+}
+
+type ListOrdersByStatusRequest struct {
+	Params ListOrdersByStatusParams `json:"params"`
+}
+
+type ListOrdersByStatusRow struct {
+	Id          string     `db:"id"`
+	OrderDate   *time.Time `db:"order_date"`
+	OrderStatus string     `db:"order_status"`
+	TotalAmount float64    `db:"total_amount"`
+}
+
+type ListOrdersByStatusPage struct {
+	Items      []ListOrdersByStatusRow `json:"items"`
+	NextOffset int                     `json:"next_offset"`
+}
+
+type UpdateOrderStatusParams struct {
+	OrderStatus string `json:"order_status"`
+	Id          string `json:"id"`
+}
+
+type UpdateOrderStatusRequest struct {
+	Params UpdateOrderStatusParams `json:"params"`
+}
+
+type DeleteOrderParams struct {
+	Id string `json:"id"`
+}
+
+type DeleteOrderRequest struct {
+	Params DeleteOrderParams `json:"params"`
+}
+
+type CountOrdersByStatusParams struct {
+	Since     *time.Time `json:"since"`
+	MinOrders int64      `json:"min_orders"`
+}
+
+type CountOrdersByStatusRequest struct {
+	Params CountOrdersByStatusParams `json:"params"`
+}
+
+type CountOrdersByStatusResult struct {
+	OrderStatus string          `db:"order_status"`
+	Count       int64           `db:"count"`
+	Revenue     sql.NullFloat64 `db:"revenue"`
+}
+
+type OrderHistory struct {
+	Id        int64            `json:"id" db:"id"`
+	RowId     string           `json:"row_id" db:"row_id"`
+	Operation string           `json:"operation" db:"operation"`
+	OldValues *json.RawMessage `json:"old_values" db:"old_values"`
+	NewValues *json.RawMessage `json:"new_values" db:"new_values"`
+	Actor     sql.NullString   `json:"actor" db:"actor"`
+	ChangedAt *time.Time       `json:"changed_at" db:"changed_at"`
+}
+
+func (item *Order) Validate() error {
+	return nil
+}
 func NewOrder_DB(db *sql.DB, preparedCache map[string]*sql.Stmt) *Order_DB {
 	return &Order_DB{
 		db:            db,
 		preparedCache: preparedCache,
 	}
 }
+func (db *Order_DB) statement(ctx context.Context, name string) *sql.Stmt {
+	stmt := db.preparedCache[name]
+	if db.tx != nil {
+		return db.tx.StmtContext(ctx, stmt)
+	}
+	return stmt
+}
+func (db *Order_DB) withTx(tx *sql.Tx) *Order_DB {
+	return &Order_DB{db: db.db, preparedCache: db.preparedCache, tx: tx}
+}
 func GetOrderByIDReadParams(params GetOrderByIDParams) ([]interface{}, error) {
 	var values []interface{}
 	values = append(values, params.OrderDate)
 	return values, nil
 }
-func GetOrderByID(ctx context.Context, db *Order_DB, requestParams GetOrderByIDParams) ([]Order, error) {
-	stmt := db.preparedCache["GetOrderByID"]
+func GetOrderByID(ctx context.Context, db *Order_DB, requestParams GetOrderByIDParams) ([]GetOrderByIDRow, error) {
+	stmt := db.statement(ctx, "GetOrderByID")
 	values, err := GetOrderByIDReadParams(requestParams)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := stmt.Query(values...)
+	rows, err := stmt.QueryContext(ctx, values...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var results []Order
+	var results []GetOrderByIDRow
 	for rows.Next() {
-		var item Order
+		var item GetOrderByIDRow
 		scanErr := rows.Scan(&item.OrderDate, &item.OrderStatus, &item.PaymentMethod, &item.TotalAmount)
 		if scanErr != nil {
 			return nil, scanErr
@@ -61,10 +152,196 @@ func GetOrderByID(ctx context.Context, db *Order_DB, requestParams GetOrderByIDP
 	}
 	return results, nil
 }
+func ListOrdersByStatusReadParams(params ListOrdersByStatusParams) ([]interface{}, error) {
+	var values []interface{}
+	values = append(values, params.OrderStatus)
+	values = append(values, params.Limit+1)
+	values = append(values, params.Offset)
+	return values, nil
+}
+func ListOrdersByStatusSortQuery(sort string) (string, error) {
+	queries := map[string]string{"": "ListOrdersByStatus", "total": "ListOrdersByStatusByTotal"}
+	query, ok := queries[sort]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrInvalidSort, sort)
+	}
+	return query, nil
+}
+func ListOrdersByStatus(ctx context.Context, db *Order_DB, requestParams ListOrdersByStatusParams) (*ListOrdersByStatusPage, error) {
+	requestParams.Limit = pageLimit(requestParams.Limit, 50, 1000)
+	query, err := ListOrdersByStatusSortQuery(requestParams.Sort)
+	if err != nil {
+		return nil, err
+	}
+	stmt := db.statement(ctx, query)
+	values, err := ListOrdersByStatusReadParams(requestParams)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []ListOrdersByStatusRow
+	for rows.Next() {
+		var item ListOrdersByStatusRow
+		scanErr := rows.Scan(&item.Id, &item.OrderDate, &item.OrderStatus, &item.TotalAmount)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		results = append(results, item)
+	}
+	page := &ListOrdersByStatusPage{}
+	if len(results) > requestParams.Limit {
+		results = results[:requestParams.Limit]
+		page.NextOffset = requestParams.Offset + requestParams.Limit
+	}
+	page.Items = results
+	return page, nil
+}
+func UpdateOrderStatusReadParams(params UpdateOrderStatusParams) ([]interface{}, error) {
+	var values []interface{}
+	values = append(values, params.OrderStatus)
+	values = append(values, params.Id)
+	return values, nil
+}
+func UpdateOrderStatus(ctx context.Context, db *Order_DB, requestParams UpdateOrderStatusParams) (int64, error) {
+	err := requestParams.Validate()
+	if err != nil {
+		return int64(0), err
+	}
+	stmt := db.statement(ctx, "UpdateOrderStatus")
+	values, err := UpdateOrderStatusReadParams(requestParams)
+	if err != nil {
+		return int64(0), err
+	}
+	values = append(values, ActorFromContext(ctx))
+	result, err := stmt.ExecContext(ctx, values...)
+	if err != nil {
+		return int64(0), err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return int64(0), err
+	}
+	return rowsAffected, nil
+}
+func DeleteOrderReadParams(params DeleteOrderParams) ([]interface{}, error) {
+	var values []interface{}
+	values = append(values, params.Id)
+	return values, nil
+}
+func DeleteOrder(ctx context.Context, db *Order_DB, requestParams DeleteOrderParams) (int64, error) {
+	err := requestParams.Validate()
+	if err != nil {
+		return int64(0), err
+	}
+	stmt := db.statement(ctx, "DeleteOrder")
+	values, err := DeleteOrderReadParams(requestParams)
+	if err != nil {
+		return int64(0), err
+	}
+	values = append(values, ActorFromContext(ctx))
+	result, err := stmt.ExecContext(ctx, values...)
+	if err != nil {
+		return int64(0), err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return int64(0), err
+	}
+	return rowsAffected, nil
+}
+func CountOrdersByStatusReadParams(params CountOrdersByStatusParams) ([]interface{}, error) {
+	var values []interface{}
+	values = append(values, params.Since)
+	values = append(values, params.MinOrders)
+	return values, nil
+}
+func CountOrdersByStatus(ctx context.Context, db *Order_DB, requestParams CountOrdersByStatusParams) ([]CountOrdersByStatusResult, error) {
+	stmt := db.statement(ctx, "CountOrdersByStatus")
+	values, err := CountOrdersByStatusReadParams(requestParams)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []CountOrdersByStatusResult
+	for rows.Next() {
+		var item CountOrdersByStatusResult
+		scanErr := rows.Scan(&item.OrderStatus, &item.Count, &item.Revenue)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		results = append(results, item)
+	}
+	return results, nil
+}
+func FindOrderHistory(ctx context.Context, db *Order_DB, id string) ([]OrderHistory, error) {
+	stmt := db.statement(ctx, "FindOrderHistory")
+	rows, err := stmt.QueryContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []OrderHistory
+	for rows.Next() {
+		var item OrderHistory
+		scanErr := rows.Scan(&item.Id, &item.RowId, &item.Operation, &item.OldValues, &item.NewValues, &item.Actor, &item.ChangedAt)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		results = append(results, item)
+	}
+	return results, nil
+}
+func (params *GetOrderByIDParams) Validate() error {
+	return nil
+}
+func (params *ListOrdersByStatusParams) Validate() error {
+	return nil
+}
+func (params *UpdateOrderStatusParams) Validate() error {
+	return nil
+}
+func (params *DeleteOrderParams) Validate() error {
+	return nil
+}
+func (params *CountOrdersByStatusParams) Validate() error {
+	return nil
+}
 func OrderPrepareStmts(db *sql.DB) (map[string]*sql.Stmt, error) {
 	preparedCache := make(map[string]*sql.Stmt)
 	var err error
-	preparedCache["GetOrderByID"], err = db.Prepare("SELECT order_date, order_status, payment_method, total_amount FROM order WHERE (1 = 1) AND (order_date = $1)")
+	preparedCache["GetOrderByID"], err = db.Prepare("SELECT \"order_date\", \"order_status\", \"payment_method\", \"total_amount\" FROM \"order\" WHERE (1 = 1) AND (\"order_date\" = $1)")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["ListOrdersByStatus"], err = db.Prepare("SELECT \"id\", \"order_date\", \"order_status\", \"total_amount\" FROM \"order\" WHERE (1 = 1) AND (\"order_status\" = $1) ORDER BY \"order_date\" DESC, \"id\" DESC LIMIT $2 OFFSET $3")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["ListOrdersByStatusByTotal"], err = db.Prepare("SELECT \"id\", \"order_date\", \"order_status\", \"total_amount\" FROM \"order\" WHERE (1 = 1) AND (\"order_status\" = $1) ORDER BY \"total_amount\" DESC NULLS LAST, \"id\" DESC LIMIT $2 OFFSET $3")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["UpdateOrderStatus"], err = db.Prepare("WITH old_rows AS (SELECT * FROM \"order\" WHERE (1 = 1) AND (\"id\" = $2) FOR UPDATE), changed AS (UPDATE \"order\" SET \"order_status\" = $1 WHERE (1 = 1) AND (\"id\" = $2) RETURNING *), history AS (INSERT INTO \"order_history\" (\"row_id\", \"operation\", \"old_values\", \"new_values\", \"actor\") SELECT changed.\"id\", 'update', to_jsonb(old_rows), to_jsonb(changed), NULLIF($3, '') FROM changed JOIN old_rows ON old_rows.\"id\" = changed.\"id\") INSERT INTO \"outbox_events\" (\"model\", \"access_name\", \"row_id\", \"payload\") SELECT 'Order', 'UpdateOrderStatus', changed.\"id\", to_jsonb(changed) FROM changed")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["DeleteOrder"], err = db.Prepare("WITH changed AS (DELETE FROM \"order\" WHERE (1 = 1) AND (\"id\" = $1) RETURNING *) INSERT INTO \"order_history\" (\"row_id\", \"operation\", \"old_values\", \"new_values\", \"actor\") SELECT changed.\"id\", 'delete', to_jsonb(changed), NULL, NULLIF($2, '') FROM changed")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["CountOrdersByStatus"], err = db.Prepare("SELECT \"order_status\", COUNT(*) AS \"count\", SUM(\"total_amount\") AS \"revenue\" FROM \"order\" WHERE (1 = 1) AND (\"order_date\" >= $1) GROUP BY \"order_status\" HAVING (COUNT(*) >= $2) ORDER BY \"revenue\" DESC")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["FindOrderHistory"], err = db.Prepare("SELECT \"id\", \"row_id\", \"operation\", \"old_values\", \"new_values\", \"actor\", \"changed_at\" FROM \"order_history\" WHERE \"row_id\" = $1 ORDER BY \"id\"")
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrPublish = errors.New("publishing event")
+
+const selectUnsentEventsQuery = "SELECT id, model, access_name, row_id, payload, created_at FROM outbox_events WHERE sent_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE"
+
+const markEventSentQuery = "UPDATE outbox_events SET sent_at = NOW() WHERE id = $1"
+
+const relayBatchSize = 100
+
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+type Event struct {
+	Id         int64            `json:"id"`
+	Model      string           `json:"model"`
+	AccessName string           `json:"access_name"`
+	RowId      string           `json:"row_id"`
+	Payload    *json.RawMessage `json:"payload"`
+	CreatedAt  *time.Time       `json:"created_at"`
+}
+
+func unsentEvents(ctx context.Context, tx *sql.Tx, limit int) ([]Event, error) {
+	rows, err := tx.QueryContext(ctx, selectUnsentEventsQuery, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []Event
+	for rows.Next() {
+		var item Event
+		scanErr := rows.Scan(&item.Id, &item.Model, &item.AccessName, &item.RowId, &item.Payload, &item.CreatedAt)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		events = append(events, item)
+	}
+	return events, rows.Err()
+}
+func RelayEventBatch(ctx context.Context, db *sql.DB, publisher Publisher, limit int) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	events, err := unsentEvents(ctx, tx, limit)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	var publishErr error
+	for _, event := range events {
+		publishErr = publisher.Publish(ctx, event)
+		if publishErr != nil {
+			publishErr = fmt.Errorf("%w %d: %w", ErrPublish, event.Id, publishErr)
+			break
+		}
+		_, err = tx.ExecContext(ctx, markEventSentQuery, event.Id)
+		if err != nil {
+			return 0, err
+		}
+		sent++
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return sent, publishErr
+}
+func RelayEvents(ctx context.Context, db *sql.DB, publisher Publisher, interval time.Duration) error {
+	for ctx.Err() == nil {
+		sent, err := RelayEventBatch(ctx, db, publisher, relayBatchSize)
+		if err != nil && !errors.Is(err, ErrPublish) {
+			return err
+		}
+		if err != nil || sent < relayBatchSize {
+			wait, cancel := context.WithTimeout(ctx, interval)
+			<-wait.Done()
+			cancel()
+		}
+	}
+	return ctx.Err()
+}
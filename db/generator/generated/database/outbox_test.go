@@ -0,0 +1,16 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayEventsStopsWithContext(t *testing.T) {
+	// The relay returns before reading the outbox when its context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, RelayEvents(ctx, nil, nil, time.Second), context.Canceled)
+}
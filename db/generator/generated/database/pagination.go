@@ -0,0 +1,31 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+func pageLimit(limit int, defaultLimit int, maxLimit int) int {
+	if limit <= 0 {
+		return defaultLimit
+	}
+	return min(limit, maxLimit)
+}
+func encodeCursor(values map[string]interface{}) string {
+	cursor, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(cursor)
+}
+func decodeCursor(cursor string) (sql.NullString, error) {
+	if cursor == "" {
+		return sql.NullString{}, nil
+	}
+	values, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || json.Unmarshal(values, &map[string]interface{}{}) != nil {
+		return sql.NullString{}, ErrInvalidCursor
+	}
+	return sql.NullString{String: string(values), Valid: true}, nil
+}
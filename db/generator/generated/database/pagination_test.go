@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageLimit(t *testing.T) {
+	assert.Equal(t, 100, pageLimit(0, 100, 1000))
+	assert.Equal(t, 20, pageLimit(20, 100, 1000))
+	assert.Equal(t, 1000, pageLimit(5000, 100, 1000))
+}
+
+func TestPageCursor(t *testing.T) {
+	id := "0190a3c4-5b6d-7e8f-9a0b-1c2d3e4f5a6b"
+	cursor, err := decodeCursor(encodeCursor(map[string]interface{}{"price": 9.5, "id": id}))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"price": 9.5, "id": "0190a3c4-5b6d-7e8f-9a0b-1c2d3e4f5a6b"}`, cursor.String)
+	assert.True(t, cursor.Valid)
+
+	// The first page has no cursor
+	cursor, err = decodeCursor("")
+	assert.NoError(t, err)
+	assert.False(t, cursor.Valid)
+
+	_, err = decodeCursor("not a cursor!")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	// Cursors hold the JSON object of the last row
+	_, err = decodeCursor(base64.RawURLEncoding.EncodeToString([]byte(id)))
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	// Invalid cursors fail before the statement runs, no database is needed
+	_, err = ListProducts(context.Background(), &Product_DB{preparedCache: map[string]*sql.Stmt{}}, ListProductsParams{Cursor: "not a cursor!"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
@@ -3,54 +3,138 @@ package database
 import (
 	"context"
 	"database/sql"
-	_ "github.com/lib/pq"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"strings"
+	"time"
 )
 
 type Product struct {
-	Sku         string  `db:"sku"`
-	ProductName string  `db:"product_name"`
-	Description string  `db:"description"`
-	Price       float64 `db:"price"`
+	Id          string         `db:"id"`
+	Version     int64          `db:"version"`
+	UpdatedAt   *time.Time     `db:"updated_at"`
+	Sku         string         `db:"sku"`
+	ProductName string         `db:"product_name"`
+	Description sql.NullString `db:"description"`
+	Price       float64        `db:"price"`
 }
 
 type Product_DB struct {
 	db            *sql.DB
 	preparedCache map[string]*sql.Stmt
+	tx            *sql.Tx
 }
 
 type GetProductByIDParams struct {
-	Sku interface{} `json:"sku"`
+	Sku string `json:"sku"`
 }
 
 type GetProductByIDRequest struct {
 	Params GetProductByIDParams `json:"params"`
 }
 
+type GetProductByIDRow struct {
+	Id    string  `db:"id"`
+	Sku   string  `db:"sku"`
+	Price float64 `db:"price"`
+}
+
+type ListProductsParams struct {
+	MaxPrice float64 `json:"max_price"`
+	Cursor   string  `json:"cursor"`
+	Limit    int     `json:"limit"`
+}
+
+type ListProductsRequest struct {
+	Params ListProductsParams `json:"params"`
+}
+
+type ListProductsRow struct {
+	Id          string  `db:"id"`
+	Sku         string  `db:"sku"`
+	ProductName string  `db:"product_name"`
+	Price       float64 `db:"price"`
+}
+
+type ListProductsPage struct {
+	Items      []ListProductsRow `json:"items"`
+	NextCursor string            `json:"next_cursor"`
+}
+
+type AddProductsParams struct {
+	Sku         string  `json:"sku"`
+	ProductName string  `json:"product_name"`
+	Price       float64 `json:"price"`
+}
+
+type AddProductsRequest struct {
+	Params AddProductsParams `json:"params"`
+}
+
+type ImportProductsParams struct {
+	Sku         string  `json:"sku"`
+	ProductName string  `json:"product_name"`
+	Price       float64 `json:"price"`
+}
+
+type ImportProductsRequest struct {
+	Params ImportProductsParams `json:"params"`
+}
+
+func (item *Product) Validate() error {
+	failed := make(map[string]string)
+	if message := validateRequired(item.Sku); message != "" {
+		failed["sku"] = message
+	}
+	if message := validateRequired(item.ProductName); message != "" {
+		failed["product_name"] = message
+	}
+	return validationResult(failed)
+}
 func NewProduct_DB(db *sql.DB, preparedCache map[string]*sql.Stmt) *Product_DB {
 	return &Product_DB{
 		db:            db,
 		preparedCache: preparedCache,
 	}
 }
+func (db *Product_DB) statement(ctx context.Context, name string) *sql.Stmt {
+	stmt := db.preparedCache[name]
+	if db.tx != nil {
+		return db.tx.StmtContext(ctx, stmt)
+	}
+	return stmt
+}
+func (db *Product_DB) withTx(tx *sql.Tx) *Product_DB {
+	return &Product_DB{db: db.db, preparedCache: db.preparedCache, tx: tx}
+}
+func (db *Product_DB) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if db.tx != nil {
+		return db.tx.QueryContext(ctx, query, args...)
+	}
+	return db.db.QueryContext(ctx, query, args...)
+}
 func GetProductByIDReadParams(params GetProductByIDParams) ([]interface{}, error) {
 	var values []interface{}
 	values = append(values, params.Sku)
 	return values, nil
 }
-func GetProductByID(ctx context.Context, db *Product_DB, requestParams GetProductByIDParams) ([]Product, error) {
-	stmt := db.preparedCache["GetProductByID"]
+func GetProductByID(ctx context.Context, db *Product_DB, requestParams GetProductByIDParams) ([]GetProductByIDRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	stmt := db.statement(ctx, "GetProductByID")
 	values, err := GetProductByIDReadParams(requestParams)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := stmt.Query(values...)
+	rows, err := stmt.QueryContext(ctx, values...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var results []Product
+	var results []GetProductByIDRow
 	for rows.Next() {
-		var item Product
+		var item GetProductByIDRow
 		scanErr := rows.Scan(&item.Id, &item.Sku, &item.Price)
 		if scanErr != nil {
 			return nil, scanErr
@@ -59,10 +143,209 @@ func GetProductByID(ctx context.Context, db *Product_DB, requestParams GetProduc
 	}
 	return results, nil
 }
+func ListProductsReadParams(params ListProductsParams) ([]interface{}, error) {
+	var values []interface{}
+	values = append(values, params.MaxPrice)
+	cursor, err := decodeCursor(params.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	values = append(values, cursor)
+	values = append(values, params.Limit+1)
+	return values, nil
+}
+func ListProducts(ctx context.Context, db *Product_DB, requestParams ListProductsParams) (*ListProductsPage, error) {
+	requestParams.Limit = pageLimit(requestParams.Limit, 100, 1000)
+	stmt := db.statement(ctx, "ListProducts")
+	values, err := ListProductsReadParams(requestParams)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []ListProductsRow
+	for rows.Next() {
+		var item ListProductsRow
+		scanErr := rows.Scan(&item.Id, &item.Sku, &item.ProductName, &item.Price)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		results = append(results, item)
+	}
+	page := &ListProductsPage{}
+	if len(results) > requestParams.Limit {
+		results = results[:requestParams.Limit]
+		last := results[len(results)-1]
+		page.NextCursor = encodeCursor(map[string]interface{}{"id": last.Id})
+	}
+	page.Items = results
+	return page, nil
+}
+func AddProductsReadParams(params AddProductsParams) ([]interface{}, error) {
+	var values []interface{}
+	values = append(values, params.Sku)
+	values = append(values, params.ProductName)
+	values = append(values, params.Price)
+	return values, nil
+}
+func AddProductsQuery(rows int) string {
+	tuples := make([]string, rows)
+	for row := range tuples {
+		tuples[row] = fmt.Sprintf("($%d, $%d, $%d)", row*3+1, row*3+2, row*3+3)
+	}
+	return "INSERT INTO \"product\" (\"sku\", \"product_name\", \"price\") VALUES " + strings.Join(tuples, ", ") + " RETURNING \"id\""
+}
+func AddProducts(ctx context.Context, db *Product_DB, rows []AddProductsParams) ([]string, error) {
+	var rowErrs []error
+	for i, row := range rows {
+		if err := row.Validate(); err != nil {
+			rowErrs = append(rowErrs, &RowError{Row: i, Err: err})
+		}
+	}
+	if len(rowErrs) > 0 {
+		return nil, errors.Join(rowErrs...)
+	}
+	owned := db.tx == nil
+	if owned {
+		tx, err := db.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+		db = db.withTx(tx)
+	}
+	ids := make([]string, 0, len(rows))
+	for start := 0; start < len(rows); start += 500 {
+		chunk := rows[start:min(start+500, len(rows))]
+		values := make([]interface{}, 0, len(chunk)*3)
+		for i, row := range chunk {
+			rowValues, err := AddProductsReadParams(row)
+			if err != nil {
+				return nil, &RowError{Row: start + i, Err: err}
+			}
+			values = append(values, rowValues...)
+		}
+		var chunkRows *sql.Rows
+		var err error
+		if len(chunk) == 500 {
+			chunkRows, err = db.statement(ctx, "AddProducts").QueryContext(ctx, values...)
+		} else {
+			chunkRows, err = db.query(ctx, AddProductsQuery(len(chunk)), values...)
+		}
+		if err == nil {
+			ids, err = scanIds(chunkRows, ids)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rows %d to %d: %w", start, start+len(chunk)-1, err)
+		}
+	}
+	if owned {
+		if err := db.tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+func ImportProductsReadParams(params ImportProductsParams) ([]interface{}, error) {
+	var values []interface{}
+	values = append(values, params.Sku)
+	values = append(values, params.ProductName)
+	values = append(values, params.Price)
+	return values, nil
+}
+func ImportProducts(ctx context.Context, db *Product_DB, rows []ImportProductsParams) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	var rowErrs []error
+	for i, row := range rows {
+		if err := row.Validate(); err != nil {
+			rowErrs = append(rowErrs, &RowError{Row: i, Err: err})
+		}
+	}
+	if len(rowErrs) > 0 {
+		return int64(0), errors.Join(rowErrs...)
+	}
+	tx, owned := db.tx, db.tx == nil
+	if owned {
+		var err error
+		tx, err = db.db.BeginTx(ctx, nil)
+		if err != nil {
+			return int64(0), err
+		}
+		defer tx.Rollback()
+	}
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("product", "sku", "product_name", "price"))
+	if err != nil {
+		return int64(0), err
+	}
+	defer stmt.Close()
+	for i, row := range rows {
+		values, err := ImportProductsReadParams(row)
+		if err != nil {
+			return int64(0), &RowError{Row: i, Err: err}
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return int64(0), copyRowError(err)
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return int64(0), copyRowError(err)
+	}
+	if err := stmt.Close(); err != nil {
+		return int64(0), copyRowError(err)
+	}
+	if owned {
+		if err := tx.Commit(); err != nil {
+			return int64(0), err
+		}
+	}
+	return int64(len(rows)), nil
+}
+func (params *GetProductByIDParams) Validate() error {
+	failed := make(map[string]string)
+	if message := validateRequired(params.Sku); message != "" {
+		failed["sku"] = message
+	}
+	return validationResult(failed)
+}
+func (params *ListProductsParams) Validate() error {
+	return nil
+}
+func (params *AddProductsParams) Validate() error {
+	failed := make(map[string]string)
+	if message := validateRequired(params.Sku); message != "" {
+		failed["sku"] = message
+	}
+	if message := validateRequired(params.ProductName); message != "" {
+		failed["product_name"] = message
+	}
+	return validationResult(failed)
+}
+func (params *ImportProductsParams) Validate() error {
+	failed := make(map[string]string)
+	if message := validateRequired(params.Sku); message != "" {
+		failed["sku"] = message
+	}
+	if message := validateRequired(params.ProductName); message != "" {
+		failed["product_name"] = message
+	}
+	return validationResult(failed)
+}
 func ProductPrepareStmts(db *sql.DB) (map[string]*sql.Stmt, error) {
 	preparedCache := make(map[string]*sql.Stmt)
 	var err error
-	preparedCache["GetProductByID"], err = db.Prepare("SELECT id, sku, price FROM product WHERE (1 = 1) AND (sku = $1)")
+	preparedCache["GetProductByID"], err = db.Prepare("SELECT \"id\", \"sku\", \"price\" FROM \"product\" WHERE (1 = 1) AND (\"sku\" = $1)")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["ListProducts"], err = db.Prepare("SELECT \"id\", \"sku\", \"product_name\", \"price\" FROM \"product\" WHERE (1 = 1) AND (\"price\" <= $1) AND ($2::jsonb IS NULL OR \"id\" > (SELECT prev.\"id\" FROM jsonb_populate_record(NULL::\"product\", $2::jsonb) AS prev)) ORDER BY \"id\" LIMIT $3")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["AddProducts"], err = db.Prepare(AddProductsQuery(500))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,7 @@
+package database
+
+import (
+	"errors"
+)
+
+var ErrInvalidSort = errors.New("invalid sort")
@@ -0,0 +1,22 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortQuery(t *testing.T) {
+	query, err := ListOrdersByStatusSortQuery("")
+	assert.NoError(t, err)
+	assert.Equal(t, "ListOrdersByStatus", query)
+
+	query, err = ListOrdersByStatusSortQuery("total")
+	assert.NoError(t, err)
+	assert.Equal(t, "ListOrdersByStatusByTotal", query)
+
+	// Sorts outside of the sort options are rejected before the database is queried
+	_, err = ListOrdersByStatus(context.Background(), &Order_DB{}, ListOrdersByStatusParams{Sort: "order_status"})
+	assert.ErrorIs(t, err, ErrInvalidSort)
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTxRollsBack(t *testing.T) {
+	if err := InitEcommerceDb(); err != nil {
+		t.Skipf("database not available: %v", err)
+	}
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+	err := EcommerceDb.WithTx(ctx, func(tx *EcommerceDbTx) error {
+		if _, err := AddUser(ctx, tx.User, AddUserParams{Name: "Jane Roe", Email: "jane.roe@example.com"}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+
+	// The user added in the rolled back transaction is not found outside of it
+	users, err := GetUserByEmail(ctx, EcommerceDb.User, GetUserByEmailParams{Email: "jane.roe@example.com"})
+	assert.NoError(t, err)
+	assert.Empty(t, users)
+
+	assert.Panics(t, func() {
+		_ = EcommerceDb.WithTx(ctx, func(tx *EcommerceDbTx) error {
+			panic("abort")
+		})
+	})
+}
@@ -3,48 +3,160 @@ package database
 import (
 	"context"
 	"database/sql"
-	_ "github.com/lib/pq"
+	"errors"
+	"github.com/lib/pq"
+	"time"
 )
 
 type User struct {
-	Email           string `db:"email"`
-	Name            string `db:"name"`
-	ShippingAddress string `db:"shipping_address"`
-	BillingAddress  string `db:"billing_address"`
+	Id              string         `db:"id"`
+	Version         int64          `db:"version"`
+	UpdatedAt       *time.Time     `db:"updated_at"`
+	DeletedAt       *time.Time     `db:"deleted_at"`
+	Email           string         `db:"email"`
+	Name            string         `db:"name"`
+	ShippingAddress sql.NullString `db:"shipping_address"`
+	BillingAddress  sql.NullString `db:"billing_address"`
 }
 
 type User_DB struct {
 	db            *sql.DB
 	preparedCache map[string]*sql.Stmt
+	tx            *sql.Tx
 }
 
 type GetUserByEmailParams struct {
-	Email interface{} `json:"email"`
+	Email string `json:"email"`
 }
 
 type GetUserByEmailRequest struct {
 	Params GetUserByEmailParams `json:"params"`
 }
 
+type GetUserByEmailRow struct {
+	Name            string         `db:"name"`
+	Email           string         `db:"email"`
+	ShippingAddress sql.NullString `db:"shipping_address"`
+	BillingAddress  sql.NullString `db:"billing_address"`
+}
+
+type GetUserByEmailIncludeDeletedParams struct {
+	Email string `json:"email"`
+}
+
+type GetUserByEmailIncludeDeletedRequest struct {
+	Params GetUserByEmailIncludeDeletedParams `json:"params"`
+}
+
+type GetUserByEmailIncludeDeletedRow struct {
+	Name            string         `db:"name"`
+	Email           string         `db:"email"`
+	ShippingAddress sql.NullString `db:"shipping_address"`
+	BillingAddress  sql.NullString `db:"billing_address"`
+}
+
 type GetUserByNameParams struct {
-	Name interface{} `json:"name"`
+	Name string `json:"name"`
 }
 
 type GetUserByNameRequest struct {
 	Params GetUserByNameParams `json:"params"`
 }
 
+type GetUserByNameRow struct {
+	Name            string         `db:"name"`
+	Email           string         `db:"email"`
+	ShippingAddress sql.NullString `db:"shipping_address"`
+	BillingAddress  sql.NullString `db:"billing_address"`
+}
+
+type GetUserByNameIncludeDeletedParams struct {
+	Name string `json:"name"`
+}
+
+type GetUserByNameIncludeDeletedRequest struct {
+	Params GetUserByNameIncludeDeletedParams `json:"params"`
+}
+
+type GetUserByNameIncludeDeletedRow struct {
+	Name            string         `db:"name"`
+	Email           string         `db:"email"`
+	ShippingAddress sql.NullString `db:"shipping_address"`
+	BillingAddress  sql.NullString `db:"billing_address"`
+}
+
 type GetUserByIDParams struct {
-	Id interface{} `json:"id"`
+	Id []string `json:"id"`
 }
 
 type GetUserByIDRequest struct {
 	Params GetUserByIDParams `json:"params"`
 }
 
+type GetUserByIDRow struct {
+	Name            string         `db:"name"`
+	Email           string         `db:"email"`
+	ShippingAddress sql.NullString `db:"shipping_address"`
+	BillingAddress  sql.NullString `db:"billing_address"`
+}
+
+type GetUserByIDIncludeDeletedParams struct {
+	Id []string `json:"id"`
+}
+
+type GetUserByIDIncludeDeletedRequest struct {
+	Params GetUserByIDIncludeDeletedParams `json:"params"`
+}
+
+type GetUserByIDIncludeDeletedRow struct {
+	Name            string         `db:"name"`
+	Email           string         `db:"email"`
+	ShippingAddress sql.NullString `db:"shipping_address"`
+	BillingAddress  sql.NullString `db:"billing_address"`
+}
+
+type GetUserWithOrdersParams struct {
+	Email string `json:"email"`
+}
+
+type GetUserWithOrdersRequest struct {
+	Params GetUserWithOrdersParams `json:"params"`
+}
+
+type GetUserWithOrdersRow struct {
+	Id    string `db:"id"`
+	Name  string `db:"name"`
+	Email string `db:"email"`
+}
+
+type GetUserWithOrdersResult struct {
+	User   GetUserWithOrdersRow
+	Orders []Order
+}
+
+type GetUserWithOrdersIncludeDeletedParams struct {
+	Email string `json:"email"`
+}
+
+type GetUserWithOrdersIncludeDeletedRequest struct {
+	Params GetUserWithOrdersIncludeDeletedParams `json:"params"`
+}
+
+type GetUserWithOrdersIncludeDeletedRow struct {
+	Id    string `db:"id"`
+	Name  string `db:"name"`
+	Email string `db:"email"`
+}
+
+type GetUserWithOrdersIncludeDeletedResult struct {
+	User   GetUserWithOrdersIncludeDeletedRow
+	Orders []Order
+}
+
 type UpdateUserParams struct {
-	Name interface{} `json:"name"`
-	Id   interface{} `json:"id"`
+	Name    string `json:"name"`
+	Id      string `json:"id"`
+	Version int64  `json:"version"`
 }
 
 type UpdateUserRequest struct {
@@ -52,10 +164,10 @@ type UpdateUserRequest struct {
 }
 
 type AddUserParams struct {
-	Name            interface{} `json:"name"`
-	Email           interface{} `json:"email"`
-	ShippingAddress interface{} `json:"shipping_address"`
-	BillingAddress  interface{} `json:"billing_address"`
+	Name            string         `json:"name"`
+	Email           string         `json:"email"`
+	ShippingAddress sql.NullString `json:"shipping_address"`
+	BillingAddress  sql.NullString `json:"billing_address"`
 }
 
 type AddUserRequest struct {
@@ -63,10 +175,10 @@ type AddUserRequest struct {
 }
 
 type AddOrReplaceUserParams struct {
-	Name            interface{} `json:"name"`
-	Email           interface{} `json:"email"`
-	ShippingAddress interface{} `json:"shipping_address"`
-	BillingAddress  interface{} `json:"billing_address"`
+	Name            string         `json:"name"`
+	Email           string         `json:"email"`
+	ShippingAddress sql.NullString `json:"shipping_address"`
+	BillingAddress  sql.NullString `json:"billing_address"`
 }
 
 type AddOrReplaceUserRequest struct {
@@ -74,38 +186,91 @@ type AddOrReplaceUserRequest struct {
 }
 
 type DeleteUserParams struct {
-	Id interface{} `json:"id"`
+	Id      string `json:"id"`
+	Version int64  `json:"version"`
 }
 
 type DeleteUserRequest struct {
 	Params DeleteUserParams `json:"params"`
 }
 
+type RestoreUserParams struct {
+	Id string `json:"id"`
+}
+
+type RestoreUserRequest struct {
+	Params RestoreUserParams `json:"params"`
+}
+
+func (item *User) Validate() error {
+	failed := make(map[string]string)
+	if message := validatePattern(item.Email, emailPattern, "must be a valid email address"); message != "" {
+		failed["email"] = message
+	}
+	return validationResult(failed)
+}
 func NewUser_DB(db *sql.DB, preparedCache map[string]*sql.Stmt) *User_DB {
 	return &User_DB{
 		db:            db,
 		preparedCache: preparedCache,
 	}
 }
+func (db *User_DB) statement(ctx context.Context, name string) *sql.Stmt {
+	stmt := db.preparedCache[name]
+	if db.tx != nil {
+		return db.tx.StmtContext(ctx, stmt)
+	}
+	return stmt
+}
+func (db *User_DB) withTx(tx *sql.Tx) *User_DB {
+	return &User_DB{db: db.db, preparedCache: db.preparedCache, tx: tx}
+}
 func GetUserByEmailReadParams(params GetUserByEmailParams) ([]interface{}, error) {
 	var values []interface{}
 	values = append(values, params.Email)
 	return values, nil
 }
-func GetUserByEmail(ctx context.Context, db *User_DB, requestParams GetUserByEmailParams) ([]User, error) {
-	stmt := db.preparedCache["GetUserByEmail"]
+func GetUserByEmail(ctx context.Context, db *User_DB, requestParams GetUserByEmailParams) ([]GetUserByEmailRow, error) {
+	stmt := db.statement(ctx, "GetUserByEmail")
 	values, err := GetUserByEmailReadParams(requestParams)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := stmt.Query(values...)
+	rows, err := stmt.QueryContext(ctx, values...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var results []User
+	var results []GetUserByEmailRow
 	for rows.Next() {
-		var item User
+		var item GetUserByEmailRow
+		scanErr := rows.Scan(&item.Name, &item.Email, &item.ShippingAddress, &item.BillingAddress)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		results = append(results, item)
+	}
+	return results, nil
+}
+func GetUserByEmailIncludeDeletedReadParams(params GetUserByEmailIncludeDeletedParams) ([]interface{}, error) {
+	var values []interface{}
+	values = append(values, params.Email)
+	return values, nil
+}
+func GetUserByEmailIncludeDeleted(ctx context.Context, db *User_DB, requestParams GetUserByEmailIncludeDeletedParams) ([]GetUserByEmailIncludeDeletedRow, error) {
+	stmt := db.statement(ctx, "GetUserByEmailIncludeDeleted")
+	values, err := GetUserByEmailIncludeDeletedReadParams(requestParams)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []GetUserByEmailIncludeDeletedRow
+	for rows.Next() {
+		var item GetUserByEmailIncludeDeletedRow
 		scanErr := rows.Scan(&item.Name, &item.Email, &item.ShippingAddress, &item.BillingAddress)
 		if scanErr != nil {
 			return nil, scanErr
@@ -119,20 +284,47 @@ func GetUserByNameReadParams(params GetUserByNameParams) ([]interface{}, error)
 	values = append(values, params.Name)
 	return values, nil
 }
-func GetUserByName(ctx context.Context, db *User_DB, requestParams GetUserByNameParams) ([]User, error) {
-	stmt := db.preparedCache["GetUserByName"]
+func GetUserByName(ctx context.Context, db *User_DB, requestParams GetUserByNameParams) ([]GetUserByNameRow, error) {
+	stmt := db.statement(ctx, "GetUserByName")
 	values, err := GetUserByNameReadParams(requestParams)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := stmt.Query(values...)
+	rows, err := stmt.QueryContext(ctx, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []GetUserByNameRow
+	for rows.Next() {
+		var item GetUserByNameRow
+		scanErr := rows.Scan(&item.Name, &item.Email, &item.ShippingAddress, &item.BillingAddress)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		results = append(results, item)
+	}
+	return results, nil
+}
+func GetUserByNameIncludeDeletedReadParams(params GetUserByNameIncludeDeletedParams) ([]interface{}, error) {
+	var values []interface{}
+	values = append(values, params.Name)
+	return values, nil
+}
+func GetUserByNameIncludeDeleted(ctx context.Context, db *User_DB, requestParams GetUserByNameIncludeDeletedParams) ([]GetUserByNameIncludeDeletedRow, error) {
+	stmt := db.statement(ctx, "GetUserByNameIncludeDeleted")
+	values, err := GetUserByNameIncludeDeletedReadParams(requestParams)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, values...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var results []User
+	var results []GetUserByNameIncludeDeletedRow
 	for rows.Next() {
-		var item User
+		var item GetUserByNameIncludeDeletedRow
 		scanErr := rows.Scan(&item.Name, &item.Email, &item.ShippingAddress, &item.BillingAddress)
 		if scanErr != nil {
 			return nil, scanErr
@@ -143,23 +335,50 @@ func GetUserByName(ctx context.Context, db *User_DB, requestParams GetUserByName
 }
 func GetUserByIDReadParams(params GetUserByIDParams) ([]interface{}, error) {
 	var values []interface{}
-	values = append(values, params.Id)
+	values = append(values, pq.Array(params.Id))
 	return values, nil
 }
-func GetUserByID(ctx context.Context, db *User_DB, requestParams GetUserByIDParams) ([]User, error) {
-	stmt := db.preparedCache["GetUserByID"]
+func GetUserByID(ctx context.Context, db *User_DB, requestParams GetUserByIDParams) ([]GetUserByIDRow, error) {
+	stmt := db.statement(ctx, "GetUserByID")
 	values, err := GetUserByIDReadParams(requestParams)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := stmt.Query(values...)
+	rows, err := stmt.QueryContext(ctx, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []GetUserByIDRow
+	for rows.Next() {
+		var item GetUserByIDRow
+		scanErr := rows.Scan(&item.Name, &item.Email, &item.ShippingAddress, &item.BillingAddress)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		results = append(results, item)
+	}
+	return results, nil
+}
+func GetUserByIDIncludeDeletedReadParams(params GetUserByIDIncludeDeletedParams) ([]interface{}, error) {
+	var values []interface{}
+	values = append(values, pq.Array(params.Id))
+	return values, nil
+}
+func GetUserByIDIncludeDeleted(ctx context.Context, db *User_DB, requestParams GetUserByIDIncludeDeletedParams) ([]GetUserByIDIncludeDeletedRow, error) {
+	stmt := db.statement(ctx, "GetUserByIDIncludeDeleted")
+	values, err := GetUserByIDIncludeDeletedReadParams(requestParams)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, values...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var results []User
+	var results []GetUserByIDIncludeDeletedRow
 	for rows.Next() {
-		var item User
+		var item GetUserByIDIncludeDeletedRow
 		scanErr := rows.Scan(&item.Name, &item.Email, &item.ShippingAddress, &item.BillingAddress)
 		if scanErr != nil {
 			return nil, scanErr
@@ -168,27 +387,134 @@ func GetUserByID(ctx context.Context, db *User_DB, requestParams GetUserByIDPara
 	}
 	return results, nil
 }
+func GetUserWithOrdersReadParams(params GetUserWithOrdersParams) ([]interface{}, error) {
+	var values []interface{}
+	values = append(values, params.Email)
+	return values, nil
+}
+func GetUserWithOrders(ctx context.Context, db *User_DB, requestParams GetUserWithOrdersParams) ([]GetUserWithOrdersResult, error) {
+	stmt := db.statement(ctx, "GetUserWithOrders")
+	values, err := GetUserWithOrdersReadParams(requestParams)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []GetUserWithOrdersResult
+	for rows.Next() {
+		var item GetUserWithOrdersResult
+		scanErr := rows.Scan(&item.User.Id, &item.User.Name, &item.User.Email)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		results = append(results, item)
+	}
+	ids := make([]string, 0, len(results))
+	positions := make(map[string]int, len(results))
+	for i, result := range results {
+		ids = append(ids, result.User.Id)
+		positions[result.User.Id] = i
+	}
+	if len(ids) == 0 {
+		return results, nil
+	}
+	orderStmt := db.statement(ctx, "GetUserWithOrdersOrder")
+	orderRows, err := orderStmt.QueryContext(ctx, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer orderRows.Close()
+	for orderRows.Next() {
+		var order Order
+		scanErr := orderRows.Scan(&order.Id, &order.OrderDate, &order.OrderStatus, &order.UserId)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		position := positions[string(order.UserId)]
+		results[position].Orders = append(results[position].Orders, order)
+	}
+	return results, nil
+}
+func GetUserWithOrdersIncludeDeletedReadParams(params GetUserWithOrdersIncludeDeletedParams) ([]interface{}, error) {
+	var values []interface{}
+	values = append(values, params.Email)
+	return values, nil
+}
+func GetUserWithOrdersIncludeDeleted(ctx context.Context, db *User_DB, requestParams GetUserWithOrdersIncludeDeletedParams) ([]GetUserWithOrdersIncludeDeletedResult, error) {
+	stmt := db.statement(ctx, "GetUserWithOrdersIncludeDeleted")
+	values, err := GetUserWithOrdersIncludeDeletedReadParams(requestParams)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.QueryContext(ctx, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []GetUserWithOrdersIncludeDeletedResult
+	for rows.Next() {
+		var item GetUserWithOrdersIncludeDeletedResult
+		scanErr := rows.Scan(&item.User.Id, &item.User.Name, &item.User.Email)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		results = append(results, item)
+	}
+	ids := make([]string, 0, len(results))
+	positions := make(map[string]int, len(results))
+	for i, result := range results {
+		ids = append(ids, result.User.Id)
+		positions[result.User.Id] = i
+	}
+	if len(ids) == 0 {
+		return results, nil
+	}
+	orderStmt := db.statement(ctx, "GetUserWithOrdersIncludeDeletedOrder")
+	orderRows, err := orderStmt.QueryContext(ctx, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer orderRows.Close()
+	for orderRows.Next() {
+		var order Order
+		scanErr := orderRows.Scan(&order.Id, &order.OrderDate, &order.OrderStatus, &order.UserId)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		position := positions[string(order.UserId)]
+		results[position].Orders = append(results[position].Orders, order)
+	}
+	return results, nil
+}
 func UpdateUserReadParams(params UpdateUserParams) ([]interface{}, error) {
 	var values []interface{}
 	values = append(values, params.Name)
 	values = append(values, params.Id)
+	values = append(values, params.Version)
 	return values, nil
 }
 func UpdateUser(ctx context.Context, db *User_DB, requestParams UpdateUserParams) (int64, error) {
-	stmt := db.preparedCache["UpdateUser"]
-	values, err := UpdateUserReadParams(requestParams)
+	err := requestParams.Validate()
 	if err != nil {
 		return int64(0), err
 	}
-	result, err := stmt.Exec(values...)
+	stmt := db.statement(ctx, "UpdateUser")
+	values, err := UpdateUserReadParams(requestParams)
 	if err != nil {
 		return int64(0), err
 	}
-	rowsAffected, err := result.RowsAffected()
+	var version int64
+	err = stmt.QueryRowContext(ctx, values...).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return int64(0), ErrVersionConflict
+	}
 	if err != nil {
 		return int64(0), err
 	}
-	return rowsAffected, nil
+	return version, nil
 }
 func AddUserReadParams(params AddUserParams) ([]interface{}, error) {
 	var values []interface{}
@@ -198,16 +524,20 @@ func AddUserReadParams(params AddUserParams) ([]interface{}, error) {
 	values = append(values, params.BillingAddress)
 	return values, nil
 }
-func AddUser(ctx context.Context, db *User_DB, requestParams AddUserParams) (int64, error) {
-	stmt := db.preparedCache["AddUser"]
+func AddUser(ctx context.Context, db *User_DB, requestParams AddUserParams) (string, error) {
+	err := requestParams.Validate()
+	if err != nil {
+		return "", err
+	}
+	stmt := db.statement(ctx, "AddUser")
 	values, err := AddUserReadParams(requestParams)
 	if err != nil {
-		return int64(0), err
+		return "", err
 	}
-	var id int64
-	queryErr := stmt.QueryRow(values...).Scan(&id)
+	var id string
+	queryErr := stmt.QueryRowContext(ctx, values...).Scan(&id)
 	if queryErr != nil {
-		return int64(0), queryErr
+		return "", queryErr
 	}
 	return id, nil
 }
@@ -223,32 +553,41 @@ func AddOrReplaceUserReadParams(params AddOrReplaceUserParams) ([]interface{}, e
 	values = append(values, params.BillingAddress)
 	return values, nil
 }
-func AddOrReplaceUser(ctx context.Context, db *User_DB, requestParams AddOrReplaceUserParams) (int64, bool, error) {
-	stmt := db.preparedCache["AddOrReplaceUser"]
+func AddOrReplaceUser(ctx context.Context, db *User_DB, requestParams AddOrReplaceUserParams) (string, bool, error) {
+	err := requestParams.Validate()
+	if err != nil {
+		return "", false, err
+	}
+	stmt := db.statement(ctx, "AddOrReplaceUser")
 	values, err := AddOrReplaceUserReadParams(requestParams)
 	if err != nil {
-		return int64(0), false, err
+		return "", false, err
 	}
-	var id int64
+	var id string
 	var inserted bool
-	queryErr := stmt.QueryRow(values...).Scan(&id, &inserted)
+	queryErr := stmt.QueryRowContext(ctx, values...).Scan(&id, &inserted)
 	if queryErr != nil {
-		return int64(0), false, queryErr
+		return "", false, queryErr
 	}
 	return id, inserted, nil
 }
 func DeleteUserReadParams(params DeleteUserParams) ([]interface{}, error) {
 	var values []interface{}
 	values = append(values, params.Id)
+	values = append(values, params.Version)
 	return values, nil
 }
 func DeleteUser(ctx context.Context, db *User_DB, requestParams DeleteUserParams) (int64, error) {
-	stmt := db.preparedCache["DeleteUser"]
+	err := requestParams.Validate()
+	if err != nil {
+		return int64(0), err
+	}
+	stmt := db.statement(ctx, "DeleteUser")
 	values, err := DeleteUserReadParams(requestParams)
 	if err != nil {
 		return int64(0), err
 	}
-	result, err := stmt.Exec(values...)
+	result, err := stmt.ExecContext(ctx, values...)
 	if err != nil {
 		return int64(0), err
 	}
@@ -256,36 +595,163 @@ func DeleteUser(ctx context.Context, db *User_DB, requestParams DeleteUserParams
 	if err != nil {
 		return int64(0), err
 	}
+	if rowsAffected == 0 {
+		return int64(0), ErrVersionConflict
+	}
 	return rowsAffected, nil
 }
+func RestoreUserReadParams(params RestoreUserParams) ([]interface{}, error) {
+	var values []interface{}
+	values = append(values, params.Id)
+	return values, nil
+}
+func RestoreUser(ctx context.Context, db *User_DB, requestParams RestoreUserParams) (int64, error) {
+	err := requestParams.Validate()
+	if err != nil {
+		return int64(0), err
+	}
+	stmt := db.statement(ctx, "RestoreUser")
+	values, err := RestoreUserReadParams(requestParams)
+	if err != nil {
+		return int64(0), err
+	}
+	result, err := stmt.ExecContext(ctx, values...)
+	if err != nil {
+		return int64(0), err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return int64(0), err
+	}
+	return rowsAffected, nil
+}
+func (params *GetUserByEmailParams) Validate() error {
+	failed := make(map[string]string)
+	if message := validatePattern(params.Email, emailPattern, "must be a valid email address"); message != "" {
+		failed["email"] = message
+	}
+	return validationResult(failed)
+}
+func (params *GetUserByEmailIncludeDeletedParams) Validate() error {
+	failed := make(map[string]string)
+	if message := validatePattern(params.Email, emailPattern, "must be a valid email address"); message != "" {
+		failed["email"] = message
+	}
+	return validationResult(failed)
+}
+func (params *GetUserByNameParams) Validate() error {
+	return nil
+}
+func (params *GetUserByNameIncludeDeletedParams) Validate() error {
+	return nil
+}
+func (params *GetUserByIDParams) Validate() error {
+	return nil
+}
+func (params *GetUserByIDIncludeDeletedParams) Validate() error {
+	return nil
+}
+func (params *GetUserWithOrdersParams) Validate() error {
+	failed := make(map[string]string)
+	if message := validatePattern(params.Email, emailPattern, "must be a valid email address"); message != "" {
+		failed["email"] = message
+	}
+	return validationResult(failed)
+}
+func (params *GetUserWithOrdersIncludeDeletedParams) Validate() error {
+	failed := make(map[string]string)
+	if message := validatePattern(params.Email, emailPattern, "must be a valid email address"); message != "" {
+		failed["email"] = message
+	}
+	return validationResult(failed)
+}
+func (params *UpdateUserParams) Validate() error {
+	return nil
+}
+func (params *AddUserParams) Validate() error {
+	failed := make(map[string]string)
+	if message := validatePattern(params.Email, emailPattern, "must be a valid email address"); message != "" {
+		failed["email"] = message
+	}
+	return validationResult(failed)
+}
+func (params *AddOrReplaceUserParams) Validate() error {
+	failed := make(map[string]string)
+	if message := validatePattern(params.Email, emailPattern, "must be a valid email address"); message != "" {
+		failed["email"] = message
+	}
+	return validationResult(failed)
+}
+func (params *DeleteUserParams) Validate() error {
+	return nil
+}
+func (params *RestoreUserParams) Validate() error {
+	failed := make(map[string]string)
+	if message := validateRequired(params.Id); message != "" {
+		failed["id"] = message
+	}
+	return validationResult(failed)
+}
 func UserPrepareStmts(db *sql.DB) (map[string]*sql.Stmt, error) {
 	preparedCache := make(map[string]*sql.Stmt)
 	var err error
-	preparedCache["GetUserByEmail"], err = db.Prepare("SELECT name, email, shipping_address, billing_address FROM user WHERE (1 = 1) AND (email = $1)")
+	preparedCache["GetUserByEmail"], err = db.Prepare("SELECT \"name\", \"email\", \"shipping_address\", \"billing_address\" FROM \"user\" WHERE (1 = 1) AND (\"email\" = $1) AND (\"deleted_at\" IS NULL)")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["GetUserByEmailIncludeDeleted"], err = db.Prepare("SELECT \"name\", \"email\", \"shipping_address\", \"billing_address\" FROM \"user\" WHERE (1 = 1) AND (\"email\" = $1)")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["GetUserByName"], err = db.Prepare("SELECT \"name\", \"email\", \"shipping_address\", \"billing_address\" FROM \"user\" WHERE (1 = 1) AND (\"name\" = $1) AND (\"deleted_at\" IS NULL)")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["GetUserByNameIncludeDeleted"], err = db.Prepare("SELECT \"name\", \"email\", \"shipping_address\", \"billing_address\" FROM \"user\" WHERE (1 = 1) AND (\"name\" = $1)")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["GetUserByID"], err = db.Prepare("SELECT \"name\", \"email\", \"shipping_address\", \"billing_address\" FROM \"user\" WHERE (1 = 1) AND (\"id\" = ANY($1)) AND (\"deleted_at\" IS NULL)")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["GetUserByIDIncludeDeleted"], err = db.Prepare("SELECT \"name\", \"email\", \"shipping_address\", \"billing_address\" FROM \"user\" WHERE (1 = 1) AND (\"id\" = ANY($1))")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["GetUserWithOrders"], err = db.Prepare("SELECT \"id\", \"name\", \"email\" FROM \"user\" WHERE (1 = 1) AND (\"email\" = $1) AND (\"deleted_at\" IS NULL)")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["GetUserWithOrdersOrder"], err = db.Prepare("SELECT \"id\", \"order_date\", \"order_status\", \"user_id\" FROM \"order\" WHERE (1 = 1) AND (\"user_id\" = ANY($1))")
+	if err != nil {
+		return nil, err
+	}
+	preparedCache["GetUserWithOrdersIncludeDeleted"], err = db.Prepare("SELECT \"id\", \"name\", \"email\" FROM \"user\" WHERE (1 = 1) AND (\"email\" = $1)")
 	if err != nil {
 		return nil, err
 	}
-	preparedCache["GetUserByName"], err = db.Prepare("SELECT name, email, shipping_address, billing_address FROM user WHERE (1 = 1) AND (name = $1)")
+	preparedCache["GetUserWithOrdersIncludeDeletedOrder"], err = db.Prepare("SELECT \"id\", \"order_date\", \"order_status\", \"user_id\" FROM \"order\" WHERE (1 = 1) AND (\"user_id\" = ANY($1))")
 	if err != nil {
 		return nil, err
 	}
-	preparedCache["GetUserByID"], err = db.Prepare("SELECT name, email, shipping_address, billing_address FROM user WHERE (1 = 1) AND (id = ANY($1))")
+	preparedCache["UpdateUser"], err = db.Prepare("UPDATE \"user\" SET \"name\" = $1, \"version\" = \"version\" + 1 WHERE (1 = 1) AND (\"id\" = $2) AND (\"version\" = $3) AND (\"deleted_at\" IS NULL) RETURNING \"version\"")
 	if err != nil {
 		return nil, err
 	}
-	preparedCache["UpdateUser"], err = db.Prepare("UPDATE user SET name = $1 WHERE (1 = 1) AND (id = $2)")
+	preparedCache["AddUser"], err = db.Prepare("INSERT INTO \"user\" (\"name\", \"email\", \"shipping_address\", \"billing_address\") VALUES ($1, $2, $3, $4) RETURNING \"id\"")
 	if err != nil {
 		return nil, err
 	}
-	preparedCache["AddUser"], err = db.Prepare("INSERT INTO user (name, email, shipping_address, billing_address) VALUES ($1, $2, $3, $4) RETURNING id")
+	preparedCache["AddOrReplaceUser"], err = db.Prepare("INSERT INTO \"user\" (\"name\", \"email\", \"shipping_address\", \"billing_address\") VALUES ($1, $2, $3, $4) ON CONFLICT (\"email\") WHERE (\"deleted_at\" IS NULL) DO UPDATE SET \"name\" = $5, \"email\" = $6, \"shipping_address\" = $7, \"billing_address\" = $8 RETURNING \"id\", (xmax = 0) AS inserted")
 	if err != nil {
 		return nil, err
 	}
-	preparedCache["AddOrReplaceUser"], err = db.Prepare("INSERT INTO user (name, email, shipping_address, billing_address) VALUES ($1, $2, $3, $4) ON CONFLICT DO UPDATE SET name = $5, email = $6, shipping_address = $7, billing_address = $8 RETURNING id, (xmax = 0)")
+	preparedCache["DeleteUser"], err = db.Prepare("UPDATE \"user\" SET \"deleted_at\" = NOW(), \"version\" = \"version\" + 1 WHERE (1 = 1) AND (\"id\" = $1) AND (\"version\" = $2) AND (\"deleted_at\" IS NULL)")
 	if err != nil {
 		return nil, err
 	}
-	preparedCache["DeleteUser"], err = db.Prepare("DELETE FROM user WHERE (1 = 1) AND (id = $1)")
+	preparedCache["RestoreUser"], err = db.Prepare("UPDATE \"user\" SET \"deleted_at\" = NULL, \"version\" = \"version\" + 1 WHERE (1 = 1) AND (\"id\" = $1) AND (\"deleted_at\" IS NOT NULL)")
 	if err != nil {
 		return nil, err
 	}
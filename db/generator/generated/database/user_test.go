@@ -10,8 +10,43 @@ import (
 func TestGetUserByName(t *testing.T) {
 
 	// Test case 1: Successful retrieval of user
-	InitEcommerceDb()
+	if err := InitEcommerceDb(); err != nil {
+		t.Skipf("database not available: %v", err)
+	}
 	users, err := GetUserByName(context.Background(), EcommerceDb.User, GetUserByNameParams{Name: "John Doe"})
 	assert.Nil(t, err)
 	assert.Equal(t, "John Doe", users[0].Name)
 }
+
+func TestAddUserParamsValidate(t *testing.T) {
+	params := AddUserParams{Name: "John Doe", Email: "john.doe"}
+	err := params.Validate()
+	assert.EqualError(t, err, "validation failed: email must be a valid email address")
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, map[string]string{"email": "must be a valid email address"}, validationErr.Fields)
+
+	// Validation fails before the statement runs, no database is needed
+	_, err = AddUser(context.Background(), &User_DB{}, params)
+	assert.ErrorAs(t, err, &validationErr)
+
+	params.Email = "john.doe@example.com"
+	assert.NoError(t, params.Validate())
+	assert.NoError(t, (&AddUserParams{}).Validate())
+}
+
+func TestRestoreUserParamsValidate(t *testing.T) {
+	// Restores are validated like their delete, the id of DeleteUser has no validations
+	assert.NoError(t, (&RestoreUserParams{Id: "00000000-0000-0000-0000-000000000000"}).Validate())
+	assert.NoError(t, (&DeleteUserParams{Id: "00000000-0000-0000-0000-000000000000"}).Validate())
+}
+
+func TestUpdateUserVersionConflict(t *testing.T) {
+	if err := InitEcommerceDb(); err != nil {
+		t.Skipf("database not available: %v", err)
+	}
+	// Versions start at 1, an update expecting version 0 finds no row to update
+	_, err := UpdateUser(context.Background(), EcommerceDb.User,
+		UpdateUserParams{Name: "John Doe", Id: "00000000-0000-0000-0000-000000000000", Version: 0})
+	assert.ErrorIs(t, err, ErrVersionConflict)
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var emailPattern *regexp.Regexp = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+var numericPattern *regexp.Regexp = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?$`)
+
+var alphaPattern *regexp.Regexp = regexp.MustCompile(`^[A-Za-z]+$`)
+
+var alphaNumericPattern *regexp.Regexp = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+var alphaNumericSpecialPattern *regexp.Regexp = regexp.MustCompile(`^[\x20-\x7E]+$`)
+
+var urlPattern *regexp.Regexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*://[^\s/?#]+[^\s]*$`)
+
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	attributes := make([]string, 0, len(e.Fields))
+	for attribute := range e.Fields {
+		attributes = append(attributes, attribute)
+	}
+	sort.Strings(attributes)
+	messages := make([]string, 0, len(attributes))
+	for _, attribute := range attributes {
+		messages = append(messages, attribute+" "+e.Fields[attribute])
+	}
+	return "validation failed: " + strings.Join(messages, ", ")
+}
+func validationResult(failed map[string]string) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: failed}
+}
+func firstValidationMessage(messages ...string) string {
+	for _, message := range messages {
+		if message != "" {
+			return message
+		}
+	}
+	return ""
+}
+func validationText(value interface{}) (string, bool) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		driverValue, err := valuer.Value()
+		if err != nil || driverValue == nil {
+			return "", false
+		}
+		value = driverValue
+	}
+	reflected := reflect.ValueOf(value)
+	for reflected.Kind() == reflect.Pointer || reflected.Kind() == reflect.Interface {
+		if reflected.IsNil() {
+			return "", false
+		}
+		reflected = reflected.Elem()
+	}
+	if !reflected.IsValid() {
+		return "", false
+	}
+	text := fmt.Sprint(reflected.Interface())
+	return text, reflected.Kind() != reflect.String || text != ""
+}
+func validationNumber(text string) float64 {
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return number
+}
+func validateRequired(value interface{}) string {
+	_, ok := validationText(value)
+	if !ok {
+		return "is required"
+	}
+	return ""
+}
+func validateMinLength(value interface{}, length int) string {
+	text, ok := validationText(value)
+	if ok && utf8.RuneCountInString(text) < length {
+		return fmt.Sprintf("must be at least %d characters long", length)
+	}
+	return ""
+}
+func validateMaxLength(value interface{}, length int) string {
+	text, ok := validationText(value)
+	if ok && utf8.RuneCountInString(text) > length {
+		return fmt.Sprintf("must be at most %d characters long", length)
+	}
+	return ""
+}
+func validateExactLength(value interface{}, length int) string {
+	text, ok := validationText(value)
+	if ok && utf8.RuneCountInString(text) != length {
+		return fmt.Sprintf("must be exactly %d characters long", length)
+	}
+	return ""
+}
+func validatePattern(value interface{}, pattern *regexp.Regexp, message string) string {
+	text, ok := validationText(value)
+	if ok && !pattern.MatchString(text) {
+		return message
+	}
+	return ""
+}
+func validateIP(value interface{}) string {
+	text, ok := validationText(value)
+	if ok && net.ParseIP(text) == nil {
+		return "must be a valid IP address"
+	}
+	return ""
+}
+func validateMinValue(value interface{}, min float64) string {
+	text, ok := validationText(value)
+	if ok && !(validationNumber(text) >= min) {
+		return fmt.Sprintf("must be at least %v", min)
+	}
+	return ""
+}
+func validateMaxValue(value interface{}, max float64) string {
+	text, ok := validationText(value)
+	if ok && !(validationNumber(text) <= max) {
+		return fmt.Sprintf("must be at most %v", max)
+	}
+	return ""
+}
+func validateEquals(value interface{}, expected string) string {
+	text, ok := validationText(value)
+	if ok && text != expected {
+		return fmt.Sprintf("must be %q", expected)
+	}
+	return ""
+}
+func validateEqualsNoCase(value interface{}, expected string) string {
+	text, ok := validationText(value)
+	if ok && !strings.EqualFold(text, expected) {
+		return fmt.Sprintf("must be %q", expected)
+	}
+	return ""
+}
+func validateMinWords(value interface{}, count int) string {
+	text, ok := validationText(value)
+	if ok && len(strings.Fields(text)) < count {
+		return fmt.Sprintf("must have at least %d words", count)
+	}
+	return ""
+}
+func validateMaxWords(value interface{}, count int) string {
+	text, ok := validationText(value)
+	if ok && len(strings.Fields(text)) > count {
+		return fmt.Sprintf("must have at most %d words", count)
+	}
+	return ""
+}
+func validateExactWords(value interface{}, count int) string {
+	text, ok := validationText(value)
+	if ok && len(strings.Fields(text)) != count {
+		return fmt.Sprintf("must have exactly %d words", count)
+	}
+	return ""
+}
@@ -0,0 +1,268 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+// Name of the unit holding the statements of the queries with IN lists, on the dialects binding them element by element
+const inListUnitName = "in_list"
+
+// emptyInLists are the lists an empty IN list is expanded to by dialect, x IN (empty) matches no row and
+// x NOT IN (empty) matches every row. MySQL has no empty lists, its empty list is an empty subquery.
+var emptyInLists = map[string]string{
+	"mysql":  "SELECT NULL FROM DUAL WHERE FALSE",
+	"sqlite": "",
+}
+
+// expandsInLists tells if a dialect binds the lists of IN filters element by element instead of as arrays, see
+// Dialect.FormatIn. The queries with IN lists of such a dialect are not prepared, they are expanded for the lists
+// they run with by the statements of GenerateInListUnit.
+func expandsInLists(dialect datahelpers.Dialect) bool {
+	_, expand := dialect.FormatIn("", "", false)
+	return expand
+}
+
+// inListExpansion is the Expand of the NamedQuery of a query binding paramRefs, one flag per placeholder telling
+// whether it's an IN list to expand, nil when the query has none
+func inListExpansion(paramRefs []defs.ParameterRef) []bool {
+	if !slices.ContainsFunc(paramRefs, func(paramRef defs.ParameterRef) bool { return paramRef.Expand }) {
+		return nil
+	}
+	expand := make([]bool, 0, len(paramRefs))
+	for _, paramRef := range paramRefs {
+		expand = append(expand, paramRef.Expand)
+	}
+	return expand
+}
+
+// inListStmtsVarName is the variable of the statements of the queries with IN lists of a model, productInListStmts
+func inListStmtsVarName(modelName string) string {
+	return golang.ToCamelCase(modelName) + "InListStmts"
+}
+
+// inListStmtsVariable generates the statements of the queries with IN lists of a model, by query name, which its
+// statement function runs instead of prepared statements, see inListStatementFunction:
+//
+//	var productInListStmts = map[string]inListStmt{
+//		"GetProductsByIds": {query: "SELECT `id`, `sku` FROM `product` WHERE (1 = 1) AND (`id` IN (?))", expand: []bool{true}},
+//	}
+func inListStmtsVariable(modelName string, queries []NamedQuery) *golang.Variable {
+	stmts := []string{}
+	for _, query := range queries {
+		if query.Expand == nil {
+			continue
+		}
+		expand := make([]string, 0, len(query.Expand))
+		for _, flag := range query.Expand {
+			expand = append(expand, strconv.FormatBool(flag))
+		}
+		stmts = append(stmts, fmt.Sprintf("%q: {query: %q, expand: []bool{%s}},\n", query.Name, query.Query, strings.Join(expand, ", ")))
+	}
+	return &golang.Variable{
+		Names:  inListStmtsVarName(modelName),
+		Values: fmt.Sprintf("map[string]inListStmt{\n%s}", strings.Join(stmts, "")),
+	}
+}
+
+// inListStatementFunction generates the statement function of a model DB of a dialect expanding IN lists, like
+// statementFunction, which returns the statement of a query with IN lists when there is one:
+//
+//	func (db *Product_DB) statement(ctx context.Context, name string) statementRunner {
+//		if inList, ok := productInListStmts[name]; ok {
+//			inList.runner = db.db
+//			if db.tx != nil {
+//				inList.runner = db.tx
+//			}
+//			return &inList
+//		}
+//		stmt := db.preparedCache[name]
+//		...
+//	}
+func inListStatementFunction(modelName, modelDBName string) *golang.FunctionDef {
+	fn := statementFunction(modelDBName)
+	fn.Returns = typeOnlyParamsCE("statementRunner")
+	fn.Body = append(golang.CodeElements{{If: &golang.IfElement{
+		Condition: fmt.Sprintf("inList, ok := %s[name]; ok", inListStmtsVarName(modelName)),
+		Then: golang.CodeElements{
+			{Assign: &golang.Assignment{Left: "inList.runner", Right: "db.db"}},
+			{If: &golang.IfElement{
+				Condition: "db.tx != nil",
+				Then:      golang.CodeElements{{Assign: &golang.Assignment{Left: "inList.runner", Right: "db.tx"}}},
+			}},
+			returnValuesCE("&inList"),
+		},
+	}}}, fn.Body...)
+	return fn
+}
+
+// GenerateInListUnit generates the statements of the queries with IN lists of a dialect binding the lists element by
+// element (see expandsInLists), which the statement functions of the model DBs return along with prepared statements
+// (see inListStatementFunction):
+//
+//	type statementRunner interface {
+//		QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error)
+//		QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row
+//		ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error)
+//	}
+//
+// An inListStmt expands its query for the lists it runs with, and runs it on the database or in the transaction of
+// the model DB.
+func GenerateInListUnit(dialect datahelpers.Dialect) *golang.UnitModule {
+	receiver := &golang.Receiver{Name: "s", Type: &golang.GoType{Name: "inListStmt"}}
+	methods := []struct{ name, returns string }{
+		{"QueryContext", "(*sql.Rows, error)"},
+		{"QueryRowContext", "*sql.Row"},
+		{"ExecContext", "(sql.Result, error)"},
+	}
+	runner, statementRunner := []string{}, []string{}
+	functions := []*golang.FunctionDef{expandInListsFunction(dialect)}
+	for _, method := range methods {
+		runner = append(runner, fmt.Sprintf("\t%s(ctx context.Context, query string, args ...interface{}) %s\n", method.name, method.returns))
+		statementRunner = append(statementRunner, fmt.Sprintf("\t%s(ctx context.Context, args ...interface{}) %s\n", method.name, method.returns))
+		functions = append(functions, &golang.FunctionDef{
+			Name:     method.name,
+			Receiver: receiver,
+			Parameters: []*golang.Parameter{
+				{Name: "ctx", Type: &golang.GoType{Name: "context.Context"}},
+				{Name: "args", Type: &golang.GoType{Name: "...interface{}"}},
+			},
+			Returns: typeOnlyParamsCE(strings.Split(strings.Trim(method.returns, "()"), ", ")...),
+			Imports: []string{"context", "database/sql"},
+			Body: golang.CodeElements{
+				{NewAssign: &golang.NewAssignment{Left: []string{"query", "values"}, Right: "expandInLists(s.query, s.expand, args)"}},
+				returnValuesCE(fmt.Sprintf("s.runner.%s(ctx, query, values...)", method.name)),
+			},
+		})
+	}
+	return &golang.UnitModule{
+		Name:    inListUnitName,
+		Imports: []string{"context", "database/sql"},
+		Types: []*golang.TypeDef{
+			// The prepared statements and the statements of the queries with IN lists
+			{Name: "statementRunner", Type: fmt.Sprintf("interface {\n%s}", strings.Join(statementRunner, ""))},
+			// The database or the transaction of a model DB, *sql.DB or *sql.Tx
+			{Name: "queryRunner", Type: fmt.Sprintf("interface {\n%s}", strings.Join(runner, ""))},
+		},
+		Structs: []*golang.StructDef{{
+			Name: "inListStmt",
+			Fields: []*golang.Field{
+				{Name: "runner", Type: &golang.GoType{Name: "queryRunner"}},
+				{Name: "query", Type: golang.GoStringType},
+				{Name: "expand", Type: &golang.GoType{Name: "[]bool"}},
+			},
+		}},
+		Constants: []*golang.Constant{{Name: "emptyInList", Value: strconv.Quote(emptyInLists[dialect.GetName()])}},
+		Functions: functions,
+	}
+}
+
+// expandInLists expands the IN lists of a query for the args it runs with: the placeholder of an arg to expand is
+// replaced by one placeholder per element of the list, and the elements are bound in its place. The placeholders are
+// numbered again for the dialects numbering them, like ?NNN. An empty list is expanded to emptyInList.
+//
+//	func expandInLists(query string, expand []bool, args []interface{}) (string, []interface{}) {
+//		var expanded strings.Builder
+//		values := make([]interface{}, 0, len(args))
+//		arg := 0
+//		for i := 0; i < len(query); i++ {
+//			if query[i] != '?' {
+//				expanded.WriteByte(query[i])
+//				continue
+//			}
+//			... // skips the number of ?NNN
+//			if !expand[arg] {
+//				values = append(values, args[arg])
+//				expanded.WriteString("?")
+//			} else if list := reflect.ValueOf(args[arg]); list.Len() == 0 {
+//				expanded.WriteString(emptyInList)
+//			} else {
+//				... // one placeholder per element of the list
+//			}
+//			arg++
+//		}
+//		return expanded.String(), values
+//	}
+func expandInListsFunction(dialect datahelpers.Dialect) *golang.FunctionDef {
+	imports := []string{"reflect", "strings"}
+	placeholder := strconv.Quote(dialect.GetPlaceholder(1))
+	if numbered, ok := strings.CutSuffix(dialect.GetPlaceholder(1), "1"); ok {
+		placeholder = fmt.Sprintf("%q + strconv.Itoa(len(values))", numbered)
+		imports = append(imports, "strconv")
+	}
+	writePlaceholder := &golang.CodeElement{FunctionCall: &golang.FunctionCall{
+		Receiver: "expanded",
+		Function: "WriteString",
+		Args:     []string{placeholder},
+	}}
+	return &golang.FunctionDef{
+		Name: "expandInLists",
+		Parameters: []*golang.Parameter{
+			{Name: "query", Type: golang.GoStringType},
+			{Name: "expand", Type: &golang.GoType{Name: "[]bool"}},
+			{Name: "args", Type: &golang.GoType{Name: "[]interface{}"}},
+		},
+		Returns: typeOnlyParamsCE("string", "[]interface{}"),
+		Imports: imports,
+		Body: golang.CodeElements{
+			{Variable: createVarCE("expanded", "strings.Builder")},
+			{NewAssign: &golang.NewAssignment{Left: "values", Right: "make([]interface{}, 0, len(args))"}},
+			{NewAssign: &golang.NewAssignment{Left: "arg", Right: "0"}},
+			{RepeatLoop: &golang.RepeatLoopElement{
+				Init:      []*golang.CodeElement{{NewAssign: &golang.NewAssignment{Left: "i", Right: "0"}}},
+				Condition: &golang.CodeElement{Literal: "i < len(query)"},
+				Step:      []*golang.CodeElement{{Literal: "i++"}},
+				Body: golang.CodeElements{
+					{If: &golang.IfElement{
+						Condition: "query[i] != '?'",
+						Then: golang.CodeElements{
+							{FunctionCall: &golang.FunctionCall{Receiver: "expanded", Function: "WriteByte", Args: []string{"query[i]"}}},
+							{Literal: "continue"},
+						},
+					}},
+					// The number of a ?NNN placeholder
+					{RepeatCond: &golang.RepeatByCondition{
+						Condition: &golang.CodeElement{Literal: "i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9'"},
+						Body:      golang.CodeElements{{Literal: "i++"}},
+					}},
+					{If: &golang.IfElement{
+						Condition: "!expand[arg]",
+						Then: golang.CodeElements{
+							{FunctionCall: appendCE("values", "args[arg]")},
+							writePlaceholder,
+						},
+						Else: golang.CodeElements{{If: &golang.IfElement{
+							Condition: "list := reflect.ValueOf(args[arg]); list.Len() == 0",
+							Then: golang.CodeElements{
+								{FunctionCall: &golang.FunctionCall{Receiver: "expanded", Function: "WriteString", Args: []string{"emptyInList"}}},
+							},
+							Else: golang.CodeElements{{RepeatLoop: &golang.RepeatLoopElement{
+								Init:      []*golang.CodeElement{{NewAssign: &golang.NewAssignment{Left: "j", Right: "0"}}},
+								Condition: &golang.CodeElement{Literal: "j < list.Len()"},
+								Step:      []*golang.CodeElement{{Literal: "j++"}},
+								Body: golang.CodeElements{
+									{If: &golang.IfElement{
+										Condition: "j > 0",
+										Then: golang.CodeElements{
+											{FunctionCall: &golang.FunctionCall{Receiver: "expanded", Function: "WriteString", Args: []string{`", "`}}},
+										},
+									}},
+									{FunctionCall: appendCE("values", "list.Index(j).Interface()")},
+									writePlaceholder,
+								},
+							}}},
+						}}},
+					}},
+					{Literal: "arg++"},
+				},
+			}},
+			returnValuesCE("expanded.String()", "values"),
+		},
+	}
+}
@@ -0,0 +1,144 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+)
+
+// The writes of dialects without RETURNING (see returnsRows) are executed, the ids of their inserts are read with
+// sql.Result.LastInsertId and the rows they change with sql.Result.RowsAffected
+
+// lastInsertIdCE reads the id of the row inserted by the statement of result, formatted as the string ids are:
+//
+//	lastInsertId, err := result.LastInsertId()
+//	if err != nil {
+//		return "", err
+//	}
+//	id := strconv.FormatInt(lastInsertId, 10)
+func lastInsertIdCE(fnReturns []*golang.Parameter) golang.CodeElements {
+	return golang.CodeElements{
+		{FunctionCall: callResultErrorCE("result", "LastInsertId", []string{}, "lastInsertId", "err", fnReturns)},
+		{NewAssign: &golang.NewAssignment{Left: "id", Right: "strconv.FormatInt(lastInsertId, 10)"}},
+	}
+}
+
+// AddLastInsertIdCodeFunction generates the insert of an add config like AddCodeFunction, for dialects without
+// RETURNING: the id of the inserted row is its LastInsertId
+func AddLastInsertIdCodeFunction(name string, modelDBName string, idType string) *golang.FunctionDef {
+	fnReturns := typeOnlyParamsCE(idType, "error")
+	codeElems := golang.CodeElements{
+		{FunctionCall: validateParamsCE("requestParams", fnReturns)},
+		{FunctionCall: lookupStmtCE(name, "db", "stmt")},
+		{FunctionCall: parseParamsCE(name, "requestParams", "values", fnReturns)},
+		{FunctionCall: execStmtCE("stmt", "values", "result", fnReturns)},
+	}
+	codeElems = append(codeElems, lastInsertIdCE(fnReturns)...)
+	codeElems = append(codeElems, returnResultNilCE("id"))
+	return &golang.FunctionDef{
+		Name:       name,
+		Parameters: ctxDBRequestParamsCE("ctx", "db", modelDBName, name, "requestParams"),
+		Body:       codeElems,
+		Returns:    fnReturns,
+		Imports:    []string{"context", "database/sql", "strconv"},
+	}
+}
+
+// AddOrReplaceLastInsertIdCodeFunction generates the upsert of an add_or_replace config like AddOrReplaceCodeFunction,
+// for dialects without RETURNING. The upsert sets the LastInsertId of a replaced row to its id (see
+// datahelpers.MySQLDialect.FormatUpsert), and affects 1 row when it inserts, 2 or 0 when it replaces:
+//
+//	func UpsertProduct(ctx context.Context, db *Product_DB, requestParams UpsertProductParams) (string, bool, error) {
+//		...
+//		result, err := stmt.ExecContext(ctx, values...)
+//		...
+//		id := strconv.FormatInt(lastInsertId, 10)
+//		rowsAffected, err := result.RowsAffected()
+//		if err != nil {
+//			return "", false, err
+//		}
+//		return id, rowsAffected == 1, nil
+//	}
+func AddOrReplaceLastInsertIdCodeFunction(name string, modelDBName string, idType string) *golang.FunctionDef {
+	fnReturns := typeOnlyParamsCE(idType, "bool", "error")
+	codeElems := golang.CodeElements{
+		{FunctionCall: validateParamsCE("requestParams", fnReturns)},
+		{FunctionCall: lookupStmtCE(name, "db", "stmt")},
+		{FunctionCall: parseParamsCE(name, "requestParams", "values", fnReturns)},
+		{FunctionCall: execStmtCE("stmt", "values", "result", fnReturns)},
+	}
+	codeElems = append(codeElems, lastInsertIdCE(fnReturns)...)
+	codeElems = append(codeElems,
+		&golang.CodeElement{FunctionCall: callRowsAffectedCE("result", "rowsAffected", "err", fnReturns)},
+		&golang.CodeElement{Return: []string{"id", "rowsAffected == 1", "nil"}},
+	)
+	return &golang.FunctionDef{
+		Name:       name,
+		Parameters: ctxDBRequestParamsCE("ctx", "db", modelDBName, name, "requestParams"),
+		Body:       codeElems,
+		Returns:    fnReturns,
+		Imports:    []string{"context", "database/sql", "strconv"},
+	}
+}
+
+// LockedUpdateExecCodeFunction generates an update with optimistic_lock like LockedUpdateCodeFunction, for dialects
+// without RETURNING: the update increments the version param it matched, so the new version follows it
+//
+//	func UpdateProductPrice(ctx context.Context, db *Product_DB, requestParams UpdateProductPriceParams) (int64, error) {
+//		... // LockedDeleteCodeFunction
+//		if rowsAffected == 0 {
+//			return int64(0), ErrVersionConflict
+//		}
+//		return requestParams.Version + 1, nil
+//	}
+func LockedUpdateExecCodeFunction(name string, modelDBName string) *golang.FunctionDef {
+	fn := LockedDeleteCodeFunction(name, modelDBName)
+	last := len(fn.Body) - 1
+	fn.Body = append(slices.Clone(fn.Body[:last]),
+		returnResultNilCE(fmt.Sprintf("requestParams.%s + 1", golang.ToPascalCase(datahelpers.VersionParam))))
+	return fn
+}
+
+// insertedIdsFunction generates the function appending the ids of the rows inserted by the INSERT of a chunk for
+// dialects without RETURNING. The LastInsertId of a multi-row INSERT is the id of its first row, InnoDB gives the rows
+// of an INSERT of known rows without ids consecutive ids, see validateAddMany:
+//
+//	func insertedIds(result sql.Result, ids []string) ([]string, error) {
+//		first, err := result.LastInsertId()
+//		if err != nil {
+//			return nil, err
+//		}
+//		rows, err := result.RowsAffected()
+//		if err != nil {
+//			return nil, err
+//		}
+//		for i := int64(0); i < rows; i++ {
+//			ids = append(ids, strconv.FormatInt(first+i, 10))
+//		}
+//		return ids, nil
+//	}
+func insertedIdsFunction() *golang.FunctionDef {
+	fnReturns := typeOnlyParamsCE("[]string", "error")
+	return &golang.FunctionDef{
+		Name: "insertedIds",
+		Parameters: []*golang.Parameter{
+			{Name: "result", Type: &golang.GoType{Name: "sql.Result"}},
+			{Name: "ids", Type: &golang.GoType{Name: "[]string"}},
+		},
+		Returns: fnReturns,
+		Imports: []string{"database/sql", "strconv"},
+		Body: golang.CodeElements{
+			{FunctionCall: callResultErrorCE("result", "LastInsertId", []string{}, "first", "err", fnReturns)},
+			{FunctionCall: callRowsAffectedCE("result", "rows", "err", fnReturns)},
+			{RepeatLoop: &golang.RepeatLoopElement{
+				Init:      []*golang.CodeElement{{NewAssign: &golang.NewAssignment{Left: "i", Right: "int64(0)"}}},
+				Condition: &golang.CodeElement{Literal: "i < rows"},
+				Step:      []*golang.CodeElement{{Literal: "i++"}},
+				Body:      golang.CodeElements{{FunctionCall: appendCE("ids", "strconv.FormatInt(first+i, 10)")}},
+			}},
+			returnResultNilCE("ids"),
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+// Name of the unit holding the error of the accesses with optimistic_lock
+const lockUnitName = "lock"
+
+// LockedUpdateCodeFunction generates an update with optimistic_lock, which returns the new version of the updated row
+// instead of the number of updated rows, and ErrVersionConflict when no row has the version param:
+//
+//	func UpdateProductPrice(ctx context.Context, db *Product_DB, requestParams UpdateProductPriceParams) (int64, error) {
+//		...
+//		var version int64
+//		err = stmt.QueryRowContext(ctx, values...).Scan(&version)
+//		if errors.Is(err, sql.ErrNoRows) {
+//			return int64(0), ErrVersionConflict
+//		}
+//		if err != nil {
+//			return int64(0), err
+//		}
+//		return version, nil
+//	}
+func LockedUpdateCodeFunction(name string, modelDBName string) *golang.FunctionDef {
+	fnReturns := typeOnlyParamsCE("int64", "error")
+	return &golang.FunctionDef{
+		Name:       name,
+		Parameters: ctxDBRequestParamsCE("ctx", "db", modelDBName, name, "requestParams"),
+		Returns:    fnReturns,
+		Imports:    []string{"context", "database/sql", "errors"},
+		Body: golang.CodeElements{
+			{FunctionCall: validateParamsCE("requestParams", fnReturns)},
+			{FunctionCall: lookupStmtCE(name, "db", "stmt")},
+			{FunctionCall: parseParamsCE(name, "requestParams", "values", fnReturns)},
+			{Variable: createVarCE("version", "int64")},
+			{Assign: &golang.Assignment{Left: "err", Right: "stmt.QueryRowContext(ctx, values...).Scan(&version)"}},
+			{If: &golang.IfElement{
+				Condition: "errors.Is(err, sql.ErrNoRows)",
+				Then:      golang.CodeElements{returnValuesCE("int64(0)", "ErrVersionConflict")},
+			}},
+			{If: &golang.IfElement{
+				Condition: "err != nil",
+				Then:      golang.CodeElements{returnValuesCE("int64(0)", "err")},
+			}},
+			returnValuesCE("version", "nil"),
+		},
+	}
+}
+
+// LockedDeleteCodeFunction generates a delete with optimistic_lock, like DeleteCodeFunction, returning
+// ErrVersionConflict when no row has the version param
+func LockedDeleteCodeFunction(name string, modelDBName string) *golang.FunctionDef {
+	fn := DeleteCodeFunction(name, modelDBName)
+	last := len(fn.Body) - 1
+	fn.Body = append(slices.Clone(fn.Body[:last]),
+		&golang.CodeElement{If: &golang.IfElement{
+			Condition: "rowsAffected == 0",
+			Then:      golang.CodeElements{returnValuesCE("int64(0)", "ErrVersionConflict")},
+		}},
+		fn.Body[last],
+	)
+	return fn
+}
+
+// GenerateLockUnit generates the error of the accesses with optimistic_lock called with a version that is not the
+// version of the row, which another call updated or deleted since it was read
+func GenerateLockUnit() *golang.UnitModule {
+	return &golang.UnitModule{
+		Name: lockUnitName,
+		Variables: []*golang.Variable{{
+			Names:  "ErrVersionConflict",
+			Values: `errors.New("version conflict")`,
+		}},
+		Imports: []string{"errors"},
+	}
+}
+
+// validateOptimisticLock checks the updates and deletes with optimistic_lock, see datahelpers.MakeUpdateQuery
+func validateOptimisticLock(modelConfig *defs.ModelConfig) []error {
+	errs := []error{}
+	modelName := modelConfig.Model.Name
+	locked := append(slices.Clone(modelConfig.Access.Update), modelConfig.Access.Delete...)
+	for _, accessConfig := range modelConfig.GetAllAccessConfig() {
+		if accessConfig.OptimisticLock && !slices.ContainsFunc(locked, func(conf defs.AccessConfig) bool { return conf.Name == accessConfig.Name }) {
+			errs = append(errs, fmt.Errorf("model %s: access %s has optimistic_lock, which only updates and deletes have", modelName, accessConfig.Name))
+		}
+	}
+	for _, accessConfig := range locked {
+		if !accessConfig.OptimisticLock {
+			continue
+		}
+		params := []string{}
+		var addFilters func(filters []defs.Filter)
+		addFilters = func(filters []defs.Filter) {
+			for _, filter := range filters {
+				params = append(params, filter.ParamName)
+				addFilters(filter.Conditions)
+			}
+		}
+		addFilters(accessConfig.Filter)
+		for _, set := range accessConfig.Set {
+			if golang.ToSnakeCase(set.Attribute) == datahelpers.VersionColumn {
+				errs = append(errs, fmt.Errorf("model %s: access %s sets %s, which optimistic_lock increments", modelName, accessConfig.Name, set.Attribute))
+			}
+			params = append(params, set.ParamName)
+		}
+		if slices.Contains(params, datahelpers.VersionParam) {
+			errs = append(errs, fmt.Errorf("model %s: access %s has a param %s, which is reserved for optimistic_lock",
+				modelName, accessConfig.Name, datahelpers.VersionParam))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,322 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang/goutils"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+// Directory of the migration files, relative to the generated package, embedded by the migration runner
+const MigrationsDir = "migrations"
+
+// Name of the unit holding the migration runner (Migrate, MigrateDown and MigrationStatus)
+const migrateUnitName = "migrate"
+
+// Migration files are named <version>_<name>.up.sql and <version>_<name>.down.sql,
+// versions are zero padded so that the lexical order of the files is the order of the migrations
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_[a-z0-9_]+\.(up|down)\.sql$`)
+
+// MigrationFiles returns the up and down scripts of a migration, as files of the migrate unit
+func MigrationFiles(version int, name string, migration *datahelpers.Migration) []*golang.File {
+	fileName := fmt.Sprintf("%04d_%s", version, golang.ToSnakeCase(name))
+	return []*golang.File{
+		{Path: path.Join(MigrationsDir, fileName+".up.sql"), Content: migration.UpSQL()},
+		{Path: path.Join(MigrationsDir, fileName+".down.sql"), Content: migration.DownSQL()},
+	}
+}
+
+// NextMigrationVersion returns the version following the highest version among the migration file names
+func NextMigrationVersion(fileNames []string) int {
+	next := 1
+	for _, fileName := range fileNames {
+		match := migrationFileRegex.FindStringSubmatch(fileName)
+		if match == nil {
+			continue
+		}
+		if version, err := strconv.Atoi(match[1]); err == nil && version >= next {
+			next = version + 1
+		}
+	}
+	return next
+}
+
+// InitialMigration creates every table and index of the family, and drops the tables on the way down
+func InitialMigration(dialect datahelpers.Dialect, dataConfig *defs.DataConfig) (*datahelpers.Migration, error) {
+	return datahelpers.DiffSchemas(dialect, datahelpers.SchemaSnapshot{}, datahelpers.SchemaSnapshot{DataConfig: *dataConfig})
+}
+
+// GenerateMigrateUnit generates the migration runner of the family, along with the initial migration:
+//
+//	//go:embed migrations/*.sql
+//	var migrationFiles embed.FS
+//	func Migrate(ctx context.Context, db *sql.DB) error {...}
+//	func MigrateDown(ctx context.Context, db *sql.DB) error {...}
+//	func MigrationStatus(ctx context.Context, db *sql.DB) ([]*SchemaMigration, error) {...}
+//
+// Applied versions are tracked in the schema_migrations table, and each migration runs in its own transaction.
+func GenerateMigrateUnit(dialect datahelpers.Dialect, dataConfig *defs.DataConfig) (*golang.UnitModule, error) {
+	initial, err := InitialMigration(dialect, dataConfig)
+	if err != nil {
+		return nil, fmt.Errorf("initial migration: %w", err)
+	}
+
+	schemaMigrationStruct := golang.GenStructForDataModel("SchemaMigration", []golang.NameWithType{
+		{Name: "version", Type: golang.GoInt64Type},
+		{Name: "name", Type: golang.GoStringType},
+		{Name: "up", Type: golang.GoStringType},
+		{Name: "down", Type: golang.GoStringType},
+		{Name: "applied_at", Type: &golang.GoType{Name: "time.Time", Source: "time"}},
+	}, false, false, false)
+
+	return &golang.UnitModule{
+		Name:    migrateUnitName,
+		Imports: []string{"embed"},
+		Structs: []*golang.StructDef{schemaMigrationStruct},
+		Functions: []*golang.FunctionDef{
+			readMigrationFunction(),
+			loadMigrationsFunction(),
+			appliedMigrationsFunction(),
+			runMigrationFunction(),
+			migrateFunction(),
+			migrateDownFunction(),
+			migrationStatusFunction(),
+		},
+		Variables: []*golang.Variable{{
+			Names:      "migrationFiles",
+			Type:       "embed.FS",
+			Directives: []string{fmt.Sprintf("go:embed %s/*.sql", MigrationsDir)},
+		}},
+		Constants: schemaMigrationsQueries(dialect),
+		Files:     MigrationFiles(1, "init", initial),
+	}, nil
+}
+
+func schemaMigrationsQueries(dialect datahelpers.Dialect) []*golang.Constant {
+	queries := []struct{ name, query string }{
+		{"createSchemaMigrationsQuery", fmt.Sprintf("CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT PRIMARY KEY, applied_at %s)",
+			dialect.SystemColumnDefinition("updated_at"))},
+		{"selectSchemaMigrationsQuery", "SELECT version, applied_at FROM schema_migrations"},
+		{"insertSchemaMigrationQuery", fmt.Sprintf("INSERT INTO schema_migrations (version) VALUES (%s)", dialect.GetPlaceholder(1))},
+		{"deleteSchemaMigrationQuery", fmt.Sprintf("DELETE FROM schema_migrations WHERE version = %s", dialect.GetPlaceholder(1))},
+	}
+	constants := make([]*golang.Constant, 0, len(queries))
+	for _, q := range queries {
+		constants = append(constants, &golang.Constant{Name: q.name, Value: strconv.Quote(q.query)})
+	}
+	return constants
+}
+
+func wrapMigrationErrorCE() *golang.ErrorHandler {
+	return &golang.ErrorHandler{ErrorReturns: []string{`fmt.Errorf("migration %s: %w", migration.Name, err)`}}
+}
+
+func ctxSQLDBParamsCE() []*golang.Parameter {
+	return []*golang.Parameter{ctxParamCE("ctx"), dbParamCE("db")}
+}
+
+// readMigration reads the up and down scripts of the migration of upPath
+func readMigrationFunction() *golang.FunctionDef {
+	fnReturns := typeOnlyParamsCE("*SchemaMigration", "error")
+	return &golang.FunctionDef{
+		Name:       "readMigration",
+		Parameters: []*golang.Parameter{{Name: "upPath", Type: golang.GoStringType}},
+		Returns:    fnReturns,
+		Imports:    []string{"fmt", "path", "strconv", "strings"},
+		Body: golang.CodeElements{
+			{NewAssign: &golang.NewAssignment{Left: "name", Right: `strings.TrimSuffix(path.Base(upPath), ".up.sql")`}},
+			goutils.FCEHNewOutReceiverArgsCE([]string{"version", "err"}, "strconv", "ParseInt",
+				[]string{`strings.SplitN(name, "_", 2)[0]`, "10", "64"},
+				&golang.ErrorHandler{ErrorReturns: []string{"nil", `fmt.Errorf("migration %s: %w", upPath, err)`}}),
+			goutils.FCEHNewOutReceiverArgsCE([]string{"up", "err"}, "migrationFiles", "ReadFile", "upPath", &golang.ErrorHandler{ErrorFunctionReturns: fnReturns}),
+			goutils.FCEHNewOutReceiverArgsCE([]string{"down", "err"}, "migrationFiles", "ReadFile",
+				`strings.TrimSuffix(upPath, ".up.sql") + ".down.sql"`, &golang.ErrorHandler{ErrorFunctionReturns: fnReturns}),
+			{StructCreation: &golang.MakeStruct{
+				NewOutput:  "migration",
+				StructType: "SchemaMigration",
+				KeyValues: golang.KeyValues{
+					{Key: "Version", Variable: "version"},
+					{Key: "Name", Variable: "name"},
+					{Key: "Up", Variable: "string(up)"},
+					{Key: "Down", Variable: "string(down)"},
+				},
+			}},
+			returnResultNilCE("migration"),
+		},
+	}
+}
+
+// loadMigrations reads the embedded migrations in version order, sorted by their parsed version rather than the
+// lexical order of the file names, which differs for versions of different widths (10000 before 9999)
+func loadMigrationsFunction() *golang.FunctionDef {
+	fnReturns := typeOnlyParamsCE("[]*SchemaMigration", "error")
+	return &golang.FunctionDef{
+		Name:    "loadMigrations",
+		Returns: fnReturns,
+		Imports: []string{"io/fs", "sort"},
+		Body: golang.CodeElements{
+			goutils.FCEHNewOutReceiverArgsCE([]string{"upPaths", "err"}, "fs", "Glob",
+				[]string{"migrationFiles", strconv.Quote(MigrationsDir + "/*.up.sql")}, &golang.ErrorHandler{ErrorFunctionReturns: fnReturns}),
+			{Variable: createVarCE("migrations", "[]*SchemaMigration")},
+			{Iterate: &golang.IterateElement{
+				Variables: []string{"_", "upPath"},
+				RangeOn:   &golang.CodeElement{Literal: "upPaths"},
+				Body: golang.CodeElements{
+					goutils.FCEHNewOutArgsCE([]string{"migration", "err"}, "readMigration", "upPath", &golang.ErrorHandler{ErrorFunctionReturns: fnReturns}),
+					{FunctionCall: appendCE("migrations", "migration")},
+				},
+			}},
+			{Literal: "sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })"},
+			returnResultNilCE("migrations"),
+		},
+	}
+}
+
+// appliedMigrations creates the schema_migrations table when missing and returns the applied versions
+func appliedMigrationsFunction() *golang.FunctionDef {
+	fnReturns := typeOnlyParamsCE("map[int64]*time.Time", "error")
+	return &golang.FunctionDef{
+		Name:       "appliedMigrations",
+		Parameters: ctxSQLDBParamsCE(),
+		Returns:    fnReturns,
+		Imports:    []string{"context", "database/sql", "time"},
+		Body: golang.CodeElements{
+			goutils.FCEHNewOutReceiverArgsCE([]string{"_", "err"}, "db", "ExecContext",
+				[]string{"ctx", "createSchemaMigrationsQuery"}, &golang.ErrorHandler{ErrorFunctionReturns: fnReturns}),
+			{FunctionCall: &golang.FunctionCall{
+				NewOutput:        []string{"rows", "err"},
+				Receiver:         "db",
+				Function:         "QueryContext",
+				Args:             []string{"ctx", "selectSchemaMigrationsQuery"},
+				ErrorHandler:     &golang.ErrorHandler{ErrorFunctionReturns: fnReturns},
+				CleanningHandler: &golang.CleanningHandler{Receiver: "rows", Function: "Close"},
+			}},
+			{NewAssign: &golang.NewAssignment{Left: "applied", Right: "make(map[int64]*time.Time)"}},
+			{RepeatCond: &golang.RepeatByCondition{
+				Condition: &golang.CodeElement{FunctionCall: &golang.FunctionCall{Receiver: "rows", Function: "Next"}},
+				Body: golang.CodeElements{
+					{Variable: createVarCE("version", "int64")},
+					{Variable: createVarCE("appliedAt", "time.Time")},
+					{FunctionCall: &golang.FunctionCall{
+						NewOutput: []string{"scanErr"},
+						Receiver:  "rows",
+						Function:  "Scan",
+						Args:      []string{"&version", "&appliedAt"},
+						ErrorHandler: &golang.ErrorHandler{
+							Error:        "scanErr",
+							ErrorReturns: []string{"nil", "scanErr"},
+						},
+					}},
+					{Assign: &golang.Assignment{Left: "applied[version]", Right: "&appliedAt"}},
+				},
+			}},
+			returnValuesCE("applied", "rows.Err()"),
+		},
+	}
+}
+
+// runMigration runs a migration script and records it (query) in one transaction
+func runMigrationFunction() *golang.FunctionDef {
+	rollback := &golang.ErrorHandler{ErrorSteps: golang.CodeElements{
+		goutils.FCReceiverCE("tx", "Rollback"),
+		returnValuesCE("err"),
+	}}
+	return &golang.FunctionDef{
+		Name: "runMigration",
+		Parameters: append(ctxSQLDBParamsCE(),
+			&golang.Parameter{Name: "script", Type: golang.GoStringType},
+			&golang.Parameter{Name: "query", Type: golang.GoStringType},
+			&golang.Parameter{Name: "version", Type: golang.GoInt64Type}),
+		Returns: typeOnlyParamsCE("error"),
+		Imports: []string{"context", "database/sql"},
+		Body: golang.CodeElements{
+			goutils.FCEHNewOutReceiverArgsCE([]string{"tx", "err"}, "db", "BeginTx", []string{"ctx", "nil"}, goutils.EHError("err")),
+			goutils.FCEHOutReceiverArgsCE([]string{"_", "err"}, "tx", "ExecContext", []string{"ctx", "script"}, rollback),
+			goutils.FCEHOutReceiverArgsCE([]string{"_", "err"}, "tx", "ExecContext", []string{"ctx", "query", "version"}, rollback),
+			returnValuesCE("tx.Commit()"),
+		},
+	}
+}
+
+// Migrate applies the pending migrations in version order
+func migrateFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:       "Migrate",
+		Parameters: ctxSQLDBParamsCE(),
+		Returns:    typeOnlyParamsCE("error"),
+		Imports:    []string{"context", "database/sql", "fmt"},
+		Body: golang.CodeElements{
+			goutils.FCEHNewOutArgsCE([]string{"migrations", "err"}, "MigrationStatus", []string{"ctx", "db"}, goutils.EHError("err")),
+			{Iterate: &golang.IterateElement{
+				Variables: []string{"_", "migration"},
+				RangeOn:   &golang.CodeElement{Literal: "migrations"},
+				Body: golang.CodeElements{
+					{If: &golang.IfElement{
+						Condition: "migration.AppliedAt != nil",
+						Then:      golang.CodeElements{{Literal: "continue"}},
+					}},
+					goutils.FCEHOutArgsCE("err", "runMigration", []string{"ctx", "db", "migration.Up", "insertSchemaMigrationQuery", "migration.Version"},
+						wrapMigrationErrorCE()),
+				},
+			}},
+			returnValuesCE("nil"),
+		},
+	}
+}
+
+// MigrateDown rolls back the latest applied migration, if any
+func migrateDownFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:       "MigrateDown",
+		Parameters: ctxSQLDBParamsCE(),
+		Returns:    typeOnlyParamsCE("error"),
+		Imports:    []string{"context", "database/sql", "fmt"},
+		Body: golang.CodeElements{
+			goutils.FCEHNewOutArgsCE([]string{"migrations", "err"}, "MigrationStatus", []string{"ctx", "db"}, goutils.EHError("err")),
+			{RepeatLoop: &golang.RepeatLoopElement{
+				Init:      golang.CodeElements{{NewAssign: &golang.NewAssignment{Left: "i", Right: "len(migrations) - 1"}}},
+				Condition: &golang.CodeElement{Literal: "i >= 0"},
+				Step:      golang.CodeElements{{Literal: "i--"}},
+				Body: golang.CodeElements{
+					{NewAssign: &golang.NewAssignment{Left: "migration", Right: "migrations[i]"}},
+					{If: &golang.IfElement{
+						Condition: "migration.AppliedAt == nil",
+						Then:      golang.CodeElements{{Literal: "continue"}},
+					}},
+					goutils.FCEHOutArgsCE("err", "runMigration", []string{"ctx", "db", "migration.Down", "deleteSchemaMigrationQuery", "migration.Version"},
+						wrapMigrationErrorCE()),
+					returnValuesCE("nil"),
+				},
+			}},
+			returnValuesCE("nil"),
+		},
+	}
+}
+
+// MigrationStatus lists the embedded migrations in version order, AppliedAt is nil for pending migrations
+func migrationStatusFunction() *golang.FunctionDef {
+	fnReturns := typeOnlyParamsCE("[]*SchemaMigration", "error")
+	return &golang.FunctionDef{
+		Name:       "MigrationStatus",
+		Parameters: ctxSQLDBParamsCE(),
+		Returns:    fnReturns,
+		Imports:    []string{"context", "database/sql"},
+		Body: golang.CodeElements{
+			goutils.FCEHNewOutCE([]string{"migrations", "err"}, "loadMigrations", &golang.ErrorHandler{ErrorFunctionReturns: fnReturns}),
+			goutils.FCEHNewOutArgsCE([]string{"applied", "err"}, "appliedMigrations", []string{"ctx", "db"}, &golang.ErrorHandler{ErrorFunctionReturns: fnReturns}),
+			{Iterate: &golang.IterateElement{
+				Variables: []string{"_", "migration"},
+				RangeOn:   &golang.CodeElement{Literal: "migrations"},
+				Body: golang.CodeElements{
+					{Assign: &golang.Assignment{Left: "migration.AppliedAt", Right: "applied[migration.Version]"}},
+				},
+			}},
+			returnResultNilCE("migrations"),
+		},
+	}
+}
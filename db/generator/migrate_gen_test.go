@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+func TestGenerateMigrateUnit(t *testing.T) {
+	config.LoadConfig()
+
+	unit, err := GenerateMigrateUnit(datahelpers.NewPostgresDialect(), validDataConfig())
+	assert.NoError(t, err)
+	assert.Equal(t, "migrate", unit.Name)
+
+	assert.Len(t, unit.Files, 2)
+	assert.Equal(t, "migrations/0001_init.up.sql", unit.Files[0].Path)
+	assert.Contains(t, unit.Files[0].Content, "CREATE TABLE \"user\" (")
+	assert.Equal(t, "migrations/0001_init.down.sql", unit.Files[1].Path)
+	assert.Equal(t, "DROP TABLE \"user\";\n", unit.Files[1].Content)
+
+	srcCode, _, err := unit.GenerateCode("database")
+	assert.NoError(t, err)
+	assert.Contains(t, srcCode, "//go:embed migrations/*.sql\nvar migrationFiles embed.FS")
+	assert.Contains(t, srcCode, `const insertSchemaMigrationQuery = "INSERT INTO schema_migrations (version) VALUES ($1)"`)
+	assert.Contains(t, srcCode, "sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })")
+	assert.Contains(t, srcCode, "func Migrate(ctx context.Context, db *sql.DB) error {")
+	assert.Contains(t, srcCode, "func MigrateDown(ctx context.Context, db *sql.DB) error {")
+	assert.Contains(t, srcCode, "func MigrationStatus(ctx context.Context, db *sql.DB) ([]*SchemaMigration, error) {")
+}
+
+func TestGenerateMigrateUnitSoftDeleteUniqueIndex(t *testing.T) {
+	config.LoadConfig()
+
+	dataConfig := validDataConfig()
+	dataConfig.Models[0].Model.SoftDelete = true
+	dataConfig.Models[0].Model.UniqueConstraints = []defs.UniqueConstraint{
+		{ConstraintName: "user_email_unique", Attributes: []int64{2000007}},
+	}
+	unit, err := GenerateMigrateUnit(datahelpers.NewPostgresDialect(), dataConfig)
+	assert.NoError(t, err)
+
+	// the unique index of a soft delete model leaves out its deleted rows, so a deleted email can be taken again
+	assert.Contains(t, unit.Files[0].Content, "CREATE UNIQUE INDEX ON \"user\" (\"email\") WHERE (deleted_at IS NULL);")
+	assert.NotContains(t, unit.Files[0].Content, "UNIQUE (\"email\")")
+}
+
+func TestNextMigrationVersion(t *testing.T) {
+	assert.Equal(t, 1, NextMigrationVersion(nil))
+	assert.Equal(t, 3, NextMigrationVersion([]string{
+		"0001_init.up.sql", "0001_init.down.sql", "0002_add_stock.up.sql", "0002_add_stock.down.sql", "0009_notes.txt",
+	}))
+}
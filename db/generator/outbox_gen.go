@@ -0,0 +1,213 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang/goutils"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+// Name of the unit holding the relay of the events of the accesses with events
+const outboxUnitName = "outbox"
+
+// Number of events RelayEvents publishes per transaction, it waits for new events when it publishes fewer
+const relayBatchSize = 100
+
+// eventFields are the fields of the events, see datahelpers.BuildCreateOutboxTable
+func eventFields() []golang.NameWithType {
+	return []golang.NameWithType{
+		{Name: "id", Type: golang.GoInt64Type},
+		{Name: "model", Type: golang.GoStringType},
+		{Name: "access_name", Type: golang.GoStringType},
+		{Name: "row_id", Type: golang.GoStringType},
+		{Name: "payload", Type: &golang.GoType{Name: "json.RawMessage", Source: "encoding/json"}},
+		{Name: "created_at", Type: golang.GoTimeType},
+	}
+}
+
+func outboxQueries() []*golang.Constant {
+	queries := []struct{ name, query string }{
+		{"selectUnsentEventsQuery", fmt.Sprintf("SELECT id, model, access_name, row_id, payload, created_at FROM %s WHERE sent_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE",
+			datahelpers.OutboxTable)},
+		{"markEventSentQuery", fmt.Sprintf("UPDATE %s SET sent_at = NOW() WHERE id = $1", datahelpers.OutboxTable)},
+	}
+	constants := make([]*golang.Constant, 0, len(queries)+1)
+	for _, q := range queries {
+		constants = append(constants, &golang.Constant{Name: q.name, Value: strconv.Quote(q.query)})
+	}
+	return append(constants, &golang.Constant{Name: "relayBatchSize", Value: strconv.Itoa(relayBatchSize)})
+}
+
+// unsentEvents reads the first events that are not sent yet, locking them until the end of tx, so that concurrent
+// relays publish them once and in order
+func unsentEventsFunction() *golang.FunctionDef {
+	fields := eventFields()
+	attributes := make([]string, 0, len(fields))
+	for _, field := range fields {
+		attributes = append(attributes, golang.ToPascalCase(field.Name))
+	}
+	fnReturns := typeOnlyParamsCE("[]Event", "error")
+	return &golang.FunctionDef{
+		Name: "unsentEvents",
+		Parameters: []*golang.Parameter{
+			ctxParamCE("ctx"),
+			{Name: "tx", Type: &golang.GoType{Name: "*sql.Tx"}},
+			{Name: "limit", Type: golang.GoIntType},
+		},
+		Returns: fnReturns,
+		Imports: []string{"context", "database/sql"},
+		Body: golang.CodeElements{
+			{FunctionCall: &golang.FunctionCall{
+				NewOutput:        []string{"rows", "err"},
+				Receiver:         "tx",
+				Function:         "QueryContext",
+				Args:             []string{"ctx", "selectUnsentEventsQuery", "limit"},
+				ErrorHandler:     &golang.ErrorHandler{ErrorFunctionReturns: fnReturns},
+				CleanningHandler: &golang.CleanningHandler{Receiver: "rows", Function: "Close"},
+			}},
+			{Variable: createVarCE("events", "[]Event")},
+			{RepeatCond: scanResultsCE("Event", attributes, "events")},
+			returnValuesCE("events", "rows.Err()"),
+		},
+	}
+}
+
+// RelayEventBatch publishes the first events that are not sent yet in order, and marks them sent in the same
+// transaction. It stops at the first event the publisher fails to publish, returning ErrPublish along with the
+// number of events sent before it, which stay sent.
+func relayEventBatchFunction() *golang.FunctionDef {
+	fnReturns := typeOnlyParamsCE("int", "error")
+	zeroErr := &golang.ErrorHandler{ErrorReturns: []string{"0", "err"}}
+	return &golang.FunctionDef{
+		Name: "RelayEventBatch",
+		Parameters: append(ctxSQLDBParamsCE(),
+			&golang.Parameter{Name: "publisher", Type: &golang.GoType{Name: "Publisher"}},
+			&golang.Parameter{Name: "limit", Type: golang.GoIntType}),
+		Returns: fnReturns,
+		Imports: []string{"context", "database/sql", "fmt"},
+		Body: golang.CodeElements{
+			goutils.FCEHNewOutReceiverArgsCE([]string{"tx", "err"}, "db", "BeginTx", []string{"ctx", "nil"}, zeroErr),
+			goutils.FCReceiverDeferCE("tx", "Rollback"),
+			goutils.FCEHNewOutArgsCE([]string{"events", "err"}, "unsentEvents", []string{"ctx", "tx", "limit"}, zeroErr),
+			{NewAssign: &golang.NewAssignment{Left: "sent", Right: "0"}},
+			{Variable: createVarCE("publishErr", "error")},
+			{Iterate: &golang.IterateElement{
+				Variables: []string{"_", "event"},
+				RangeOn:   &golang.CodeElement{Literal: "events"},
+				Body: golang.CodeElements{
+					{Assign: &golang.Assignment{Left: "publishErr", Right: "publisher.Publish(ctx, event)"}},
+					{If: &golang.IfElement{
+						Condition: "publishErr != nil",
+						Then: golang.CodeElements{
+							{Assign: &golang.Assignment{Left: "publishErr", Right: `fmt.Errorf("%w %d: %w", ErrPublish, event.Id, publishErr)`}},
+							{Literal: "break"},
+						},
+					}},
+					goutils.FCEHOutReceiverArgsCE([]string{"_", "err"}, "tx", "ExecContext", []string{"ctx", "markEventSentQuery", "event.Id"}, zeroErr),
+					{Literal: "sent++"},
+				},
+			}},
+			goutils.FCEHOutReceiverCE("err", "tx", "Commit", zeroErr),
+			returnValuesCE("sent", "publishErr"),
+		},
+	}
+}
+
+// RelayEvents publishes the events as they are added until ctx is done, waiting interval when it has published all
+// of them or the publisher failed. Events are published at least once: an event is published again when its
+// transaction fails after the publisher published it.
+func relayEventsFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name: "RelayEvents",
+		Parameters: append(ctxSQLDBParamsCE(),
+			&golang.Parameter{Name: "publisher", Type: &golang.GoType{Name: "Publisher"}},
+			&golang.Parameter{Name: "interval", Type: &golang.GoType{Name: "time.Duration", Source: "time"}}),
+		Returns: typeOnlyParamsCE("error"),
+		Imports: []string{"context", "database/sql", "errors", "time"},
+		Body: golang.CodeElements{
+			{RepeatCond: &golang.RepeatByCondition{
+				Condition: &golang.CodeElement{Literal: "ctx.Err() == nil"},
+				Body: golang.CodeElements{
+					goutils.FCNewOutArgsCE([]string{"sent", "err"}, "RelayEventBatch", []string{"ctx", "db", "publisher", "relayBatchSize"}),
+					{If: &golang.IfElement{
+						Condition: "err != nil && !errors.Is(err, ErrPublish)",
+						Then:      golang.CodeElements{returnValuesCE("err")},
+					}},
+					{If: &golang.IfElement{
+						Condition: "err != nil || sent < relayBatchSize",
+						Then: golang.CodeElements{
+							goutils.FCNewOutReceiverArgsCE([]string{"wait", "cancel"}, "context", "WithTimeout", []string{"ctx", "interval"}),
+							{Literal: "<-wait.Done()"},
+							goutils.FCCE("cancel"),
+						},
+					}},
+				},
+			}},
+			returnValuesCE("ctx.Err()"),
+		},
+	}
+}
+
+// GenerateOutboxUnit generates the relay of the events the accesses with events add to the outbox, which hands them
+// to a Publisher of the caller, a message broker for instance:
+//
+//	type Publisher interface {
+//		Publish(ctx context.Context, event Event) error
+//	}
+//	func RelayEvents(ctx context.Context, db *sql.DB, publisher Publisher, interval time.Duration) error {...}
+//	func RelayEventBatch(ctx context.Context, db *sql.DB, publisher Publisher, limit int) (int, error) {...}
+//
+// Events are published in the order of the writes that added them, one relay at a time.
+func GenerateOutboxUnit() *golang.UnitModule {
+	return &golang.UnitModule{
+		Name:    outboxUnitName,
+		Imports: []string{"context", "errors"},
+		Structs: []*golang.StructDef{golang.GenStructForDataModel("Event", eventFields(), true, false, false)},
+		Types: []*golang.TypeDef{{
+			Name: "Publisher",
+			Type: "interface {\n\tPublish(ctx context.Context, event Event) error\n}",
+		}},
+		Variables: []*golang.Variable{{
+			Names:  "ErrPublish",
+			Values: `errors.New("publishing event")`,
+		}},
+		Constants: outboxQueries(),
+		Functions: []*golang.FunctionDef{
+			unsentEventsFunction(),
+			relayEventBatchFunction(),
+			relayEventsFunction(),
+		},
+	}
+}
+
+// validateEvents checks the accesses with events, whose events are added to the outbox with Postgres JSONB by the
+// statements of the updates, adds and deletes, see datahelpers.MakeUpdateQuery. The deletes of a soft delete model
+// and their restores add the events of the rows they set and clear the deletion time of.
+func validateEvents(modelConfig *defs.ModelConfig, dialect datahelpers.Dialect) []error {
+	errs := []error{}
+	modelName := modelConfig.Model.Name
+	writes := slices.Concat(modelConfig.Access.Update, modelConfig.Access.Add, modelConfig.Access.Delete)
+	for _, accessConfig := range modelConfig.GetAllAccessConfig() {
+		if !accessConfig.Events {
+			continue
+		}
+		isAccess := func(access defs.AccessConfig) bool { return access.Name == accessConfig.Name }
+		switch {
+		case slices.ContainsFunc(modelConfig.Access.AddOrReplace, isAccess):
+			errs = append(errs, fmt.Errorf("model %s: access %s is an add_or_replace, which has no events: its upsert writes no outbox events",
+				modelName, accessConfig.Name))
+		case slices.ContainsFunc(modelConfig.Access.AddMany, isAccess):
+			errs = append(errs, fmt.Errorf("model %s: access %s is an add_many, which has no events: its batch inserts write no outbox events",
+				modelName, accessConfig.Name))
+		case !slices.ContainsFunc(writes, isAccess):
+			errs = append(errs, fmt.Errorf("model %s: access %s has events, which only updates, adds and deletes have", modelName, accessConfig.Name))
+		case dialect.GetName() != "postgres":
+			errs = append(errs, fmt.Errorf("model %s: access %s has events, which need the postgres driver", modelName, accessConfig.Name))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,302 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+// Name of the unit holding the helpers of the paginated finds
+const paginationUnitName = "pagination"
+
+const decodeCursorFunctionName = "decodeCursor"
+
+// pageStructName is the struct returned by a paginated find, GetProductsPage
+func pageStructName(name string) string {
+	return name + "Page"
+}
+
+// generatePageStruct generates the page returned by a paginated find, items are the found models (or results with
+// their included models) and the next page is read with NextCursor (keyset) or NextOffset (offset), which are
+// empty on the last page:
+//
+//	type GetProductsPage struct {
+//		Items      []Product `json:"items"`
+//		NextCursor string    `json:"next_cursor"`
+//	}
+func generatePageStruct(name, itemTypeName string, pagination *defs.Pagination) *golang.StructDef {
+	fields := []golang.NameWithType{{Name: "items", Type: &golang.GoType{Name: "[]" + itemTypeName}}}
+	switch pagination.Type {
+	case datahelpers.PaginationKeyset:
+		fields = append(fields, golang.NameWithType{Name: "next_cursor", Type: golang.GoStringType})
+	case datahelpers.PaginationOffset:
+		fields = append(fields, golang.NameWithType{Name: "next_offset", Type: golang.GoIntType})
+	}
+	return golang.GenStructForDataModel(pageStructName(name), fields, true, false, false)
+}
+
+// pageLimitCE bounds the limit of the request params by the limits of the pagination config, before the params
+// are bound
+func pageLimitCE(pagination *defs.Pagination, paramsName string) *golang.CodeElement {
+	defaultLimit, maxLimit := pagination.Limits()
+	limit := fmt.Sprintf("%s.%s", paramsName, golang.ToPascalCase(datahelpers.LimitParam))
+	return &golang.CodeElement{Assign: &golang.Assignment{
+		Left:  limit,
+		Right: fmt.Sprintf("pageLimit(%s, %d, %d)", limit, defaultLimit, maxLimit),
+	}}
+}
+
+// pageCE makes the page of the scanned results, the query reads one row more than the limit when there is a
+// next page:
+//
+//	page := &GetProductsPage{}
+//	if len(results) > requestParams.Limit {
+//		results = results[:requestParams.Limit]
+//		last := results[len(results)-1]
+//		page.NextCursor = encodeCursor(map[string]interface{}{"price": last.Price, "id": last.Id})
+//	}
+//	page.Items = results
+//
+// The cursor of a keyset page holds the columns the find is ordered by and the id of its last row, see
+// datahelpers.KeysetColumns. rowField is the model of a result, empty or .Product for the results of finds
+// including other models.
+func pageCE(name string, pagination *defs.Pagination, orderBy []defs.OrderBy, paramsName, resultsName, pageName, rowField string) golang.CodeElements {
+	limit := fmt.Sprintf("%s.%s", paramsName, golang.ToPascalCase(datahelpers.LimitParam))
+	next := golang.CodeElements{}
+	switch pagination.Type {
+	case datahelpers.PaginationKeyset:
+		columns := datahelpers.KeysetColumns(orderBy)
+		values := make([]string, 0, len(columns))
+		for _, column := range columns {
+			values = append(values, fmt.Sprintf("%q: last%s.%s", column, rowField, golang.ToPascalCase(column)))
+		}
+		next = append(next,
+			&golang.CodeElement{NewAssign: &golang.NewAssignment{Left: "last", Right: fmt.Sprintf("%s[len(%s)-1]", resultsName, resultsName)}},
+			&golang.CodeElement{Assign: &golang.Assignment{
+				Left:  pageName + ".NextCursor",
+				Right: fmt.Sprintf("encodeCursor(map[string]interface{}{%s})", strings.Join(values, ", ")),
+			}})
+	case datahelpers.PaginationOffset:
+		next = append(next, &golang.CodeElement{Assign: &golang.Assignment{
+			Left:  pageName + ".NextOffset",
+			Right: fmt.Sprintf("%s.%s + %s", paramsName, golang.ToPascalCase(datahelpers.OffsetParam), limit),
+		}})
+	}
+	return golang.CodeElements{
+		{NewAssign: &golang.NewAssignment{Left: pageName, Right: fmt.Sprintf("&%s{}", pageStructName(name))}},
+		{If: &golang.IfElement{
+			Condition: fmt.Sprintf("len(%s) > %s", resultsName, limit),
+			Then: append(golang.CodeElements{
+				{Assign: &golang.Assignment{Left: resultsName, Right: fmt.Sprintf("%s[:%s]", resultsName, limit)}},
+			}, next...),
+		}},
+		{Assign: &golang.Assignment{Left: pageName + ".Items", Right: resultsName}},
+	}
+}
+
+// GeneratePaginationUnit generates the helpers of the paginated finds: the bounds of page sizes, and the opaque
+// cursors of keyset pages, which encode the sort columns and the id of the last row of a page
+func GeneratePaginationUnit() *golang.UnitModule {
+	return &golang.UnitModule{
+		Name: paginationUnitName,
+		Functions: []*golang.FunctionDef{
+			pageLimitFunction(),
+			encodeCursorFunction(),
+			decodeCursorFunction(),
+		},
+		Variables: []*golang.Variable{{
+			Names:  "ErrInvalidCursor",
+			Values: `errors.New("invalid page cursor")`,
+		}},
+		Imports: []string{"errors"},
+	}
+}
+
+// pageLimit is the limit of a request, defaultLimit when it's not set and at most maxLimit
+func pageLimitFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name: "pageLimit",
+		Parameters: []*golang.Parameter{
+			{Name: "limit", Type: golang.GoIntType},
+			{Name: "defaultLimit", Type: golang.GoIntType},
+			{Name: "maxLimit", Type: golang.GoIntType},
+		},
+		Returns: typeOnlyParamsCE("int"),
+		Body: golang.CodeElements{
+			{If: &golang.IfElement{
+				Condition: "limit <= 0",
+				Then:      golang.CodeElements{returnValuesCE("defaultLimit")},
+			}},
+			returnValuesCE("min(limit, maxLimit)"),
+		},
+	}
+}
+
+// encodeCursor encodes the values of the last row of a page as a JSON object, by column
+func encodeCursorFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:       "encodeCursor",
+		Parameters: []*golang.Parameter{{Name: "values", Type: &golang.GoType{Name: "map[string]interface{}"}}},
+		Returns:    typeOnlyParamsCE("string"),
+		Imports:    []string{"encoding/base64", "encoding/json"},
+		Body: golang.CodeElements{
+			// The values are scanned columns, which marshal
+			{NewAssign: &golang.NewAssignment{Left: []string{"cursor", "_"}, Right: "json.Marshal(values)"}},
+			returnValuesCE("base64.RawURLEncoding.EncodeToString(cursor)"),
+		},
+	}
+}
+
+// decodeCursor returns the JSON object encoded in a cursor, which the query reads as a row of the table, NULL for
+// the empty cursor of the first page
+func decodeCursorFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:       decodeCursorFunctionName,
+		Parameters: []*golang.Parameter{{Name: "cursor", Type: golang.GoStringType}},
+		Returns:    typeOnlyParamsCE("sql.NullString", "error"),
+		Imports:    []string{"database/sql", "encoding/base64", "encoding/json"},
+		Body: golang.CodeElements{
+			{If: &golang.IfElement{
+				Condition: `cursor == ""`,
+				Then:      golang.CodeElements{returnValuesCE("sql.NullString{}", "nil")},
+			}},
+			{NewAssign: &golang.NewAssignment{Left: []string{"values", "err"}, Right: "base64.RawURLEncoding.DecodeString(cursor)"}},
+			{If: &golang.IfElement{
+				Condition: "err != nil || json.Unmarshal(values, &map[string]interface{}{}) != nil",
+				Then:      golang.CodeElements{returnValuesCE("sql.NullString{}", "ErrInvalidCursor")},
+			}},
+			returnValuesCE("sql.NullString{String: string(values), Valid: true}", "nil"),
+		},
+	}
+}
+
+// validatePagination checks the pagination of finds, see datahelpers.MakeFindQuery
+func validatePagination(modelConfig *defs.ModelConfig, dialect datahelpers.Dialect) []error {
+	errs := []error{}
+	modelName := modelConfig.Model.Name
+	for _, accessConfig := range modelConfig.GetAllAccessConfig() {
+		if accessConfig.Pagination != nil && !slices.ContainsFunc(modelConfig.Access.Find, func(find defs.AccessConfig) bool {
+			return find.Name == accessConfig.Name
+		}) {
+			errs = append(errs, fmt.Errorf("model %s: access %s is paginated, which only finds are", modelName, accessConfig.Name))
+		}
+	}
+
+	for _, accessConfig := range modelConfig.Access.Find {
+		pagination := accessConfig.Pagination
+		if pagination == nil {
+			continue
+		}
+		switch pagination.Type {
+		case datahelpers.PaginationOffset:
+		case datahelpers.PaginationKeyset:
+			// The cursor is compared to the row populated from its JSON, see datahelpers.MakeFindQuery
+			if dialect.GetName() != "postgres" {
+				errs = append(errs, fmt.Errorf("model %s: access %s has keyset pagination, which needs the postgres driver", modelName, accessConfig.Name))
+			}
+			// The cursor of the next page is the id of the last model of the page
+			if !slices.ContainsFunc(accessConfig.Attributes, func(attr string) bool { return golang.ToSnakeCase(attr) == "id" }) {
+				errs = append(errs, fmt.Errorf("model %s: access %s has keyset pagination, which needs id among its attributes", modelName, accessConfig.Name))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("model %s: access %s has pagination type %q, expected %s or %s",
+				modelName, accessConfig.Name, pagination.Type, datahelpers.PaginationOffset, datahelpers.PaginationKeyset))
+		}
+		if pagination.DefaultLimit < 0 || pagination.MaxLimit < 0 {
+			errs = append(errs, fmt.Errorf("model %s: access %s has a negative page limit", modelName, accessConfig.Name))
+		} else if pagination.MaxLimit > 0 && pagination.DefaultLimit > pagination.MaxLimit {
+			errs = append(errs, fmt.Errorf("model %s: access %s has default_limit %d above max_limit %d",
+				modelName, accessConfig.Name, pagination.DefaultLimit, pagination.MaxLimit))
+		}
+	}
+	return errs
+}
+
+// notNullColumns are the snake case columns of a model that cannot be NULL, see modelFields
+func notNullColumns(modelConfig *defs.ModelConfig, dataConfig *defs.DataConfig) map[string]bool {
+	columns := map[string]bool{}
+	for _, column := range datahelpers.ModelSystemColumns(&modelConfig.Model) {
+		columns[column.Name] = column != datahelpers.SoftDeleteColumn
+	}
+	for _, attributeId := range modelConfig.Model.Attributes {
+		if attribute, ok := config.Attributes[attributeId]; ok {
+			columns[golang.ToSnakeCase(attribute.Name)] = attribute.NotNull
+		}
+	}
+	references, _ := dataConfig.References(&modelConfig.Model)
+	for _, reference := range references {
+		columns[reference.Column] = reference.NotNull
+	}
+	return columns
+}
+
+// keysetGoTypes are the Go types of the columns the cursor of a keyset page holds exactly: integers, text and uuids,
+// timestamps and booleans. The cursor is JSON, a NUMERIC or floating point column read as a float64 would be rounded,
+// and the rows equal to the last row of the page would be skipped or read again.
+var keysetGoTypes = map[string]bool{"int": true, "int16": true, "int64": true, "string": true, "time.Time": true, "bool": true}
+
+// inexactColumns are the snake case columns of the attributes of a model the cursor of a keyset page cannot hold,
+// along with their database type, see keysetGoTypes. The system and reference columns are integers, uuids and
+// timestamps.
+func inexactColumns(modelConfig *defs.ModelConfig) map[string]string {
+	columns := map[string]string{}
+	for _, attributeId := range modelConfig.Model.Attributes {
+		attribute, ok := config.Attributes[attributeId]
+		if !ok {
+			continue
+		}
+		postgresType := datahelpers.GetPostgresType(attribute.TypeId)
+		if !keysetGoTypes[datahelpers.PostgresToGoType(postgresType)] {
+			columns[golang.ToSnakeCase(attribute.Name)] = postgresType
+		}
+	}
+	return columns
+}
+
+// validateKeysetOrdering checks that the cursor of a keyset page can hold the columns the find is ordered by, see
+// datahelpers.KeysetColumns: the find reads them, they are not NULL and of exact types, and the rows following the
+// cursor are found by a single row comparison, so that they are ordered in one direction and the find has no sort
+// options.
+func validateKeysetOrdering(modelName string, accessConfig *defs.AccessConfig, notNull map[string]bool, inexact map[string]string) []error {
+	errs := []error{}
+	if len(accessConfig.SortOptions) > 0 {
+		errs = append(errs, fmt.Errorf("model %s: access %s has keyset pagination, which does not support sort options",
+			modelName, accessConfig.Name))
+	}
+	direction := ""
+	for i, order := range accessConfig.OrderBy {
+		column := golang.ToSnakeCase(order.Attribute)
+		if column == "id" {
+			// The orderings after id don't change the order of the rows
+			break
+		}
+		if !slices.ContainsFunc(accessConfig.Attributes, func(attr string) bool { return golang.ToSnakeCase(attr) == column }) {
+			errs = append(errs, fmt.Errorf("model %s: access %s has keyset pagination, which needs order_by attribute %s among its attributes",
+				modelName, accessConfig.Name, order.Attribute))
+		}
+		if !notNull[column] {
+			errs = append(errs, fmt.Errorf("model %s: access %s has keyset pagination, which cannot order by nullable attribute %s",
+				modelName, accessConfig.Name, order.Attribute))
+		}
+		if columnType, ok := inexact[column]; ok {
+			errs = append(errs, fmt.Errorf("model %s: access %s has keyset pagination, which cannot order by %s attribute %s, expected integers, text, uuids or timestamps",
+				modelName, accessConfig.Name, columnType, order.Attribute))
+		}
+		orderDirection := strings.ToUpper(order.Direction)
+		if orderDirection == "" {
+			orderDirection = datahelpers.KeywordASC
+		}
+		if i == 0 {
+			direction = orderDirection
+		} else if orderDirection != direction {
+			errs = append(errs, fmt.Errorf("model %s: access %s has keyset pagination, which orders all its attributes in the same direction",
+				modelName, accessConfig.Name))
+		}
+	}
+	return errs
+}
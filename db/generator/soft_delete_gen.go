@@ -0,0 +1,142 @@
+package generator
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+// includeDeletedName is the variant of a find of a soft delete model that finds the deleted rows too,
+// GetProductByIDIncludeDeleted
+func includeDeletedName(name string) string {
+	return name + "IncludeDeleted"
+}
+
+// restoreName is the access restoring the rows soft deleted by a delete, RestoreProduct for DeleteProduct
+func restoreName(name string) string {
+	return "Restore" + strings.TrimPrefix(name, "Delete")
+}
+
+// restoreConfig is the access restoring the rows soft deleted by a delete config, filtering them like the delete.
+// Restores don't check the version, they bring back the row as it was deleted, but a restore with optimistic_lock
+// increments it, see datahelpers.MakeRestoreQuery. A restore writes the history rows and the events of its delete.
+func restoreConfig(deleteConfig *defs.AccessConfig) defs.AccessConfig {
+	restore := *deleteConfig
+	restore.Name = restoreName(deleteConfig.Name)
+	return restore
+}
+
+// restoreValidations are the validations of the params of a restore: the params of its = filters, the key of the rows
+// it brings back, are required besides the validations of their attributes, as a restore of an empty key restores
+// no row without failing
+func restoreValidations(validations *attributeValidations, restore *defs.AccessConfig) *attributeValidations {
+	required := &attributeValidations{calls: maps.Clone(validations.calls), patterns: validations.patterns}
+	for _, filter := range restore.Filter {
+		attribute := golang.ToSnakeCase(filter.Attribute)
+		isRequired := func(call *validationCall) bool { return call.check == "validateRequired" }
+		if filter.Operator != datahelpers.OperatorEquals || slices.ContainsFunc(required.calls[attribute], isRequired) {
+			continue
+		}
+		required.calls[attribute] = append([]*validationCall{{check: "validateRequired"}}, required.calls[attribute]...)
+	}
+	return required
+}
+
+// softDeleteAccess returns the accesses of a soft delete model: its finds, updates and aggregates leave the deleted
+// rows out, and each find is followed by its IncludeDeleted variant. Its add_or_replaces replace the rows that are
+// not deleted, the rows of its partial unique indexes. The deletes are generated by GenerateSoftDeleteConfigs.
+func softDeleteAccess(access defs.Access) defs.Access {
+	excludeDeleted := func(configs []defs.AccessConfig) []defs.AccessConfig {
+		excluded := make([]defs.AccessConfig, 0, len(configs))
+		for _, conf := range configs {
+			conf.ExcludeDeleted = true
+			excluded = append(excluded, conf)
+		}
+		return excluded
+	}
+	finds := make([]defs.AccessConfig, 0, 2*len(access.Find))
+	for _, find := range excludeDeleted(access.Find) {
+		included := find
+		included.Name = includeDeletedName(find.Name)
+		included.ExcludeDeleted = false
+		finds = append(finds, find, included)
+	}
+	access.Find = finds
+	access.Update = excludeDeleted(access.Update)
+	access.Aggregate = excludeDeleted(access.Aggregate)
+	access.AddOrReplace = excludeDeleted(access.AddOrReplace)
+	return access
+}
+
+// softDeleteVariants are the names of the accesses generated for a soft delete model besides its access configs,
+// the IncludeDeleted variants of its finds and the restores of its deletes
+func softDeleteVariants(modelConfig *defs.ModelConfig) []string {
+	if !modelConfig.Model.SoftDelete {
+		return nil
+	}
+	names := make([]string, 0, len(modelConfig.Access.Find)+len(modelConfig.Access.Delete))
+	for _, find := range modelConfig.Access.Find {
+		names = append(names, includeDeletedName(find.Name))
+	}
+	for _, deleteConfig := range modelConfig.Access.Delete {
+		names = append(names, restoreName(deleteConfig.Name))
+	}
+	return names
+}
+
+// GenerateSoftDeleteConfigs generates the deletes of a soft delete model, like GenerateDeleteConfigs, setting the
+// deletion time of the rows instead of deleting them, along with the restore of each delete clearing it:
+//
+//	func DeleteProduct(ctx context.Context, db *Product_DB, requestParams DeleteProductParams) (int64, error) {
+//		stmt := db.statement(ctx, "DeleteProduct") // UPDATE product SET deleted_at = NOW() WHERE (1 = 1) AND (id = $1) AND (deleted_at IS NULL)
+//		...
+//	}
+//
+//	func RestoreProduct(ctx context.Context, db *Product_DB, requestParams RestoreProductParams) (int64, error) {
+//		stmt := db.statement(ctx, "RestoreProduct") // UPDATE product SET deleted_at = NULL WHERE (1 = 1) AND (id = $1) AND (deleted_at IS NOT NULL)
+//		...
+//	}
+//
+// Both return the number of rows they changed, rows that are already deleted or restored are left as they are.
+// A restore has the params of its delete but the version, see restoreConfig.
+func GenerateSoftDeleteConfigs(dialect datahelpers.Dialect, modelName string, modelDBName string, fieldTypes map[string]*golang.GoType,
+	deleteConfig []defs.AccessConfig) ([]NamedQuery, []*golang.FunctionDef, []*golang.StructDef, error) {
+	functions := make([]*golang.FunctionDef, 0, 4*len(deleteConfig))
+	reqs := make([]*golang.StructDef, 0, 4*len(deleteConfig))
+	queries := make([]NamedQuery, 0, 2*len(deleteConfig))
+
+	for _, conf := range deleteConfig {
+		params, err := readAccessParams(&conf, fieldTypes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		restore := restoreConfig(&conf)
+		for _, variant := range []struct {
+			conf      *defs.AccessConfig
+			makeQuery func(datahelpers.Dialect, string, *defs.AccessConfig) (string, []defs.ParameterRef)
+			locked    bool
+		}{
+			{&conf, datahelpers.MakeSoftDeleteQuery, conf.OptimisticLock},
+			{&restore, datahelpers.MakeRestoreQuery, false},
+		} {
+			query, paramRefs := variant.makeQuery(dialect, modelName, variant.conf)
+			queries = append(queries, NamedQuery{Name: variant.conf.Name, Query: query, Expand: inListExpansion(paramRefs)})
+			reqs = append(reqs, generateAccessStructs(paramRefs, params, variant.conf.Name)...)
+			functions = append(functions, ReadParamsFunction(paramRefs, params, variant.conf.Name, "values", "params"))
+			fn := DeleteCodeFunction(variant.conf.Name, modelDBName)
+			if variant.locked {
+				fn = LockedDeleteCodeFunction(variant.conf.Name, modelDBName)
+			}
+			withActor(fn, variant.conf)
+			if err := withTimeout(fn, variant.conf); err != nil {
+				return nil, nil, nil, err
+			}
+			functions = append(functions, fn)
+		}
+	}
+	return queries, functions, reqs, nil
+}
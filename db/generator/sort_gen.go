@@ -0,0 +1,166 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+// Name of the unit holding the helpers of the finds with sort options
+const sortUnitName = "sort"
+
+// sortQueryName is the prepared query of a sort option, ListProductsByPrice for the price option of ListProducts
+func sortQueryName(name string, option defs.SortOption) string {
+	return name + "By" + golang.ToPascalCase(option.Name)
+}
+
+// sortQueryFunctionName is the function returning the prepared query of the sort param, ListProductsSortQuery
+func sortQueryFunctionName(name string) string {
+	return name + "SortQuery"
+}
+
+// sortQueries are the queries of the sort options of a find, the queries of the find ordered by the options
+func sortQueries(dialect datahelpers.Dialect, modelName string, conf *defs.AccessConfig) []NamedQuery {
+	queries := make([]NamedQuery, 0, len(conf.SortOptions))
+	for _, option := range conf.SortOptions {
+		sorted := *conf
+		sorted.OrderBy = option.OrderBy
+		query, paramRefs := datahelpers.MakeFindQuery(dialect, modelName, &sorted)
+		queries = append(queries, NamedQuery{Name: sortQueryName(conf.Name, option), Query: query, Expand: inListExpansion(paramRefs)})
+	}
+	return queries
+}
+
+// SortQueryFunction generates the function returning the prepared query of a sort param, the query of the find for
+// the empty sort, and ErrInvalidSort for a sort that is not among the sort options of the find:
+//
+//	func ListProductsSortQuery(sort string) (string, error) {
+//		queries := map[string]string{"": "ListProducts", "price": "ListProductsByPrice"}
+//		query, ok := queries[sort]
+//		if !ok {
+//			return "", fmt.Errorf("%w %q", ErrInvalidSort, sort)
+//		}
+//		return query, nil
+//	}
+func SortQueryFunction(conf *defs.AccessConfig) *golang.FunctionDef {
+	entries := []string{fmt.Sprintf("%q: %q", "", conf.Name)}
+	for _, option := range conf.SortOptions {
+		entries = append(entries, fmt.Sprintf("%q: %q", option.Name, sortQueryName(conf.Name, option)))
+	}
+	return &golang.FunctionDef{
+		Name:       sortQueryFunctionName(conf.Name),
+		Parameters: []*golang.Parameter{{Name: datahelpers.SortParam, Type: golang.GoStringType}},
+		Returns:    typeOnlyParamsCE("string", "error"),
+		Imports:    []string{"fmt"},
+		Body: golang.CodeElements{
+			{NewAssign: &golang.NewAssignment{
+				Left:  "queries",
+				Right: fmt.Sprintf("map[string]string{%s}", strings.Join(entries, ", ")),
+			}},
+			{NewAssign: &golang.NewAssignment{Left: []string{"query", "ok"}, Right: "queries[sort]"}},
+			{If: &golang.IfElement{
+				Condition: "!ok",
+				Then:      golang.CodeElements{returnValuesCE(`""`, `fmt.Errorf("%w %q", ErrInvalidSort, sort)`)},
+			}},
+			returnValuesCE("query", "nil"),
+		},
+	}
+}
+
+// findStmtCE looks up the prepared statement of a find, the statement of the sort param for a find with sort options
+func findStmtCE(name string, sorted bool, paramsName string, fnReturns []*golang.Parameter) golang.CodeElements {
+	if !sorted {
+		return golang.CodeElements{{FunctionCall: lookupStmtCE(name, "db", "stmt")}}
+	}
+	return golang.CodeElements{
+		{FunctionCall: &golang.FunctionCall{
+			NewOutput:    []string{"query", "err"},
+			Function:     sortQueryFunctionName(name),
+			Args:         []string{fmt.Sprintf("%s.%s", paramsName, golang.ToPascalCase(datahelpers.SortParam))},
+			ErrorHandler: &golang.ErrorHandler{ErrorFunctionReturns: fnReturns},
+		}},
+		{FunctionCall: &golang.FunctionCall{NewOutput: "stmt", Receiver: "db", Function: statementFunctionName, Args: []string{"ctx", "query"}}},
+	}
+}
+
+// GenerateSortUnit generates the error of the finds called with a sort that is not among their sort options
+func GenerateSortUnit() *golang.UnitModule {
+	return &golang.UnitModule{
+		Name: sortUnitName,
+		Variables: []*golang.Variable{{
+			Names:  "ErrInvalidSort",
+			Values: `errors.New("invalid sort")`,
+		}},
+		Imports: []string{"errors"},
+	}
+}
+
+// validateOrdering checks the order_by and the sort options of finds, see datahelpers.MakeOrderByClause
+func validateOrdering(dataConfig *defs.DataConfig, modelConfig *defs.ModelConfig, dialect datahelpers.Dialect) []error {
+	errs := []error{}
+	modelName := modelConfig.Model.Name
+	isFind := func(accessConfig defs.AccessConfig) bool {
+		return slices.ContainsFunc(modelConfig.Access.Find, func(find defs.AccessConfig) bool { return find.Name == accessConfig.Name })
+	}
+	isAggregate := func(accessConfig defs.AccessConfig) bool {
+		return slices.ContainsFunc(modelConfig.Access.Aggregate, func(aggregate defs.AccessConfig) bool { return aggregate.Name == accessConfig.Name })
+	}
+	for _, accessConfig := range modelConfig.GetAllAccessConfig() {
+		if len(accessConfig.OrderBy) > 0 && !isFind(accessConfig) && !isAggregate(accessConfig) {
+			errs = append(errs, fmt.Errorf("model %s: access %s is sorted, which only finds and aggregates are", modelName, accessConfig.Name))
+		}
+		if len(accessConfig.SortOptions) > 0 && !isFind(accessConfig) {
+			errs = append(errs, fmt.Errorf("model %s: access %s has sort options, which only finds have", modelName, accessConfig.Name))
+		}
+	}
+
+	columns := modelColumns(modelConfig, dataConfig)
+	validateOrderBy := func(accessName string, orderBy []defs.OrderBy) {
+		for _, order := range orderBy {
+			if !columns[golang.ToSnakeCase(order.Attribute)] {
+				errs = append(errs, fmt.Errorf("model %s: access %s orders by unknown attribute %s", modelName, accessName, order.Attribute))
+			}
+			if direction := strings.ToUpper(order.Direction); direction != "" && direction != datahelpers.KeywordASC && direction != datahelpers.KeywordDESC {
+				errs = append(errs, fmt.Errorf("model %s: access %s orders %s by direction %q, expected %s or %s",
+					modelName, accessName, order.Attribute, order.Direction, datahelpers.KeywordASC, datahelpers.KeywordDESC))
+			}
+			if nulls := strings.ToUpper(order.Nulls); nulls != "" && nulls != datahelpers.NullsFirst && nulls != datahelpers.NullsLast {
+				errs = append(errs, fmt.Errorf("model %s: access %s sorts the nulls of %s %q, expected %s or %s",
+					modelName, accessName, order.Attribute, order.Nulls, datahelpers.NullsFirst, datahelpers.NullsLast))
+			}
+			// MySQL sorts nulls first ascending and last descending, and has no NULLS clause
+			if order.Nulls != "" && dialect.GetName() == "mysql" {
+				errs = append(errs, fmt.Errorf("model %s: access %s sorts the nulls of %s, which the mysql driver doesn't", modelName, accessName, order.Attribute))
+			}
+		}
+	}
+	for _, accessConfig := range modelConfig.Access.Find {
+		validateOrderBy(accessConfig.Name, accessConfig.OrderBy)
+		optionNames := map[string]bool{}
+		for _, option := range accessConfig.SortOptions {
+			switch {
+			case option.Name == "":
+				errs = append(errs, fmt.Errorf("model %s: access %s has a sort option without a name", modelName, accessConfig.Name))
+			case optionNames[option.Name]:
+				errs = append(errs, fmt.Errorf("model %s: access %s has sort option %s more than once", modelName, accessConfig.Name, option.Name))
+			case len(option.OrderBy) == 0:
+				errs = append(errs, fmt.Errorf("model %s: access %s has sort option %s without order_by", modelName, accessConfig.Name, option.Name))
+			}
+			optionNames[option.Name] = true
+			validateOrderBy(accessConfig.Name, option.OrderBy)
+		}
+		if accessConfig.Pagination != nil && accessConfig.Pagination.Type == datahelpers.PaginationKeyset {
+			errs = append(errs, validateKeysetOrdering(modelName, &accessConfig, notNullColumns(modelConfig, dataConfig), inexactColumns(modelConfig))...)
+		}
+	}
+	// Aggregates are ordered by the columns of their results
+	for _, accessConfig := range modelConfig.Access.Aggregate {
+		columns = aggregateColumns(&accessConfig)
+		validateOrderBy(accessConfig.Name, accessConfig.OrderBy)
+	}
+	return errs
+}
@@ -0,0 +1,236 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+// Methods of the model DB structs looking up prepared statements and binding them to a transaction
+const (
+	statementFunctionName = "statement"
+	withTxFunctionName    = "withTx"
+	queryFunctionName     = "query"
+	execFunctionName      = "exec"
+)
+
+// isolationLevels are the sql.IsolationLevel of the isolation_level of the database config
+var isolationLevels = map[string]string{
+	"read_uncommitted": "sql.LevelReadUncommitted",
+	"read_committed":   "sql.LevelReadCommitted",
+	"repeatable_read":  "sql.LevelRepeatableRead",
+	"serializable":     "sql.LevelSerializable",
+}
+
+// familyTxStructName is the struct of the model DBs of a transaction, EcommerceDbTx
+func familyTxStructName(varName string) string {
+	return varName + "Tx"
+}
+
+// txOptions is the expression of the default *sql.TxOptions of WithTx, nil for the isolation level of the database
+func txOptions(dbConf *defs.DatabaseConfig) (string, error) {
+	if dbConf.IsolationLevel == "" {
+		return "nil", nil
+	}
+	level, ok := isolationLevels[strings.ToLower(dbConf.IsolationLevel)]
+	if !ok {
+		return "", fmt.Errorf("dataconf has isolation level %q, expected read_uncommitted, read_committed, repeatable_read or serializable",
+			dbConf.IsolationLevel)
+	}
+	return fmt.Sprintf("&sql.TxOptions{Isolation: %s}", level), nil
+}
+
+// statementFunction generates the lookup of the prepared statements of a model DB, which run in the transaction of
+// the model DBs of WithTx. Statements of a transaction are closed with the transaction.
+//
+//	func (db *User_DB) statement(ctx context.Context, name string) *sql.Stmt {
+//		stmt := db.preparedCache[name]
+//		if db.tx != nil {
+//			return db.tx.StmtContext(ctx, stmt)
+//		}
+//		return stmt
+//	}
+func statementFunction(modelDBName string) *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:     statementFunctionName,
+		Receiver: &golang.Receiver{Name: "db", Type: &golang.GoType{Name: modelDBName}},
+		Parameters: []*golang.Parameter{
+			{Name: "ctx", Type: &golang.GoType{Name: "context.Context"}},
+			{Name: "name", Type: golang.GoStringType},
+		},
+		Returns: typeOnlyParamsCE("*sql.Stmt"),
+		Imports: []string{"context", "database/sql"},
+		Body: golang.CodeElements{
+			{NewAssign: &golang.NewAssignment{Left: "stmt", Right: "db.preparedCache[name]"}},
+			{If: &golang.IfElement{
+				Condition: "db.tx != nil",
+				Then:      golang.CodeElements{returnValuesCE("db.tx.StmtContext(ctx, stmt)")},
+			}},
+			returnValuesCE("stmt"),
+		},
+	}
+}
+
+// queryFunction generates the query of a model DB for the queries that are not prepared, in the transaction of the
+// model DB in a transaction
+//
+//	func (db *Product_DB) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+//		if db.tx != nil {
+//			return db.tx.QueryContext(ctx, query, args...)
+//		}
+//		return db.db.QueryContext(ctx, query, args...)
+//	}
+func queryFunction(modelDBName string) *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:     queryFunctionName,
+		Receiver: &golang.Receiver{Name: "db", Type: &golang.GoType{Name: modelDBName}},
+		Parameters: []*golang.Parameter{
+			{Name: "ctx", Type: &golang.GoType{Name: "context.Context"}},
+			{Name: "query", Type: golang.GoStringType},
+			{Name: "args", Type: &golang.GoType{Name: "...interface{}"}},
+		},
+		Returns: typeOnlyParamsCE("*sql.Rows", "error"),
+		Imports: []string{"context", "database/sql"},
+		Body: golang.CodeElements{
+			{If: &golang.IfElement{
+				Condition: "db.tx != nil",
+				Then:      golang.CodeElements{returnValuesCE("db.tx.QueryContext(ctx, query, args...)")},
+			}},
+			returnValuesCE("db.db.QueryContext(ctx, query, args...)"),
+		},
+	}
+}
+
+// execFunction generates the exec of a model DB for the statements that are not prepared, like queryFunction, for the
+// dialects whose inserts return no rows (see returnsRows)
+//
+//	func (db *Product_DB) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+//		if db.tx != nil {
+//			return db.tx.ExecContext(ctx, query, args...)
+//		}
+//		return db.db.ExecContext(ctx, query, args...)
+//	}
+func execFunction(modelDBName string) *golang.FunctionDef {
+	fn := queryFunction(modelDBName)
+	fn.Name = execFunctionName
+	fn.Returns = typeOnlyParamsCE("sql.Result", "error")
+	fn.Body = golang.CodeElements{
+		{If: &golang.IfElement{
+			Condition: "db.tx != nil",
+			Then:      golang.CodeElements{returnValuesCE("db.tx.ExecContext(ctx, query, args...)")},
+		}},
+		returnValuesCE("db.db.ExecContext(ctx, query, args...)"),
+	}
+	return fn
+}
+
+// withTxFunction generates the copy of a model DB running its statements in a transaction
+func withTxFunction(modelDBName string) *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:       withTxFunctionName,
+		Receiver:   &golang.Receiver{Name: "db", Type: &golang.GoType{Name: modelDBName}},
+		Parameters: []*golang.Parameter{{Name: "tx", Type: &golang.GoType{Name: "*sql.Tx"}}},
+		Returns:    typeOnlyParamsCE("*" + modelDBName),
+		Imports:    []string{"database/sql"},
+		Body: golang.CodeElements{
+			returnValuesCE(fmt.Sprintf("&%s{db: db.db, preparedCache: db.preparedCache, tx: tx}", modelDBName)),
+		},
+	}
+}
+
+// generateFamilyTx generates the transactions of a family: the struct of the model DBs of a transaction, and the
+// WithTx and WithTxOptions methods of the family running a function in a transaction. The transaction is committed
+// when the function returns nil, and rolled back when it returns an error or panics:
+//
+//	err := EcommerceDb.WithTx(ctx, func(tx *EcommerceDbTx) error {
+//		if _, err := UpdateOrderStatus(ctx, tx.Order, orderParams); err != nil {
+//			return err
+//		}
+//		_, err := AddOrderItem(ctx, tx.OrderItem, itemParams)
+//		return err
+//	})
+func generateFamilyTx(dataConf *defs.DataConfig, modelNameMaps modelNameMappings, varName, familyTypeName string) (
+	*golang.StructDef, []*golang.FunctionDef, error) {
+	options, err := txOptions(dataConf.DatabaseConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	txStructName := familyTxStructName(varName)
+	fields := make([]golang.NameWithType, 0, len(modelNameMaps))
+	models := make([]string, 0, len(modelNameMaps))
+	for _, nameMap := range modelNameMaps {
+		fields = append(fields, golang.NameWithType{
+			Name: nameMap.ModelStructName,
+			Type: &golang.GoType{Name: "*" + nameMap.ModelDBStructName},
+		})
+		models = append(models, fmt.Sprintf("%s: f.%s.%s(tx)", nameMap.ModelStructName, nameMap.ModelStructName, withTxFunctionName))
+	}
+	txStruct := golang.GenStructForDataModel(txStructName, fields, false, false, false)
+
+	receiver := &golang.Receiver{Name: "f", Type: &golang.GoType{Name: familyTypeName}}
+	fnParam := &golang.Parameter{Name: "fn", Type: &golang.GoType{Name: fmt.Sprintf("func(tx *%s) error", txStructName)}}
+	ctxParam := &golang.Parameter{Name: "ctx", Type: &golang.GoType{Name: "context.Context"}}
+	withTx := &golang.FunctionDef{
+		Name:       "WithTx",
+		Receiver:   receiver,
+		Parameters: []*golang.Parameter{ctxParam, fnParam},
+		Returns:    typeOnlyParamsCE("error"),
+		Imports:    []string{"context", "database/sql"},
+		Body: golang.CodeElements{
+			returnValuesCE(fmt.Sprintf("f.WithTxOptions(ctx, %s, fn)", options)),
+		},
+	}
+	withTxOptions := &golang.FunctionDef{
+		Name:     "WithTxOptions",
+		Receiver: receiver,
+		Parameters: []*golang.Parameter{
+			ctxParam,
+			{Name: "opts", Type: &golang.GoType{Name: "*sql.TxOptions"}},
+			fnParam,
+		},
+		Returns: typeOnlyParamsCE("error"),
+		Imports: []string{"context", "database/sql", "errors"},
+		Body: golang.CodeElements{
+			{NewAssign: &golang.NewAssignment{Left: []string{"tx", "err"}, Right: "f.db.BeginTx(ctx, opts)"}},
+			{If: &golang.IfElement{Condition: "err != nil", Then: golang.CodeElements{returnValuesCE("err")}}},
+			{FunctionCall: &golang.FunctionCall{Function: "rollbackOnPanic", Args: []string{"tx"}, Defer: true}},
+			{Assign: &golang.Assignment{
+				Left:  "err",
+				Right: fmt.Sprintf("fn(&%s{%s})", txStructName, strings.Join(models, ", ")),
+			}},
+			{If: &golang.IfElement{
+				Condition: "err != nil",
+				Then: golang.CodeElements{
+					{If: &golang.IfElement{
+						Condition: "rollbackErr := tx.Rollback(); rollbackErr != nil",
+						Then:      golang.CodeElements{returnValuesCE("errors.Join(err, rollbackErr)")},
+					}},
+					returnValuesCE("err"),
+				},
+			}},
+			returnValuesCE("tx.Commit()"),
+		},
+	}
+	return txStruct, []*golang.FunctionDef{withTx, withTxOptions, rollbackOnPanicFunction()}, nil
+}
+
+// rollbackOnPanic is deferred by WithTxOptions, it rolls the transaction back and panics again when the function
+// of the transaction panics
+func rollbackOnPanicFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:       "rollbackOnPanic",
+		Parameters: []*golang.Parameter{{Name: "tx", Type: &golang.GoType{Name: "*sql.Tx"}}},
+		Imports:    []string{"database/sql"},
+		Body: golang.CodeElements{
+			{If: &golang.IfElement{
+				Condition: "recovered := recover(); recovered != nil",
+				Then: golang.CodeElements{
+					{FunctionCall: &golang.FunctionCall{Output: "_", Receiver: "tx", Function: "Rollback"}},
+					{FunctionCall: &golang.FunctionCall{Function: "panic", Args: []string{"recovered"}}},
+				},
+			}},
+		},
+	}
+}
@@ -0,0 +1,270 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+// Operators understood by the query makers used in generation
+var supportedOperators = map[string]bool{
+	datahelpers.OperatorEquals:            true,
+	datahelpers.OperatorNotEquals:         true,
+	datahelpers.OperatorLessThan:          true,
+	datahelpers.OperatorLessThanEquals:    true,
+	datahelpers.OperatorGreaterThan:       true,
+	datahelpers.OperatorGreaterThanEquals: true,
+	datahelpers.OperatorIn:                true,
+	datahelpers.OperatorBetween:           true,
+}
+
+var logicalOperators = map[string]bool{
+	datahelpers.LogicalAnd: true,
+	datahelpers.LogicalOr:  true,
+	datahelpers.LogicalNot: true,
+}
+
+// ValidateDataConfig checks a data config before generation, so that problems are reported
+// together and with the model/access they belong to, instead of surfacing as broken generated code.
+// Attributes are resolved against the loaded catalog (config.Attributes). The checks of an access feature are next
+// to its generator, validatePagination in pagination_gen.go, validateAudit in audit_gen.go and so on.
+// All problems found are joined into a single error, nil when the config is valid.
+func ValidateDataConfig(dataConfig *defs.DataConfig) error {
+	errs := []error{}
+	if dataConfig.FamilyName == "" {
+		errs = append(errs, fmt.Errorf("family_name is required"))
+	}
+	if len(dataConfig.Models) == 0 {
+		errs = append(errs, fmt.Errorf("models are required"))
+	}
+	// Types are checked against the type maps of the dialect of the driver, postgres when it's unknown
+	var dialect datahelpers.Dialect = datahelpers.NewPostgresDialect()
+	if dataConfig.DatabaseConfig == nil {
+		errs = append(errs, fmt.Errorf("connection_config is required"))
+	} else {
+		errs = append(errs, validateDatabaseConfig(dataConfig.DatabaseConfig)...)
+		if driverDialect, err := datahelpers.DialectForDriver(dataConfig.DatabaseConfig.DriverName); err == nil {
+			dialect = driverDialect
+		}
+	}
+
+	// All models are generated into one package, so access names must be unique across the family
+	accessNames := map[string]string{}
+	modelNames := map[string]bool{}
+	for i := range dataConfig.Models {
+		modelConfig := &dataConfig.Models[i]
+		modelName := modelConfig.Model.Name
+		if modelName == "" {
+			errs = append(errs, fmt.Errorf("models[%d]: name is required", i))
+			continue
+		}
+		if modelNames[golang.ToPascalCase(modelName)] {
+			errs = append(errs, fmt.Errorf("model %s: defined more than once", modelName))
+		}
+		modelNames[golang.ToPascalCase(modelName)] = true
+
+		references, err := dataConfig.References(&modelConfig.Model)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		errs = append(errs, validateModel(modelConfig, dialect, references)...)
+		errs = append(errs, validateIncludes(dataConfig, modelConfig)...)
+		errs = append(errs, validatePagination(modelConfig, dialect)...)
+		errs = append(errs, validateOrdering(dataConfig, modelConfig, dialect)...)
+		errs = append(errs, validateAggregates(dataConfig, modelConfig)...)
+		errs = append(errs, validateAddOrReplace(modelConfig)...)
+		errs = append(errs, validateAddMany(modelConfig, dialect)...)
+		errs = append(errs, validateOptimisticLock(modelConfig)...)
+		errs = append(errs, validateAudit(modelConfig, dialect)...)
+		errs = append(errs, validateEvents(modelConfig, dialect)...)
+		for _, accessConfig := range modelConfig.GetAllAccessConfig() {
+			if accessConfig.Name == "" {
+				errs = append(errs, fmt.Errorf("model %s: access name is required", modelName))
+				continue
+			}
+			if owner, ok := accessNames[accessConfig.Name]; ok {
+				errs = append(errs, fmt.Errorf("model %s: access %s is already defined by model %s", modelName, accessConfig.Name, owner))
+			}
+			accessNames[accessConfig.Name] = modelName
+			if accessConfig.Timeout != "" {
+				if timeout, err := time.ParseDuration(accessConfig.Timeout); err != nil || timeout <= 0 {
+					errs = append(errs, fmt.Errorf("model %s: access %s has timeout %q, expected a positive duration like 500ms or 2s",
+						modelName, accessConfig.Name, accessConfig.Timeout))
+				}
+			}
+		}
+		// The IncludeDeleted finds and restores of soft delete models are generated in the same package
+		for _, name := range softDeleteVariants(modelConfig) {
+			if owner, ok := accessNames[name]; ok {
+				errs = append(errs, fmt.Errorf("model %s: soft_delete access %s is already defined by model %s", modelName, name, owner))
+			}
+			accessNames[name] = modelName
+		}
+		// The history access of a model with audit is generated in the same package
+		if modelConfig.Model.Audit {
+			name := historyAccessName(golang.ToPascalCase(modelName))
+			if owner, ok := accessNames[name]; ok {
+				errs = append(errs, fmt.Errorf("model %s: audit access %s is already defined by model %s", modelName, name, owner))
+			}
+			accessNames[name] = modelName
+		}
+	}
+	// Tables are created in reference order, see DataConfig.ModelsInReferenceOrder
+	if _, err := dataConfig.ModelsInReferenceOrder(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Connection details are baked into the generated SetupDBConnection, see SetupDBConnectionFunction. The login of
+// drivers without a server is not required, see sqlDriver.
+func validateDatabaseConfig(dbConf *defs.DatabaseConfig) []error {
+	errs := []error{}
+	var dialect datahelpers.Dialect
+	var driverErr error
+	if dbConf.DriverName != "" {
+		dialect, driverErr = datahelpers.DialectForDriver(dbConf.DriverName)
+	}
+	server := dialect == nil || sqlDrivers[dialect.GetName()].server
+	required := []struct {
+		key     string
+		missing bool
+	}{
+		{"driver_name", dbConf.DriverName == ""},
+		{"user_name", server && dbConf.UserName == ""},
+		{"password", server && dbConf.Password == ""},
+		{"host", server && dbConf.Host == ""},
+		{"port", server && dbConf.Port == 0},
+		{"db_name", dbConf.DBName == ""},
+		{"conn_config", dbConf.ConnectionConfig == nil},
+		{"conn_pool_config", dbConf.ConnectionPoolConfig == nil},
+	}
+	for _, r := range required {
+		if r.missing {
+			errs = append(errs, fmt.Errorf("connection_config.%s is required", r.key))
+		}
+	}
+	if driverErr != nil {
+		errs = append(errs, fmt.Errorf("connection_config.driver_name: %w", driverErr))
+	}
+	return errs
+}
+
+func validateModel(modelConfig *defs.ModelConfig, dialect datahelpers.Dialect, references []defs.Reference) []error {
+	errs := []error{}
+	modelName := modelConfig.Model.Name
+
+	known := map[string]bool{}
+	for _, column := range datahelpers.ModelSystemColumns(&modelConfig.Model) {
+		known[column.Name] = true
+	}
+	modelAttributeIds := map[int64]bool{}
+	for _, attributeId := range modelConfig.Model.Attributes {
+		attribute, ok := config.Attributes[attributeId]
+		if !ok {
+			errs = append(errs, fmt.Errorf("model %s: attribute %d not found in catalog", modelName, attributeId))
+			continue
+		}
+		if _, err := dialect.DatabaseType(attribute.TypeId); err != nil {
+			errs = append(errs, fmt.Errorf("model %s: attribute %d (%s) has type %d without a %s type mapping",
+				modelName, attributeId, attribute.Name, attribute.TypeId, dialect.GetName()))
+		}
+		modelAttributeIds[attributeId] = true
+		known[golang.ToSnakeCase(attribute.Name)] = true
+	}
+	for _, reference := range references {
+		if known[reference.Column] {
+			errs = append(errs, fmt.Errorf("model %s: reference column %s is already a column of the model", modelName, reference.Column))
+		}
+		known[reference.Column] = true
+	}
+
+	for _, index := range modelConfig.Model.GetIndexes() {
+		for _, attributeId := range index.Attributes {
+			if !modelAttributeIds[attributeId] {
+				errs = append(errs, fmt.Errorf("model %s: index %s uses attribute %d, which is not a model attribute",
+					modelName, index.IndexName, attributeId))
+			}
+		}
+	}
+
+	checkAttr := func(accessName, attr string) {
+		if !known[golang.ToSnakeCase(attr)] {
+			errs = append(errs, fmt.Errorf("model %s: access %s uses unknown attribute %s", modelName, accessName, attr))
+		}
+	}
+
+	for _, accessConfig := range modelConfig.GetAllAccessConfig() {
+		for _, attr := range accessConfig.Attributes {
+			checkAttr(accessConfig.Name, attr)
+		}
+		for _, update := range append(accessConfig.Set[:len(accessConfig.Set):len(accessConfig.Set)], accessConfig.Values...) {
+			checkAttr(accessConfig.Name, update.Attribute)
+			if update.ParamName == "" {
+				errs = append(errs, fmt.Errorf("model %s: access %s sets %s without param_name", modelName, accessConfig.Name, update.Attribute))
+			}
+		}
+		for _, attr := range append(accessConfig.Autoincrement[:len(accessConfig.Autoincrement):len(accessConfig.Autoincrement)], accessConfig.CaptureTimestamp...) {
+			checkAttr(accessConfig.Name, attr)
+		}
+		for _, filter := range accessConfig.Filter {
+			errs = append(errs, validateFilter(modelName, accessConfig.Name, filter, checkAttr)...)
+		}
+	}
+	return errs
+}
+
+// aggregateFunctions are the functions of aggregates, see datahelpers.MakeAggregateQuery
+var aggregateFunctions = []string{datahelpers.AggregateCount, datahelpers.AggregateSum, datahelpers.AggregateAvg,
+	datahelpers.AggregateMin, datahelpers.AggregateMax}
+
+// modelColumns are the snake case columns of a model: system columns, attributes found in the catalog and references
+func modelColumns(modelConfig *defs.ModelConfig, dataConfig *defs.DataConfig) map[string]bool {
+	columns := map[string]bool{}
+	for _, column := range datahelpers.ModelSystemColumns(&modelConfig.Model) {
+		columns[column.Name] = true
+	}
+	for _, attributeId := range modelConfig.Model.Attributes {
+		if attribute, ok := config.Attributes[attributeId]; ok {
+			columns[golang.ToSnakeCase(attribute.Name)] = true
+		}
+	}
+	references, _ := dataConfig.References(&modelConfig.Model)
+	for _, reference := range references {
+		columns[reference.Column] = true
+	}
+	return columns
+}
+
+func validateFilter(modelName, accessName string, filter defs.Filter, checkAttr func(string, string)) []error {
+	errs := []error{}
+	operator := strings.ToUpper(filter.Operator)
+	if logicalOperators[operator] {
+		if len(filter.Conditions) == 0 {
+			errs = append(errs, fmt.Errorf("model %s: access %s has %s filter without conditions", modelName, accessName, operator))
+		}
+		for _, condition := range filter.Conditions {
+			errs = append(errs, validateFilter(modelName, accessName, condition, checkAttr)...)
+		}
+		return errs
+	}
+
+	if !supportedOperators[operator] {
+		return append(errs, fmt.Errorf("model %s: access %s has unsupported operator %q", modelName, accessName, filter.Operator))
+	}
+	if filter.Attribute == "" {
+		errs = append(errs, fmt.Errorf("model %s: access %s has %s filter without attribute", modelName, accessName, operator))
+	} else {
+		checkAttr(accessName, filter.Attribute)
+	}
+	if filter.ParamName == "" {
+		errs = append(errs, fmt.Errorf("model %s: access %s filters %s without param_name", modelName, accessName, filter.Attribute))
+	}
+	return errs
+}
@@ -0,0 +1,588 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+)
+
+func validDataConfig() *defs.DataConfig {
+	return &defs.DataConfig{
+		FamilyName: "EcommerceDB",
+		DatabaseConfig: &defs.DatabaseConfig{
+			DriverName:           "postgres",
+			UserName:             "user",
+			Password:             "password",
+			Host:                 "localhost",
+			Port:                 5432,
+			DBName:               "ecommerce",
+			ConnectionConfig:     &defs.ConnectionConfig{IdleTimeoutSecs: 10, MaxLifetimeMins: 30},
+			ConnectionPoolConfig: &defs.ConnectionPoolConfig{MaxIdleConns: 5, MaxOpenConns: 10},
+		},
+		Models: []defs.ModelConfig{
+			{
+				Model: defs.Model{Name: "User", Attributes: []int64{2000007, 2000008}},
+				Access: defs.Access{
+					Find: []defs.AccessConfig{{
+						Name:       "GetUserByEmail",
+						Attributes: []string{"id", "name", "email"},
+						Filter:     []defs.Filter{{Attribute: "email", Operator: "=", ParamName: "email"}},
+					}},
+					Update: []defs.AccessConfig{{
+						Name:   "UpdateUserName",
+						Set:    []defs.Update{{Attribute: "name", ParamName: "name"}},
+						Filter: []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestValidateDataConfig(t *testing.T) {
+	config.LoadConfig()
+
+	tests := []struct {
+		name     string
+		modify   func(dc *defs.DataConfig)
+		expected []string
+	}{
+		{
+			name:   "valid",
+			modify: func(dc *defs.DataConfig) {},
+		},
+		{
+			name: "missing family and connection",
+			modify: func(dc *defs.DataConfig) {
+				dc.FamilyName = ""
+				dc.DatabaseConfig = nil
+			},
+			expected: []string{"family_name is required", "connection_config is required"},
+		},
+		{
+			name: "incomplete connection config",
+			modify: func(dc *defs.DataConfig) {
+				dc.DatabaseConfig.Password = ""
+				dc.DatabaseConfig.ConnectionPoolConfig = nil
+			},
+			expected: []string{"connection_config.password is required", "connection_config.conn_pool_config is required"},
+		},
+		{
+			name: "driver without dialect",
+			modify: func(dc *defs.DataConfig) {
+				dc.DatabaseConfig.DriverName = "oracle"
+			},
+			expected: []string{`connection_config.driver_name: no SQL dialect for driver "oracle"`},
+		},
+		{
+			name: "mysql driver",
+			modify: func(dc *defs.DataConfig) {
+				dc.DatabaseConfig.DriverName = "mysql"
+			},
+		},
+		{
+			name: "sqlite driver without login",
+			modify: func(dc *defs.DataConfig) {
+				dc.DatabaseConfig.DriverName = "sqlite3"
+				dc.DatabaseConfig.UserName, dc.DatabaseConfig.Password = "", ""
+				dc.DatabaseConfig.Host, dc.DatabaseConfig.Port = "", 0
+			},
+		},
+		{
+			name: "sqlite driver without database file",
+			modify: func(dc *defs.DataConfig) {
+				dc.DatabaseConfig.DriverName = "sqlite3"
+				dc.DatabaseConfig.DBName = ""
+			},
+			expected: []string{"connection_config.db_name is required"},
+		},
+		{
+			name: "unknown catalog attribute",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Model.Attributes = append(dc.Models[0].Model.Attributes, 42)
+			},
+			expected: []string{"model User: attribute 42 not found in catalog"},
+		},
+		{
+			name: "unknown access attribute",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Find[0].Attributes = append(dc.Models[0].Access.Find[0].Attributes, "nickname")
+			},
+			expected: []string{"model User: access GetUserByEmail uses unknown attribute nickname"},
+		},
+		{
+			name: "unsupported operator and missing param",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Find[0].Filter = []defs.Filter{
+					{Attribute: "email", Operator: "~", ParamName: "email"},
+					{Operator: "OR", Conditions: []defs.Filter{{Attribute: "name", Operator: "="}}},
+				}
+			},
+			expected: []string{
+				`model User: access GetUserByEmail has unsupported operator "~"`,
+				"model User: access GetUserByEmail filters name without param_name",
+			},
+		},
+		{
+			name: "duplicate access name across models",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models = append(dc.Models, defs.ModelConfig{
+					Model: defs.Model{Name: "Seller", Attributes: []int64{2000018}},
+					Access: defs.Access{Delete: []defs.AccessConfig{{
+						Name:   "UpdateUserName",
+						Filter: []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}},
+					}}},
+				})
+			},
+			expected: []string{"model Seller: access UpdateUserName is already defined by model User"},
+		},
+		{
+			name: "relationships",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Model.ID = 1
+				dc.Models = append(dc.Models, defs.ModelConfig{
+					Model: defs.Model{ID: 2, Name: "Seller", Attributes: []int64{2000018},
+						Relationships: []defs.Relationship{{Type: "BelongsTo", TargetModelID: 1, OnDelete: "set null"}}},
+					Access: defs.Access{Find: []defs.AccessConfig{{
+						Name:       "GetSellersOfUser",
+						Attributes: []string{"id", "user_id"},
+						Filter:     []defs.Filter{{Attribute: "user_id", Operator: "=", ParamName: "user_id"}},
+					}}},
+				})
+			},
+		},
+		{
+			name: "invalid relationships",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Model.ID = 1
+				dc.Models[0].Model.Relationships = []defs.Relationship{
+					{Type: "Parent", TargetModelID: 1},
+					{Type: "BelongsTo", TargetModelID: 7},
+					{Type: "Refers", TargetModelID: 1, Name: "referrer", OnUpdate: "DROP"},
+					{Type: "BelongsTo", TargetModelID: 2},
+				}
+				dc.Models = append(dc.Models, defs.ModelConfig{
+					Model: defs.Model{ID: 2, Name: "Seller", Relationships: []defs.Relationship{{Type: "Child", TargetModelID: 1}}},
+				})
+			},
+			expected: []string{
+				`model User: relationships[0]: unknown relationship type "Parent"`,
+				"model User: relationships[1]: target model 7 not found",
+				`model User: relationships[2]: unknown referential action "DROP"`,
+				"relationships form a cycle: User -> Seller -> User",
+			},
+		},
+		{
+			name: "includes",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Model.ID = 1
+				dc.Models[0].Access.Find[0].Include = []defs.Include{{Model: "Seller", Attributes: []string{"id", "user_id"}}}
+				dc.Models = append(dc.Models, defs.ModelConfig{
+					Model: defs.Model{ID: 2, Name: "Seller", Relationships: []defs.Relationship{{Type: "Child", TargetModelID: 1}}},
+				})
+			},
+		},
+		{
+			name: "invalid includes",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Model.ID = 1
+				dc.Models[0].Access.Find[0].Attributes = []string{"name"}
+				dc.Models[0].Access.Find[0].Include = []defs.Include{
+					{Model: "Seller", Attributes: []string{"rating"}},
+					{Model: "Seller"},
+					{Model: "Store"},
+					{Model: "Product"},
+				}
+				dc.Models[0].Access.Update[0].Include = []defs.Include{{Model: "Seller"}}
+				dc.Models = append(dc.Models,
+					defs.ModelConfig{Model: defs.Model{ID: 2, Name: "Seller", Relationships: []defs.Relationship{{Type: "BelongsTo", TargetModelID: 1}}}},
+					defs.ModelConfig{Model: defs.Model{ID: 3, Name: "Product"}},
+				)
+			},
+			expected: []string{
+				"model User: access UpdateUserName includes models, which only finds do",
+				"model User: access GetUserByEmail includes models, which needs id among its attributes",
+				"model User: access GetUserByEmail includes Seller with unknown attribute rating",
+				"model User: access GetUserByEmail includes Seller more than once",
+				"model User: access GetUserByEmail: included model Store not found",
+				"model User: access GetUserByEmail: included model Product has no relationship to User",
+			},
+		},
+		{
+			name: "pagination",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Find[0].Pagination = &defs.Pagination{Type: "keyset", DefaultLimit: 20, MaxLimit: 100}
+			},
+		},
+		{
+			name: "invalid pagination",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Find[0].Attributes = []string{"name"}
+				dc.Models[0].Access.Find[0].Pagination = &defs.Pagination{Type: "keyset", DefaultLimit: 200, MaxLimit: 100}
+				dc.Models[0].Access.Find = append(dc.Models[0].Access.Find, defs.AccessConfig{
+					Name: "ListUsers", Attributes: []string{"id"}, Pagination: &defs.Pagination{Type: "page", MaxLimit: -1},
+				})
+				dc.Models[0].Access.Update[0].Pagination = &defs.Pagination{Type: "offset"}
+			},
+			expected: []string{
+				"model User: access UpdateUserName is paginated, which only finds are",
+				"model User: access GetUserByEmail has keyset pagination, which needs id among its attributes",
+				"model User: access GetUserByEmail has default_limit 200 above max_limit 100",
+				`model User: access ListUsers has pagination type "page", expected offset or keyset`,
+				"model User: access ListUsers has a negative page limit",
+			},
+		},
+		{
+			name: "ordering",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Find[0].OrderBy = []defs.OrderBy{{Attribute: "name", Direction: "desc", Nulls: "last"}}
+				dc.Models[0].Access.Find[0].SortOptions = []defs.SortOption{{Name: "email", OrderBy: []defs.OrderBy{{Attribute: "email"}}}}
+				dc.Models[0].Access.Find[0].Pagination = &defs.Pagination{Type: "offset"}
+			},
+		},
+		{
+			name: "invalid ordering",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Find[0].OrderBy = []defs.OrderBy{{Attribute: "age", Direction: "up", Nulls: "middle"}}
+				dc.Models[0].Access.Find[0].SortOptions = []defs.SortOption{
+					{Name: "email", OrderBy: []defs.OrderBy{{Attribute: "email"}}},
+					{Name: "email", OrderBy: []defs.OrderBy{{Attribute: "name"}}},
+					{Name: "none"},
+				}
+				dc.Models[0].Access.Find[0].Pagination = &defs.Pagination{Type: "keyset"}
+				dc.Models[0].Access.Update[0].OrderBy = []defs.OrderBy{{Attribute: "name"}}
+			},
+			expected: []string{
+				"model User: access UpdateUserName is sorted, which only finds and aggregates are",
+				"model User: access GetUserByEmail orders by unknown attribute age",
+				`model User: access GetUserByEmail orders age by direction "up", expected ASC or DESC`,
+				`model User: access GetUserByEmail sorts the nulls of age "middle", expected FIRST or LAST`,
+				"model User: access GetUserByEmail has sort option email more than once",
+				"model User: access GetUserByEmail has sort option none without order_by",
+				"model User: access GetUserByEmail has keyset pagination, which does not support sort options",
+				"model User: access GetUserByEmail has keyset pagination, which needs order_by attribute age among its attributes",
+				"model User: access GetUserByEmail has keyset pagination, which cannot order by nullable attribute age",
+			},
+		},
+		{
+			name: "sorted keyset pagination",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Find[0].Attributes = append(dc.Models[0].Access.Find[0].Attributes, "updatedAt", "version")
+				dc.Models[0].Access.Find[0].OrderBy = []defs.OrderBy{{Attribute: "updatedAt", Direction: "desc"}, {Attribute: "version", Direction: "DESC"}}
+				dc.Models[0].Access.Find[0].Pagination = &defs.Pagination{Type: "keyset"}
+			},
+		},
+		{
+			name: "invalid sorted keyset pagination",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Find[0].Attributes = append(dc.Models[0].Access.Find[0].Attributes, "updatedAt")
+				dc.Models[0].Access.Find[0].OrderBy = []defs.OrderBy{{Attribute: "updatedAt", Direction: "DESC"}, {Attribute: "version"}}
+				dc.Models[0].Access.Find[0].Pagination = &defs.Pagination{Type: "keyset"}
+			},
+			expected: []string{
+				"model User: access GetUserByEmail has keyset pagination, which needs order_by attribute version among its attributes",
+				"model User: access GetUserByEmail has keyset pagination, which orders all its attributes in the same direction",
+			},
+		},
+		{
+			name: "inexact keyset pagination",
+			modify: func(dc *defs.DataConfig) {
+				// The cursor would round the price read as a float64
+				dc.Models[0].Model.Attributes = append(dc.Models[0].Model.Attributes, 2000004)
+				dc.Models[0].Access.Find[0].Attributes = append(dc.Models[0].Access.Find[0].Attributes, "price")
+				dc.Models[0].Access.Find[0].OrderBy = []defs.OrderBy{{Attribute: "price"}}
+				dc.Models[0].Access.Find[0].Pagination = &defs.Pagination{Type: "keyset"}
+			},
+			expected: []string{
+				"model User: access GetUserByEmail has keyset pagination, which cannot order by NUMERIC(33,18) attribute price, expected integers, text, uuids or timestamps",
+			},
+		},
+		{
+			name: "aggregate",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Aggregate = []defs.AccessConfig{{
+					Name:       "CountUsersByName",
+					GroupBy:    []string{"name"},
+					Aggregates: []defs.Aggregate{{Function: "count"}, {Function: "MAX", Attribute: "email"}},
+					Having:     []defs.Filter{{Attribute: "count", Operator: ">", ParamName: "min_users"}},
+					OrderBy:    []defs.OrderBy{{Attribute: "count", Direction: "DESC"}},
+				}}
+			},
+		},
+		{
+			name: "invalid aggregate",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Aggregate = []defs.AccessConfig{{
+					Name:       "CountUsersByAge",
+					Attributes: []string{"name"},
+					GroupBy:    []string{"age"},
+					Aggregates: []defs.Aggregate{{Function: "MEDIAN", Attribute: "name"}, {Function: "SUM"}, {Function: "COUNT", Name: "age"}},
+					Having:     []defs.Filter{{Attribute: "total", Operator: ">", ParamName: "min_total"}},
+					OrderBy:    []defs.OrderBy{{Attribute: "email"}},
+				}, {
+					Name: "CountNothing",
+				}}
+				dc.Models[0].Access.Find[0].GroupBy = []string{"name"}
+			},
+			expected: []string{
+				"model User: access GetUserByEmail aggregates, which only aggregate accesses do",
+				"model User: access CountUsersByAge selects attributes, pages or includes models, which aggregates don't",
+				"model User: access CountUsersByAge groups by unknown attribute age",
+				`model User: access CountUsersByAge has aggregate function "MEDIAN", expected one of COUNT, SUM, AVG, MIN, MAX`,
+				"model User: access CountUsersByAge has SUM aggregate without attribute",
+				"model User: access CountUsersByAge has result age more than once",
+				"model User: access CountUsersByAge has having filter on unknown aggregate total",
+				"model User: access CountUsersByAge orders by unknown attribute email",
+				"model User: access CountNothing has no aggregates",
+			},
+		},
+		{
+			name: "timeout",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Find[0].Timeout = "2s"
+				dc.Models[0].Access.Update[0].Timeout = "-1s"
+			},
+			expected: []string{`model User: access UpdateUserName has timeout "-1s", expected a positive duration like 500ms or 2s`},
+		},
+		{
+			name: "soft delete",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Model.SoftDelete = true
+				// The deletion time is a column of soft delete models
+				dc.Models[0].Access.Find[0].Attributes = append(dc.Models[0].Access.Find[0].Attributes, "deleted_at")
+				dc.Models[0].Access.Delete = []defs.AccessConfig{{
+					Name:   "DeleteUser",
+					Filter: []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}},
+				}}
+			},
+		},
+		{
+			name: "soft delete variants already defined",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Model.SoftDelete = true
+				dc.Models[0].Access.Find = append(dc.Models[0].Access.Find, defs.AccessConfig{
+					Name:       "GetUserByEmailIncludeDeleted",
+					Attributes: []string{"id", "name"},
+					Filter:     []defs.Filter{{Attribute: "email", Operator: "=", ParamName: "email"}},
+				})
+				dc.Models[0].Access.Delete = []defs.AccessConfig{{
+					Name:   "DeleteUser",
+					Filter: []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}},
+				}, {
+					Name:   "RestoreUser",
+					Filter: []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}},
+				}}
+			},
+			expected: []string{
+				"model User: soft_delete access GetUserByEmailIncludeDeleted is already defined by model User",
+				"model User: soft_delete access RestoreUser is already defined by model User",
+			},
+		},
+		{
+			name: "optimistic lock",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Update[0].OptimisticLock = true
+				dc.Models[0].Access.Delete = []defs.AccessConfig{{
+					Name:           "DeleteUser",
+					Filter:         []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}},
+					OptimisticLock: true,
+				}}
+			},
+		},
+		{
+			name: "invalid optimistic lock",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Find[0].OptimisticLock = true
+				dc.Models[0].Access.Update[0].OptimisticLock = true
+				dc.Models[0].Access.Update[0].Set = append(dc.Models[0].Access.Update[0].Set, defs.Update{Attribute: "version", ParamName: "version"})
+			},
+			expected: []string{
+				"model User: access GetUserByEmail has optimistic_lock, which only updates and deletes have",
+				"model User: access UpdateUserName sets version, which optimistic_lock increments",
+				"model User: access UpdateUserName has a param version, which is reserved for optimistic_lock",
+			},
+		},
+		{
+			name: "add many",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.AddMany = []defs.AccessConfig{{
+					Name:      "AddUsers",
+					Values:    []defs.Update{{Attribute: "name", ParamName: "name"}, {Attribute: "email", ParamName: "email"}},
+					ChunkSize: 500,
+				}, {
+					Name:   "ImportUsers",
+					Values: []defs.Update{{Attribute: "email", ParamName: "email"}},
+					Copy:   true,
+				}}
+			},
+		},
+		{
+			name: "invalid add many",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Find[0].Copy = true
+				dc.Models[0].Access.AddMany = []defs.AccessConfig{{
+					Name:      "AddUsers",
+					Values:    []defs.Update{{Attribute: "name", ParamName: "name"}, {Attribute: "email", ParamName: "email"}},
+					Filter:    []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}},
+					ChunkSize: 40000,
+				}, {
+					Name:      "ImportUsers",
+					Copy:      true,
+					ChunkSize: 100,
+				}}
+			},
+			expected: []string{
+				"model User: access GetUserByEmail has chunk_size or copy, which only add_many accesses have",
+				"model User: access AddUsers filters, sets or selects attributes, which add_many accesses don't",
+				"model User: access AddUsers binds 80000 params per chunk, above the 65535 of a statement",
+				"model User: access ImportUsers inserts no values",
+				"model User: access ImportUsers copies its rows, which have no chunk_size",
+			},
+		},
+		{
+			name: "audit",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Model.Audit = true
+				dc.Models[0].Model.SoftDelete = true
+				dc.Models[0].Access.Delete = []defs.AccessConfig{{
+					Name:   "DeleteUser",
+					Filter: []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}},
+				}}
+			},
+		},
+		{
+			name: "invalid audit",
+			modify: func(dc *defs.DataConfig) {
+				dc.DatabaseConfig.DriverName = "mysql"
+				dc.Models[0].Model.Audit = true
+				dc.Models[0].Access.Find = append(dc.Models[0].Access.Find, defs.AccessConfig{
+					Name:       "FindUserHistory",
+					Attributes: []string{"id", "name"},
+					Filter:     []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}},
+				})
+				dc.Models[0].Model.UniqueConstraints = []defs.UniqueConstraint{{ConstraintName: "user_email_unique", Attributes: []int64{2000007}}}
+				dc.Models[0].Access.AddOrReplace = []defs.AccessConfig{{
+					Name:   "AddOrReplaceUser",
+					Values: []defs.Update{{Attribute: "email", ParamName: "email"}, {Attribute: "name", ParamName: "name"}},
+				}}
+				dc.Models[0].Access.AddMany = []defs.AccessConfig{{
+					Name:   "AddUsers",
+					Values: []defs.Update{{Attribute: "name", ParamName: "name"}},
+				}}
+			},
+			expected: []string{
+				"model User: has audit, which needs the postgres driver",
+				"model User: access AddOrReplaceUser is an add_or_replace, audit doesn't record the rows it replaces",
+				"model User: access AddUsers is an add_many, audit doesn't record the rows of batch inserts",
+				"model User: audit access FindUserHistory is already defined by model User",
+			},
+		},
+		{
+			name: "mysql driver features",
+			modify: func(dc *defs.DataConfig) {
+				dc.DatabaseConfig.DriverName = "mysql"
+				dc.Models[0].Access.Find = append(dc.Models[0].Access.Find,
+					defs.AccessConfig{
+						Name:       "ListUsers",
+						Attributes: []string{"id", "name"},
+						Pagination: &defs.Pagination{Type: "keyset"},
+					},
+					defs.AccessConfig{
+						Name:       "ListUsersByName",
+						Attributes: []string{"id", "name"},
+						OrderBy:    []defs.OrderBy{{Attribute: "name", Nulls: "last"}},
+						Pagination: &defs.Pagination{Type: "offset"},
+					})
+				dc.Models[0].Access.AddMany = []defs.AccessConfig{
+					{Name: "ImportUsers", Values: []defs.Update{{Attribute: "name", ParamName: "name"}}, Copy: true},
+					{Name: "AddUsersWithIds", Values: []defs.Update{{Attribute: "id", ParamName: "id"}, {Attribute: "name", ParamName: "name"}}},
+					{Name: "AddUsers", Values: []defs.Update{{Attribute: "name", ParamName: "name"}}},
+				}
+			},
+			expected: []string{
+				"model User: access ListUsers has keyset pagination, which needs the postgres driver",
+				"model User: access ListUsersByName sorts the nulls of name, which the mysql driver doesn't",
+				"model User: access ImportUsers copies its rows, which needs the postgres driver",
+				"model User: access AddUsersWithIds inserts the id, which the database assigns to the batch inserts of the mysql driver",
+			},
+		},
+		{
+			name: "add_or_replace",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Model.UniqueConstraints = []defs.UniqueConstraint{{ConstraintName: "user_email_unique", Attributes: []int64{2000007}}}
+				dc.Models[0].Access.AddOrReplace = []defs.AccessConfig{
+					{Name: "AddOrReplaceUser", Values: []defs.Update{{Attribute: "email", ParamName: "email"}, {Attribute: "name", ParamName: "name"}}},
+					{Name: "ReplaceUser", Values: []defs.Update{{Attribute: "id", ParamName: "id"}, {Attribute: "name", ParamName: "name"}}},
+				}
+			},
+		},
+		{
+			name: "add_or_replace without unique key",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Model.UniqueConstraints = []defs.UniqueConstraint{{ConstraintName: "user_email_unique", Attributes: []int64{2000007}}}
+				dc.Models[0].Access.AddOrReplace = []defs.AccessConfig{{
+					Name: "AddOrReplaceUser", Values: []defs.Update{{Attribute: "name", ParamName: "name"}},
+				}}
+			},
+			expected: []string{
+				"model User: access AddOrReplaceUser has no unique key among its values, expected the attributes of a unique constraint or the id",
+			},
+		},
+		{
+			name: "events",
+			modify: func(dc *defs.DataConfig) {
+				dc.Models[0].Access.Update[0].Events = true
+				// The delete of a soft delete model and its restore add events
+				dc.Models[0].Model.SoftDelete = true
+				dc.Models[0].Access.Delete = []defs.AccessConfig{{
+					Name:   "DeleteUser",
+					Filter: []defs.Filter{{Attribute: "id", Operator: "=", ParamName: "id"}},
+					Events: true,
+				}}
+			},
+		},
+		{
+			name: "invalid events",
+			modify: func(dc *defs.DataConfig) {
+				dc.DatabaseConfig.DriverName = "mysql"
+				dc.Models[0].Access.Find[0].Events = true
+				dc.Models[0].Access.Update[0].Events = true
+				dc.Models[0].Model.UniqueConstraints = []defs.UniqueConstraint{{ConstraintName: "user_email_unique", Attributes: []int64{2000007}}}
+				dc.Models[0].Access.AddOrReplace = []defs.AccessConfig{{
+					Name:   "AddOrReplaceUser",
+					Values: []defs.Update{{Attribute: "email", ParamName: "email"}, {Attribute: "name", ParamName: "name"}},
+					Events: true,
+				}}
+				dc.Models[0].Access.AddMany = []defs.AccessConfig{{
+					Name:   "AddUsers",
+					Values: []defs.Update{{Attribute: "name", ParamName: "name"}},
+					Events: true,
+				}}
+			},
+			expected: []string{
+				"model User: access GetUserByEmail has events, which only updates, adds and deletes have",
+				"model User: access UpdateUserName has events, which need the postgres driver",
+				"model User: access AddOrReplaceUser is an add_or_replace, which has no events: its upsert writes no outbox events",
+				"model User: access AddUsers is an add_many, which has no events: its batch inserts write no outbox events",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dc := validDataConfig()
+			tt.modify(dc)
+			err := ValidateDataConfig(dc)
+			if len(tt.expected) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			for _, msg := range tt.expected {
+				assert.Contains(t, err.Error(), msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,539 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/base"
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/models"
+)
+
+// Name of the unit holding the checks called by the generated Validate methods
+const validationUnitName = "validation"
+
+// errMissingValidationParam skips the catalog validations that are missing a parameter of their rule
+var errMissingValidationParam = errors.New("missing parameter")
+
+// validationCheck is a check of the validation unit, returning the message of a failed validation, "" otherwise:
+//
+//	func validateMaxLength(value interface{}, length int) string {
+//		text, ok := validationText(value)
+//		if ok && utf8.RuneCountInString(text) > length {
+//			return fmt.Sprintf("must be at most %d characters long", length)
+//		}
+//		return ""
+//	}
+//
+// Values that are not set (nil or "") pass every check but validateRequired.
+type validationCheck struct {
+	name      string
+	params    []*golang.Parameter
+	text      string
+	condition string
+	message   string
+	imports   []string
+}
+
+var (
+	lengthParam   = []*golang.Parameter{{Name: "length", Type: golang.GoIntType}}
+	countParam    = []*golang.Parameter{{Name: "count", Type: golang.GoIntType}}
+	expectedParam = []*golang.Parameter{{Name: "expected", Type: golang.GoStringType}}
+)
+
+var validationChecks = []validationCheck{
+	{name: "validateRequired", text: "_", condition: "!ok", message: `"is required"`},
+	{name: "validateMinLength", params: lengthParam, condition: "ok && utf8.RuneCountInString(text) < length",
+		message: `fmt.Sprintf("must be at least %d characters long", length)`, imports: []string{"fmt", "unicode/utf8"}},
+	{name: "validateMaxLength", params: lengthParam, condition: "ok && utf8.RuneCountInString(text) > length",
+		message: `fmt.Sprintf("must be at most %d characters long", length)`, imports: []string{"fmt", "unicode/utf8"}},
+	{name: "validateExactLength", params: lengthParam, condition: "ok && utf8.RuneCountInString(text) != length",
+		message: `fmt.Sprintf("must be exactly %d characters long", length)`, imports: []string{"fmt", "unicode/utf8"}},
+	{name: "validatePattern", params: []*golang.Parameter{
+		{Name: "pattern", Type: &golang.GoType{Name: "*regexp.Regexp", Source: "regexp"}},
+		{Name: "message", Type: golang.GoStringType},
+	}, condition: "ok && !pattern.MatchString(text)", message: "message", imports: []string{"regexp"}},
+	{name: "validateIP", condition: "ok && net.ParseIP(text) == nil", message: `"must be a valid IP address"`, imports: []string{"net"}},
+	// Texts that are not numbers fail the comparison with NaN
+	{name: "validateMinValue", params: []*golang.Parameter{{Name: "min", Type: golang.GoFloat64Type}},
+		condition: "ok && !(validationNumber(text) >= min)", message: `fmt.Sprintf("must be at least %v", min)`, imports: []string{"fmt"}},
+	{name: "validateMaxValue", params: []*golang.Parameter{{Name: "max", Type: golang.GoFloat64Type}},
+		condition: "ok && !(validationNumber(text) <= max)", message: `fmt.Sprintf("must be at most %v", max)`, imports: []string{"fmt"}},
+	{name: "validateEquals", params: expectedParam, condition: "ok && text != expected",
+		message: `fmt.Sprintf("must be %q", expected)`, imports: []string{"fmt"}},
+	{name: "validateEqualsNoCase", params: expectedParam, condition: "ok && !strings.EqualFold(text, expected)",
+		message: `fmt.Sprintf("must be %q", expected)`, imports: []string{"fmt", "strings"}},
+	{name: "validateMinWords", params: countParam, condition: "ok && len(strings.Fields(text)) < count",
+		message: `fmt.Sprintf("must have at least %d words", count)`, imports: []string{"fmt", "strings"}},
+	{name: "validateMaxWords", params: countParam, condition: "ok && len(strings.Fields(text)) > count",
+		message: `fmt.Sprintf("must have at most %d words", count)`, imports: []string{"fmt", "strings"}},
+	{name: "validateExactWords", params: countParam, condition: "ok && len(strings.Fields(text)) != count",
+		message: `fmt.Sprintf("must have exactly %d words", count)`, imports: []string{"fmt", "strings"}},
+}
+
+// validationPattern is a pattern of the rules checked with validatePattern, declared by the validation unit
+type validationPattern struct {
+	name, pattern, message string
+}
+
+var (
+	emailPattern               = validationPattern{"emailPattern", `^[^@\s]+@[^@\s]+\.[^@\s]+$`, "must be a valid email address"}
+	numericPattern             = validationPattern{"numericPattern", `^[+-]?[0-9]+(\.[0-9]+)?$`, "must be numeric"}
+	alphaPattern               = validationPattern{"alphaPattern", `^[A-Za-z]+$`, "must contain letters only"}
+	alphaNumericPattern        = validationPattern{"alphaNumericPattern", `^[A-Za-z0-9]+$`, "must contain letters and digits only"}
+	alphaNumericSpecialPattern = validationPattern{"alphaNumericSpecialPattern", `^[\x20-\x7E]+$`,
+		"must contain letters, digits and special characters only"}
+	urlPattern = validationPattern{"urlPattern", `^[A-Za-z][A-Za-z0-9+.-]*://[^\s/?#]+[^\s]*$`, "must be a valid URL"}
+)
+
+var validationPatterns = []validationPattern{
+	emailPattern, numericPattern, alphaPattern, alphaNumericPattern, alphaNumericSpecialPattern, urlPattern,
+}
+
+// validationCall is the call of a check on a field, with the arguments following the field
+type validationCall struct {
+	check string
+	args  []string
+	// pattern of match_pattern, declared by the model unit
+	pattern string
+}
+
+func (c *validationCall) code(field string) string {
+	return fmt.Sprintf("%s(%s)", c.check, strings.Join(append([]string{field}, c.args...), ", "))
+}
+
+// validationParams are the parameters of a catalog validation, see models.Validation
+type validationParams map[string]interface{}
+
+func (p validationParams) int(key string) (string, error) {
+	value, ok := p[key].(float64)
+	if !ok {
+		return "", fmt.Errorf("%w %s", errMissingValidationParam, key)
+	}
+	if value != float64(int(value)) || value < 0 {
+		return "", fmt.Errorf("%s must be a positive integer, got %v", key, value)
+	}
+	return strconv.Itoa(int(value)), nil
+}
+
+func (p validationParams) number(key string) (string, error) {
+	value, ok := p[key].(float64)
+	if !ok {
+		return "", fmt.Errorf("%w %s", errMissingValidationParam, key)
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64), nil
+}
+
+func (p validationParams) text(key string) (string, error) {
+	value, ok := p[key].(string)
+	if !ok {
+		return "", fmt.Errorf("%w %s", errMissingValidationParam, key)
+	}
+	return value, nil
+}
+
+func checkCall(check string) func(validationParams) (*validationCall, error) {
+	return func(validationParams) (*validationCall, error) {
+		return &validationCall{check: check}, nil
+	}
+}
+
+func patternCall(pattern validationPattern) func(validationParams) (*validationCall, error) {
+	return func(validationParams) (*validationCall, error) {
+		return &validationCall{check: "validatePattern", args: []string{pattern.name, strconv.Quote(pattern.message)}}, nil
+	}
+}
+
+func paramCall(check string, read func(validationParams, string) (string, error), key string) func(validationParams) (*validationCall, error) {
+	return func(params validationParams) (*validationCall, error) {
+		value, err := read(params, key)
+		if err != nil {
+			return nil, err
+		}
+		return &validationCall{check: check, args: []string{value}}, nil
+	}
+}
+
+func quotedText(params validationParams, key string) (string, error) {
+	value, err := params.text(key)
+	return strconv.Quote(value), err
+}
+
+// Rules of the catalog validations checked by the generated code, keyed by rule name.
+// The comparisons with other fields and the file rules (size, height and width) are not checked.
+var validationRules = map[string]func(validationParams) (*validationCall, error){
+	"required":              checkCall("validateRequired"),
+	"min_length":            paramCall("validateMinLength", validationParams.int, "length"),
+	"max_length":            paramCall("validateMaxLength", validationParams.int, "length"),
+	"exact_length":          paramCall("validateExactLength", validationParams.int, "length"),
+	"email":                 patternCall(emailPattern),
+	"numeric":               patternCall(numericPattern),
+	"alpha_only":            patternCall(alphaPattern),
+	"alpha_numeric":         patternCall(alphaNumericPattern),
+	"alpha_numeric_special": patternCall(alphaNumericSpecialPattern),
+	"valid_url":             patternCall(urlPattern),
+	"valid_ip":              checkCall("validateIP"),
+	"min_value":             paramCall("validateMinValue", validationParams.number, "value"),
+	"max_value":             paramCall("validateMaxValue", validationParams.number, "value"),
+	"equals":                paramCall("validateEquals", quotedText, "value"),
+	"equals_nocase":         paramCall("validateEqualsNoCase", quotedText, "value"),
+	"min_words_count":       paramCall("validateMinWords", validationParams.int, "count"),
+	"max_words_count":       paramCall("validateMaxWords", validationParams.int, "count"),
+	"exact_words_count":     paramCall("validateExactWords", validationParams.int, "count"),
+	"match_pattern": func(params validationParams) (*validationCall, error) {
+		pattern, err := params.text("pattern")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, err
+		}
+		return &validationCall{check: "validatePattern", args: []string{strconv.Quote("must match " + pattern)}, pattern: pattern}, nil
+	},
+}
+
+// readValidationCall reads the call checking a catalog validation, nil for the rules that are not checked
+func readValidationCall(validation *models.Validation) (*validationCall, error) {
+	rule, ok := validationRules[validation.RuleName]
+	if !ok {
+		return nil, nil
+	}
+	params := validationParams{}
+	if validation.ValidationParams != "" {
+		if err := json.Unmarshal([]byte(validation.ValidationParams), &params); err != nil {
+			return nil, fmt.Errorf("invalid validation_params: %w", err)
+		}
+	}
+	return rule(params)
+}
+
+// attributeValidations are the checks of the validated attributes of a model, keyed by attribute name
+type attributeValidations struct {
+	calls map[string][]*validationCall
+	// patterns of the match_pattern validations, `var productSkuPattern = regexp.MustCompile(...)`
+	patterns []*golang.Variable
+}
+
+// readAttributeValidations reads the catalog validations of the attributes of a model.
+// Validations of rules that are not checked, or missing a parameter, are skipped with a warning.
+func readAttributeValidations(modelStructName string, model *defs.Model) (*attributeValidations, error) {
+	validations := &attributeValidations{calls: map[string][]*validationCall{}}
+	for _, attributeId := range model.Attributes {
+		attrName, _, attrValidations, err := readTypeAndValidations(attributeId)
+		if err != nil {
+			return nil, err
+		}
+		for _, validation := range attrValidations {
+			call, err := readValidationCall(validation)
+			if errors.Is(err, errMissingValidationParam) {
+				base.LOG.Warn("Validation skipped", "model", model.Name, "attribute", attrName, "rule", validation.RuleName, "error", err)
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("model %s: attribute %s: validation %s: %w", model.Name, attrName, validation.RuleName, err)
+			}
+			if call == nil {
+				base.LOG.Warn("Validation rule is not checked by the generated code", "model", model.Name, "attribute", attrName, "rule", validation.RuleName)
+				continue
+			}
+			if call.pattern != "" {
+				patternName := fmt.Sprintf("%s%sPattern", golang.ToCamelCase(modelStructName), golang.ToPascalCase(attrName))
+				validations.patterns = append(validations.patterns, patternVariable(patternName, call.pattern))
+				call.args = append([]string{patternName}, call.args...)
+			}
+			validations.calls[attrName] = append(validations.calls[attrName], call)
+		}
+	}
+	return validations, nil
+}
+
+func patternVariable(name, pattern string) *golang.Variable {
+	literal := strconv.Quote(pattern)
+	if !strings.Contains(pattern, "`") {
+		literal = "`" + pattern + "`"
+	}
+	return &golang.Variable{
+		Names:  name,
+		Type:   "*regexp.Regexp",
+		Values: fmt.Sprintf("regexp.MustCompile(%s)", literal),
+	}
+}
+
+// validatedField is a field of a struct holding the value of an attribute
+type validatedField struct {
+	attribute string
+	field     string
+}
+
+// ValidateMethod generates the Validate method of a struct, returning the failed validations of its fields
+// keyed by attribute name, nil when every field is valid:
+//
+//	func (params *AddProductParams) Validate() error {
+//		failed := make(map[string]string)
+//		if message := firstValidationMessage(validateMaxLength(params.Sku, 64), validateRequired(params.Sku)); message != "" {
+//			failed["sku"] = message
+//		}
+//		return validationResult(failed)
+//	}
+func (v *attributeValidations) ValidateMethod(structName, receiverName string, fields []validatedField) *golang.FunctionDef {
+	body := golang.CodeElements{}
+	imports := []string{}
+	for _, field := range fields {
+		calls := v.calls[field.attribute]
+		if len(calls) == 0 {
+			continue
+		}
+		checks := make([]string, 0, len(calls))
+		for _, call := range calls {
+			checks = append(checks, call.code(receiverName+"."+field.field))
+			if call.pattern != "" {
+				imports = append(imports, "regexp")
+			}
+		}
+		check := checks[0]
+		if len(checks) > 1 {
+			check = fmt.Sprintf("firstValidationMessage(%s)", strings.Join(checks, ", "))
+		}
+		body = append(body, &golang.CodeElement{If: &golang.IfElement{
+			Condition: fmt.Sprintf(`message := %s; message != ""`, check),
+			Then: golang.CodeElements{
+				{Assign: &golang.Assignment{Left: fmt.Sprintf("failed[%q]", field.attribute), Right: "message"}},
+			},
+		}})
+	}
+	if len(body) == 0 {
+		body = append(body, returnValuesCE("nil"))
+	} else {
+		body = append(golang.CodeElements{{FunctionCall: makeNewMapCE("failed", "map[string]string")}}, body...)
+		body = append(body, returnValuesCE("validationResult(failed)"))
+	}
+	return &golang.FunctionDef{
+		Name:     "Validate",
+		Receiver: &golang.Receiver{Name: receiverName, Type: &golang.GoType{Name: structName}},
+		Returns:  typeOnlyParamsCE("error"),
+		Body:     body,
+		Imports:  imports,
+	}
+}
+
+// ModelValidateMethod generates the Validate method of the model struct
+func (v *attributeValidations) ModelValidateMethod(modelStructName string, model *defs.Model) (*golang.FunctionDef, error) {
+	fields := make([]validatedField, 0, len(model.Attributes))
+	for _, attributeId := range model.Attributes {
+		attrName, _, _, err := readTypeAndValidations(attributeId)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, validatedField{attribute: attrName, field: golang.ToPascalCase(attrName)})
+	}
+	return v.ValidateMethod(modelStructName, "item", fields), nil
+}
+
+// ParamsValidateMethod generates the Validate method of the params struct of an access config.
+// Params are validated as the attribute they are set to, inserted as or compared to (=, !=, <, <=, >, >=).
+func (v *attributeValidations) ParamsValidateMethod(conf *defs.AccessConfig) *golang.FunctionDef {
+	fields := []validatedField{}
+	seen := map[string]bool{}
+	addField := func(attribute, paramName string) {
+		if paramName == "" || seen[paramName] {
+			return
+		}
+		seen[paramName] = true
+		fields = append(fields, validatedField{attribute: golang.ToSnakeCase(attribute), field: golang.ToPascalCase(paramName)})
+	}
+	for _, value := range conf.Values {
+		addField(value.Attribute, value.ParamName)
+	}
+	for _, set := range conf.Set {
+		addField(set.Attribute, set.ParamName)
+	}
+	var addFilters func(filters []defs.Filter)
+	addFilters = func(filters []defs.Filter) {
+		for _, filter := range filters {
+			switch filter.Operator {
+			case "=", "!=", "<>", "<", "<=", ">", ">=":
+				addField(filter.Attribute, filter.ParamName)
+			}
+			addFilters(filter.Conditions)
+		}
+	}
+	addFilters(conf.Filter)
+	return v.ValidateMethod(conf.Name+"Params", "params", fields)
+}
+
+// GenerateValidationUnit generates the checks called by the Validate methods of the model and params structs.
+// A failed Validate returns a *ValidationError, holding the message of the failed validation of each attribute.
+func GenerateValidationUnit() *golang.UnitModule {
+	functions := []*golang.FunctionDef{
+		validationErrorFunction(),
+		validationResultFunction(),
+		firstValidationMessageFunction(),
+		validationTextFunction(),
+		validationNumberFunction(),
+	}
+	for _, check := range validationChecks {
+		functions = append(functions, validationCheckFunction(check))
+	}
+
+	variables := make([]*golang.Variable, 0, len(validationPatterns))
+	for _, pattern := range validationPatterns {
+		variables = append(variables, patternVariable(pattern.name, pattern.pattern))
+	}
+
+	return &golang.UnitModule{
+		Name:    validationUnitName,
+		Imports: []string{"regexp"},
+		Structs: []*golang.StructDef{golang.GenStructForDataModel("ValidationError", []golang.NameWithType{
+			{Name: "fields", Type: &golang.GoType{Name: "map[string]string"}},
+		}, false, false, false)},
+		Functions: functions,
+		Variables: variables,
+	}
+}
+
+func validationCheckFunction(check validationCheck) *golang.FunctionDef {
+	text := check.text
+	if text == "" {
+		text = "text"
+	}
+	return &golang.FunctionDef{
+		Name:       check.name,
+		Parameters: append([]*golang.Parameter{{Name: "value", Type: golang.GoInterfaceType}}, check.params...),
+		Returns:    typeOnlyParamsCE("string"),
+		Imports:    check.imports,
+		Body: golang.CodeElements{
+			{NewAssign: &golang.NewAssignment{Left: []string{text, "ok"}, Right: "validationText(value)"}},
+			{If: &golang.IfElement{
+				Condition: check.condition,
+				Then:      golang.CodeElements{returnValuesCE(check.message)},
+			}},
+			returnValuesCE(`""`),
+		},
+	}
+}
+
+// Error lists the failed validations in attribute order, "validation failed: email must be a valid email address, sku is required"
+func validationErrorFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:     "Error",
+		Receiver: &golang.Receiver{Name: "e", Type: &golang.GoType{Name: "ValidationError"}},
+		Returns:  typeOnlyParamsCE("string"),
+		Imports:  []string{"sort", "strings"},
+		Body: golang.CodeElements{
+			{NewAssign: &golang.NewAssignment{Left: "attributes", Right: "make([]string, 0, len(e.Fields))"}},
+			{Iterate: &golang.IterateElement{
+				Variables: []string{"attribute"},
+				RangeOn:   &golang.CodeElement{Literal: "e.Fields"},
+				Body:      golang.CodeElements{{FunctionCall: appendCE("attributes", "attribute")}},
+			}},
+			{Literal: "sort.Strings(attributes)"},
+			{NewAssign: &golang.NewAssignment{Left: "messages", Right: "make([]string, 0, len(attributes))"}},
+			{Iterate: &golang.IterateElement{
+				Variables: []string{"_", "attribute"},
+				RangeOn:   &golang.CodeElement{Literal: "attributes"},
+				Body:      golang.CodeElements{{FunctionCall: appendCE("messages", `attribute+" "+e.Fields[attribute]`)}},
+			}},
+			returnValuesCE(`"validation failed: " + strings.Join(messages, ", ")`),
+		},
+	}
+}
+
+// validationResult returns the failed validations as a *ValidationError, nil when none failed
+func validationResultFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:       "validationResult",
+		Parameters: []*golang.Parameter{{Name: "failed", Type: &golang.GoType{Name: "map[string]string"}}},
+		Returns:    typeOnlyParamsCE("error"),
+		Body: golang.CodeElements{
+			{If: &golang.IfElement{
+				Condition: "len(failed) == 0",
+				Then:      golang.CodeElements{returnValuesCE("nil")},
+			}},
+			returnValuesCE("&ValidationError{Fields: failed}"),
+		},
+	}
+}
+
+// firstValidationMessage returns the message of the first failed validation of a field
+func firstValidationMessageFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:       "firstValidationMessage",
+		Parameters: []*golang.Parameter{{Name: "messages", Type: &golang.GoType{Name: "...string"}}},
+		Returns:    typeOnlyParamsCE("string"),
+		Body: golang.CodeElements{
+			{Iterate: &golang.IterateElement{
+				Variables: []string{"_", "message"},
+				RangeOn:   &golang.CodeElement{Literal: "messages"},
+				Body: golang.CodeElements{{If: &golang.IfElement{
+					Condition: `message != ""`,
+					Then:      golang.CodeElements{returnValuesCE("message")},
+				}}},
+			}},
+			returnValuesCE(`""`),
+		},
+	}
+}
+
+// validationText returns the text of a value, dereferencing pointers and reading the value of sql.Null types
+// (driver.Valuer), ok is false when the value is nil, NULL or ""
+func validationTextFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:       "validationText",
+		Parameters: []*golang.Parameter{{Name: "value", Type: golang.GoInterfaceType}},
+		Returns:    typeOnlyParamsCE("string", "bool"),
+		Imports:    []string{"database/sql/driver", "fmt", "reflect"},
+		Body: golang.CodeElements{
+			{If: &golang.IfElement{
+				Condition: "valuer, ok := value.(driver.Valuer); ok",
+				Then: golang.CodeElements{
+					{NewAssign: &golang.NewAssignment{Left: []string{"driverValue", "err"}, Right: "valuer.Value()"}},
+					{If: &golang.IfElement{
+						Condition: "err != nil || driverValue == nil",
+						Then:      golang.CodeElements{returnValuesCE(`""`, "false")},
+					}},
+					{Assign: &golang.Assignment{Left: "value", Right: "driverValue"}},
+				},
+			}},
+			{NewAssign: &golang.NewAssignment{Left: "reflected", Right: "reflect.ValueOf(value)"}},
+			{RepeatCond: &golang.RepeatByCondition{
+				Condition: &golang.CodeElement{Literal: "reflected.Kind() == reflect.Pointer || reflected.Kind() == reflect.Interface"},
+				Body: golang.CodeElements{
+					{If: &golang.IfElement{
+						Condition: "reflected.IsNil()",
+						Then:      golang.CodeElements{returnValuesCE(`""`, "false")},
+					}},
+					{Assign: &golang.Assignment{Left: "reflected", Right: "reflected.Elem()"}},
+				},
+			}},
+			{If: &golang.IfElement{
+				Condition: "!reflected.IsValid()",
+				Then:      golang.CodeElements{returnValuesCE(`""`, "false")},
+			}},
+			{NewAssign: &golang.NewAssignment{Left: "text", Right: "fmt.Sprint(reflected.Interface())"}},
+			returnValuesCE("text", `reflected.Kind() != reflect.String || text != ""`),
+		},
+	}
+}
+
+// validationNumber parses the text of a number, NaN when it is not a number
+func validationNumberFunction() *golang.FunctionDef {
+	return &golang.FunctionDef{
+		Name:       "validationNumber",
+		Parameters: []*golang.Parameter{{Name: "text", Type: golang.GoStringType}},
+		Returns:    typeOnlyParamsCE("float64"),
+		Imports:    []string{"math", "strconv"},
+		Body: golang.CodeElements{
+			{NewAssign: &golang.NewAssignment{Left: []string{"number", "err"}, Right: "strconv.ParseFloat(text, 64)"}},
+			{If: &golang.IfElement{
+				Condition: "err != nil",
+				Then:      golang.CodeElements{returnValuesCE("math.NaN()")},
+			}},
+			returnValuesCE("number"),
+		},
+	}
+}
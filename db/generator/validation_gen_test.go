@@ -0,0 +1,80 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/models"
+)
+
+func TestParamsValidateMethod(t *testing.T) {
+	config.LoadConfig()
+	catalogAttributes, catalogValidations := config.Attributes, config.Validations
+	defer func() { config.Attributes, config.Validations = catalogAttributes, catalogValidations }()
+	config.Validations = map[int64]models.Validation{
+		1: {UniqueID: models.UniqueID{ID: 1}, RuleName: "required"},
+		2: {UniqueID: models.UniqueID{ID: 2}, RuleName: "max_length", ValidationParams: `{"length": 64}`},
+		3: {UniqueID: models.UniqueID{ID: 3}, RuleName: "match_pattern", ValidationParams: `{"pattern": "^[A-Z]{3}-[0-9]+$"}`},
+		4: {UniqueID: models.UniqueID{ID: 4}, RuleName: "min_value", ValidationParams: `{}`},
+		5: {UniqueID: models.UniqueID{ID: 5}, RuleName: "max_file_size", ValidationParams: `{"size": 1024}`},
+		6: {UniqueID: models.UniqueID{ID: 6}, RuleName: "min_value", ValidationParams: `{"value": 0.5}`},
+	}
+	config.Attributes = map[int64]models.AttributeRow{
+		1: {UniqueID: models.UniqueID{ID: 1, Name: "sku"}, TypeId: 1000001, ValidationIds: []int64{1, 2, 3}},
+		2: {UniqueID: models.UniqueID{ID: 2, Name: "price"}, TypeId: 1000005, ValidationIds: []int64{4, 5, 6}},
+		3: {UniqueID: models.UniqueID{ID: 3, Name: "description"}, TypeId: 1000001},
+	}
+
+	model := &defs.Model{Name: "Product", Attributes: []int64{1, 2, 3}}
+	validations, err := readAttributeValidations("Product", model)
+	assert.NoError(t, err)
+	assert.Len(t, validations.patterns, 1)
+	assert.Equal(t, "var productSkuPattern *regexp.Regexp = regexp.MustCompile(`^[A-Z]{3}-[0-9]+$`)", validations.patterns[0].ToCode())
+
+	// Params are validated as the attribute they are inserted as, set to or compared to
+	fn := validations.ParamsValidateMethod(&defs.AccessConfig{
+		Name:   "UpdateProductPrice",
+		Set:    []defs.Update{{Attribute: "price", ParamName: "new_price"}},
+		Filter: []defs.Filter{{Attribute: "sku", Operator: "=", ParamName: "sku"}, {Attribute: "price", Operator: "BETWEEN", ParamName: "range"}},
+	})
+	code, imports := fn.FunctionCode()
+	assert.Equal(t, `func (params *UpdateProductPriceParams) Validate() error {
+	failed := make(map[string]string)
+	if message := validateMinValue(params.NewPrice, 0.5); message != "" {
+		failed["price"] = message
+	}
+	if message := firstValidationMessage(validateRequired(params.Sku), validateMaxLength(params.Sku, 64), validatePattern(params.Sku, productSkuPattern, "must match ^[A-Z]{3}-[0-9]+$")); message != "" {
+		failed["sku"] = message
+	}
+	return validationResult(failed)
+}`, code)
+	assert.Equal(t, map[string]bool{"regexp": true}, imports)
+
+	fn = validations.ParamsValidateMethod(&defs.AccessConfig{
+		Name:   "GetProductByDescription",
+		Filter: []defs.Filter{{Attribute: "description", Operator: "=", ParamName: "description"}},
+	})
+	code, _ = fn.FunctionCode()
+	assert.Equal(t, "func (params *GetProductByDescriptionParams) Validate() error {\n\treturn nil\n}", code)
+
+	// The key of a restore is required even when its attribute isn't, the validations of the model are left as they are
+	restore := &defs.AccessConfig{
+		Name:   "RestoreProduct",
+		Filter: []defs.Filter{{Attribute: "description", Operator: "=", ParamName: "description"}},
+	}
+	code, _ = restoreValidations(validations, restore).ParamsValidateMethod(restore).FunctionCode()
+	assert.Equal(t, `func (params *RestoreProductParams) Validate() error {
+	failed := make(map[string]string)
+	if message := validateRequired(params.Description); message != "" {
+		failed["description"] = message
+	}
+	return validationResult(failed)
+}`, code)
+	assert.Empty(t, validations.calls["description"])
+
+	config.Validations[2] = models.Validation{UniqueID: models.UniqueID{ID: 2}, RuleName: "max_length", ValidationParams: `{"length": "64"`}
+	_, err = readAttributeValidations("Product", model)
+	assert.EqualError(t, err, "model Product: attribute sku: validation max_length: invalid validation_params: unexpected end of JSON input")
+}
@@ -0,0 +1,299 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/models"
+)
+
+type Options struct {
+	FamilyName string
+	// Namespace of the models and new attributes, the snake cased family name when empty
+	Namespace string
+	// FirstAttributeID is the id of the first new attribute, 0 continues after the highest catalog attribute id
+	FirstAttributeID int64
+	// FirstModelID is the id of the model of the first table, 0 starts at 1
+	FirstModelID int
+}
+
+// Result is the catalog entries and data config of an imported schema, dsgen import writes it as one YAML document
+type Result struct {
+	// Types are the catalog types of the model attributes
+	Types []models.TypeInfo `yaml:"types"`
+	// Attributes are the attributes the catalog is missing, catalog attributes with the name and type of a column are reused
+	Attributes      []models.AttributeRow `yaml:"attributes"`
+	defs.DataConfig `yaml:",inline"`
+	// Warnings are lossy type mappings and keys that can't be part of a model
+	Warnings []string `yaml:"-"`
+}
+
+// postgresTypeAliases are the pg_dump and information_schema spellings of the types the type maps use
+var postgresTypeAliases = map[string]string{
+	"INT": "INTEGER", "INT4": "INTEGER", "INT2": "SMALLINT", "INT8": "BIGINT", "BOOL": "BOOLEAN",
+	"FLOAT4": "REAL", "FLOAT8": "DOUBLE PRECISION", "DECIMAL": "NUMERIC",
+	"VARCHAR": "CHARACTER VARYING", "CHAR": "CHARACTER", "BPCHAR": "CHARACTER",
+	"TIMESTAMPTZ": "TIMESTAMP WITH TIME ZONE", "TIMETZ": "TIME WITH TIME ZONE",
+	"TIMESTAMP": "TIMESTAMP WITHOUT TIME ZONE", "TIME": "TIME WITHOUT TIME ZONE",
+}
+
+// postgresTypeFallbacks are the closest mapped types of types without a type mapping, tried in order
+var postgresTypeFallbacks = map[string][]string{
+	"NUMERIC":                     {"DECIMAL"},
+	"REAL":                        {"DECIMAL"},
+	"DOUBLE PRECISION":            {"DECIMAL"},
+	"BIGINT":                      {"INTEGER"},
+	"CHARACTER VARYING":           {"TEXT"},
+	"CHARACTER":                   {"TEXT"},
+	"CITEXT":                      {"TEXT"},
+	"UUID":                        {"TEXT"},
+	"JSON":                        {"JSONB"},
+	"TIMESTAMP WITHOUT TIME ZONE": {"TIMESTAMP WITH TIME ZONE"},
+	"TIME WITHOUT TIME ZONE":      {"TIME WITH TIME ZONE"},
+}
+
+var typeModifierRegex = regexp.MustCompile(`\([^)]*\)`)
+
+// PostgresTypeId picks the type of a column by looking its Postgres type up in the type maps in reverse.
+// Types that only map after dropping their length or precision, or by falling back to a close type,
+// come with a note on what the import loses.
+func PostgresTypeId(columnType string) (int64, string, error) {
+	if typeId, ok := datahelpers.GetTypeIdForPostgresType(columnType); ok {
+		return typeId, "", nil
+	}
+	normalized := datahelpers.NormalizeDatabaseType(columnType)
+	baseType := strings.Join(strings.Fields(typeModifierRegex.ReplaceAllString(normalized, " ")), " ")
+	if alias, ok := postgresTypeAliases[baseType]; ok {
+		baseType = alias
+	}
+	if typeId, ok := datahelpers.GetTypeIdForPostgresType(baseType); ok {
+		if baseType != normalized && strings.Contains(normalized, "(") {
+			return typeId, fmt.Sprintf("%s imported as %s", columnType, baseType), nil
+		}
+		return typeId, "", nil
+	}
+	for _, fallback := range postgresTypeFallbacks[baseType] {
+		if typeId, ok := datahelpers.GetTypeIdForPostgresType(fallback); ok {
+			return typeId, fmt.Sprintf("%s imported as %s", columnType, fallback), nil
+		}
+	}
+	return 0, "", fmt.Errorf("no type maps to %s", columnType)
+}
+
+type attributeKey struct {
+	name   string
+	typeId int64
+}
+
+type importer struct {
+	options   Options
+	family    string
+	result    *Result
+	usedTypes map[int64]bool
+	// attributeIds of catalog and new attributes by column name and type
+	attributeIds    map[attributeKey]int64
+	nextAttributeID int64
+}
+
+// Import maps every table of schema to a model with an attribute per column, a unique constraint per
+// unique key and an index per index, plus a Get<Model>By<Columns> find access per unique key.
+// The system columns (id, version, updated_at) are generated for every model and are not imported.
+// Attributes and types are looked up in the loaded catalog (config.Attributes, config.PostgresTypeMaps).
+// Columns whose type doesn't map are reported together in the error.
+func Import(schema *Schema, options Options) (*Result, error) {
+	if options.FamilyName == "" {
+		return nil, fmt.Errorf("family name is required")
+	}
+	imp := &importer{
+		options:      options,
+		family:       golang.ToSnakeCase(options.FamilyName),
+		result:       &Result{DataConfig: defs.DataConfig{FamilyName: options.FamilyName}},
+		usedTypes:    map[int64]bool{},
+		attributeIds: map[attributeKey]int64{},
+	}
+	if imp.options.Namespace == "" {
+		imp.options.Namespace = imp.family
+	}
+
+	catalogIds := make([]int64, 0, len(config.Attributes))
+	for id := range config.Attributes {
+		catalogIds = append(catalogIds, id)
+	}
+	sort.Slice(catalogIds, func(i, j int) bool { return catalogIds[i] < catalogIds[j] })
+	for _, id := range catalogIds {
+		attribute := config.Attributes[id]
+		key := attributeKey{attribute.Name, attribute.TypeId}
+		if _, ok := imp.attributeIds[key]; !ok {
+			imp.attributeIds[key] = id
+		}
+		imp.nextAttributeID = id + 1
+	}
+	if options.FirstAttributeID != 0 {
+		imp.nextAttributeID = options.FirstAttributeID
+	}
+	modelID := options.FirstModelID
+	if modelID == 0 {
+		modelID = 1
+	}
+
+	errs := []error{}
+	for i, table := range schema.Tables {
+		modelConfig, err := imp.importTable(table, modelID+i)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		imp.result.Models = append(imp.result.Models, *modelConfig)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	for typeId := range imp.usedTypes {
+		typeInfo, ok := config.Types[typeId]
+		if !ok {
+			typeInfo = models.TypeInfo{UniqueID: models.UniqueID{ID: typeId}}
+		}
+		imp.result.Types = append(imp.result.Types, typeInfo)
+	}
+	sort.Slice(imp.result.Types, func(i, j int) bool { return imp.result.Types[i].ID < imp.result.Types[j].ID })
+	return imp.result, nil
+}
+
+func (imp *importer) warn(format string, args ...interface{}) {
+	imp.result.Warnings = append(imp.result.Warnings, fmt.Sprintf(format, args...))
+}
+
+// attribute returns the id of the attribute for a column, adding a new one when the catalog has none
+func (imp *importer) attribute(name string, typeId int64) int64 {
+	imp.usedTypes[typeId] = true
+	key := attributeKey{name, typeId}
+	if id, ok := imp.attributeIds[key]; ok {
+		return id
+	}
+	id := imp.nextAttributeID
+	imp.nextAttributeID++
+	imp.attributeIds[key] = id
+	imp.result.Attributes = append(imp.result.Attributes, models.AttributeRow{
+		UniqueID:      models.UniqueID{ID: id, Namespace: imp.options.Namespace, Family: imp.family, Name: name},
+		TypeId:        typeId,
+		ValidationIds: []int64{},
+	})
+	return id
+}
+
+func (imp *importer) importTable(table *Table, modelID int) (*defs.ModelConfig, error) {
+	modelName := golang.ToPascalCase(table.Name)
+	if golang.ToSnakeCase(modelName) != table.Name {
+		imp.warn("table %s: model %s is generated as table %s", table.Name, modelName, golang.ToSnakeCase(modelName))
+	}
+	modelConfig := &defs.ModelConfig{Model: defs.Model{
+		ID:         modelID,
+		Namespace:  imp.options.Namespace,
+		Family:     imp.family,
+		Name:       modelName,
+		Attributes: []int64{},
+	}}
+
+	systemColumns := map[string]bool{}
+	for _, column := range datahelpers.SystemColumns {
+		systemColumns[column.Name] = true
+	}
+	errs := []error{}
+	hasID := false
+	columnNames := []string{}
+	columnAttributes := map[string]int64{}
+	for _, column := range table.Columns {
+		if systemColumns[column.Name] {
+			hasID = hasID || column.Name == "id"
+			continue
+		}
+		typeId, note, err := PostgresTypeId(column.Type)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("table %s: column %s: %w", table.Name, column.Name, err))
+			continue
+		}
+		if note != "" {
+			imp.warn("table %s: column %s: %s", table.Name, column.Name, note)
+		}
+		if golang.ToSnakeCase(column.Name) != column.Name {
+			imp.warn("table %s: column %s is generated as column %s", table.Name, column.Name, golang.ToSnakeCase(column.Name))
+		}
+		attributeId := imp.attribute(column.Name, typeId)
+		modelConfig.Model.Attributes = append(modelConfig.Model.Attributes, attributeId)
+		columnNames = append(columnNames, column.Name)
+		columnAttributes[column.Name] = attributeId
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if !hasID {
+		imp.warn("table %s: has no id column, models are keyed by the system column id", table.Name)
+	}
+
+	// keyAttributes is false when a key column is a system column, those keys are part of every model
+	keyAttributes := func(key Key) ([]int64, bool) {
+		attributeIds := []int64{}
+		for _, column := range key.Columns {
+			attributeId, ok := columnAttributes[column]
+			if !ok {
+				return nil, false
+			}
+			attributeIds = append(attributeIds, attributeId)
+		}
+		return attributeIds, true
+	}
+
+	uniques := table.Uniques
+	if len(table.PrimaryKey) > 0 && !(len(table.PrimaryKey) == 1 && table.PrimaryKey[0] == "id") {
+		imp.warn("table %s: primary key (%s) is imported as a unique constraint", table.Name, strings.Join(table.PrimaryKey, ", "))
+		uniques = append([]Key{{Name: table.Name + "_pkey", Columns: table.PrimaryKey}}, uniques...)
+	}
+	uniqueColumns := map[string]bool{}
+	for _, key := range uniques {
+		attributeIds, ok := keyAttributes(key)
+		if !ok {
+			if !(len(key.Columns) == 1 && key.Columns[0] == "id") {
+				imp.warn("table %s: unique key %s over system columns is skipped", table.Name, key.Name)
+			}
+			continue
+		}
+		if uniqueColumns[strings.Join(key.Columns, ",")] {
+			continue
+		}
+		uniqueColumns[strings.Join(key.Columns, ",")] = true
+		modelConfig.Model.UniqueConstraints = append(modelConfig.Model.UniqueConstraints, defs.UniqueConstraint{
+			ConstraintName: key.Name,
+			Attributes:     attributeIds,
+		})
+		modelConfig.Access.Find = append(modelConfig.Access.Find, findByKeyAccess(modelName, columnNames, key))
+	}
+	for _, key := range table.Indexes {
+		attributeIds, ok := keyAttributes(key)
+		if !ok {
+			imp.warn("table %s: index %s over system columns is skipped", table.Name, key.Name)
+			continue
+		}
+		modelConfig.Model.Indexes = append(modelConfig.Model.Indexes, defs.ModelIndex{IndexName: key.Name, Attributes: attributeIds})
+	}
+	return modelConfig, nil
+}
+
+// findByKeyAccess reads all columns of the row with the given unique key values
+func findByKeyAccess(modelName string, columnNames []string, key Key) defs.AccessConfig {
+	accessConfig := defs.AccessConfig{
+		Name:       "Get" + modelName + "By" + strings.Join(golang.ToPascalCaseArray(key.Columns), "And"),
+		Attributes: append([]string{"id"}, columnNames...),
+	}
+	for _, column := range key.Columns {
+		accessConfig.Filter = append(accessConfig.Filter, defs.Filter{Attribute: column, Operator: "=", ParamName: column})
+	}
+	return accessConfig
+}
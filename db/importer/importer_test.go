@@ -0,0 +1,235 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/models"
+)
+
+const pgDumpDDL = `--
+-- PostgreSQL database dump
+--
+SET statement_timeout = 0;
+SELECT pg_catalog.set_config('search_path', '', false);
+
+CREATE FUNCTION public.touch() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+  NEW.updated_at = now(); -- keep in sync
+  RETURN NEW;
+END;
+$$;
+
+CREATE TABLE public.customer (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    email character varying(255) NOT NULL,
+    name text,
+    "loyaltyPoints" integer DEFAULT 0,
+    created_at timestamp with time zone DEFAULT now(),
+    CONSTRAINT customer_points_check CHECK (("loyaltyPoints" >= 0))
+);
+
+CREATE TABLE public.order_line (
+    order_no text NOT NULL,
+    line_no smallint NOT NULL,
+    price numeric(10,2),
+    location public.geography(Point,4326),
+    note text UNIQUE,
+    PRIMARY KEY (order_no, line_no)
+);
+
+ALTER TABLE ONLY public.customer
+    ADD CONSTRAINT customer_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.customer
+    ADD CONSTRAINT customer_email_key UNIQUE (email);
+CREATE INDEX customer_name_idx ON public.customer USING btree (name);
+CREATE INDEX customer_lower_email_idx ON public.customer USING btree (lower((email)::text));
+CREATE INDEX customer_recent_idx ON public.customer USING btree (created_at) WHERE (created_at > '2020-01-01');
+`
+
+func TestParseDDL(t *testing.T) {
+	schema, err := ParseDDL(pgDumpDDL)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Table{
+		{
+			Name: "customer",
+			Columns: []Column{
+				{Name: "id", Type: "uuid"},
+				{Name: "email", Type: "character varying(255)"},
+				{Name: "name", Type: "text"},
+				{Name: "loyaltyPoints", Type: "integer"},
+				{Name: "created_at", Type: "timestamp with time zone"},
+			},
+			PrimaryKey: []string{"id"},
+			Uniques:    []Key{{Name: "customer_email_key", Columns: []string{"email"}}},
+			Indexes:    []Key{{Name: "customer_name_idx", Columns: []string{"name"}}},
+		},
+		{
+			Name: "order_line",
+			Columns: []Column{
+				{Name: "order_no", Type: "text"},
+				{Name: "line_no", Type: "smallint"},
+				{Name: "price", Type: "numeric(10,2)"},
+				{Name: "location", Type: "geography(Point,4326)"},
+				{Name: "note", Type: "text"},
+			},
+			PrimaryKey: []string{"order_no", "line_no"},
+			Uniques:    []Key{{Name: "order_line_note_key", Columns: []string{"note"}}},
+		},
+	}, schema.Tables)
+
+	_, err = ParseDDL("CREATE INDEX missing_idx ON public.missing (id);")
+	assert.EqualError(t, err, "CREATE INDEX missing_idx: table missing is not defined")
+}
+
+func TestParseInformationSchema(t *testing.T) {
+	schema, err := ParseInformationSchema([]byte(`{
+		"columns": [
+			{"table_schema": "public", "table_name": "customer", "column_name": "email", "ordinal_position": 2,
+			 "data_type": "character varying", "udt_name": "varchar", "character_maximum_length": 255},
+			{"table_schema": "public", "table_name": "customer", "column_name": "id", "ordinal_position": 1,
+			 "data_type": "uuid", "udt_name": "uuid"},
+			{"table_schema": "public", "table_name": "customer", "column_name": "tags", "ordinal_position": 3,
+			 "data_type": "ARRAY", "udt_name": "_text"},
+			{"table_schema": "public", "table_name": "customer", "column_name": "balance", "ordinal_position": 4,
+			 "data_type": "numeric", "udt_name": "numeric", "numeric_precision": 33, "numeric_scale": 18}
+		],
+		"key_columns": [
+			{"table_schema": "public", "table_name": "customer", "constraint_name": "customer_pkey",
+			 "constraint_type": "PRIMARY KEY", "column_name": "id", "ordinal_position": 1},
+			{"table_schema": "public", "table_name": "customer", "constraint_name": "customer_email_key",
+			 "constraint_type": "UNIQUE", "column_name": "email", "ordinal_position": 1}
+		]
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []*Table{{
+		Name: "customer",
+		Columns: []Column{
+			{Name: "id", Type: "uuid"},
+			{Name: "email", Type: "character varying(255)"},
+			{Name: "tags", Type: "text[]"},
+			{Name: "balance", Type: "numeric(33,18)"},
+		},
+		PrimaryKey: []string{"id"},
+		Uniques:    []Key{{Name: "customer_email_key", Columns: []string{"email"}}},
+	}}, schema.Tables)
+}
+
+func TestPostgresTypeId(t *testing.T) {
+	config.LoadConfig()
+
+	testCases := []struct {
+		columnType string
+		typeId     int64
+		note       string
+	}{
+		{"text", 1000001, ""},
+		{"integer", 1000004, ""},
+		{"int4", 1000004, ""},
+		{"numeric(33, 18)", 1000005, ""},
+		{"GEOGRAPHY(Point,4326)", 1000025, ""},
+		{"timestamptz", 1000019, ""},
+		{"numeric(10,2)", 1000003, "numeric(10,2) imported as DECIMAL"},
+		{"character varying(255)", 1000001, "character varying(255) imported as TEXT"},
+		{"bigint", 1000004, "bigint imported as INTEGER"},
+		{"timestamp(3) with time zone", 1000019, "timestamp(3) with time zone imported as TIMESTAMP WITH TIME ZONE"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.columnType, func(t *testing.T) {
+			typeId, note, err := PostgresTypeId(tc.columnType)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.typeId, typeId)
+			assert.Equal(t, tc.note, note)
+		})
+	}
+
+	_, _, err := PostgresTypeId("text[]")
+	assert.EqualError(t, err, "no type maps to text[]")
+}
+
+func TestImport(t *testing.T) {
+	config.LoadConfig()
+	catalogAttributes := config.Attributes
+	defer func() { config.Attributes = catalogAttributes }()
+	config.Attributes = map[int64]models.AttributeRow{
+		2000007: {UniqueID: models.UniqueID{ID: 2000007, Name: "email"}, TypeId: 1000001},
+		2000008: {UniqueID: models.UniqueID{ID: 2000008, Name: "name"}, TypeId: 1000004},
+	}
+
+	schema, err := ParseDDL(pgDumpDDL)
+	assert.NoError(t, err)
+	result, err := Import(schema, Options{FamilyName: "LegacyShop", FirstModelID: 3000101})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int64{1000001, 1000003, 1000004, 1000014, 1000019, 1000025}, typeIds(result.Types))
+	assert.Equal(t, "text", result.Types[0].Name)
+	// email is reused from the catalog, name is a new attribute because its type differs
+	assert.Equal(t, []models.AttributeRow{
+		{UniqueID: models.UniqueID{ID: 2000009, Namespace: "legacy_shop", Family: "legacy_shop", Name: "name"}, TypeId: 1000001, ValidationIds: []int64{}},
+		{UniqueID: models.UniqueID{ID: 2000010, Namespace: "legacy_shop", Family: "legacy_shop", Name: "loyaltyPoints"}, TypeId: 1000004, ValidationIds: []int64{}},
+		{UniqueID: models.UniqueID{ID: 2000011, Namespace: "legacy_shop", Family: "legacy_shop", Name: "created_at"}, TypeId: 1000019, ValidationIds: []int64{}},
+		{UniqueID: models.UniqueID{ID: 2000012, Namespace: "legacy_shop", Family: "legacy_shop", Name: "order_no"}, TypeId: 1000001, ValidationIds: []int64{}},
+		{UniqueID: models.UniqueID{ID: 2000013, Namespace: "legacy_shop", Family: "legacy_shop", Name: "line_no"}, TypeId: 1000014, ValidationIds: []int64{}},
+		{UniqueID: models.UniqueID{ID: 2000014, Namespace: "legacy_shop", Family: "legacy_shop", Name: "price"}, TypeId: 1000003, ValidationIds: []int64{}},
+		{UniqueID: models.UniqueID{ID: 2000015, Namespace: "legacy_shop", Family: "legacy_shop", Name: "location"}, TypeId: 1000025, ValidationIds: []int64{}},
+		{UniqueID: models.UniqueID{ID: 2000016, Namespace: "legacy_shop", Family: "legacy_shop", Name: "note"}, TypeId: 1000001, ValidationIds: []int64{}},
+	}, result.Attributes)
+
+	assert.Equal(t, "LegacyShop", result.FamilyName)
+	assert.Len(t, result.Models, 2)
+	customer := result.Models[0]
+	assert.Equal(t, defs.Model{
+		ID: 3000101, Namespace: "legacy_shop", Family: "legacy_shop", Name: "Customer",
+		Attributes:        []int64{2000007, 2000009, 2000010, 2000011},
+		UniqueConstraints: []defs.UniqueConstraint{{ConstraintName: "customer_email_key", Attributes: []int64{2000007}}},
+		Indexes:           []defs.ModelIndex{{IndexName: "customer_name_idx", Attributes: []int64{2000009}}},
+	}, customer.Model)
+	assert.Equal(t, []defs.AccessConfig{{
+		Name:       "GetCustomerByEmail",
+		Attributes: []string{"id", "email", "name", "loyaltyPoints", "created_at"},
+		Filter:     []defs.Filter{{Attribute: "email", Operator: "=", ParamName: "email"}},
+	}}, customer.Access.Find)
+
+	orderLine := result.Models[1]
+	assert.Equal(t, "OrderLine", orderLine.Model.Name)
+	assert.Equal(t, 3000102, orderLine.Model.ID)
+	assert.Equal(t, []defs.UniqueConstraint{
+		{ConstraintName: "order_line_pkey", Attributes: []int64{2000012, 2000013}},
+		{ConstraintName: "order_line_note_key", Attributes: []int64{2000016}},
+	}, orderLine.Model.UniqueConstraints)
+	assert.Equal(t, []string{"GetOrderLineByOrderNoAndLineNo", "GetOrderLineByNote"},
+		[]string{orderLine.Access.Find[0].Name, orderLine.Access.Find[1].Name})
+
+	assert.Equal(t, []string{
+		"table customer: column email: character varying(255) imported as TEXT",
+		"table customer: column loyaltyPoints is generated as column loyalty_points",
+		"table order_line: column price: numeric(10,2) imported as DECIMAL",
+		"table order_line: has no id column, models are keyed by the system column id",
+		"table order_line: primary key (order_no, line_no) is imported as a unique constraint",
+	}, result.Warnings)
+
+	// The imported config is valid once the new attributes are in the catalog
+	for _, attribute := range result.Attributes {
+		config.Attributes[attribute.ID] = attribute
+	}
+	result.DatabaseConfig = &defs.DatabaseConfig{DriverName: "postgres", UserName: "u", Password: "p", Host: "h", Port: 5432, DBName: "legacy",
+		ConnectionConfig: &defs.ConnectionConfig{}, ConnectionPoolConfig: &defs.ConnectionPoolConfig{}}
+	assert.NoError(t, generator.ValidateDataConfig(&result.DataConfig))
+
+	_, err = Import(&Schema{Tables: []*Table{{Name: "t", Columns: []Column{{Name: "tags", Type: "text[]"}, {Name: "doc", Type: "xml"}}}}},
+		Options{FamilyName: "LegacyShop"})
+	assert.EqualError(t, err, "table t: column tags: no type maps to text[]\ntable t: column doc: no type maps to xml")
+}
+
+func typeIds(types []models.TypeInfo) []int64 {
+	ids := []int64{}
+	for _, typeInfo := range types {
+		ids = append(ids, typeInfo.ID)
+	}
+	return ids
+}
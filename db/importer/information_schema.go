@@ -0,0 +1,136 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// InformationSchemaQuery exports the snapshot ParseInformationSchema reads for the tables of the public schema:
+//
+//	psql -XAt -d ecommerce -c "$(dsgen import -print-query)" > ecommerce.information_schema.json
+const InformationSchemaQuery = `SELECT json_build_object(
+  'columns', (SELECT coalesce(json_agg(c ORDER BY c.table_name, c.ordinal_position), '[]') FROM (
+    SELECT col.table_schema, col.table_name, col.column_name, col.ordinal_position, col.data_type, col.udt_name,
+           col.character_maximum_length, col.numeric_precision, col.numeric_scale
+    FROM information_schema.columns col
+    JOIN information_schema.tables t ON t.table_schema = col.table_schema AND t.table_name = col.table_name
+    WHERE col.table_schema = 'public' AND t.table_type = 'BASE TABLE') c),
+  'key_columns', (SELECT coalesce(json_agg(k ORDER BY k.table_name, k.constraint_name, k.ordinal_position), '[]') FROM (
+    SELECT tc.table_schema, tc.table_name, tc.constraint_name, tc.constraint_type, kcu.column_name, kcu.ordinal_position
+    FROM information_schema.table_constraints tc
+    JOIN information_schema.key_column_usage kcu
+      ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+    WHERE tc.table_schema = 'public' AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE')) k)
+);`
+
+// InformationSchemaSnapshot is the JSON InformationSchemaQuery exports
+type InformationSchemaSnapshot struct {
+	Columns    []InformationSchemaColumn    `json:"columns"`
+	KeyColumns []InformationSchemaKeyColumn `json:"key_columns"`
+}
+
+// InformationSchemaColumn is a row of information_schema.columns
+type InformationSchemaColumn struct {
+	TableSchema            string `json:"table_schema"`
+	TableName              string `json:"table_name"`
+	ColumnName             string `json:"column_name"`
+	OrdinalPosition        int    `json:"ordinal_position"`
+	DataType               string `json:"data_type"`
+	UDTName                string `json:"udt_name"`
+	CharacterMaximumLength *int   `json:"character_maximum_length"`
+	NumericPrecision       *int   `json:"numeric_precision"`
+	NumericScale           *int   `json:"numeric_scale"`
+}
+
+// InformationSchemaKeyColumn is a column of a PRIMARY KEY or UNIQUE constraint,
+// information_schema.table_constraints joined with key_column_usage
+type InformationSchemaKeyColumn struct {
+	TableSchema     string `json:"table_schema"`
+	TableName       string `json:"table_name"`
+	ConstraintName  string `json:"constraint_name"`
+	ConstraintType  string `json:"constraint_type"`
+	ColumnName      string `json:"column_name"`
+	OrdinalPosition int    `json:"ordinal_position"`
+}
+
+// ParseInformationSchema reads the tables, primary keys and unique constraints of an information_schema
+// snapshot (see InformationSchemaQuery). information_schema has no indexes, only unique constraints are imported.
+func ParseInformationSchema(data []byte) (*Schema, error) {
+	snapshot := InformationSchemaSnapshot{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("reading information_schema snapshot: %w", err)
+	}
+
+	columns := append([]InformationSchemaColumn{}, snapshot.Columns...)
+	sort.SliceStable(columns, func(i, j int) bool {
+		if columns[i].TableName != columns[j].TableName {
+			return columns[i].TableName < columns[j].TableName
+		}
+		return columns[i].OrdinalPosition < columns[j].OrdinalPosition
+	})
+	schema := &Schema{}
+	for _, column := range columns {
+		table := schema.Table(column.TableName)
+		if table == nil {
+			table = &Table{Name: column.TableName}
+			schema.Tables = append(schema.Tables, table)
+		}
+		table.Columns = append(table.Columns, Column{Name: column.ColumnName, Type: column.columnType()})
+	}
+
+	keyColumns := append([]InformationSchemaKeyColumn{}, snapshot.KeyColumns...)
+	sort.SliceStable(keyColumns, func(i, j int) bool {
+		if keyColumns[i].TableName != keyColumns[j].TableName {
+			return keyColumns[i].TableName < keyColumns[j].TableName
+		}
+		if keyColumns[i].ConstraintName != keyColumns[j].ConstraintName {
+			return keyColumns[i].ConstraintName < keyColumns[j].ConstraintName
+		}
+		return keyColumns[i].OrdinalPosition < keyColumns[j].OrdinalPosition
+	})
+	errs := []error{}
+	for i := 0; i < len(keyColumns); {
+		key := keyColumns[i]
+		keyColumnNames := []string{}
+		for ; i < len(keyColumns) && keyColumns[i].TableName == key.TableName && keyColumns[i].ConstraintName == key.ConstraintName; i++ {
+			keyColumnNames = append(keyColumnNames, keyColumns[i].ColumnName)
+		}
+		table := schema.Table(key.TableName)
+		if table == nil {
+			errs = append(errs, fmt.Errorf("constraint %s: table %s has no columns", key.ConstraintName, key.TableName))
+			continue
+		}
+		switch key.ConstraintType {
+		case "PRIMARY KEY":
+			table.PrimaryKey = keyColumnNames
+		case "UNIQUE":
+			table.addUnique(key.ConstraintName, keyColumnNames)
+		default:
+			errs = append(errs, fmt.Errorf("constraint %s: unsupported constraint_type %q", key.ConstraintName, key.ConstraintType))
+		}
+	}
+	return schema, errors.Join(errs...)
+}
+
+// columnType spells the type of a column the way pg_dump does, "character varying(255)", "numeric(10,2)", "text[]"
+func (c *InformationSchemaColumn) columnType() string {
+	switch c.DataType {
+	case "USER-DEFINED":
+		return c.UDTName
+	case "ARRAY":
+		// Array udt names are the element udt name prefixed with an underscore, _text is text[]
+		return strings.TrimPrefix(c.UDTName, "_") + "[]"
+	case "character varying", "character":
+		if c.CharacterMaximumLength != nil {
+			return fmt.Sprintf("%s(%d)", c.DataType, *c.CharacterMaximumLength)
+		}
+	case "numeric":
+		if c.NumericPrecision != nil && c.NumericScale != nil {
+			return fmt.Sprintf("numeric(%d,%d)", *c.NumericPrecision, *c.NumericScale)
+		}
+	}
+	return c.DataType
+}
@@ -0,0 +1,391 @@
+// Package importer reverse-engineers a data config from an existing Postgres schema, read either
+// from pg_dump DDL (ParseDDL) or from an information_schema snapshot (ParseInformationSchema).
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Schema is the part of a database schema that maps to models: tables with their columns and keys
+type Schema struct {
+	Tables []*Table
+}
+
+type Table struct {
+	Name       string
+	Columns    []Column
+	PrimaryKey []string
+	Uniques    []Key
+	Indexes    []Key
+}
+
+type Column struct {
+	Name string
+	// Type as the database spells it, e.g. "character varying(255)"
+	Type string
+}
+
+// Key is a unique constraint or a (non unique) index over columns
+type Key struct {
+	Name    string
+	Columns []string
+}
+
+// Table returns the table with the given name, nil if the schema has none
+func (s *Schema) Table(name string) *Table {
+	for _, table := range s.Tables {
+		if table.Name == name {
+			return table
+		}
+	}
+	return nil
+}
+
+func (t *Table) addUnique(name string, columns []string) {
+	if name == "" {
+		// Postgres names unnamed unique constraints <table>_<columns>_key
+		name = t.Name + "_" + strings.Join(columns, "_") + "_key"
+	}
+	t.Uniques = append(t.Uniques, Key{Name: name, Columns: columns})
+}
+
+var (
+	createTableRegex     = regexp.MustCompile(`(?is)^CREATE\s+(?:(?:GLOBAL|LOCAL)\s+)?(?:(?:TEMP|TEMPORARY|UNLOGGED)\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?((?:"[^"]+"|[^\s(])+)\s*\(`)
+	alterTableKeyRegex   = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?((?:"[^"]+"|\S)+)\s+ADD\s+CONSTRAINT\s+((?:"[^"]+"|\S)+)\s+(UNIQUE|PRIMARY\s+KEY)\b`)
+	createIndexRegex     = regexp.MustCompile(`(?is)^CREATE\s+(UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(?:((?:"[^"]+"|\S)+)\s+)?ON\s+(?:ONLY\s+)?((?:"[^"]+"|[^\s(])+)(?:\s+USING\s+\w+)?\s*\(`)
+	namedConstraintRegex = regexp.MustCompile(`(?is)^CONSTRAINT\s+((?:"[^"]+"|\S)+)\s+(.*)$`)
+	primaryKeyRegex      = regexp.MustCompile(`(?i)^PRIMARY\s+KEY\b`)
+	partialIndexRegex    = regexp.MustCompile(`(?i)\bWHERE\b`)
+	identifierRegex      = regexp.MustCompile(`^(?:"[^"]+"|[A-Za-z_][A-Za-z0-9_$]*)$`)
+	typeSchemaRegex      = regexp.MustCompile(`^(?:"[^"]+"|[A-Za-z_][A-Za-z0-9_]*)\.`)
+)
+
+// Column constraints end the type of a column definition
+var columnConstraintKeywords = map[string]bool{
+	"NOT": true, "NULL": true, "DEFAULT": true, "PRIMARY": true, "UNIQUE": true, "CHECK": true,
+	"REFERENCES": true, "CONSTRAINT": true, "COLLATE": true, "GENERATED": true,
+}
+
+// ParseDDL reads the tables, primary keys, unique constraints and indexes of pg_dump schema output
+// (pg_dump --schema-only). Statements other than CREATE TABLE, ALTER TABLE ... ADD CONSTRAINT and
+// CREATE INDEX are skipped, as are expression and partial indexes, which have no model counterpart.
+// Names are unqualified, the importer maps one schema at a time.
+func ParseDDL(ddl string) (*Schema, error) {
+	schema := &Schema{}
+	errs := []error{}
+	for _, statement := range splitStatements(ddl) {
+		if err := schema.parseStatement(statement); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return schema, errors.Join(errs...)
+}
+
+func (s *Schema) parseStatement(statement string) error {
+	if match := createTableRegex.FindStringSubmatchIndex(statement); match != nil {
+		name := identifier(statement[match[2]:match[3]])
+		body, ok := parenthesized(statement, match[1]-1)
+		if !ok {
+			return fmt.Errorf("CREATE TABLE %s: unbalanced parentheses", name)
+		}
+		if s.Table(name) != nil {
+			return fmt.Errorf("CREATE TABLE %s: table is defined more than once", name)
+		}
+		table := &Table{Name: name}
+		s.Tables = append(s.Tables, table)
+		return table.parseDefinitions(body)
+	}
+
+	if match := alterTableKeyRegex.FindStringSubmatchIndex(statement); match != nil {
+		name := identifier(statement[match[2]:match[3]])
+		table := s.Table(name)
+		if table == nil {
+			return fmt.Errorf("ALTER TABLE %s: table is not defined", name)
+		}
+		return table.parseTableConstraint(identifier(statement[match[4]:match[5]]), statement[match[6]:])
+	}
+
+	if match := createIndexRegex.FindStringSubmatchIndex(statement); match != nil {
+		name := ""
+		if match[4] >= 0 {
+			name = identifier(statement[match[4]:match[5]])
+		}
+		tableName := identifier(statement[match[6]:match[7]])
+		table := s.Table(tableName)
+		if table == nil {
+			return fmt.Errorf("CREATE INDEX %s: table %s is not defined", name, tableName)
+		}
+		body, ok := parenthesized(statement, match[1]-1)
+		if !ok {
+			return fmt.Errorf("CREATE INDEX %s: unbalanced parentheses", name)
+		}
+		columns, ok := keyColumns(body)
+		if !ok || partialIndexRegex.MatchString(statement[match[1]+len(body):]) {
+			return nil
+		}
+		if name == "" {
+			name = tableName + "_" + strings.Join(columns, "_") + "_idx"
+		}
+		if match[2] >= 0 {
+			table.addUnique(name, columns)
+		} else {
+			table.Indexes = append(table.Indexes, Key{Name: name, Columns: columns})
+		}
+	}
+	return nil
+}
+
+// parseDefinitions reads the column and table constraint definitions of CREATE TABLE
+func (t *Table) parseDefinitions(body string) error {
+	errs := []error{}
+	for _, definition := range splitTopLevel(body, ',') {
+		words := strings.Fields(definition)
+		if len(words) == 0 {
+			continue
+		}
+		switch strings.ToUpper(words[0]) {
+		case "CONSTRAINT":
+			match := namedConstraintRegex.FindStringSubmatch(definition)
+			if match == nil {
+				errs = append(errs, fmt.Errorf("table %s: incomplete constraint %q", t.Name, definition))
+				continue
+			}
+			if err := t.parseTableConstraint(identifier(match[1]), match[2]); err != nil {
+				errs = append(errs, err)
+			}
+		case "PRIMARY", "UNIQUE", "CHECK", "FOREIGN", "EXCLUDE", "LIKE":
+			if err := t.parseTableConstraint("", definition); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			if err := t.parseColumn(definition); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *Table) parseColumn(definition string) error {
+	words := splitTopLevel(definition, ' ')
+	column := Column{Name: identifier(words[0])}
+	typeWords := []string{}
+	constraints := []string{}
+	for i, word := range words[1:] {
+		if columnConstraintKeywords[strings.ToUpper(word)] {
+			constraints = words[i+1:]
+			break
+		}
+		typeWords = append(typeWords, word)
+	}
+	if len(typeWords) == 0 {
+		return fmt.Errorf("table %s: column %s has no type", t.Name, column.Name)
+	}
+	column.Type = unqualifiedType(strings.Join(typeWords, " "))
+	t.Columns = append(t.Columns, column)
+
+	constraintName := ""
+	for i, word := range constraints {
+		switch strings.ToUpper(word) {
+		case "CONSTRAINT":
+			if i+1 < len(constraints) {
+				constraintName = identifier(constraints[i+1])
+			}
+		case "UNIQUE":
+			t.addUnique(constraintName, []string{column.Name})
+			constraintName = ""
+		case "PRIMARY":
+			t.PrimaryKey = []string{column.Name}
+			constraintName = ""
+		}
+	}
+	return nil
+}
+
+// parseTableConstraint reads a PRIMARY KEY or UNIQUE table constraint, other constraints are skipped
+func (t *Table) parseTableConstraint(name, definition string) error {
+	isPrimaryKey := primaryKeyRegex.MatchString(definition)
+	if !isPrimaryKey && !strings.HasPrefix(strings.ToUpper(definition), "UNIQUE") {
+		return nil
+	}
+	open := strings.Index(definition, "(")
+	if open < 0 {
+		return fmt.Errorf("table %s: constraint %q has no columns", t.Name, definition)
+	}
+	body, ok := parenthesized(definition, open)
+	if !ok {
+		return fmt.Errorf("table %s: constraint %q has unbalanced parentheses", t.Name, definition)
+	}
+	columns, ok := keyColumns(body)
+	if !ok {
+		return fmt.Errorf("table %s: constraint %q is not over plain columns", t.Name, definition)
+	}
+	if isPrimaryKey {
+		t.PrimaryKey = columns
+	} else {
+		t.addUnique(name, columns)
+	}
+	return nil
+}
+
+// keyColumns reads the column list of a key, false when an entry is an expression
+func keyColumns(body string) ([]string, bool) {
+	columns := []string{}
+	for _, entry := range splitTopLevel(body, ',') {
+		// Drop sort order, NULLS FIRST/LAST and operator classes, "name DESC" indexes column name
+		words := splitTopLevel(entry, ' ')
+		if len(words) == 0 || !identifierRegex.MatchString(words[0]) {
+			return nil, false
+		}
+		columns = append(columns, identifier(words[0]))
+	}
+	return columns, len(columns) > 0
+}
+
+// identifier unquotes a possibly schema qualified name and drops the schema,
+// unquoted names are folded to lower case like Postgres does
+func identifier(name string) string {
+	parts := []string{}
+	for len(name) > 0 {
+		var part string
+		if name[0] == '"' {
+			end := strings.Index(name[1:], `"`)
+			if end < 0 {
+				part, name = name[1:], ""
+			} else {
+				part, name = name[1:end+1], name[end+2:]
+			}
+		} else {
+			end := strings.Index(name, ".")
+			if end < 0 {
+				end = len(name)
+			}
+			part, name = strings.ToLower(name[:end]), name[end:]
+		}
+		parts = append(parts, part)
+		name = strings.TrimPrefix(name, ".")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return parts[len(parts)-1]
+}
+
+// unqualifiedType drops the schema of types pg_dump qualifies, "public.geography(Point,4326)" is "geography(Point,4326)"
+func unqualifiedType(columnType string) string {
+	return typeSchemaRegex.ReplaceAllString(columnType, "")
+}
+
+// parenthesized returns what is between the parenthesis at open and its match
+func parenthesized(s string, open int) (string, bool) {
+	depth := 0
+	inQuote := byte(0)
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inQuote != 0:
+			if c == inQuote {
+				inQuote = 0
+			}
+		case c == '\'' || c == '"':
+			inQuote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return s[open+1 : i], true
+			}
+		}
+	}
+	return "", false
+}
+
+// splitTopLevel splits on sep outside of parentheses and quotes, dropping empty parts
+func splitTopLevel(s string, sep byte) []string {
+	parts := []string{}
+	depth := 0
+	inQuote := byte(0)
+	start := 0
+	flush := func(end int) {
+		if part := strings.TrimSpace(s[start:end]); part != "" {
+			parts = append(parts, part)
+		}
+		start = end + 1
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inQuote != 0:
+			if c == inQuote {
+				inQuote = 0
+			}
+		case c == '\'' || c == '"':
+			inQuote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && (c == sep || sep == ' ' && (c == '\t' || c == '\n' || c == '\r')):
+			flush(i)
+		}
+	}
+	flush(len(s))
+	return parts
+}
+
+var dollarQuoteRegex = regexp.MustCompile(`^\$[A-Za-z_0-9]*\$`)
+
+// splitStatements splits SQL on semicolons, skipping comments, quoted strings and
+// dollar quoted function bodies. Whitespace in every statement is collapsed to single spaces.
+func splitStatements(sql string) []string {
+	statements := []string{}
+	var sb strings.Builder
+	flush := func() {
+		if statement := strings.Join(strings.Fields(sb.String()), " "); statement != "" {
+			statements = append(statements, statement)
+		}
+		sb.Reset()
+	}
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		// stop is where a comment or quoted part ends, the end of sql when it isn't closed
+		stop := len(sql)
+		switch {
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+				stop = i + end
+			}
+			sb.WriteByte('\n')
+			i = stop
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+				stop = i + 2 + end + 2
+			}
+			sb.WriteByte(' ')
+			i = stop - 1
+		case c == '\'' || c == '"':
+			if end := strings.IndexByte(sql[i+1:], c); end >= 0 {
+				stop = i + 1 + end + 1
+			}
+			sb.WriteString(sql[i:stop])
+			i = stop - 1
+		case c == '$' && dollarQuoteRegex.MatchString(sql[i:]):
+			tag := dollarQuoteRegex.FindString(sql[i:])
+			if end := strings.Index(sql[i+len(tag):], tag); end >= 0 {
+				stop = i + len(tag) + end + len(tag)
+			}
+			sb.WriteString(sql[i:stop])
+			i = stop - 1
+		case c == ';':
+			flush()
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
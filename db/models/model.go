@@ -1,5 +1,7 @@
 package models
 
+import "fmt"
+
 type UniqueID struct {
 	ID        int64  `yaml:"id"`
 	Namespace string `yaml:"namespace"`
@@ -8,25 +10,31 @@ type UniqueID struct {
 }
 
 type TypeInfo struct {
-	UniqueID
+	UniqueID    `yaml:",inline"`
 	ElementType string `yaml:"element_type"`
 	WidgetType  string `yaml:"widget_type"`
 }
 
 type Validation struct {
-	UniqueID
-	RuleName string   `yaml:"rule_name"`
+	UniqueID `yaml:",inline"`
+	RuleName string   `yaml:"rule_name" json:"rule_name"`
 	Params   []string `yaml:"params"`
+	// ValidationParams is a JSON object holding the parameters of the rule, for example {"length": 64} for max_length
+	ValidationParams string `yaml:"validation_params" json:"validation_params"`
 }
 
 type AttributeRow struct {
-	UniqueID
+	UniqueID      `yaml:",inline"`
 	TypeId        int64   `yaml:"type_id" json:"type_id"`
 	ValidationIds []int64 `yaml:"validations" json:"validations"`
+	// NotNull columns are created NOT NULL, the fields of the other columns are sql.Null* types or pointers
+	NotNull bool `yaml:"not_null" json:"not_null"`
+	// Default is the SQL expression of the DEFAULT of the column, e.g. 0 or 'pending', none when empty
+	Default string `yaml:"default" json:"default"`
 }
 
 type Attribute struct {
-	UniqueID
+	UniqueID    `yaml:",inline"`
 	Type        TypeInfo     `yaml:"type"`
 	Validations []Validation `yaml:"validations"`
 }
@@ -48,13 +56,41 @@ const (
 	Referes
 )
 
+// Names of the relationship types in model definitions (relationships[].type)
+var relationTypeNames = map[RelationType]string{
+	Child:     "Child",
+	Children:  "Children",
+	BelongsTo: "BelongsTo",
+	Referes:   "Refers",
+}
+
+func (r RelationType) String() string {
+	if name, ok := relationTypeNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("RelationType(%d)", int(r))
+}
+
+// ParseRelationType reads a relationship type name, Referes is accepted for Refers
+func ParseRelationType(name string) (RelationType, error) {
+	if name == "Referes" {
+		return Referes, nil
+	}
+	for relationType, typeName := range relationTypeNames {
+		if typeName == name {
+			return relationType, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown relationship type %q, expected Child, Children, BelongsTo or Refers", name)
+}
+
 type Constraint struct {
 	ConstraintName string      `yaml:"constraint_name"`
 	Attributes     []Attribute `yaml:"attributes"`
 }
 
 type Model struct {
-	UniqueID
+	UniqueID          `yaml:",inline"`
 	Attributes        []Attribute  `yaml:"attributes"`
 	UniqueConstraints []Constraint `yaml:"unique_constraints"`
 }
@@ -65,8 +101,8 @@ type DataAccessRequest struct {
 }
 
 type DataAccessDef struct {
-	UniqueID
-	Request DataAccessRequest `yaml:"request"`
+	UniqueID `yaml:",inline"`
+	Request  DataAccessRequest `yaml:"request"`
 }
 
 type TypeMapping struct {
@@ -4,6 +4,7 @@ go 1.22.0
 
 require (
 	github.com/iancoleman/strcase v0.3.0
+	github.com/lib/pq v1.12.3
 	github.com/stretchr/testify v1.9.0
 	golang.org/x/text v0.16.0
 	gopkg.in/yaml.v3 v3.0.1
@@ -31,7 +32,6 @@ require (
 
 require (
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
-	github.com/google/uuid v1.6.0
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/spf13/viper v1.19.0
 )
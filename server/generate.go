@@ -0,0 +1,275 @@
+package server
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/models"
+)
+
+// ErrInvalidRequest wraps every error caused by the posted data, as opposed to generation failures
+var ErrInvalidRequest = errors.New("invalid request")
+
+// Package of the generated data access code, matches dsgen generate
+const generatedModuleName = "database"
+
+type GeneratedQuery struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+type GeneratedModel struct {
+	Name    string           `json:"name"`
+	Table   string           `json:"table"`
+	DDL     string           `json:"ddl"`
+	Queries []GeneratedQuery `json:"queries"`
+}
+
+type GenerateResponse struct {
+	FamilyName string           `json:"family_name"`
+	DDL        string           `json:"ddl"`
+	Models     []GeneratedModel `json:"models"`
+	// Generated Go sources, migration files and go.mod, keyed by path relative to the module root
+	Files map[string]string `json:"files"`
+}
+
+// The generator reads attributes from the global catalog (config.Attributes),
+// so requests are generated one at a time, with the posted attributes layered over the loaded catalog
+var generateMu sync.Mutex
+
+// ToDataConfig converts posted models into the data config the generator works from
+func ToDataConfig(data RequestData) (*defs.DataConfig, error) {
+	if data.FamilyName == "" {
+		return nil, fmt.Errorf("%w: family_name is required", ErrInvalidRequest)
+	}
+
+	dataConfig := &defs.DataConfig{
+		FamilyName:     data.FamilyName,
+		Models:         make([]defs.ModelConfig, 0, len(data.Models)),
+		DatabaseConfig: data.DatabaseConfig,
+	}
+	if dataConfig.DatabaseConfig == nil {
+		// Placeholder connection for previews, only ends up in the generated SetupDBConnection
+		dataConfig.DatabaseConfig = &defs.DatabaseConfig{
+			DriverName:           "postgres",
+			UserName:             golang.ToSnakeCase(data.FamilyName) + "_user",
+			Password:             golang.ToSnakeCase(data.FamilyName) + "_password",
+			Host:                 "localhost",
+			Port:                 5432,
+			DBName:               golang.ToSnakeCase(data.FamilyName),
+			ConnectionConfig:     &defs.ConnectionConfig{IdleTimeoutSecs: 10, MaxLifetimeMins: 30},
+			ConnectionPoolConfig: &defs.ConnectionPoolConfig{MaxIdleConns: 5, MaxOpenConns: 10},
+		}
+	}
+
+	for _, model := range data.Models {
+		modelConfig := defs.ModelConfig{
+			Model: defs.Model{
+				ID:            model.ID,
+				Namespace:     model.Namespace,
+				Family:        model.Family,
+				Name:          model.Name,
+				Attributes:    toInt64s(model.Attributes),
+				Relationships: model.Relationships,
+				SoftDelete:    model.SoftDelete,
+				Audit:         model.Audit,
+			},
+			Access: model.Access,
+		}
+		for _, uc := range model.UniqueConstraints {
+			modelConfig.Model.UniqueConstraints = append(modelConfig.Model.UniqueConstraints,
+				defs.UniqueConstraint{ConstraintName: uc.ConstraintName, Attributes: toInt64s(uc.Attributes)})
+		}
+		for _, index := range model.Indexes {
+			modelConfig.Model.Indexes = append(modelConfig.Model.Indexes,
+				defs.ModelIndex{IndexName: index.IndexName, Attributes: toInt64s(index.Attributes)})
+		}
+		dataConfig.Models = append(dataConfig.Models, modelConfig)
+	}
+	return dataConfig, nil
+}
+
+// Generate runs the generation pipeline on the posted data: DDL (SchemaBuilder), the SQL of every access
+// and the Go sources of the data access module (generator.GenerateDB)
+func Generate(data RequestData) (response *GenerateResponse, err error) {
+	dataConfig, err := ToDataConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	generateMu.Lock()
+	defer generateMu.Unlock()
+
+	restore, err := useAttributes(data.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+
+	// Code elements panic on template errors, a bad request must not take the server down
+	defer func() {
+		if r := recover(); r != nil {
+			response, err = nil, fmt.Errorf("generating family %s: %v", dataConfig.FamilyName, r)
+		}
+	}()
+
+	if err := generator.ValidateDataConfig(dataConfig); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	response = &GenerateResponse{
+		FamilyName: dataConfig.FamilyName,
+		Models:     make([]GeneratedModel, 0, len(dataConfig.Models)),
+		Files:      map[string]string{},
+	}
+
+	dialect, err := datahelpers.DialectForDriver(dataConfig.DatabaseConfig.DriverName)
+	if err != nil {
+		return nil, err
+	}
+	schemaBuilder := datahelpers.NewSchemaBuilder(dialect, dataConfig.DatabaseConfig.DBName, *dataConfig)
+	// Tables follow the tables they reference, the family was validated to have no cycles
+	orderedModels, err := dataConfig.ModelsInReferenceOrder()
+	if err != nil {
+		return nil, err
+	}
+	var ddl strings.Builder
+	for _, modelConfig := range orderedModels {
+		tableDDL := schemaBuilder.BuildCreateTable(modelConfig)
+		ddl.WriteString(tableDDL)
+		ddl.WriteString("\n")
+
+		queries, err := generator.ExplainModel(*modelConfig, dataConfig)
+		if err != nil {
+			return nil, fmt.Errorf("model %s: %w", modelConfig.Model.Name, err)
+		}
+		generatedModel := GeneratedModel{
+			Name:    modelConfig.Model.Name,
+			Table:   golang.ToSnakeCase(modelConfig.Model.Name),
+			DDL:     tableDDL,
+			Queries: make([]GeneratedQuery, 0, len(queries)),
+		}
+		for _, query := range queries {
+			generatedModel.Queries = append(generatedModel.Queries, GeneratedQuery{Name: query.Name, Query: query.Query})
+		}
+		response.Models = append(response.Models, generatedModel)
+	}
+	// The events of all the models go to one outbox, see BuildCreateOutboxTable
+	if dataConfig.HasEvents() {
+		ddl.WriteString(schemaBuilder.BuildCreateOutboxTable())
+		ddl.WriteString("\n\n")
+	}
+	response.DDL = ddl.String()
+
+	unitModules, err := generator.GenerateDB(dataConfig)
+	if err != nil {
+		return nil, fmt.Errorf("generating family %s: %w", dataConfig.FamilyName, err)
+	}
+	cleanDeps := map[string]string{}
+	for _, unit := range unitModules {
+		src, deps, err := unit.GenerateCode(generatedModuleName)
+		if err != nil {
+			return nil, err
+		}
+		for dep := range deps {
+			cleanDeps[dep.Source] = dep.Version
+		}
+		response.Files[path.Join(generatedModuleName, unit.Name+".go")] = src
+		for _, file := range unit.Files {
+			response.Files[path.Join(generatedModuleName, file.Path)] = file.Content
+		}
+	}
+	driver, err := generator.DriverRequirement(dataConfig)
+	if err != nil {
+		return nil, err
+	}
+	project := golang.Project{
+		Name:         golang.ToSnakeCase(dataConfig.FamilyName),
+		GoVersion:    "1.22",
+		Requirements: []*golang.ProjectRequirement{driver},
+	}
+	response.Files["go.mod"] = golang.GoModContent(project, cleanDeps)
+
+	return response, nil
+}
+
+// WriteZip writes the generated files, schema.sql and queries.sql into a zip archive
+func (r *GenerateResponse) WriteZip(w io.Writer) error {
+	var queries strings.Builder
+	for _, model := range r.Models {
+		fmt.Fprintf(&queries, "-- model %s\n", model.Name)
+		for _, query := range model.Queries {
+			fmt.Fprintf(&queries, "-- %s\n%s;\n\n", query.Name, query.Query)
+		}
+	}
+
+	files := map[string]string{
+		"schema.sql":  r.DDL,
+		"queries.sql": queries.String(),
+	}
+	for name, content := range r.Files {
+		files[name] = content
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		fw, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, files[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// useAttributes layers the posted attributes over config.Attributes, the returned func restores the catalog
+func useAttributes(attributes []Attribute) (func(), error) {
+	saved := config.Attributes
+	merged := make(map[int64]models.AttributeRow, len(saved)+len(attributes))
+	for id, attribute := range saved {
+		merged[id] = attribute
+	}
+	for _, attr := range attributes {
+		if _, ok := config.Types[int64(attr.TypeID)]; !ok {
+			return nil, fmt.Errorf("%w: attribute %d (%s) has unknown type %d", ErrInvalidRequest, attr.ID, attr.Name, attr.TypeID)
+		}
+		merged[int64(attr.ID)] = models.AttributeRow{
+			UniqueID: models.UniqueID{
+				ID:        int64(attr.ID),
+				Namespace: attr.Namespace,
+				Family:    attr.Family,
+				Name:      attr.Name,
+			},
+			TypeId:        int64(attr.TypeID),
+			ValidationIds: toInt64s(attr.Validations),
+		}
+	}
+	config.Attributes = merged
+	return func() { config.Attributes = saved }, nil
+}
+
+func toInt64s(ids []int) []int64 {
+	result := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, int64(id))
+	}
+	return result
+}
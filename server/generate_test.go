@@ -0,0 +1,112 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+)
+
+const bookRequest = `{
+	"family_name": "Shop",
+	"attributes": [{"id": 9000001, "name": "title", "type_id": 1000001}],
+	"models": [{
+		"id": 1,
+		"name": "Book",
+		"attributes": [9000001, 2000004],
+		"access": {
+			"find": [{
+				"name": "GetBookByTitle",
+				"attributes": ["id", "title", "price"],
+				"filter": [{"attribute": "title", "operator": "=", "param_name": "title"}]
+			}]
+		}
+	}]
+}`
+
+func postGenerate(t *testing.T, target string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	GenerateSQLHandler(rec, req)
+	return rec
+}
+
+func TestGenerateSQLHandler_JSON(t *testing.T) {
+	config.LoadConfig()
+
+	rec := postGenerate(t, "/generate-sql", bookRequest)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var response GenerateResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "Shop", response.FamilyName)
+	assert.Contains(t, response.DDL, "CREATE TABLE \"book\"")
+	assert.Equal(t, []GeneratedQuery{
+		{Name: "GetBookByTitle", Query: `SELECT "id", "title", "price" FROM "book" WHERE (1 = 1) AND ("title" = $1)`},
+	}, response.Models[0].Queries)
+	assert.Contains(t, response.Files["database/book.go"], "func GetBookByTitle(")
+	assert.Contains(t, response.Files, "database/Shop.go")
+	assert.Contains(t, response.Files["go.mod"], "module shop")
+	assert.Contains(t, response.Files["database/migrate.go"], "func Migrate(ctx context.Context, db *sql.DB) error {")
+	assert.Equal(t, "DROP TABLE \"book\";\n", response.Files["database/migrations/0001_init.down.sql"])
+
+	// Posted attributes only live for the request
+	_, ok := config.Attributes[9000001]
+	assert.False(t, ok)
+}
+
+func TestGenerateSQLHandler_Zip(t *testing.T) {
+	config.LoadConfig()
+
+	rec := postGenerate(t, "/generate-sql?format=zip", bookRequest)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	assert.NoError(t, err)
+	names := []string{}
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"database/Shop.go", "database/audit.go", "database/batch.go", "database/book.go", "database/lock.go",
+		"database/migrate.go",
+		"database/migrations/0001_init.down.sql", "database/migrations/0001_init.up.sql", "database/outbox.go", "database/pagination.go", "database/sort.go", "database/validation.go", "go.mod", "queries.sql", "schema.sql"}, names)
+}
+
+func TestGenerateSQLHandler_Errors(t *testing.T) {
+	config.LoadConfig()
+
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		expected int
+		message  string
+	}{
+		{name: "method", method: http.MethodGet, expected: http.StatusMethodNotAllowed},
+		{name: "malformed json", method: http.MethodPost, body: `{`, expected: http.StatusBadRequest},
+		{name: "missing family", method: http.MethodPost, body: `{"models": []}`, expected: http.StatusBadRequest,
+			message: "family_name is required"},
+		{name: "unknown attribute", method: http.MethodPost, body: `{"family_name": "x", "models": [{"name": "A", "attributes": [1]}]}`,
+			expected: http.StatusBadRequest, message: "model A: attribute 1 not found in catalog"},
+		{name: "unknown type", method: http.MethodPost, body: `{"family_name": "x", "attributes": [{"id": 1, "name": "a", "type_id": 7}]}`,
+			expected: http.StatusBadRequest, message: "attribute 1 (a) has unknown type 7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/generate-sql", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			GenerateSQLHandler(rec, req)
+			assert.Equal(t, tt.expected, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.message)
+		})
+	}
+}
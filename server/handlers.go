@@ -3,14 +3,20 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
-	"text/template"
 
-	"github.com/google/uuid"
+	"stellarsky.ai/platform/codegen/data-service-generator/base"
+	"stellarsky.ai/platform/codegen/data-service-generator/constructs/golang"
+	"stellarsky.ai/platform/codegen/data-service-generator/db/generator"
+	datahelpers "stellarsky.ai/platform/codegen/data-service-generator/db/generator/data-helpers"
 )
 
+// GenerateSQLHandler generates DDL, per access SQL and the Go data access module for the posted
+// attributes and models (see RequestData). The response is JSON (GenerateResponse), or a zip archive
+// with ?format=zip or "Accept: application/zip".
 func GenerateSQLHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
@@ -24,133 +30,63 @@ func GenerateSQLHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate SQL from the received data
-	sqlFiles, err := generateSQL(data)
+	response, err := Generate(data)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		base.LOG.Error("GenerateSQLHandler", "error", err, "family", data.FamilyName)
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidRequest) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
 
-	// Respond with generated SQL files (example just returns DDL SQL)
-	fmt.Fprintf(w, "DDL SQL:\n%s", sqlFiles.DDL)
-}
-
-// generateSQL generates DDL, DML, and Query SQL based on the provided data
-func generateSQL(data RequestData) (struct {
-	DDL, DML, Query string
-}, error) {
-	// Placeholder for SQL generation logic
-	return struct {
-		DDL, DML, Query string
-	}{
-		DDL:   "CREATE TABLE example (...);",
-		DML:   "INSERT INTO example (...);",
-		Query: "SELECT * FROM example;",
-	}, nil
-}
-
-// Function to generate DDL SQL for a given model using text/template
-func GenerateDDL(model Model, attributes []Attribute) (string, error) {
-	attrMap := make(map[int]Attribute)
-	for _, attr := range attributes {
-		attrMap[attr.ID] = attr
+	if r.URL.Query().Get("format") == "zip" || strings.Contains(r.Header.Get("Accept"), "application/zip") {
+		var archive bytes.Buffer
+		if err := response.WriteZip(&archive); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", golang.ToSnakeCase(response.FamilyName)+".zip"))
+		w.Write(archive.Bytes())
+		return
 	}
 
-	id, _ := uuid.NewV7()
-	id.ID()
-	type TableColumn struct {
-		ColumnName string
-		Type       string
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		base.LOG.Error("GenerateSQLHandler encoding response", "error", err)
 	}
+}
 
-	var columns []TableColumn
-	columns = append(columns, TableColumn{ColumnName: "id", Type: "UUID PRIMARY KEY"})
-	for _, attrID := range model.Attributes {
-		attr, exists := attrMap[attrID]
-		if !exists {
-			return "", fmt.Errorf("attribute ID %d not found", attrID)
-		}
-		columns = append(columns, TableColumn{ColumnName: attr.Name, Type: resolvePostgresType(attr.TypeID)})
+// GenerateDDL returns the CREATE TABLE and CREATE INDEX statements of a model, the same DDL
+// the generation pipeline (Generate, dsgen ddl) produces for it
+func GenerateDDL(model Model, attributes []Attribute) (string, error) {
+	familyName := model.Family
+	if familyName == "" {
+		familyName = model.Name
 	}
-	columns = append(columns, TableColumn{ColumnName: "created_at", Type: "TIMESTAMP WITH TIME ZONE"})
-	columns = append(columns, TableColumn{ColumnName: "updated_at", Type: "TIMESTAMP WITH TIME ZONE"})
-	columns = append(columns, TableColumn{ColumnName: "updated_id", Type: "BIGINT"})
-	columns = append(columns, TableColumn{ColumnName: "deleted_at", Type: "TIMESTAMP WITH TIME ZONE"})
-	columns = append(columns, TableColumn{ColumnName: "is_deleted", Type: "BOOLEAN"})
-	columns = append(columns, TableColumn{ColumnName: "version", Type: "INT"})
-
-	// Setup the template
-	const tableTemplate = `CREATE TABLE {{.Namespace}}.{{.Name}} (
-    {{- range $index, $col := .Columns}}
-    {{if $index}},{{end}}{{$col.ColumnName}} {{$col.Type}}
-	{{- end}}
-    {{- if .Constraints}}
-    ,{{- range $index, $constraint := .Constraints}}{{if $index}},{{end}}UNIQUE ({{join $constraint.Columns ","}})
-    {{- end}}
-    {{- end}}
-);`
-
-	tmpl, err := template.New("table").Funcs(template.FuncMap{
-		"join": strings.Join,
-	}).Parse(tableTemplate)
+	dataConfig, err := ToDataConfig(RequestData{FamilyName: familyName, Attributes: attributes, Models: []Model{model}})
 	if err != nil {
 		return "", err
 	}
 
-	var constraints []struct {
-		Name    string
-		Columns []string
-	}
+	generateMu.Lock()
+	defer generateMu.Unlock()
 
-	for _, uc := range model.UniqueConstraints {
-		var ucColumns []string
-		for _, attrID := range uc.Attributes {
-			attr, exists := attrMap[attrID]
-			if !exists {
-				return "", fmt.Errorf("attribute ID %d not found for unique constraint", attrID)
-			}
-			ucColumns = append(ucColumns, attr.Name)
-		}
-		constraints = append(constraints, struct {
-			Name    string
-			Columns []string
-		}{Name: uc.ConstraintName, Columns: ucColumns})
+	restore, err := useAttributes(attributes)
+	if err != nil {
+		return "", err
 	}
+	defer restore()
 
-	data := struct {
-		Namespace   string
-		Name        string
-		Columns     []TableColumn
-		Constraints []struct {
-			Name    string
-			Columns []string
-		}
-	}{
-		Namespace:   model.Namespace,
-		Name:        model.Name,
-		Columns:     columns,
-		Constraints: constraints,
+	if err := generator.ValidateDataConfig(dataConfig); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidRequest, err)
 	}
-
-	var sql bytes.Buffer
-	if err = tmpl.Execute(&sql, data); err != nil {
+	dialect, err := datahelpers.DialectForDriver(dataConfig.DatabaseConfig.DriverName)
+	if err != nil {
 		return "", err
 	}
-
-	return sql.String(), nil
-}
-
-// Dummy function to resolve PostgreSQL data types based on TypeID
-func resolvePostgresType(typeID int) string {
-	// Implement actual logic based on your type mappings
-	switch typeID {
-	case 1:
-		return "VARCHAR(255)"
-	case 2:
-		return "TEXT"
-	case 3:
-		return "MONEY"
-	default:
-		return "VARCHAR(255)"
-	}
+	schemaBuilder := datahelpers.NewSchemaBuilder(dialect, dataConfig.DatabaseConfig.DBName, *dataConfig)
+	return schemaBuilder.BuildCreateTable(&dataConfig.Models[0]), nil
 }
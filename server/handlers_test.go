@@ -0,0 +1,69 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"stellarsky.ai/platform/codegen/data-service-generator/config"
+)
+
+func TestGenerateDDL(t *testing.T) {
+	config.LoadConfig()
+
+	attributes := []Attribute{
+		{ID: 9000001, Name: "sku", TypeID: 1000001},
+		{ID: 9000002, Name: "price", TypeID: 1000005},
+	}
+	model := Model{
+		ID:         1,
+		Namespace:  "public",
+		Family:     "product",
+		Name:       "product",
+		Attributes: []int{9000001, 9000002},
+		UniqueConstraints: []struct {
+			ConstraintName string `json:"constraint_name"`
+			Attributes     []int  `json:"attributes"`
+		}{
+			{ConstraintName: "sku_unique", Attributes: []int{9000001}},
+		},
+	}
+
+	expected := "CREATE TABLE \"product\" (\n" +
+		"\t\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n" +
+		"\t\"version\" INTEGER NOT NULL DEFAULT 1,\n" +
+		"\t\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+		"\t\"sku\" TEXT,\n" +
+		"\t\"price\" NUMERIC(33,18)\n" +
+		");\n\n" +
+		"CREATE UNIQUE INDEX ON \"product\" (\"sku\");\n"
+
+	ddl, err := GenerateDDL(model, attributes)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, ddl)
+
+	// Same table as the generation pipeline
+	response, err := Generate(RequestData{FamilyName: "product", Attributes: attributes, Models: []Model{model}})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, response.Models[0].DDL)
+
+	// Soft delete tables have a deleted_at column, their unique indexes leave the deleted rows out
+	model.SoftDelete = true
+	ddl, err = GenerateDDL(model, attributes)
+	assert.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE \"product\" (\n"+
+		"\t\"id\" UUID PRIMARY KEY DEFAULT gen_random_uuid(),\n"+
+		"\t\"version\" INTEGER NOT NULL DEFAULT 1,\n"+
+		"\t\"updated_at\" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,\n"+
+		"\t\"deleted_at\" TIMESTAMP WITH TIME ZONE,\n"+
+		"\t\"sku\" TEXT,\n"+
+		"\t\"price\" NUMERIC(33,18)\n"+
+		");\n\n"+
+		"CREATE UNIQUE INDEX ON \"product\" (\"sku\") WHERE (deleted_at IS NULL);\n", ddl)
+	model.SoftDelete = false
+
+	_, err = GenerateDDL(Model{Name: "product", Attributes: []int{9000003}}, attributes)
+	assert.ErrorContains(t, err, "attribute 9000003 not found in catalog")
+
+	_, err = GenerateDDL(model, []Attribute{{ID: 9000001, Name: "sku", TypeID: 3}})
+	assert.ErrorContains(t, err, "unknown type 3")
+}
@@ -1,5 +1,7 @@
 package server
 
+import "stellarsky.ai/platform/codegen/data-service-generator/db/generator/defs"
+
 // Define structures to match your JSON input for attributes and models
 type Attribute struct {
 	ID          int    `json:"id"`
@@ -21,13 +23,22 @@ type Model struct {
 		ConstraintName string `json:"constraint_name"`
 		Attributes     []int  `json:"attributes"`
 	} `json:"unique_constraints,omitempty"`
-	Relationships []struct {
-		Type          string `json:"type"`
-		TargetModelID int    `json:"target_model_id"`
-	} `json:"relationships,omitempty"`
+	Indexes []struct {
+		IndexName  string `json:"index_name"`
+		Attributes []int  `json:"attributes"`
+	} `json:"indexes,omitempty"`
+	Relationships []defs.Relationship `json:"relationships,omitempty"`
+	// SoftDelete keeps deleted rows in a deleted_at column, see defs.Model.SoftDelete
+	SoftDelete bool `json:"soft_delete,omitempty"`
+	// Audit keeps the history of the rows in a <model>_history table, see defs.Model.Audit
+	Audit  bool        `json:"audit,omitempty"`
+	Access defs.Access `json:"access"`
 }
 
 type RequestData struct {
+	FamilyName string      `json:"family_name"`
 	Attributes []Attribute `json:"attributes"`
 	Models     []Model     `json:"models"`
+	// Optional, only used for the connection setup of the generated code
+	DatabaseConfig *defs.DatabaseConfig `json:"connection_config,omitempty"`
 }